@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	enclaveConfig "github.com/EnclaveRunner/shareddeps/config"
 )
 
@@ -9,14 +11,103 @@ type AppConfig struct {
 
 	StorageDir string `mapstructure:"storage_dir" validate:"omitempty"`
 
+	// Port of the HTTP gateway served next to gRPC. Zero, the default,
+	// disables it.
+	HTTPPort int `mapstructure:"http_port" validate:"min=0,max=65535"`
+
+	// Port of the Prometheus metrics endpoint. Zero disables it.
+	MetricsPort int `mapstructure:"metrics_port" validate:"min=0,max=65535"`
+
+	// Driver is "postgres" or "sqlite". SQLite keeps the metadata in the
+	// file at Path and ignores the connection settings, it suits single-node
+	// deployments and tests.
 	Database struct {
+		Driver   string `mapstructure:"driver"   validate:"oneof=postgres sqlite"`
+		Path     string `mapstructure:"path"     validate:"required_if=Driver sqlite"`
 		Host     string `mapstructure:"host"     validate:"required,hostname|ip"`
 		Port     int    `mapstructure:"port"     validate:"required,numeric,min=1,max=65535"`
 		Username string `mapstructure:"username" validate:"required"`
 		Password string `mapstructure:"password" validate:"required"`
 		Database string `mapstructure:"database" validate:"required"`
 		SSLMode  string `mapstructure:"sslmode"  validate:"oneof=disable require verify-ca verify-full"`
+
+		// Apply pending migrations on start. Disable to run them with the
+		// migrate subcommand instead.
+		AutoMigrate bool `mapstructure:"auto_migrate"`
 	} `mapstructure:"database" validate:"required"`
+
+	Compatibility struct {
+		// Tags whose Wasm interface must not break when they are moved to
+		// another version, e.g. "stable". Empty disables the check.
+		ProtectedTags []string `mapstructure:"protected_tags"`
+	} `mapstructure:"compatibility"`
+
+	// Quota applied to namespaces that are created implicitly by an upload or
+	// without an explicit quota. Zero means unlimited.
+	Quota struct {
+		MaxBytes    int64 `mapstructure:"max_bytes"    validate:"min=0"`
+		MaxVersions int64 `mapstructure:"max_versions" validate:"min=0"`
+	} `mapstructure:"quota"`
+
+	Trash struct {
+		// How long deleted versions can be restored before they are purged.
+		// Zero deletes versions immediately, versions still in the trash
+		// from before are all purged on the next run.
+		Retention     time.Duration `mapstructure:"retention"      validate:"min=0"`
+		PurgeInterval time.Duration `mapstructure:"purge_interval" validate:"gt=0"`
+	} `mapstructure:"trash"`
+
+	// Blobs pushed through the OCI API are staged in StagingDir until a
+	// manifest references them. Replicas behind one endpoint must share it.
+	// Blobs larger than MaxUploadSize bytes are rejected, zero means
+	// unlimited.
+	OCI struct {
+		StagingDir    string `mapstructure:"staging_dir"     validate:"omitempty"`
+		MaxUploadSize int64  `mapstructure:"max_upload_size" validate:"min=0"`
+	} `mapstructure:"oci"`
+
+	// How long the old name of a moved package keeps resolving
+	RedirectTTL time.Duration `mapstructure:"redirect_ttl" validate:"min=0"`
+
+	// Pull-through proxy mode, in which versions that are missing locally
+	// are fetched from an upstream registry. Disabled without upstream host.
+	// Tags resolved through the upstream are cached for TagTTL.
+	Proxy struct {
+		UpstreamHost string        `mapstructure:"upstream_host" validate:"omitempty,hostname|ip"`
+		UpstreamPort int           `mapstructure:"upstream_port" validate:"min=1,max=65535"`
+		TagTTL       time.Duration `mapstructure:"tag_ttl"       validate:"min=0"`
+	} `mapstructure:"proxy"`
+
+	// Peers, given as host:port, to which uploads, deletions and tag changes
+	// are replicated. Failed attempts are retried with exponential backoff
+	// starting at RetryInterval.
+	Replication struct {
+		Peers            []string      `mapstructure:"peers"              validate:"dive,hostname_port"`
+		PollInterval     time.Duration `mapstructure:"poll_interval"      validate:"gt=0"`
+		RetryInterval    time.Duration `mapstructure:"retry_interval"     validate:"gt=0"`
+		MaxRetryInterval time.Duration `mapstructure:"max_retry_interval" validate:"gtefield=RetryInterval"`
+	} `mapstructure:"replication"`
+
+	// Pulls are counted in hourly and daily buckets, which are pruned once
+	// they are older than the respective retention. Pulls are buffered in
+	// memory and written every FlushInterval, zero writes every pull
+	// immediately.
+	PullStats struct {
+		HourlyRetention time.Duration `mapstructure:"hourly_retention" validate:"gt=0"`
+		DailyRetention  time.Duration `mapstructure:"daily_retention"  validate:"gtefield=HourlyRetention"`
+		PruneInterval   time.Duration `mapstructure:"prune_interval"   validate:"gt=0"`
+		FlushInterval   time.Duration `mapstructure:"flush_interval"   validate:"min=0"`
+	} `mapstructure:"pull_stats"`
+
+	// Exporter is one of "none", "stdout" or "otlp". SampleRatio applies to
+	// traces started by the registry, incoming trace contexts keep their
+	// sampling decision.
+	Tracing struct {
+		Exporter     string  `mapstructure:"exporter"      validate:"oneof=none stdout otlp"`
+		OTLPEndpoint string  `mapstructure:"otlp_endpoint" validate:"required_if=Exporter otlp"`
+		OTLPInsecure bool    `mapstructure:"otlp_insecure"`
+		SampleRatio  float64 `mapstructure:"sample_ratio"  validate:"min=0,max=1"`
+	} `mapstructure:"tracing"`
 }
 
 //nolint:mnd // Default port for gRPC service
@@ -25,11 +116,48 @@ var Defaults = []enclaveConfig.DefaultValue{
 	{Key: "log_level", Value: "info"},
 	{Key: "human_readable_output", Value: "true"},
 	{Key: "storage_dir", Value: "/data"},
+	{Key: "http_port", Value: 0},
+	{Key: "metrics_port", Value: 9090},
 
+	{Key: "database.driver", Value: "postgres"},
+	{Key: "database.path", Value: "/data/registry.db"},
 	{Key: "database.port", Value: 5432},
 	{Key: "database.host", Value: "localhost"},
 	{Key: "database.sslmode", Value: "disable"},
 	{Key: "database.username", Value: "enclave_user"},
 	{Key: "database.password", Value: "enclave_password"},
 	{Key: "database.database", Value: "enclave_db"},
+	{Key: "database.auto_migrate", Value: true},
+
+	{Key: "compatibility.protected_tags", Value: []string{}},
+
+	{Key: "quota.max_bytes", Value: 0},
+	{Key: "quota.max_versions", Value: 0},
+
+	{Key: "trash.retention", Value: "168h"},
+	{Key: "trash.purge_interval", Value: "1h"},
+
+	{Key: "oci.staging_dir", Value: "/data/.oci-staging"},
+	{Key: "oci.max_upload_size", Value: 256 << 20},
+
+	{Key: "redirect_ttl", Value: "720h"},
+
+	{Key: "proxy.upstream_host", Value: ""},
+	{Key: "proxy.upstream_port", Value: 9876},
+	{Key: "proxy.tag_ttl", Value: "5m"},
+
+	{Key: "replication.peers", Value: []string{}},
+	{Key: "replication.poll_interval", Value: "5s"},
+	{Key: "replication.retry_interval", Value: "1s"},
+	{Key: "replication.max_retry_interval", Value: "5m"},
+
+	{Key: "pull_stats.hourly_retention", Value: "168h"},
+	{Key: "pull_stats.daily_retention", Value: "8760h"},
+	{Key: "pull_stats.prune_interval", Value: "1h"},
+	{Key: "pull_stats.flush_interval", Value: "10s"},
+
+	{Key: "tracing.exporter", Value: "none"},
+	{Key: "tracing.otlp_endpoint", Value: "localhost:4317"},
+	{Key: "tracing.otlp_insecure", Value: false},
+	{Key: "tracing.sample_ratio", Value: 1.0},
 }
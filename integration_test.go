@@ -6,44 +6,96 @@ import (
 	"artifact-registry/proto_gen"
 	"artifact-registry/registry"
 	"artifact-registry/registry/memoryRegistry"
+	"artifact-registry/tracing"
+	"artifact-registry/wasm"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/EnclaveRunner/shareddeps"
+	"github.com/EnclaveRunner/shareddeps/auth"
 	configShareddeps "github.com/EnclaveRunner/shareddeps/config"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var (
 	usedPorts     = map[int]bool{}
 	usedPortsLock sync.Mutex
-	dbInitOnce    sync.Once
-	sharedDB      orm.DB
+	// Holds the SQLite database unless the tests run against Postgres
+	dbDir string
 )
 
-func configureServer(
+// TestMain runs the tests against SQLite, or against Postgres if
+// ENCLAVE_DATABASE_DRIVER is set to postgres
+func TestMain(m *testing.M) {
+	var err error
+	dbDir, err = os.MkdirTemp("", "artifact-registry-test")
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dbDir)
+	os.Exit(code)
+}
+
+// testConfig returns the configuration of a test server. All servers share
+// one database, like replicas do.
+func testConfig(
 	t *testing.T,
 	storageDir string,
-) (registryClient proto_gen.RegistryServiceClient, startServer func()) {
+	extraDefaults ...configShareddeps.DefaultValue,
+) *config.AppConfig {
 	t.Helper()
-	port := getAvailablePort(t)
 
-	defaults := []configShareddeps.DefaultValue{
-		{Key: "port", Value: port},
+	// The registry's own defaults fill in everything tests do not override
+	defaults := slices.Clone(config.Defaults)
+	defaults = append(defaults, []configShareddeps.DefaultValue{
 		{Key: "production_environment", Value: false},
 		{Key: "log_level", Value: "debug"},
 		{Key: "human_readable_output", Value: true},
 		{Key: "storage_dir", Value: storageDir},
+		{
+			Key:   "oci.staging_dir",
+			Value: filepath.Join(storageDir, ".oci-staging"),
+		},
 
+		{Key: "database.driver", Value: orm.DriverSQLite},
+		{Key: "database.path", Value: filepath.Join(dbDir, "registry.db")},
 		{Key: "database.port", Value: 5432},
 		{Key: "database.host", Value: "localhost"},
 		{Key: "database.sslmode", Value: "disable"},
 		{Key: "database.username", Value: "enclave_user"},
 		{Key: "database.password", Value: "enclave_password"},
 		{Key: "database.database", Value: "enclave_db"},
-	}
+
+		// Pull counts are checked right after the pulls
+		{Key: "pull_stats.flush_interval", Value: 0},
+	}...)
+	defaults = append(defaults, extraDefaults...)
 
 	cfg := &config.AppConfig{}
 	err := configShareddeps.PopulateAppConfig(
@@ -53,19 +105,54 @@ func configureServer(
 		defaults...)
 	assert.NoError(t, err)
 
-	memRegistry := memoryRegistry.New()
+	return cfg
+}
 
-	// Initialize DB only once across all tests to avoid migration conflicts
-	dbInitOnce.Do(func() {
-		sharedDB = orm.InitDB(cfg)
-	})
+// testDB connects to the shared database and migrates it, like every
+// replica does on startup
+func testDB(t *testing.T) *orm.DB {
+	t.Helper()
+
+	db := orm.InitDB(testConfig(t, t.TempDir()))
 
-	server := shareddeps.InitGRPCServer()
+	return &db
+}
+
+func configureServer(
+	t *testing.T,
+	storageDir string,
+	extraDefaults ...configShareddeps.DefaultValue,
+) (registryClient proto_gen.RegistryServiceClient, startServer func()) {
+	t.Helper()
+	port := getAvailablePort(t)
 
-	proto_gen.RegisterRegistryServiceServer(
-		server,
-		registry.NewServer(memRegistry, sharedDB),
+	cfg := testConfig(
+		t,
+		storageDir,
+		append(
+			[]configShareddeps.DefaultValue{{Key: "port", Value: port}},
+			extraDefaults...,
+		)...,
+	)
+	memRegistry := memoryRegistry.New()
+	db := orm.InitDB(cfg)
+
+	server := grpc.NewServer(tracing.ServerOptions()...)
+
+	registryServer := registry.NewServer(
+		registry.InstrumentRegistry(memRegistry, "memory"),
+		&db,
+		cfg,
 	)
+	proto_gen.RegisterRegistryServiceServer(server, registryServer)
+
+	// The port can be overridden to know it before the server is created
+	if cfg.Port != port {
+		usedPortsLock.Lock()
+		usedPorts[port] = false
+		usedPortsLock.Unlock()
+		port = cfg.Port
+	}
 
 	client := proto_gen.NewRegistryServiceClient(
 		shareddeps.InitGRPCClient(
@@ -80,6 +167,11 @@ func configureServer(
 			usedPorts[port] = false
 			usedPortsLock.Unlock()
 		}()
+		go registryServer.RunReplication(t.Context())
+		go registryServer.RunPullFlusher(
+			t.Context(),
+			cfg.PullStats.FlushInterval,
+		)
 		shareddeps.StartGRPCServer(cfg, server)
 	}
 }
@@ -508,210 +600,2220 @@ func TestQueryArtifacts(t *testing.T) {
 	assert.Equal(t, "query-app1", resp.Artifacts[0].Package.Name)
 }
 
-// TestDeleteArtifact tests artifact deletion
-func TestDeleteArtifact(t *testing.T) {
+// wasmModule returns a minimal core module that imports env.log and exports a
+// single function with the given (three letter) name
+func wasmModule(export string) []byte {
+	module := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		// type section: () -> ()
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		// import section: env.log
+		0x02, 0x0b, 0x01, 0x03, 'e', 'n', 'v', 0x03, 'l', 'o', 'g', 0x00, 0x00,
+		// function section
+		0x03, 0x02, 0x01, 0x00,
+		// export section
+		0x07, 0x07, 0x01, 0x03,
+	}
+	module = append(module, export[:3]...)
+	module = append(module,
+		0x00, 0x01,
+		// code section
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b,
+	)
+
+	return module
+}
+
+// TestQueryArtifactsBySymbol tests that the Wasm interface is extracted on
+// upload and can be used to filter queries
+func TestQueryArtifactsBySymbol(t *testing.T) {
 	t.Parallel()
 
 	client, startServer := configureServer(t, t.TempDir())
 	go startServer()
 
-	fqn := &proto_gen.PackageName{
-		Namespace: "delete-test-user",
-		Name:      "delete-test-app",
-	}
-	tags := []string{"v1.0.0"}
-	content := []byte("to be deleted")
-
-	artifact := uploadArtifact(t, client, fqn, tags, content)
+	ns := "query-symbol-test"
+	runner := uploadArtifact(
+		t,
+		client,
+		&proto_gen.PackageName{Namespace: ns, Name: "runner"},
+		[]string{"v1"},
+		wasmModule("run"),
+	)
+	uploadArtifact(
+		t,
+		client,
+		&proto_gen.PackageName{Namespace: ns, Name: "starter"},
+		[]string{"v1"},
+		wasmModule("go_"),
+	)
+	uploadArtifact(
+		t,
+		client,
+		&proto_gen.PackageName{Namespace: ns, Name: "opaque"},
+		[]string{"v1"},
+		[]byte("not a wasm binary"),
+	)
 
-	// Verify artifact exists
-	getReq := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
-			VersionHash: artifact.VersionHash,
-		},
-	}
+	assert.NotNil(t, runner.Interface)
+	assert.Len(t, runner.Interface.Imports, 1)
+	assert.Equal(t, "env", runner.Interface.Imports[0].Module)
+	assert.Equal(t, "log", runner.Interface.Imports[0].Name)
+	assert.Len(t, runner.Interface.Exports, 1)
+	assert.Equal(t, "run", runner.Interface.Exports[0].Name)
+	assert.Equal(t, "func", runner.Interface.Exports[0].Kind)
+	assert.Equal(t, "() -> ()", runner.Interface.Exports[0].Signature)
 
-	retrieved, err := client.GetArtifact(t.Context(), getReq)
+	resp, err := client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Namespace: &ns,
+		Exports:   []*proto_gen.SymbolFilter{{Name: "run"}},
+	})
 	assert.NoError(t, err)
-	assert.NotNil(t, retrieved)
+	assert.Len(t, resp.Artifacts, 1)
+	assert.Equal(t, runner.VersionHash, resp.Artifacts[0].VersionHash)
+	assert.NotNil(t, resp.Artifacts[0].Interface)
 
-	// Delete the artifact
-	deleted, err := client.DeleteArtifact(t.Context(), getReq)
+	module := "env"
+	resp, err = client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Namespace: &ns,
+		Imports: []*proto_gen.SymbolFilter{
+			{Module: &module, Name: "log"},
+		},
+	})
 	assert.NoError(t, err)
-	assert.NotNil(t, deleted)
-	assert.Equal(t, artifact.VersionHash, deleted.VersionHash)
-
-	// Verify artifact no longer exists
-	_, err = client.GetArtifact(t.Context(), getReq)
-	assert.Error(t, err)
+	assert.Len(t, resp.Artifacts, 2)
 
-	// Verify pull fails
-	pullStream, err := client.PullArtifact(t.Context(), getReq)
+	// The non-Wasm artifact is stored but has no interface
+	resp, err = client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Namespace: &ns,
+	})
 	assert.NoError(t, err)
-	_, err = pullStream.Recv()
+	assert.Len(t, resp.Artifacts, 3)
+	for _, a := range resp.Artifacts {
+		if a.Package.Name == "opaque" {
+			assert.Nil(t, a.Interface)
+		}
+	}
+
+	// Symbol filters need a name
+	_, err = client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Exports: []*proto_gen.SymbolFilter{{}},
+	})
 	assert.Error(t, err)
 }
 
-// TestDeleteArtifactByTag tests deletion using tag identifier
-func TestDeleteArtifactByTag(t *testing.T) {
+// TestCompareVersions tests the interface diff between two versions
+func TestCompareVersions(t *testing.T) {
 	t.Parallel()
 
 	client, startServer := configureServer(t, t.TempDir())
 	go startServer()
 
 	fqn := &proto_gen.PackageName{
-		Namespace: "delete-by-tag-test",
-		Name:      "delete-by-tag-app",
+		Namespace: "compare-versions-test",
+		Name:      "app",
+	}
+	uploadArtifact(t, client, fqn, []string{"v1"}, wasmModule("run"))
+	uploadArtifact(t, client, fqn, []string{"v2"}, wasmModule("go_"))
+	uploadArtifact(t, client, fqn, []string{"opaque"}, []byte("not wasm"))
+
+	byTag := func(tag string) *proto_gen.ArtifactIdentifier {
+		return &proto_gen.ArtifactIdentifier{
+			Package:    fqn,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: tag},
+		}
 	}
-	tags := []string{"v1.0.0", "deleteme"}
-	content := []byte("to be deleted by tag")
-
-	artifact := uploadArtifact(t, client, fqn, tags, content)
 
-	// Delete by tag
-	deleteReq := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_Tag{
-			Tag: "deleteme",
+	resp, err := client.CompareVersions(
+		t.Context(),
+		&proto_gen.CompareVersionsRequest{
+			Base:      byTag("v1"),
+			Candidate: byTag("v2"),
 		},
+	)
+	assert.NoError(t, err)
+	assert.False(t, resp.Compatible)
+	assert.Len(t, resp.Changes, 2)
+	for _, change := range resp.Changes {
+		assert.Equal(t, "export", change.Direction)
+		switch change.Type {
+		case proto_gen.InterfaceChange_REMOVED:
+			assert.Equal(t, "run", change.Before.Name)
+			assert.True(t, change.Breaking)
+		case proto_gen.InterfaceChange_ADDED:
+			assert.Equal(t, "go_", change.After.Name)
+			assert.False(t, change.Breaking)
+		default:
+			t.Errorf("unexpected change type %v", change.Type)
+		}
 	}
 
-	deleted, err := client.DeleteArtifact(t.Context(), deleteReq)
+	resp, err = client.CompareVersions(
+		t.Context(),
+		&proto_gen.CompareVersionsRequest{
+			Base:      byTag("v1"),
+			Candidate: byTag("v1"),
+		},
+	)
 	assert.NoError(t, err)
-	assert.Equal(t, artifact.VersionHash, deleted.VersionHash)
+	assert.True(t, resp.Compatible)
+	assert.Empty(t, resp.Changes)
 
-	// Verify artifact is deleted
-	getReq := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
-			VersionHash: artifact.VersionHash,
+	// Versions without an interface cannot be compared
+	_, err = client.CompareVersions(
+		t.Context(),
+		&proto_gen.CompareVersionsRequest{
+			Base:      byTag("v1"),
+			Candidate: byTag("opaque"),
 		},
-	}
-	_, err = client.GetArtifact(t.Context(), getReq)
-	assert.Error(t, err)
+	)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Versions of different packages cannot be compared
+	other := byTag("v1")
+	other.Package = &proto_gen.PackageName{Namespace: "other", Name: "app"}
+	_, err = client.CompareVersions(
+		t.Context(),
+		&proto_gen.CompareVersionsRequest{Base: byTag("v1"), Candidate: other},
+	)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
 }
 
-// TestMultipleVersions tests uploading multiple versions of the same artifact
-func TestMultipleVersions(t *testing.T) {
+// TestProtectedTagRejectsBreakingChange tests that protected tags cannot be
+// moved to versions that break their interface
+func TestProtectedTagRejectsBreakingChange(t *testing.T) {
 	t.Parallel()
 
-	client, startServer := configureServer(t, t.TempDir())
+	client, startServer := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{
+			Key:   "compatibility.protected_tags",
+			Value: []string{"stable"},
+		},
+	)
 	go startServer()
 
 	fqn := &proto_gen.PackageName{
-		Namespace: "multiversion-test",
-		Name:      "multiversion-app",
+		Namespace: "protected-tag-test",
+		Name:      "app",
 	}
-
-	// Upload version 1
-	v1Tags := []string{"v1.0.0"}
-	v1Content := []byte("version 1 content")
-	v1 := uploadArtifact(t, client, fqn, v1Tags, v1Content)
-
-	// Upload version 2
-	v2Tags := []string{"v2.0.0", "latest"}
-	v2Content := []byte("version 2 content - updated")
-	v2 := uploadArtifact(t, client, fqn, v2Tags, v2Content)
-
-	// Verify different hashes
-	assert.NotEqual(t, v1.VersionHash, v2.VersionHash)
-
-	// Query all versions
-	name := fqn.Name
-	resp, err := client.QueryArtifacts(
-		t.Context(),
-		&proto_gen.ArtifactQuery{Name: &name},
+	stable := uploadArtifact(
+		t,
+		client,
+		fqn,
+		[]string{"stable"},
+		wasmModule("run"),
 	)
-	assert.NoError(t, err)
-	assert.Len(t, resp.Artifacts, 2)
 
-	// Pull v1
-	v1Req := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_Tag{
-			Tag: "v1.0.0",
+	// Uploading a breaking version under the protected tag is rejected
+	stream, err := client.UploadArtifact(t.Context())
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Send(&proto_gen.UploadArtifactRequest{
+		Request: &proto_gen.UploadArtifactRequest_Metadata{
+			Metadata: &proto_gen.UploadMetadata{
+				Fqn:  fqn,
+				Tags: []string{"stable"},
+			},
 		},
-	}
-	pulled := pullArtifact(t, client, v1Req)
-	assert.Equal(t, v1Content, pulled)
+	}))
+	assert.NoError(t, stream.Send(&proto_gen.UploadArtifactRequest{
+		Request: &proto_gen.UploadArtifactRequest_Content{
+			Content: &proto_gen.ArtifactContent{Data: wasmModule("go_")},
+		},
+	}))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
 
-	// Pull v2
-	v2Req := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_Tag{
-			Tag: "v2.0.0",
+	// The same version is fine under an unprotected tag ...
+	candidate := uploadArtifact(
+		t,
+		client,
+		fqn,
+		[]string{"next"},
+		wasmModule("go_"),
+	)
+
+	// ... but cannot be promoted afterwards
+	_, err = client.SetTags(t.Context(), &proto_gen.SetTagsRequest{
+		Artifact: &proto_gen.ArtifactIdentifier{
+			Package: fqn,
+			Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+				VersionHash: candidate.VersionHash,
+			},
 		},
-	}
-	pulled = pullArtifact(t, client, v2Req)
-	assert.Equal(t, v2Content, pulled)
+		Tags: []string{"next", "stable"},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
 
-	// Pull latest (should be v2)
-	latestReq := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_Tag{
-			Tag: "latest",
+	retrieved, err := client.GetArtifact(
+		t.Context(),
+		&proto_gen.ArtifactIdentifier{
+			Package:    fqn,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "stable"},
 		},
-	}
-	pulled = pullArtifact(t, client, latestReq)
-	assert.Equal(t, v2Content, pulled)
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, stable.VersionHash, retrieved.VersionHash)
 }
 
-// TestLargeArtifact tests uploading and downloading a large artifact
-func TestLargeArtifact(t *testing.T) {
+// TestArtifactDetails tests that size, media type and uploader are recorded
+func TestArtifactDetails(t *testing.T) {
 	t.Parallel()
 
 	client, startServer := configureServer(t, t.TempDir())
 	go startServer()
 
 	fqn := &proto_gen.PackageName{
-		Namespace: "large-file-test",
-		Name:      "large-file-app",
-	}
-	tags := []string{"v1.0.0"}
-
-	// Create 10MB of content
-	largeContent := make([]byte, 10*1024*1024)
-	for i := range largeContent {
-		largeContent[i] = byte(i % 256)
+		Namespace: "artifact-details-test",
+		Name:      "app",
 	}
+	ctx := metadata.AppendToOutgoingContext(
+		t.Context(),
+		registry.UserMetadataKey,
+		"alice",
+	)
 
-	artifact := uploadArtifact(t, client, fqn, tags, largeContent)
-	assert.NotEmpty(t, artifact.VersionHash)
+	module := wasmModule("run")
+	uploaded := uploadArtifactWithMetadata(
+		ctx,
+		t,
+		client,
+		&proto_gen.UploadMetadata{Fqn: fqn, Tags: []string{"v1"}},
+		module,
+	)
+	assert.Equal(t, int64(len(module)), uploaded.Metadata.SizeBytes)
+	assert.Equal(t, wasm.MediaTypeModule, uploaded.Metadata.MediaType)
+	assert.Equal(t, "alice", uploaded.Metadata.Uploader)
+	assert.Contains(t, uploaded.Metadata.UserAgent, "grpc-go")
 
-	// Pull and verify
-	pullReq := &proto_gen.ArtifactIdentifier{
-		Package: fqn,
-		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
-			VersionHash: artifact.VersionHash,
+	retrieved, err := client.GetArtifact(
+		t.Context(),
+		&proto_gen.ArtifactIdentifier{
+			Package:    fqn,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
 		},
-	}
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, uploaded.Metadata.SizeBytes, retrieved.Metadata.SizeBytes)
+	assert.Equal(t, uploaded.Metadata.MediaType, retrieved.Metadata.MediaType)
+	assert.Equal(t, "alice", retrieved.Metadata.Uploader)
+	assert.Equal(t, uploaded.Metadata.UserAgent, retrieved.Metadata.UserAgent)
 
-	pulled := pullArtifact(t, client, pullReq)
-	assert.Equal(t, largeContent, pulled)
+	// Uploads without a forwarded identity are attributed to nobody
+	anonymous := uploadArtifact(
+		t,
+		client,
+		fqn,
+		[]string{"v2"},
+		[]byte("plain content"),
+	)
+	assert.Equal(t, wasm.MediaTypeUnknown, anonymous.Metadata.MediaType)
+	assert.Equal(t, auth.UnauthenticatedUser, anonymous.Metadata.Uploader)
 }
 
-// TestInvalidFQN tests validation of PackageName
-func TestInvalidFQN(t *testing.T) {
+// TestArtifactLabels tests setting labels and filtering by them
+func TestArtifactLabels(t *testing.T) {
 	t.Parallel()
 
 	client, startServer := configureServer(t, t.TempDir())
 	go startServer()
 
-	testCases := []struct {
-		name string
-		fqn  *proto_gen.PackageName
-	}{
-		{
-			name: "missing namespace",
-			fqn: &proto_gen.PackageName{
-				Namespace: "",
-				Name:      "app",
+	ns := "artifact-labels-test"
+	first := uploadArtifactWithMetadata(
+		t.Context(),
+		t,
+		client,
+		&proto_gen.UploadMetadata{
+			Fqn:  &proto_gen.PackageName{Namespace: ns, Name: "app"},
+			Tags: []string{"v1"},
+			Labels: map[string]string{
+				"git.commit": "abc123",
+				"owner":      "team-a",
 			},
 		},
-		{
-			name: "missing name",
-			fqn: &proto_gen.PackageName{
+		[]byte("labelled content 1"),
+	)
+	assert.Equal(t, "abc123", first.Labels["git.commit"])
+
+	second := uploadArtifact(
+		t,
+		client,
+		&proto_gen.PackageName{Namespace: ns, Name: "app"},
+		[]string{"v2"},
+		[]byte("labelled content 2"),
+	)
+	assert.Empty(t, second.Labels)
+
+	updated, err := client.SetLabels(t.Context(), &proto_gen.SetLabelsRequest{
+		Artifact: &proto_gen.ArtifactIdentifier{
+			Package:    &proto_gen.PackageName{Namespace: ns, Name: "app"},
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v2"},
+		},
+		Labels: map[string]string{
+			"owner":     "team-b",
+			"build.url": "https://ci.example.com/42",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, second.VersionHash, updated.VersionHash)
+	assert.Equal(t, map[string]string{
+		"owner":     "team-b",
+		"build.url": "https://ci.example.com/42",
+	}, updated.Labels)
+
+	action := proto_gen.AuditAction_SET_LABELS
+	audited, err := client.ListAuditEvents(t.Context(), &proto_gen.AuditQuery{
+		Namespace: &ns,
+		Action:    &action,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, audited.Events, 1) {
+		assert.Equal(t, second.VersionHash, audited.Events[0].VersionHash)
+		assert.Equal(
+			t,
+			"build.url=https://ci.example.com/42, owner=team-b",
+			audited.Events[0].Detail,
+		)
+	}
+
+	query := func(selectors ...*proto_gen.LabelSelector) []string {
+		resp, queryErr := client.QueryArtifacts(
+			t.Context(),
+			&proto_gen.ArtifactQuery{Namespace: &ns, LabelSelectors: selectors},
+		)
+		assert.NoError(t, queryErr)
+
+		hashes := make([]string, 0, len(resp.Artifacts))
+		for _, a := range resp.Artifacts {
+			hashes = append(hashes, a.VersionHash)
+		}
+
+		return hashes
+	}
+
+	assert.ElementsMatch(t, []string{first.VersionHash}, query(
+		&proto_gen.LabelSelector{
+			Key:      "owner",
+			Operator: proto_gen.LabelSelector_EQUALS,
+			Values:   []string{"team-a"},
+		},
+	))
+	assert.ElementsMatch(t, []string{second.VersionHash}, query(
+		&proto_gen.LabelSelector{
+			Key:      "build.url",
+			Operator: proto_gen.LabelSelector_EXISTS,
+		},
+	))
+	assert.ElementsMatch(
+		t,
+		[]string{first.VersionHash, second.VersionHash},
+		query(&proto_gen.LabelSelector{
+			Key:      "owner",
+			Operator: proto_gen.LabelSelector_IN,
+			Values:   []string{"team-a", "team-b"},
+		}),
+	)
+	assert.ElementsMatch(t, []string{first.VersionHash}, query(
+		&proto_gen.LabelSelector{
+			Key:      "build.url",
+			Operator: proto_gen.LabelSelector_DOES_NOT_EXIST,
+		},
+	))
+
+	// Equality needs exactly one value
+	_, err = client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		LabelSelectors: []*proto_gen.LabelSelector{
+			{Key: "owner", Operator: proto_gen.LabelSelector_EQUALS},
+		},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPackages(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	ns := "packages-test"
+	explicit := &proto_gen.PackageName{Namespace: ns, Name: "explicit"}
+
+	created, err := client.CreatePackage(t.Context(), &proto_gen.Package{
+		Name:        explicit,
+		Description: "An explicitly created package",
+		Homepage:    "https://example.com",
+		Owners:      []string{"alice"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "An explicitly created package", created.Description)
+	assert.Equal(t, []string{"alice"}, created.Owners)
+	assert.Equal(t, proto_gen.Visibility_PUBLIC, created.Visibility)
+
+	_, err = client.CreatePackage(
+		t.Context(),
+		&proto_gen.Package{Name: explicit},
+	)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.CreatePackage(t.Context(), &proto_gen.Package{
+		Name:     &proto_gen.PackageName{Namespace: ns, Name: "bad-homepage"},
+		Homepage: "ftp://example.com",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	updated, err := client.UpdatePackage(t.Context(), &proto_gen.Package{
+		Name:               explicit,
+		Readme:             "# Explicit",
+		Owners:             []string{"bob", "alice"},
+		Visibility:         proto_gen.Visibility_PRIVATE,
+		Deprecated:         true,
+		DeprecationMessage: "use implicit instead",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Description)
+	assert.Equal(t, "# Explicit", updated.Readme)
+	assert.Equal(t, []string{"alice", "bob"}, updated.Owners)
+	assert.Equal(t, proto_gen.Visibility_PRIVATE, updated.Visibility)
+	assert.True(t, updated.Deprecated)
+
+	_, err = client.UpdatePackage(t.Context(), &proto_gen.Package{
+		Name: &proto_gen.PackageName{Namespace: ns, Name: "missing"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Uploading the first version creates the package record implicitly
+	implicit := &proto_gen.PackageName{Namespace: ns, Name: "implicit"}
+	artifact := uploadArtifact(
+		t,
+		client,
+		implicit,
+		[]string{"v1"},
+		[]byte("implicit package content"),
+	)
+	id := &proto_gen.ArtifactIdentifier{
+		Package: implicit,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifact.VersionHash,
+		},
+	}
+
+	retrieved, err := client.GetArtifact(t.Context(), id)
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved.PackageInfo) {
+		assert.Equal(t, "implicit", retrieved.PackageInfo.Name.Name)
+	}
+
+	listed, err := client.ListPackages(
+		t.Context(),
+		&proto_gen.PackageQuery{Namespace: &ns},
+	)
+	assert.NoError(t, err)
+	if assert.Len(t, listed.Packages, 2) {
+		assert.Equal(t, "explicit", listed.Packages[0].Name.Name)
+		assert.Equal(t, "implicit", listed.Packages[1].Name.Name)
+	}
+
+	// Packages with versions cannot be deleted
+	_, err = client.DeletePackage(
+		t.Context(),
+		&proto_gen.DeletePackageRequest{Package: implicit},
+	)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The record survives the deletion of the last version
+	_, err = client.DeleteArtifact(t.Context(), id)
+	assert.NoError(t, err)
+	_, err = client.GetPackage(t.Context(), implicit)
+	assert.NoError(t, err)
+
+	deleted, err := client.DeletePackage(
+		t.Context(),
+		&proto_gen.DeletePackageRequest{Package: implicit},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "implicit", deleted.Name.Name)
+	_, err = client.GetPackage(t.Context(), implicit)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestNamespaceQuotas(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	ns := "namespace-quota-test"
+	created, err := client.CreateNamespace(t.Context(), &proto_gen.Namespace{
+		Name:  ns,
+		Quota: &proto_gen.NamespaceQuota{MaxBytes: 20, MaxVersions: 2},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), created.Quota.MaxBytes)
+	assert.Equal(t, int64(0), created.Usage.Versions)
+
+	_, err = client.CreateNamespace(
+		t.Context(),
+		&proto_gen.Namespace{Name: ns},
+	)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	pkg := &proto_gen.PackageName{Namespace: ns, Name: "app"}
+	uploadArtifact(t, client, pkg, []string{"v1"}, []byte("0123456789"))
+
+	// Exceeds the remaining 10 bytes
+	_, err = tryUploadArtifact(t, client, pkg, []byte("0123456789abc"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	uploadArtifact(t, client, pkg, []string{"v2"}, []byte("abcdefghij"))
+
+	// Both quotas are used up now
+	_, err = tryUploadArtifact(t, client, pkg, []byte("x"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	got, err := client.GetNamespace(
+		t.Context(),
+		&proto_gen.NamespaceName{Name: ns},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), got.Usage.Bytes)
+	assert.Equal(t, int64(2), got.Usage.Versions)
+
+	// Rejected uploads never reach the store, but are audited all the same
+	action := proto_gen.AuditAction_UPLOAD
+	audited, err := client.ListAuditEvents(t.Context(), &proto_gen.AuditQuery{
+		Namespace: &ns,
+		Action:    &action,
+	})
+	assert.NoError(t, err)
+	var rejected []*proto_gen.AuditEvent
+	for _, event := range audited.GetEvents() {
+		if !event.Succeeded {
+			rejected = append(rejected, event)
+		}
+	}
+	if assert.Len(t, rejected, 2) {
+		assert.Contains(t, rejected[0].Error, "quota")
+		assert.Equal(t, "app", rejected[0].Package.Name)
+	}
+
+	updated, err := client.UpdateNamespace(t.Context(), &proto_gen.Namespace{
+		Name:  ns,
+		Quota: &proto_gen.NamespaceQuota{},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updated.Quota.MaxVersions)
+	uploadArtifact(t, client, pkg, []string{"v3"}, []byte("unlimited now"))
+
+	// Uploads create namespaces implicitly
+	implicit := "namespace-implicit-test"
+	uploadArtifact(
+		t,
+		client,
+		&proto_gen.PackageName{Namespace: implicit, Name: "app"},
+		nil,
+		[]byte("implicit namespace"),
+	)
+	listed, err := client.ListNamespaces(
+		t.Context(),
+		&proto_gen.NamespaceQuery{},
+	)
+	assert.NoError(t, err)
+	names := make([]string, 0, len(listed.Namespaces))
+	for _, n := range listed.Namespaces {
+		names = append(names, n.Name)
+	}
+	assert.Contains(t, names, ns)
+	assert.Contains(t, names, implicit)
+
+	deleted, err := client.DeleteNamespace(
+		t.Context(),
+		&proto_gen.NamespaceName{Name: ns},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted.Usage.Versions)
+
+	_, err = client.GetArtifact(t.Context(), &proto_gen.ArtifactIdentifier{
+		Package:    pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetNamespace(
+		t.Context(),
+		&proto_gen.NamespaceName{Name: ns},
+	)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestVersionStates(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	pkg := &proto_gen.PackageName{Namespace: "version-state-test", Name: "app"}
+	artifact := uploadArtifact(
+		t,
+		client,
+		pkg,
+		[]string{"latest"},
+		[]byte("version state content"),
+	)
+	assert.Equal(t, proto_gen.VersionState_ACTIVE, artifact.State)
+
+	byHash := &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifact.VersionHash,
+		},
+	}
+	byTag := &proto_gen.ArtifactIdentifier{
+		Package:    pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "latest"},
+	}
+
+	deprecated, err := client.SetVersionState(
+		t.Context(),
+		&proto_gen.SetVersionStateRequest{
+			Artifact: byTag,
+			State:    proto_gen.VersionState_DEPRECATED,
+			Reason:   "use v2",
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, proto_gen.VersionState_DEPRECATED, deprecated.State)
+	assert.Equal(t, "use v2", deprecated.StateReason)
+
+	// Deprecated versions still resolve, but come with a warning
+	var header metadata.MD
+	_, err = client.GetArtifact(t.Context(), byTag, grpc.Header(&header))
+	assert.NoError(t, err)
+	if assert.Len(t, header.Get(registry.WarningMetadataKey), 1) {
+		assert.Contains(t, header.Get(registry.WarningMetadataKey)[0], "use v2")
+	}
+
+	_, err = client.SetVersionState(
+		t.Context(),
+		&proto_gen.SetVersionStateRequest{
+			Artifact: byHash,
+			State:    proto_gen.VersionState_YANKED,
+			Reason:   "broken build\nsee ticket – 42",
+		},
+	)
+	assert.NoError(t, err)
+
+	// Yanked versions are gone for tags but remain pullable by hash
+	_, err = client.GetArtifact(t.Context(), byTag)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	pullStream, err := client.PullArtifact(t.Context(), byTag)
+	assert.NoError(t, err)
+	_, err = pullStream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	pullStream, err = client.PullArtifact(t.Context(), byHash)
+	assert.NoError(t, err)
+	chunk, err := pullStream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("version state content"), chunk.Data)
+	header, err = pullStream.Header()
+	assert.NoError(t, err)
+	// The reason is escaped, headers only carry printable ASCII
+	if assert.Len(t, header.Get(registry.WarningMetadataKey), 1) {
+		assert.Equal(
+			t,
+			"version "+artifact.VersionHash+" is yanked: "+
+				`broken build\nsee ticket \u2013 42`,
+			header.Get(registry.WarningMetadataKey)[0],
+		)
+	}
+
+	active, err := client.SetVersionState(
+		t.Context(),
+		&proto_gen.SetVersionStateRequest{
+			Artifact: byHash,
+			State:    proto_gen.VersionState_ACTIVE,
+			Reason:   "ignored",
+		},
+	)
+	assert.NoError(t, err)
+	assert.Empty(t, active.StateReason)
+
+	header = nil
+	_, err = client.GetArtifact(t.Context(), byTag, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Empty(t, header.Get(registry.WarningMetadataKey))
+}
+
+func TestDeletePackageWithVersions(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	pkg := &proto_gen.PackageName{Namespace: "bulk-delete-test", Name: "app"}
+	uploadArtifact(t, client, pkg, []string{"v1"}, []byte("bulk delete 1"))
+	uploadArtifact(t, client, pkg, []string{"v2"}, []byte("bulk delete 2"))
+
+	_, err := client.DeletePackage(
+		t.Context(),
+		&proto_gen.DeletePackageRequest{Package: pkg},
+	)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	deleted, err := client.DeletePackage(
+		t.Context(),
+		&proto_gen.DeletePackageRequest{Package: pkg, DeleteVersions: true},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "app", deleted.Name.Name)
+
+	resp, err := client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Namespace: &pkg.Namespace,
+		Name:      &pkg.Name,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Artifacts)
+	_, err = client.GetPackage(t.Context(), pkg)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The versions went to the trash
+	trashed, err := client.ListDeleted(
+		t.Context(),
+		&proto_gen.DeletedQuery{Namespace: &pkg.Namespace},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, trashed.Artifacts, 2)
+}
+
+func TestMovePackage(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	from := &proto_gen.PackageName{Namespace: "move-test-ci", Name: "app"}
+	to := &proto_gen.PackageName{Namespace: "move-test-prod", Name: "service"}
+
+	_, err := client.CreatePackage(t.Context(), &proto_gen.Package{
+		Name:        from,
+		Description: "moving package",
+	})
+	assert.NoError(t, err)
+	first := uploadArtifactWithMetadata(
+		t.Context(),
+		t,
+		client,
+		&proto_gen.UploadMetadata{
+			Fqn:    from,
+			Tags:   []string{"v1"},
+			Labels: map[string]string{"team": "a"},
+		},
+		wasmModule("foo"),
+	)
+	uploadArtifact(t, client, from, []string{"v2"}, []byte("second version"))
+
+	// The target must not exist yet
+	taken := &proto_gen.PackageName{Namespace: "move-test-prod", Name: "taken"}
+	uploadArtifact(t, client, taken, nil, []byte("taken version"))
+	_, err = client.MovePackage(t.Context(), &proto_gen.MovePackageRequest{
+		From: from,
+		To:   taken,
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	moved, err := client.MovePackage(t.Context(), &proto_gen.MovePackageRequest{
+		From:     from,
+		To:       to,
+		Redirect: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "moving package", moved.Description)
+	assert.Equal(t, "service", moved.Name.Name)
+
+	retrieved, err := client.GetArtifact(
+		t.Context(),
+		&proto_gen.ArtifactIdentifier{
+			Package:    to,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, first.VersionHash, retrieved.VersionHash)
+	assert.Equal(t, "a", retrieved.Labels["team"])
+	assert.NotNil(t, retrieved.Interface)
+
+	content := pullArtifact(t, client, &proto_gen.ArtifactIdentifier{
+		Package:    to,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v2"},
+	})
+	assert.Equal(t, []byte("second version"), content)
+
+	// Reads of the old name follow the redirect
+	redirected := pullArtifact(t, client, &proto_gen.ArtifactIdentifier{
+		Package:    from,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v2"},
+	})
+	assert.Equal(t, []byte("second version"), redirected)
+
+	resp, err := client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Namespace: &from.Namespace,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Artifacts)
+	_, err = client.GetPackage(t.Context(), from)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// linkHookRegistry runs hook before every link, to act while a package move
+// copies its blobs
+type linkHookRegistry struct {
+	registry.Registry
+
+	hook func() error
+}
+
+func (r *linkHookRegistry) LinkArtifact(
+	ctx context.Context,
+	from, to *proto_gen.PackageName,
+	hash string,
+) error {
+	if err := r.hook(); err != nil {
+		return err
+	}
+
+	return r.Registry.LinkArtifact(ctx, from, to, hash)
+}
+
+// configureMoveServer returns a registry server whose links run hook and its
+// HTTP gateway
+func configureMoveServer(
+	t *testing.T,
+	hook func(gateway *httptest.Server) error,
+) (*registry.Server, *httptest.Server) {
+	t.Helper()
+
+	hookRegistry := &linkHookRegistry{Registry: memoryRegistry.New()}
+	server := registry.NewServer(hookRegistry, testDB(t), &config.AppConfig{})
+	gateway := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(gateway.Close)
+	hookRegistry.hook = func() error { return hook(gateway) }
+
+	return server, gateway
+}
+
+// TestMovePackageConcurrentUpload tests that a version uploaded while a move
+// copies blobs is moved along with its blob
+func TestMovePackageQuota(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	from := &proto_gen.PackageName{Namespace: "move-quota-src", Name: "app"}
+	to := &proto_gen.PackageName{Namespace: "move-quota-dst", Name: "app"}
+	uploadArtifact(t, client, from, nil, []byte("0123456789"))
+	trashed := uploadArtifact(t, client, from, nil, []byte("abcdefghij"))
+	_, err := client.DeleteArtifact(t.Context(), &proto_gen.ArtifactIdentifier{
+		Package: from,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: trashed.VersionHash,
+		},
+	})
+	assert.NoError(t, err)
+
+	// Trashed versions take up storage, but do not count as versions
+	_, err = client.CreateNamespace(t.Context(), &proto_gen.Namespace{
+		Name:  to.Namespace,
+		Quota: &proto_gen.NamespaceQuota{MaxBytes: 15, MaxVersions: 1},
+	})
+	assert.NoError(t, err)
+	move := &proto_gen.MovePackageRequest{From: from, To: to}
+	_, err = client.MovePackage(t.Context(), move)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = client.UpdateNamespace(t.Context(), &proto_gen.Namespace{
+		Name:  to.Namespace,
+		Quota: &proto_gen.NamespaceQuota{MaxBytes: 20, MaxVersions: 1},
+	})
+	assert.NoError(t, err)
+	_, err = client.MovePackage(t.Context(), move)
+	assert.NoError(t, err)
+}
+
+func TestMovePackageConcurrentUpload(t *testing.T) {
+	t.Parallel()
+
+	from := &proto_gen.PackageName{Namespace: "move-upload-ci", Name: "app"}
+	to := &proto_gen.PackageName{Namespace: "move-upload-prod", Name: "app"}
+
+	var once sync.Once
+	server, gateway := configureMoveServer(
+		t,
+		func(gateway *httptest.Server) error {
+			once.Do(func() {
+				resp, _ := gatewayRequest(
+					t,
+					gateway,
+					http.MethodPut,
+					"/v1/move-upload-ci/app?tag=v2",
+					strings.NewReader("uploaded during move"),
+				)
+				assert.Equal(t, http.StatusCreated, resp.StatusCode)
+			})
+
+			return nil
+		},
+	)
+
+	resp, _ := gatewayRequest(
+		t,
+		gateway,
+		http.MethodPut,
+		"/v1/move-upload-ci/app?tag=v1",
+		strings.NewReader("uploaded before move"),
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	_, err := server.MovePackage(t.Context(), &proto_gen.MovePackageRequest{
+		From: from,
+		To:   to,
+	})
+	assert.NoError(t, err)
+
+	for tag, content := range map[string]string{
+		"v1": "uploaded before move",
+		"v2": "uploaded during move",
+	} {
+		resp, body := gatewayRequest(
+			t,
+			gateway,
+			http.MethodGet,
+			"/v1/move-upload-prod/app/"+tag+"/content",
+			nil,
+		)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, content, string(body))
+	}
+
+	resp, _ = gatewayRequest(
+		t,
+		gateway,
+		http.MethodGet,
+		"/v1/move-upload-ci/app/v1",
+		nil,
+	)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestMovePackageRetry tests that a move that failed while copying blobs is
+// completed by requesting it again
+func TestMovePackageRetry(t *testing.T) {
+	t.Parallel()
+
+	from := &proto_gen.PackageName{Namespace: "move-retry-ci", Name: "app"}
+	to := &proto_gen.PackageName{Namespace: "move-retry-prod", Name: "app"}
+
+	failed := false
+	server, gateway := configureMoveServer(t, func(*httptest.Server) error {
+		if failed {
+			return nil
+		}
+		failed = true
+
+		return registry.ErrStorageUnavailable
+	})
+
+	resp, _ := gatewayRequest(
+		t,
+		gateway,
+		http.MethodPut,
+		"/v1/move-retry-ci/app?tag=v1",
+		strings.NewReader("moved after retry"),
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	request := &proto_gen.MovePackageRequest{From: from, To: to}
+	_, err := server.MovePackage(t.Context(), request)
+	assert.Error(t, err)
+
+	_, err = server.MovePackage(t.Context(), request)
+	assert.NoError(t, err)
+
+	resp, body := gatewayRequest(
+		t,
+		gateway,
+		http.MethodGet,
+		"/v1/move-retry-prod/app/v1/content",
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "moved after retry", string(body))
+}
+
+func TestCopyArtifact(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	source := &proto_gen.PackageName{Namespace: "copy-test-ci", Name: "app"}
+	target := &proto_gen.PackageName{Namespace: "copy-test-prod", Name: "app"}
+
+	original := uploadArtifactWithMetadata(
+		t.Context(),
+		t,
+		client,
+		&proto_gen.UploadMetadata{
+			Fqn:    source,
+			Tags:   []string{"latest"},
+			Labels: map[string]string{"commit": "abc123"},
+		},
+		wasmModule("run"),
+	)
+
+	request := &proto_gen.CopyArtifactRequest{
+		Source: &proto_gen.ArtifactIdentifier{
+			Package:    source,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "latest"},
+		},
+		Target: target,
+		Tags:   []string{"v1.0.0", "stable"},
+	}
+	copied, err := client.CopyArtifact(t.Context(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, original.VersionHash, copied.VersionHash)
+	assert.Equal(t, source.Namespace, copied.CopiedFrom.GetNamespace())
+	assert.Equal(t, source.Name, copied.CopiedFrom.GetName())
+	assert.ElementsMatch(t, []string{"stable", "v1.0.0"}, copied.Tags)
+	assert.Equal(t, "abc123", copied.Labels["commit"])
+	assert.Equal(t, original.Metadata.SizeBytes, copied.Metadata.SizeBytes)
+	assert.NotNil(t, copied.Interface)
+	assert.Nil(t, original.CopiedFrom)
+
+	content := pullArtifact(t, client, &proto_gen.ArtifactIdentifier{
+		Package:    target,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "stable"},
+	})
+	assert.Equal(t, wasmModule("run"), content)
+
+	// Both versions are independent of each other
+	_, err = client.DeleteArtifact(t.Context(), &proto_gen.ArtifactIdentifier{
+		Package: source,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: original.VersionHash,
+		},
+	})
+	assert.NoError(t, err)
+	content = pullArtifact(t, client, &proto_gen.ArtifactIdentifier{
+		Package:    target,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1.0.0"},
+	})
+	assert.Equal(t, wasmModule("run"), content)
+
+	// A version cannot be copied into its own package
+	_, err = client.CopyArtifact(t.Context(), &proto_gen.CopyArtifactRequest{
+		Source: &proto_gen.ArtifactIdentifier{
+			Package:    target,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "stable"},
+		},
+		Target: target,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestBatchGetArtifacts(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	pkg := &proto_gen.PackageName{Namespace: "batch-test", Name: "app"}
+	other := &proto_gen.PackageName{Namespace: "batch-test", Name: "lib"}
+	first := uploadArtifact(t, client, pkg, []string{"v1"}, []byte("first"))
+	second := uploadArtifact(t, client, other, []string{"v1"}, []byte("second"))
+
+	resp, err := client.BatchGetArtifacts(
+		t.Context(),
+		&proto_gen.BatchGetArtifactsRequest{
+			Identifiers: []*proto_gen.ArtifactIdentifier{
+				{
+					Package:    pkg,
+					Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+				},
+				{
+					Package: other,
+					Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+						VersionHash: second.VersionHash,
+					},
+				},
+				{
+					Package:    pkg,
+					Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "missing"},
+				},
+				{
+					Package:    pkg,
+					Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: ""},
+				},
+				{
+					Package:    other,
+					Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+				},
+			},
+		},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 5)
+
+	assert.Equal(t, first.VersionHash, resp.Results[0].GetArtifact().VersionHash)
+	assert.Equal(t, []string{"v1"}, resp.Results[0].GetArtifact().Tags)
+	assert.Equal(t, second.VersionHash, resp.Results[1].GetArtifact().VersionHash)
+	assert.Equal(
+		t,
+		uint32(codes.NotFound),
+		resp.Results[2].GetError().GetCode(),
+	)
+	assert.Equal(
+		t,
+		uint32(codes.InvalidArgument),
+		resp.Results[3].GetError().GetCode(),
+	)
+	assert.Equal(t, second.VersionHash, resp.Results[4].GetArtifact().VersionHash)
+	assert.Equal(t, "lib", resp.Results[4].GetArtifact().PackageInfo.Name.Name)
+}
+
+func TestHTTPGateway(t *testing.T) {
+	t.Parallel()
+
+	gateway := configureGateway(t)
+	do := func(method, path string, body io.Reader, headers ...string) (
+		*http.Response,
+		[]byte,
+	) {
+		t.Helper()
+
+		return gatewayRequest(t, gateway, method, path, body, headers...)
+	}
+
+	content := []byte("http gateway content")
+	resp, body := do(
+		http.MethodPut,
+		"/v1/http-test/app?tag=v1&label=team=a",
+		bytes.NewReader(content),
+		registry.UserMetadataKey, "alice",
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	uploaded := &proto_gen.Artifact{}
+	assert.NoError(t, protojson.Unmarshal(body, uploaded))
+	assert.Equal(t, []string{"v1"}, uploaded.Tags)
+	// HTTP clients cannot claim to be a user
+	assert.Equal(t, auth.UnauthenticatedUser, uploaded.Metadata.Uploader)
+
+	resp, body = do(http.MethodGet, "/v1/http-test/app/v1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	retrieved := &proto_gen.Artifact{}
+	assert.NoError(t, protojson.Unmarshal(body, retrieved))
+	assert.Equal(t, uploaded.VersionHash, retrieved.VersionHash)
+	assert.Equal(t, "a", retrieved.Labels["team"])
+
+	resp, body = do(http.MethodGet, "/v1/http-test/app/v1/content", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, content, body)
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, `"`+uploaded.VersionHash+`"`, etag)
+
+	resp, _ = do(
+		http.MethodGet,
+		"/v1/http-test/app/"+uploaded.VersionHash+"/content",
+		nil,
+		"If-None-Match", etag,
+	)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, body = do(
+		http.MethodGet,
+		"/v1/http-test/app/v1/content",
+		nil,
+		"Range", "bytes=5-11",
+	)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, content[5:12], body)
+	assert.Equal(
+		t,
+		"bytes 5-11/"+strconv.Itoa(len(content)),
+		resp.Header.Get("Content-Range"),
+	)
+
+	resp, _ = do(
+		http.MethodGet,
+		"/v1/http-test/app/v1/content",
+		nil,
+		"Range", "bytes=1000-",
+	)
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+
+	resp, _ = do(
+		http.MethodPut,
+		"/v1/http-test/app/v1/tags",
+		strings.NewReader(`{"tags": ["v2", "stable"]}`),
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = do(http.MethodGet, "/v1/http-test/app/v1", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, body = do(http.MethodGet, "/v1/artifacts?namespace=http-test", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	list := &proto_gen.ArtifactListResponse{}
+	assert.NoError(t, protojson.Unmarshal(body, list))
+	assert.Len(t, list.Artifacts, 1)
+	assert.ElementsMatch(t, []string{"stable", "v2"}, list.Artifacts[0].Tags)
+
+	resp, _ = do(http.MethodDelete, "/v1/http-test/app/stable", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do(http.MethodGet, "/v1/http-test/app/v2/content", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestHTTPPullWithoutSize tests pulling versions stored before their size
+// was recorded
+func TestHTTPPullWithoutSize(t *testing.T) {
+	t.Parallel()
+
+	pkg := &proto_gen.PackageName{Namespace: "http-size-test", Name: "old"}
+	content := []byte("content without recorded size")
+	reg := memoryRegistry.New()
+	hash, err := reg.StoreArtifact(t.Context(), pkg, bytes.NewReader(content))
+	assert.NoError(t, err)
+	db := testDB(t)
+	err = db.CreateArtifactMeta(t.Context(), pkg, hash, orm.ArtifactDetails{})
+	assert.NoError(t, err)
+	gateway := startGateway(t, reg, db, &config.AppConfig{})
+
+	resp, body := gatewayRequest(
+		t,
+		gateway,
+		http.MethodGet,
+		"/v1/http-size-test/old/"+hash+"/content",
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, content, body)
+	assert.Equal(t, int64(len(content)), resp.ContentLength)
+
+	resp, body = gatewayRequest(
+		t,
+		gateway,
+		http.MethodGet,
+		"/v1/http-size-test/old/"+hash+"/content",
+		nil,
+		"Range", "bytes=8-14",
+	)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "without", string(body))
+	assert.Equal(
+		t,
+		"bytes 8-14/"+strconv.Itoa(len(content)),
+		resp.Header.Get("Content-Range"),
+	)
+}
+
+func TestOCIUploadStaging(t *testing.T) {
+	t.Parallel()
+
+	// Replicas behind one endpoint share the staging directory
+	db := testDB(t)
+	cfg := &config.AppConfig{}
+	cfg.OCI.StagingDir = t.TempDir()
+	cfg.OCI.MaxUploadSize = 16
+	first := startGateway(t, memoryRegistry.New(), db, cfg)
+	second := startGateway(t, memoryRegistry.New(), db, cfg)
+
+	resp, _ := gatewayRequest(
+		t,
+		first,
+		http.MethodPost,
+		"/v2/oci-test/staged/blobs/uploads/",
+		nil,
+	)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location := strings.TrimPrefix(resp.Header.Get("Location"), first.URL)
+	resp, _ = gatewayRequest(
+		t,
+		first,
+		http.MethodPatch,
+		location,
+		strings.NewReader("0123456789"),
+	)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, _ = gatewayRequest(t, second, http.MethodGet, location, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "0-9", resp.Header.Get("Range"))
+
+	// Chunks beyond the maximum upload size are rejected as a whole
+	resp, _ = gatewayRequest(
+		t,
+		second,
+		http.MethodPatch,
+		location,
+		strings.NewReader("abcdefghij"),
+	)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	resp, _ = gatewayRequest(t, first, http.MethodGet, location, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "0-9", resp.Header.Get("Range"))
+
+	sum := sha256.Sum256([]byte("0123456789abc"))
+	resp, _ = gatewayRequest(
+		t,
+		second,
+		http.MethodPut,
+		location+"?digest=sha256:"+hex.EncodeToString(sum[:]),
+		strings.NewReader("abc"),
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, body := gatewayRequest(
+		t,
+		first,
+		http.MethodGet,
+		"/v2/oci-test/staged/blobs/sha256:"+hex.EncodeToString(sum[:]),
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "0123456789abc", string(body))
+}
+
+func TestOCIDistribution(t *testing.T) {
+	t.Parallel()
+
+	gateway := configureGateway(t)
+	do := func(method, path string, body io.Reader, headers ...string) (
+		*http.Response,
+		[]byte,
+	) {
+		t.Helper()
+
+		return gatewayRequest(t, gateway, method, path, body, headers...)
+	}
+	digest := func(content []byte) string {
+		sum := sha256.Sum256(content)
+
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	resp, _ := do(http.MethodGet, "/v2/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The layer is pushed in one request, the config in chunks
+	layer := wasmModule("run")
+	resp, _ = do(
+		http.MethodPost,
+		"/v2/oci-test/app/blobs/uploads/?digest="+digest(layer),
+		bytes.NewReader(layer),
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	config := []byte(`{"architecture":"wasm","os":"wasip1"}`)
+	resp, _ = do(http.MethodPost, "/v2/oci-test/app/blobs/uploads/", nil)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location := strings.TrimPrefix(resp.Header.Get("Location"), gateway.URL)
+	resp, _ = do(
+		http.MethodPatch,
+		location,
+		bytes.NewReader(config[:10]),
+		"Content-Range", "0-9",
+	)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "0-9", resp.Header.Get("Range"))
+	resp, _ = do(
+		http.MethodPut,
+		location+"?digest="+digest(config),
+		bytes.NewReader(config[10:]),
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, _ = do(
+		http.MethodPut,
+		"/v2/oci-test/app/blobs/uploads/unknown?digest="+digest(config),
+		nil,
+	)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Chunks sent concurrently are all kept
+	chunk := bytes.Repeat([]byte("c"), 64<<10)
+	resp, _ = do(http.MethodPost, "/v2/oci-test/app/blobs/uploads/", nil)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location = strings.TrimPrefix(resp.Header.Get("Location"), gateway.URL)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			resp, _ := do(http.MethodPatch, location, bytes.NewReader(chunk))
+			assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		})
+	}
+	wg.Wait()
+	resp, _ = do(
+		http.MethodPut,
+		location+"?digest="+digest(bytes.Repeat(chunk, 8)),
+		nil,
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     registry.OCIManifestMediaType,
+		"config": map[string]any{
+			"mediaType": registry.OCIConfigMediaType,
+			"digest":    digest(config),
+			"size":      len(config),
+		},
+		"layers": []map[string]any{{
+			"mediaType": registry.OCILayerMediaType,
+			"digest":    digest(layer),
+			"size":      len(layer),
+		}},
+	})
+	assert.NoError(t, err)
+	resp, _ = do(
+		http.MethodPut,
+		"/v2/oci-test/app/manifests/v1",
+		bytes.NewReader(manifest),
+		"Content-Type", registry.OCIManifestMediaType,
+	)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	manifestDigest := resp.Header.Get("Docker-Content-Digest")
+	// Clients check the digest of what they pushed
+	assert.Equal(t, digest(manifest), manifestDigest)
+
+	// The manifest is served as it was pushed
+	resp, body := do(http.MethodGet, "/v2/oci-test/app/manifests/v1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, manifest, body)
+	assert.Equal(t, manifestDigest, resp.Header.Get("Docker-Content-Digest"))
+	resp, body = do(
+		http.MethodGet,
+		"/v2/oci-test/app/manifests/"+manifestDigest,
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, manifest, body)
+	var pulled struct {
+		Config struct{ Digest string } `json:"config"`
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &pulled))
+	assert.Len(t, pulled.Layers, 1)
+	assert.Equal(t, digest(layer), pulled.Layers[0].Digest)
+	assert.Equal(t, registry.OCILayerMediaType, pulled.Layers[0].MediaType)
+
+	resp, body = do(
+		http.MethodGet,
+		"/v2/oci-test/app/blobs/"+pulled.Layers[0].Digest,
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, layer, body)
+	resp, body = do(
+		http.MethodGet,
+		"/v2/oci-test/app/blobs/"+pulled.Config.Digest,
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, config, body)
+
+	// Manifests whose reference is a digest must match it
+	resp, _ = do(
+		http.MethodPut,
+		"/v2/oci-test/app/manifests/"+digest(config),
+		bytes.NewReader(manifest),
+		"Content-Type", registry.OCIManifestMediaType,
+	)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = do(
+		http.MethodHead,
+		"/v2/oci-test/app/manifests/"+manifestDigest,
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body = do(http.MethodGet, "/v2/oci-test/app/tags/list", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"name": "oci-test/app", "tags": ["v1"]}`, string(body))
+
+	// Deleting a tag keeps the version
+	resp, _ = do(http.MethodDelete, "/v2/oci-test/app/manifests/v1", nil)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp, _ = do(http.MethodGet, "/v2/oci-test/app/manifests/v1", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = do(
+		http.MethodGet,
+		"/v2/oci-test/app/manifests/"+manifestDigest,
+		nil,
+	)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// TestPullThroughProxy tests that a proxy fetches content missing locally
+// from its upstream
+func TestPullThroughProxy(t *testing.T) {
+	t.Parallel()
+
+	upstreamPort := getAvailablePort(t)
+	upstream, startUpstream := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{Key: "port", Value: upstreamPort},
+	)
+	go startUpstream()
+
+	proxy, startProxy := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{
+			Key:   "proxy.upstream_host",
+			Value: "localhost",
+		},
+		configShareddeps.DefaultValue{
+			Key:   "proxy.upstream_port",
+			Value: upstreamPort,
+		},
+	)
+	go startProxy()
+
+	fqn := &proto_gen.PackageName{Namespace: "proxy-test", Name: "app"}
+	content := []byte("proxied content")
+	uploaded := uploadArtifact(t, upstream, fqn, []string{"v1"}, content)
+
+	// Both servers share the database, so only the blob is missing locally
+	pulled := pullArtifact(t, proxy, &proto_gen.ArtifactIdentifier{
+		Package:    fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+	})
+	assert.Equal(t, content, pulled)
+
+	pulled = pullArtifact(t, proxy, &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: uploaded.VersionHash,
+		},
+	})
+	assert.Equal(t, content, pulled)
+
+	// Misses of the upstream are misses of the proxy
+	_, err := proxy.GetArtifact(t.Context(), &proto_gen.ArtifactIdentifier{
+		Package:    fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "missing"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestPullThroughProxyQuota tests that versions fetched from the upstream
+// count towards the quota of the proxy
+func TestPullThroughProxyQuota(t *testing.T) {
+	t.Parallel()
+
+	upstreamPort := getAvailablePort(t)
+	upstream, startUpstream := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{Key: "port", Value: upstreamPort},
+	)
+	go startUpstream()
+
+	// The proxy has its own database, so the metadata is fetched as well
+	proxy, startProxy := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{
+			Key:   "database.path",
+			Value: filepath.Join(t.TempDir(), "proxy.db"),
+		},
+		configShareddeps.DefaultValue{
+			Key:   "proxy.upstream_host",
+			Value: "localhost",
+		},
+		configShareddeps.DefaultValue{
+			Key:   "proxy.upstream_port",
+			Value: upstreamPort,
+		},
+		configShareddeps.DefaultValue{Key: "quota.max_bytes", Value: 16},
+	)
+	go startProxy()
+
+	fqn := &proto_gen.PackageName{Namespace: "proxy-quota-test", Name: "app"}
+	small := []byte("small")
+	uploadArtifact(t, upstream, fqn, []string{"small"}, small)
+	uploadArtifact(
+		t,
+		upstream,
+		fqn,
+		[]string{"large"},
+		[]byte("content larger than the quota"),
+	)
+
+	pulled := pullArtifact(t, proxy, &proto_gen.ArtifactIdentifier{
+		Package:    fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "small"},
+	})
+	assert.Equal(t, small, pulled)
+
+	stream, err := proxy.PullArtifact(
+		t.Context(),
+		&proto_gen.ArtifactIdentifier{
+			Package:    fqn,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "large"},
+		},
+	)
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// TestReplication tests that uploads are replicated to a peer
+func TestReplication(t *testing.T) {
+	t.Parallel()
+
+	peerPort := getAvailablePort(t)
+	peer, startPeer := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{Key: "port", Value: peerPort},
+	)
+	go startPeer()
+
+	primary, startPrimary := configureServer(
+		t,
+		t.TempDir(),
+		configShareddeps.DefaultValue{
+			Key:   "replication.peers",
+			Value: []string{"localhost:" + strconv.Itoa(peerPort)},
+		},
+		configShareddeps.DefaultValue{
+			Key:   "replication.poll_interval",
+			Value: "100ms",
+		},
+	)
+	go startPrimary()
+
+	fqn := &proto_gen.PackageName{Namespace: "replication-test", Name: "app"}
+	content := []byte("replicated content")
+	uploaded := uploadArtifact(t, primary, fqn, []string{"v1"}, content)
+
+	// Both servers share the database, so the peer can only serve the
+	// content once it has been replicated
+	replicated := func(pkg *proto_gen.PackageName) bool {
+		stream, err := peer.PullArtifact(
+			t.Context(),
+			&proto_gen.ArtifactIdentifier{
+				Package: pkg,
+				Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+					VersionHash: uploaded.VersionHash,
+				},
+			},
+		)
+		if err != nil {
+			return false
+		}
+		chunk, err := stream.Recv()
+
+		return err == nil && bytes.Equal(content, chunk.Data)
+	}
+	assert.Eventually(t, func() bool {
+		return replicated(fqn)
+	}, 30*time.Second, 200*time.Millisecond)
+
+	// Moved versions are uploaded to the peer under their new name
+	moved := &proto_gen.PackageName{Namespace: fqn.Namespace, Name: "moved"}
+	_, err := primary.MovePackage(t.Context(), &proto_gen.MovePackageRequest{
+		From: fqn,
+		To:   moved,
+	})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return replicated(moved)
+	}, 30*time.Second, 200*time.Millisecond)
+
+	// The cursor of the peer moves once the push has succeeded
+	var replicationStatus *proto_gen.ReplicationStatus
+	assert.Eventually(t, func() bool {
+		var err error
+		replicationStatus, err = primary.GetReplicationStatus(
+			t.Context(),
+			&proto_gen.ReplicationStatusRequest{},
+		)
+
+		return err == nil && len(replicationStatus.Peers) == 1 &&
+			replicationStatus.Peers[0].LastReplicated != nil
+	}, 30*time.Second, 200*time.Millisecond)
+	assert.Equal(t, int64(0), replicationStatus.Peers[0].Failures)
+}
+
+// TestDeleteArtifact tests artifact deletion
+func TestDeleteArtifact(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{
+		Namespace: "delete-test-user",
+		Name:      "delete-test-app",
+	}
+	tags := []string{"v1.0.0"}
+	content := []byte("to be deleted")
+
+	artifact := uploadArtifact(t, client, fqn, tags, content)
+
+	// Verify artifact exists
+	getReq := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifact.VersionHash,
+		},
+	}
+
+	retrieved, err := client.GetArtifact(t.Context(), getReq)
+	assert.NoError(t, err)
+	assert.NotNil(t, retrieved)
+
+	// Delete the artifact
+	deleted, err := client.DeleteArtifact(t.Context(), getReq)
+	assert.NoError(t, err)
+	assert.NotNil(t, deleted)
+	assert.Equal(t, artifact.VersionHash, deleted.VersionHash)
+
+	// Verify artifact no longer exists
+	_, err = client.GetArtifact(t.Context(), getReq)
+	assert.Error(t, err)
+
+	// Verify pull fails
+	pullStream, err := client.PullArtifact(t.Context(), getReq)
+	assert.NoError(t, err)
+	_, err = pullStream.Recv()
+	assert.Error(t, err)
+}
+
+func TestDeleteNamespaceWithoutRecord(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	// Versions stored before namespaces had records. The database is shared
+	// with the server.
+	pkg := &proto_gen.PackageName{Namespace: "legacy-ns-test", Name: "app"}
+	err := testDB(t).CreateArtifactMeta(
+		t.Context(),
+		pkg,
+		"legacyhash",
+		orm.ArtifactDetails{},
+	)
+	assert.NoError(t, err)
+
+	ns, err := client.DeleteNamespace(
+		t.Context(),
+		&proto_gen.NamespaceName{Name: pkg.Namespace},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.Namespace, ns.GetName())
+
+	result, err := client.QueryArtifacts(t.Context(), &proto_gen.ArtifactQuery{
+		Namespace: &pkg.Namespace,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.GetArtifacts())
+
+	_, err = client.DeleteNamespace(
+		t.Context(),
+		&proto_gen.NamespaceName{Name: pkg.Namespace},
+	)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestNamespaceQuotaCountsTrash(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	ns := "quota-trash-test"
+	_, err := client.CreateNamespace(t.Context(), &proto_gen.Namespace{
+		Name:  ns,
+		Quota: &proto_gen.NamespaceQuota{MaxBytes: 20, MaxVersions: 1},
+	})
+	assert.NoError(t, err)
+
+	pkg := &proto_gen.PackageName{Namespace: ns, Name: "app"}
+	trashed := uploadArtifact(t, client, pkg, nil, []byte("0123456789"))
+	byHash := &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: trashed.VersionHash,
+		},
+	}
+	_, err = client.DeleteArtifact(t.Context(), byHash)
+	assert.NoError(t, err)
+
+	// The trashed blob still takes up 10 of the 20 bytes, but no version
+	namespace, err := client.GetNamespace(
+		t.Context(),
+		&proto_gen.NamespaceName{Name: ns},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), namespace.GetUsage().GetTrashedBytes())
+	assert.Zero(t, namespace.GetUsage().GetVersions())
+	_, err = tryUploadArtifact(t, client, pkg, []byte("0123456789abc"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	uploadArtifact(t, client, pkg, nil, []byte("abcdefghij"))
+
+	// Purging frees the storage again
+	_, err = client.PurgeArtifact(t.Context(), &proto_gen.DeletedIdentifier{
+		Package:     pkg,
+		VersionHash: trashed.VersionHash,
+	})
+	assert.NoError(t, err)
+	_, err = client.UpdateNamespace(t.Context(), &proto_gen.Namespace{
+		Name:  ns,
+		Quota: &proto_gen.NamespaceQuota{MaxBytes: 20, MaxVersions: 2},
+	})
+	assert.NoError(t, err)
+	uploadArtifact(t, client, pkg, nil, []byte("klmnopqrst"))
+}
+
+// purgeFailingStore fails to purge the version with the given hash
+type purgeFailingStore struct {
+	orm.MetadataStore
+
+	hash string
+}
+
+func (s *purgeFailingStore) PurgeArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	hash string,
+) error {
+	if hash == s.hash {
+		return io.ErrUnexpectedEOF
+	}
+
+	return s.MetadataStore.PurgeArtifactMeta(ctx, pkg, hash)
+}
+
+func TestPurgeExpiredArtifactsContinues(t *testing.T) {
+	t.Parallel()
+
+	store := orm.NewMemoryStore()
+	pkg := &proto_gen.PackageName{Namespace: "purge-test", Name: "app"}
+	for _, hash := range []string{"h1", "h2", "h3"} {
+		err := store.CreateArtifactMeta(
+			t.Context(),
+			pkg,
+			hash,
+			orm.ArtifactDetails{},
+		)
+		assert.NoError(t, err)
+		assert.NoError(t, store.TrashArtifactMeta(t.Context(), pkg, hash, ""))
+	}
+
+	cfg := &config.AppConfig{}
+	cfg.Trash.Retention = time.Nanosecond
+	server := registry.NewServer(
+		memoryRegistry.New(),
+		&purgeFailingStore{MetadataStore: store, hash: "h2"},
+		cfg,
+	)
+
+	// The failure is reported, but the other versions are purged
+	err := server.PurgeExpiredArtifacts(t.Context())
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	trashed, err := store.GetDeletedArtifactMetas(
+		t.Context(),
+		orm.TrashFilter{Namespace: pkg.Namespace},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, trashed, 1)
+	assert.Equal(t, "h2", trashed[0].Hash)
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	ns := "trash-test"
+	pkg := &proto_gen.PackageName{Namespace: ns, Name: "app"}
+	first := uploadArtifact(
+		t,
+		client,
+		pkg,
+		[]string{"latest", "v1"},
+		[]byte("trashed content"),
+	)
+	byHash := &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: first.VersionHash,
+		},
+	}
+
+	ctx := metadata.AppendToOutgoingContext(
+		t.Context(),
+		registry.UserMetadataKey,
+		"alice",
+	)
+	_, err := client.DeleteArtifact(ctx, byHash)
+	assert.NoError(t, err)
+
+	_, err = client.GetArtifact(t.Context(), byHash)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	deleted, err := client.ListDeleted(
+		t.Context(),
+		&proto_gen.DeletedQuery{Namespace: &ns},
+	)
+	assert.NoError(t, err)
+	if assert.Len(t, deleted.Artifacts, 1) {
+		entry := deleted.Artifacts[0]
+		assert.Equal(t, first.VersionHash, entry.Artifact.VersionHash)
+		assert.ElementsMatch(t, []string{"latest", "v1"}, entry.Tags)
+		assert.Equal(t, "alice", entry.DeletedBy)
+		assert.True(t, entry.Expires.AsTime().After(entry.Deleted.AsTime()))
+	}
+
+	// The tags were released and can be taken by another version
+	second := uploadArtifact(
+		t,
+		client,
+		pkg,
+		[]string{"latest"},
+		[]byte("replacement content"),
+	)
+
+	// The trashed version has to be restored instead of uploaded again, its
+	// content is kept for that
+	_, err = tryUploadArtifact(t, client, pkg, []byte("trashed content"))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "RestoreArtifact")
+
+	id := &proto_gen.DeletedIdentifier{
+		Package:     pkg,
+		VersionHash: first.VersionHash,
+	}
+	restored, err := client.RestoreArtifact(t.Context(), id)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v1"}, restored.Tags)
+	assert.Equal(
+		t,
+		[]byte("trashed content"),
+		pullArtifact(t, client, byHash),
+	)
+
+	latest, err := client.GetArtifact(t.Context(), &proto_gen.ArtifactIdentifier{
+		Package:    pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "latest"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, second.VersionHash, latest.VersionHash)
+
+	// Restoring twice fails, as the version is no longer in the trash
+	_, err = client.RestoreArtifact(t.Context(), id)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.DeleteArtifact(t.Context(), byHash)
+	assert.NoError(t, err)
+	purged, err := client.PurgeArtifact(t.Context(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1"}, purged.Tags)
+
+	_, err = client.RestoreArtifact(t.Context(), id)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	pullStream, err := client.PullArtifact(t.Context(), byHash)
+	assert.NoError(t, err)
+	_, err = pullStream.Recv()
+	assert.Error(t, err)
+
+	deleted, err = client.ListDeleted(
+		t.Context(),
+		&proto_gen.DeletedQuery{Namespace: &ns},
+	)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted.Artifacts)
+
+	// Purged versions can be uploaded again
+	uploadArtifact(t, client, pkg, nil, []byte("trashed content"))
+	assert.Equal(
+		t,
+		[]byte("trashed content"),
+		pullArtifact(t, client, byHash),
+	)
+}
+
+// TestDeleteArtifactByTag tests deletion using tag identifier
+func TestDeleteArtifactByTag(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{
+		Namespace: "delete-by-tag-test",
+		Name:      "delete-by-tag-app",
+	}
+	tags := []string{"v1.0.0", "deleteme"}
+	content := []byte("to be deleted by tag")
+
+	artifact := uploadArtifact(t, client, fqn, tags, content)
+
+	// Delete by tag
+	deleteReq := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{
+			Tag: "deleteme",
+		},
+	}
+
+	deleted, err := client.DeleteArtifact(t.Context(), deleteReq)
+	assert.NoError(t, err)
+	assert.Equal(t, artifact.VersionHash, deleted.VersionHash)
+
+	// Verify artifact is deleted
+	getReq := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifact.VersionHash,
+		},
+	}
+	_, err = client.GetArtifact(t.Context(), getReq)
+	assert.Error(t, err)
+}
+
+// TestMultipleVersions tests uploading multiple versions of the same artifact
+func TestMultipleVersions(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{
+		Namespace: "multiversion-test",
+		Name:      "multiversion-app",
+	}
+
+	// Upload version 1
+	v1Tags := []string{"v1.0.0"}
+	v1Content := []byte("version 1 content")
+	v1 := uploadArtifact(t, client, fqn, v1Tags, v1Content)
+
+	// Upload version 2
+	v2Tags := []string{"v2.0.0", "latest"}
+	v2Content := []byte("version 2 content - updated")
+	v2 := uploadArtifact(t, client, fqn, v2Tags, v2Content)
+
+	// Verify different hashes
+	assert.NotEqual(t, v1.VersionHash, v2.VersionHash)
+
+	// Query all versions
+	name := fqn.Name
+	resp, err := client.QueryArtifacts(
+		t.Context(),
+		&proto_gen.ArtifactQuery{Name: &name},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Artifacts, 2)
+
+	// Pull v1
+	v1Req := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{
+			Tag: "v1.0.0",
+		},
+	}
+	pulled := pullArtifact(t, client, v1Req)
+	assert.Equal(t, v1Content, pulled)
+
+	// Pull v2
+	v2Req := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{
+			Tag: "v2.0.0",
+		},
+	}
+	pulled = pullArtifact(t, client, v2Req)
+	assert.Equal(t, v2Content, pulled)
+
+	// Pull latest (should be v2)
+	latestReq := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{
+			Tag: "latest",
+		},
+	}
+	pulled = pullArtifact(t, client, latestReq)
+	assert.Equal(t, v2Content, pulled)
+}
+
+// TestLargeArtifact tests uploading and downloading a large artifact
+func TestLargeArtifact(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{
+		Namespace: "large-file-test",
+		Name:      "large-file-app",
+	}
+	tags := []string{"v1.0.0"}
+
+	// Create 10MB of content
+	largeContent := make([]byte, 10*1024*1024)
+	for i := range largeContent {
+		largeContent[i] = byte(i % 256)
+	}
+
+	artifact := uploadArtifact(t, client, fqn, tags, largeContent)
+	assert.NotEmpty(t, artifact.VersionHash)
+
+	// Pull and verify
+	pullReq := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifact.VersionHash,
+		},
+	}
+
+	pulled := pullArtifact(t, client, pullReq)
+	assert.Equal(t, largeContent, pulled)
+}
+
+// TestInvalidFQN tests validation of PackageName
+func TestInvalidFQN(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	testCases := []struct {
+		name string
+		fqn  *proto_gen.PackageName
+	}{
+		{
+			name: "missing namespace",
+			fqn: &proto_gen.PackageName{
+				Namespace: "",
+				Name:      "app",
+			},
+		},
+		{
+			name: "missing name",
+			fqn: &proto_gen.PackageName{
 				Namespace: "user",
 				Name:      "",
 			},
@@ -803,6 +2905,48 @@ func TestNonExistentArtifact(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// TestMissingArtifactContent tests that content missing from the storage is
+// reported as not found instead of as an internal error
+func TestMissingArtifactContent(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{
+		Namespace: "missing-content-test",
+		Name:      "testapp",
+	}
+	uploadArtifact(t, client, fqn, []string{"v1"}, []byte("missing content"))
+
+	// Servers share the database but not the storage
+	otherClient, startOtherServer := configureServer(t, t.TempDir())
+	go startOtherServer()
+
+	stream, err := otherClient.PullArtifact(
+		t.Context(),
+		&proto_gen.ArtifactIdentifier{
+			Package: fqn,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{
+				Tag: "v1",
+			},
+		},
+	)
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	var info *errdetails.ErrorInfo
+	for _, detail := range status.Convert(err).Details() {
+		if detail, ok := detail.(*errdetails.ErrorInfo); ok {
+			info = detail
+		}
+	}
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "ARTIFACT_CONTENT_NOT_FOUND", info.GetReason())
+	}
+}
+
 // TestArtifactWithMultipleTags tests artifact with multiple tags from the start
 func TestArtifactWithMultipleTags(t *testing.T) {
 	t.Parallel()
@@ -834,6 +2978,457 @@ func TestArtifactWithMultipleTags(t *testing.T) {
 	}
 }
 
+// configureGateway serves the HTTP gateway of a registry server backed by
+// the shared database
+func configureGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	cfg := &config.AppConfig{}
+	cfg.OCI.StagingDir = t.TempDir()
+
+	return startGateway(t, memoryRegistry.New(), testDB(t), cfg)
+}
+
+// startGateway starts a gateway with the given backends and configuration
+func startGateway(
+	t *testing.T,
+	reg registry.Registry,
+	db orm.MetadataStore,
+	cfg *config.AppConfig,
+) *httptest.Server {
+	t.Helper()
+
+	gateway := httptest.NewServer(
+		registry.NewServer(reg, db, cfg).HTTPHandler(),
+	)
+	t.Cleanup(gateway.Close)
+
+	return gateway
+}
+
+// gatewayRequest sends a request to the gateway and returns the response
+// with its body. headers are given as name/value pairs.
+func gatewayRequest(
+	t *testing.T,
+	gateway *httptest.Server,
+	method, path string,
+	body io.Reader,
+	headers ...string,
+) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(
+		t.Context(),
+		method,
+		gateway.URL+path,
+		body,
+	)
+	assert.NoError(t, err)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := gateway.Client().Do(req)
+	assert.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	content, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	return resp, content
+}
+
+// TestAuditLog tests that changes are recorded with their actor and tags and
+// can be listed page by page
+func TestAuditLog(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{Namespace: "audit-test", Name: "app"}
+	ctx := metadata.AppendToOutgoingContext(
+		t.Context(),
+		registry.UserMetadataKey,
+		"alice",
+	)
+
+	uploaded := uploadArtifactWithMetadata(
+		ctx,
+		t,
+		client,
+		&proto_gen.UploadMetadata{Fqn: fqn, Tags: []string{"v1"}},
+		[]byte("audited content"),
+	)
+	id := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: uploaded.VersionHash,
+		},
+	}
+
+	_, err := client.SetTags(ctx, &proto_gen.SetTagsRequest{
+		Artifact: id,
+		Tags:     []string{"v2", "latest"},
+	})
+	assert.NoError(t, err)
+
+	_, err = client.DeleteArtifact(ctx, id)
+	assert.NoError(t, err)
+
+	_, err = client.RestoreArtifact(ctx, &proto_gen.DeletedIdentifier{
+		Package:     fqn,
+		VersionHash: uploaded.VersionHash,
+	})
+	assert.NoError(t, err)
+	// Failed changes are recorded as well
+	_, err = client.RestoreArtifact(ctx, &proto_gen.DeletedIdentifier{
+		Package:     fqn,
+		VersionHash: uploaded.VersionHash,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	namespace := fqn.Namespace
+	listed, err := client.ListAuditEvents(t.Context(), &proto_gen.AuditQuery{
+		Namespace: &namespace,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, listed.NextPageToken)
+	assert.Len(t, listed.Events, 5)
+	if len(listed.Events) != 5 {
+		return
+	}
+
+	// Newest first
+	failedRestore := listed.Events[0]
+	assert.Equal(t, proto_gen.AuditAction_RESTORE, failedRestore.Action)
+	assert.False(t, failedRestore.Succeeded)
+	assert.NotEmpty(t, failedRestore.Error)
+
+	restore := listed.Events[1]
+	assert.Equal(t, proto_gen.AuditAction_RESTORE, restore.Action)
+	assert.True(t, restore.Succeeded)
+	assert.ElementsMatch(t, []string{"v2", "latest"}, restore.TagsAfter)
+
+	trash := listed.Events[2]
+	assert.Equal(t, proto_gen.AuditAction_TRASH, trash.Action)
+	assert.ElementsMatch(t, []string{"v2", "latest"}, trash.TagsBefore)
+	assert.Empty(t, trash.TagsAfter)
+
+	setTags := listed.Events[3]
+	assert.Equal(t, proto_gen.AuditAction_SET_TAGS, setTags.Action)
+	assert.Equal(t, []string{"v1"}, setTags.TagsBefore)
+	assert.ElementsMatch(t, []string{"v2", "latest"}, setTags.TagsAfter)
+
+	upload := listed.Events[4]
+	assert.Equal(t, proto_gen.AuditAction_UPLOAD, upload.Action)
+	assert.Equal(t, "alice", upload.Actor)
+	assert.NotEmpty(t, upload.ClientAddress)
+	assert.Equal(t, uploaded.VersionHash, upload.VersionHash)
+	assert.Equal(t, fqn.Name, upload.Package.Name)
+	assert.Empty(t, upload.TagsBefore)
+	assert.Equal(t, []string{"v1"}, upload.TagsAfter)
+	assert.True(t, upload.Succeeded)
+	assert.NotNil(t, upload.Time)
+
+	action := proto_gen.AuditAction_RESTORE
+	restores, err := client.ListAuditEvents(t.Context(), &proto_gen.AuditQuery{
+		Namespace: &namespace,
+		Action:    &action,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, restores.Events, 2)
+
+	// Paging returns the same events
+	var paged []*proto_gen.AuditEvent
+	query := &proto_gen.AuditQuery{Namespace: &namespace, PageSize: 2}
+	for range 4 {
+		page, err := client.ListAuditEvents(t.Context(), query)
+		assert.NoError(t, err)
+		paged = append(paged, page.Events...)
+		if page.NextPageToken == "" {
+			break
+		}
+		query.PageToken = page.NextPageToken
+	}
+	assert.Len(t, paged, len(listed.Events))
+
+	_, err = client.ListAuditEvents(t.Context(), &proto_gen.AuditQuery{
+		PageToken: "invalid",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestPullStats tests that pulls are counted per tag and client
+func TestPullStats(t *testing.T) {
+	t.Parallel()
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	fqn := &proto_gen.PackageName{Namespace: "pull-stats-test", Name: "app"}
+	uploaded := uploadArtifact(t, client, fqn, []string{"v1"}, []byte("pulled"))
+	byTag := &proto_gen.ArtifactIdentifier{
+		Package:    fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+	}
+	byHash := &proto_gen.ArtifactIdentifier{
+		Package: fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: uploaded.VersionHash,
+		},
+	}
+
+	retrieved, err := client.GetArtifact(t.Context(), byHash)
+	assert.NoError(t, err)
+	assert.Nil(t, retrieved.Metadata.LastPulledAt)
+
+	pullAs := func(user string, id *proto_gen.ArtifactIdentifier) {
+		ctx := metadata.AppendToOutgoingContext(
+			t.Context(),
+			registry.UserMetadataKey,
+			user,
+		)
+		stream, err := client.PullArtifact(ctx, id)
+		assert.NoError(t, err)
+		for err == nil {
+			_, err = stream.Recv()
+		}
+		assert.ErrorIs(t, err, io.EOF)
+	}
+	before := time.Now()
+	pullAs("alice", byTag)
+	pullAs("alice", byTag)
+	pullAs("bob", byHash)
+
+	retrieved, err = client.GetArtifact(t.Context(), byHash)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), retrieved.Metadata.Pulls)
+	assert.WithinDuration(
+		t,
+		time.Now(),
+		retrieved.Metadata.LastPulledAt.AsTime(),
+		time.Minute,
+	)
+
+	total, err := client.GetPullStats(t.Context(), &proto_gen.PullStatsRequest{
+		Package: fqn,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, total.Series, 1)
+	if len(total.Series) == 1 {
+		assert.Empty(t, total.Series[0].Key)
+		assert.Equal(t, int64(3), total.Series[0].Total)
+		assert.NotEmpty(t, total.Series[0].Buckets)
+	}
+
+	byTagStats, err := client.GetPullStats(
+		t.Context(),
+		&proto_gen.PullStatsRequest{
+			Package:  fqn,
+			Interval: proto_gen.PullStatsInterval_HOURLY,
+			Since:    timestamppb.New(before.Truncate(time.Hour)),
+			GroupBy:  proto_gen.PullStatsGrouping_BY_TAG,
+		},
+	)
+	assert.NoError(t, err)
+	totals := map[string]int64{}
+	for _, series := range byTagStats.Series {
+		totals[series.Key] = series.Total
+	}
+	assert.Equal(t, map[string]int64{"": 1, "v1": 2}, totals)
+
+	version := uploaded.VersionHash
+	byClient, err := client.GetPullStats(t.Context(), &proto_gen.PullStatsRequest{
+		Package:     fqn,
+		VersionHash: &version,
+		GroupBy:     proto_gen.PullStatsGrouping_BY_CLIENT,
+	})
+	assert.NoError(t, err)
+	totals = map[string]int64{}
+	for _, series := range byClient.Series {
+		totals[series.Key] = series.Total
+	}
+	assert.Equal(t, map[string]int64{"alice": 2, "bob": 1}, totals)
+
+	_, err = client.GetPullStats(t.Context(), &proto_gen.PullStatsRequest{
+		Package: &proto_gen.PackageName{Namespace: "pull-stats-test"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestConcurrentPulls tests that no pulls are lost when a version is pulled
+// concurrently, with pulls written immediately and buffered
+func TestConcurrentPulls(t *testing.T) {
+	t.Parallel()
+
+	for _, flushInterval := range []string{"0s", "50ms"} {
+		t.Run("flush interval "+flushInterval, func(t *testing.T) {
+			t.Parallel()
+
+			client, startServer := configureServer(
+				t,
+				t.TempDir(),
+				configShareddeps.DefaultValue{
+					Key:   "pull_stats.flush_interval",
+					Value: flushInterval,
+				},
+			)
+			go startServer()
+
+			fqn := &proto_gen.PackageName{
+				Namespace: "concurrent-pulls-test",
+				Name:      "app-" + flushInterval,
+			}
+			uploaded := uploadArtifact(t, client, fqn, nil, []byte("hot"))
+			id := &proto_gen.ArtifactIdentifier{
+				Package: fqn,
+				Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+					VersionHash: uploaded.VersionHash,
+				},
+			}
+
+			const pulls = 50
+			var wg sync.WaitGroup
+			for range pulls {
+				wg.Go(func() {
+					assert.Equal(t, []byte("hot"), pullArtifact(t, client, id))
+				})
+			}
+			wg.Wait()
+
+			assert.Eventually(t, func() bool {
+				retrieved, err := client.GetArtifact(t.Context(), id)
+
+				return err == nil && retrieved.Metadata.Pulls == pulls
+			}, 5*time.Second, 50*time.Millisecond)
+
+			stats, err := client.GetPullStats(
+				t.Context(),
+				&proto_gen.PullStatsRequest{
+					Package:  fqn,
+					Interval: proto_gen.PullStatsInterval_HOURLY,
+				},
+			)
+			assert.NoError(t, err)
+			var total int64
+			for _, series := range stats.Series {
+				total += series.Total
+			}
+			assert.Equal(t, int64(pulls), total)
+		})
+	}
+}
+
+// TestMigrations tests that replicas migrating concurrently wait for each
+// other and leave all migrations applied
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	cfg := testConfig(t, t.TempDir())
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			db := orm.Connect(cfg)
+			_, err := db.MigrateUp(t.Context())
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	db := orm.Connect(cfg)
+	statuses, err := db.GetMigrationStatus(t.Context())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, statuses)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d", status.Version)
+		assert.False(t, status.Unknown, "migration %d", status.Version)
+	}
+
+	// The baseline cannot be reverted without dropping all data
+	_, err = db.MigrateDown(t.Context(), len(statuses))
+	var badInput *orm.BadInputError
+	assert.ErrorAs(t, err, &badInput)
+	statuses, err = db.GetMigrationStatus(t.Context())
+	assert.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+
+	for _, args := range [][]string{
+		{},
+		{"sideways"},
+		{"up", "1"},
+		{"down", "0"},
+		{"down", "one"},
+	} {
+		err := runMigrate(nil, args, io.Discard)
+		assert.ErrorIs(t, err, errMigrateUsage, "args %v", args)
+	}
+}
+
+// TestTracing checks that the spans of an RPC, its database methods and its
+// storage calls join the trace of the caller
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, 1)
+	tracing.Install(provider)
+
+	client, startServer := configureServer(t, t.TempDir())
+	go startServer()
+
+	ctx, span := provider.Tracer("test").Start(t.Context(), "client")
+	header := http.Header{}
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+	ctx = metadata.AppendToOutgoingContext(
+		ctx,
+		"traceparent",
+		header.Get("traceparent"),
+	)
+
+	fqn := &proto_gen.PackageName{Namespace: "tracing-test", Name: "app"}
+	uploadArtifactWithMetadata(
+		ctx,
+		t,
+		client,
+		&proto_gen.UploadMetadata{Fqn: fqn, Tags: []string{"v1"}},
+		[]byte("traced content"),
+	)
+
+	stream, err := client.PullArtifact(ctx, &proto_gen.ArtifactIdentifier{
+		Package:    fqn,
+		Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: "v1"},
+	})
+	assert.NoError(t, err)
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	assert.ErrorIs(t, err, io.EOF)
+	span.End()
+
+	// The server ends its span after the client received the last message
+	var names []string
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, provider.ForceFlush(t.Context()))
+		names = names[:0]
+		for _, stub := range exporter.GetSpans() {
+			if stub.SpanContext.TraceID() == span.SpanContext().TraceID() {
+				names = append(names, stub.Name)
+			}
+		}
+
+		return slices.Contains(
+			names,
+			"registry.RegistryService/PullArtifact",
+		)
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Contains(t, names, "registry.RegistryService/UploadArtifact")
+	assert.Contains(t, names, "orm.CreateArtifactMeta")
+	assert.Contains(t, names, "orm.GetArtifactMetaByTag")
+	assert.Contains(t, names, "storage.StoreArtifact")
+	assert.Contains(t, names, "storage.GetArtifact")
+}
+
 // Helper function to upload an artifact
 func uploadArtifact(
 	t *testing.T,
@@ -844,16 +3439,31 @@ func uploadArtifact(
 ) *proto_gen.Artifact {
 	t.Helper()
 
-	stream, err := client.UploadArtifact(t.Context())
+	return uploadArtifactWithMetadata(
+		t.Context(),
+		t,
+		client,
+		&proto_gen.UploadMetadata{Fqn: fqn, Tags: tags},
+		content,
+	)
+}
+
+func uploadArtifactWithMetadata(
+	ctx context.Context,
+	t *testing.T,
+	client proto_gen.RegistryServiceClient,
+	metadata *proto_gen.UploadMetadata,
+	content []byte,
+) *proto_gen.Artifact {
+	t.Helper()
+
+	stream, err := client.UploadArtifact(ctx)
 	assert.NoError(t, err)
 
 	// Send metadata
 	err = stream.Send(&proto_gen.UploadArtifactRequest{
 		Request: &proto_gen.UploadArtifactRequest_Metadata{
-			Metadata: &proto_gen.UploadMetadata{
-				Fqn:  fqn,
-				Tags: tags,
-			},
+			Metadata: metadata,
 		},
 	})
 	assert.NoError(t, err)
@@ -883,6 +3493,34 @@ func uploadArtifact(
 	return artifact
 }
 
+// tryUploadArtifact uploads content in a single chunk and returns the
+// server's response, for uploads that are expected to be rejected
+func tryUploadArtifact(
+	t *testing.T,
+	client proto_gen.RegistryServiceClient,
+	fqn *proto_gen.PackageName,
+	content []byte,
+) (*proto_gen.Artifact, error) {
+	t.Helper()
+
+	stream, err := client.UploadArtifact(t.Context())
+	assert.NoError(t, err)
+
+	// Send errors surface in CloseAndRecv if the server aborted the stream
+	_ = stream.Send(&proto_gen.UploadArtifactRequest{
+		Request: &proto_gen.UploadArtifactRequest_Metadata{
+			Metadata: &proto_gen.UploadMetadata{Fqn: fqn},
+		},
+	})
+	_ = stream.Send(&proto_gen.UploadArtifactRequest{
+		Request: &proto_gen.UploadArtifactRequest_Content{
+			Content: &proto_gen.ArtifactContent{Data: content},
+		},
+	})
+
+	return stream.CloseAndRecv()
+}
+
 // Helper function to pull an artifact
 func pullArtifact(
 	t *testing.T,
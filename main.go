@@ -2,13 +2,25 @@ package main
 
 import (
 	"artifact-registry/config"
+	"artifact-registry/metrics"
 	"artifact-registry/orm"
 	proto "artifact-registry/proto_gen"
 	"artifact-registry/registry"
 	"artifact-registry/registry/filesystemRegistry"
+	"artifact-registry/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/EnclaveRunner/shareddeps"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -17,8 +29,36 @@ func main() {
 		cfg, "artifact-registry", "v0.5.1", config.Defaults...,
 	)
 
-	// initialize gRPC server
-	server := shareddeps.InitGRPCServer()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal().Err(err).Msg("Failed to migrate database")
+		}
+
+		return
+	}
+
+	// Background work stops and the server shuts down on a signal
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(ctx, cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize tracing")
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to flush traces")
+		}
+	}()
+
+	// initialize gRPC server, instrumented for metrics and tracing
+	server := grpc.NewServer(
+		append(metrics.ServerOptions(), tracing.ServerOptions()...)...,
+	)
 	db := orm.InitDB(cfg)
 	// Initialize filesystem registry
 	storageDir := filesystemRegistry.GetStorageDir(cfg)
@@ -31,10 +71,101 @@ func main() {
 		Str("storage_dir", storageDir).
 		Msg("Filesystem registry initialized")
 
-	proto.RegisterRegistryServiceServer(
-		server,
-		registry.NewServer(fsRegistry, db),
+	registryServer := registry.NewServer(
+		registry.InstrumentRegistry(fsRegistry, "filesystem"),
+		&db,
+		cfg,
 	)
+	proto.RegisterRegistryServiceServer(server, registryServer)
+	metrics.MustRegister(registryServer.UsageCollector())
+
+	// Fill in details of artifacts uploaded by older versions
+	go func() {
+		err := registryServer.BackfillArtifactDetails(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to backfill artifact details")
+		}
+	}()
+
+	// Finish package moves interrupted by a previous crash
+	go func() {
+		err := registryServer.ResumePackageMoves(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to resume package moves")
+		}
+	}()
+
+	go registryServer.RunTrashPurger(ctx, cfg.Trash.PurgeInterval)
+
+	go registryServer.RunPullStatsPruner(ctx, cfg.PullStats.PruneInterval)
+
+	var flusher sync.WaitGroup
+	flusher.Go(func() {
+		registryServer.RunPullFlusher(ctx, cfg.PullStats.FlushInterval)
+	})
+
+	go registryServer.RunReplication(ctx)
+
+	var httpServers []*http.Server
+	if cfg.HTTPPort != 0 {
+		gateway := newHTTPGateway(cfg.HTTPPort, registryServer)
+		httpServers = append(httpServers, gateway)
+		log.Info().Int("port", cfg.HTTPPort).Msg("Starting HTTP gateway")
+		go serveHTTP(gateway, "HTTP gateway")
+	}
+
+	if cfg.MetricsPort != 0 {
+		metricsServer := newMetricsServer(cfg.MetricsPort)
+		httpServers = append(httpServers, metricsServer)
+		log.Info().Int("port", cfg.MetricsPort).Msg("Serving metrics")
+		go serveHTTP(metricsServer, "metrics server")
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Info().Msg("Shutting down, waiting for running requests")
+		for _, httpServer := range httpServers {
+			err := httpServer.Shutdown(context.Background())
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to shut down HTTP server")
+			}
+		}
+		server.GracefulStop()
+	}()
 
 	shareddeps.StartGRPCServer(cfg, server)
+
+	// The flusher flushes once more when stopped, pulls served while the
+	// server drained are flushed after it
+	flusher.Wait()
+	if err := registryServer.FlushPulls(context.Background()); err != nil {
+		log.Error().Err(err).Msg("Failed to flush pulls")
+	}
+}
+
+func newHTTPGateway(port int, registryServer *registry.Server) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           registryServer.HTTPHandler(),
+		ReadHeaderTimeout: 10 * time.Second, //nolint:mnd // Slowloris guard
+	}
+}
+
+func newMetricsServer(port int) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second, //nolint:mnd // Slowloris guard
+	}
+}
+
+// serveHTTP serves until the server is shut down
+func serveHTTP(server *http.Server, name string) {
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal().Err(err).Msgf("Failed to start %s", name)
+	}
 }
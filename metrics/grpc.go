@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"artifact-registry/proto_gen"
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServerOptions instruments a gRPC server with the RPC metrics
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptor),
+		grpc.ChainStreamInterceptor(streamInterceptor),
+	}
+}
+
+func unaryInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeRPC(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+func streamInterceptor(
+	srv any,
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	method := path.Base(info.FullMethod)
+	activeStreams.WithLabelValues(method).Inc()
+	defer activeStreams.WithLabelValues(method).Dec()
+
+	start := time.Now()
+	err := handler(srv, &countingStream{ServerStream: stream})
+	observeRPC(info.FullMethod, start, err)
+
+	return err
+}
+
+// observeRPC records a finished RPC. Errors of the registry carry the code of
+// their ServiceError, which is what failures are broken down by.
+func observeRPC(fullMethod string, start time.Time, err error) {
+	method := path.Base(fullMethod)
+	code := status.Code(err)
+
+	rpcRequests.WithLabelValues(method, code.String()).Inc()
+	rpcDuration.
+		WithLabelValues(method, code.String()).
+		Observe(time.Since(start).Seconds())
+	if code != codes.OK {
+		failures.WithLabelValues(method, code.String()).Inc()
+	}
+}
+
+// countingStream counts the artifact content passing through a stream
+type countingStream struct {
+	grpc.ServerStream
+}
+
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if content, ok := m.(*proto_gen.ArtifactContent); ok && err == nil {
+		pulledBytes.Add(float64(len(content.Data)))
+	}
+
+	//nolint:wrapcheck // Errors of the stream are passed on unchanged
+	return err
+}
+
+func (s *countingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if request, ok := m.(*proto_gen.UploadArtifactRequest); ok && err == nil {
+		uploadedBytes.Add(float64(len(request.GetContent().GetData())))
+	}
+
+	//nolint:wrapcheck // Errors of the stream are passed on unchanged
+	return err
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"artifact-registry/proto_gen"
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/registry.RegistryService/TestUnary",
+	}
+	failing := func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	succeeding := func(context.Context, any) (any, error) {
+		return "ok", nil
+	}
+
+	_, err := unaryInterceptor(t.Context(), nil, info, failing)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	resp, err := unaryInterceptor(t.Context(), nil, info, succeeding)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	assert.InDelta(t, 1, testutil.ToFloat64(
+		rpcRequests.WithLabelValues("TestUnary", "NotFound"),
+	), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(
+		rpcRequests.WithLabelValues("TestUnary", "OK"),
+	), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(
+		failures.WithLabelValues("TestUnary", "NotFound"),
+	), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(
+		failures.WithLabelValues("TestUnary", "OK"),
+	), 0)
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	t.Parallel()
+
+	info := &grpc.StreamServerInfo{
+		FullMethod: "/registry.RegistryService/TestStream",
+	}
+	before := testutil.ToFloat64(pulledBytes)
+
+	err := streamInterceptor(
+		nil,
+		&fakeStream{},
+		info,
+		func(_ any, stream grpc.ServerStream) error {
+			assert.InDelta(t, 1, testutil.ToFloat64(
+				activeStreams.WithLabelValues("TestStream"),
+			), 0)
+
+			return stream.SendMsg(&proto_gen.ArtifactContent{Data: []byte("abc")})
+		},
+	)
+	assert.NoError(t, err)
+
+	assert.InDelta(t, 0, testutil.ToFloat64(
+		activeStreams.WithLabelValues("TestStream"),
+	), 0)
+	assert.InDelta(t, before+3, testutil.ToFloat64(pulledBytes), 0)
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+}
+
+func (s *fakeStream) SendMsg(any) error {
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package metrics collects the Prometheus metrics of the registry and serves
+// them for scraping
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "artifact_registry"
+
+// Registry holds all metrics of the registry, next to the Go runtime and
+// process metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	rpcRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Handled RPCs by method and status code.",
+	}, []string{"method", "code"})
+	rpcDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of handled RPCs by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	activeStreams = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpc_active_streams",
+		Help:      "Streaming RPCs in progress by method.",
+	}, []string{"method"})
+	failures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failures_total",
+		Help:      "Failed RPCs by method and the code of the returned error.",
+	}, []string{"method", "code"})
+
+	uploadedBytes = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploaded_bytes_total",
+		Help:      "Artifact content received by uploads.",
+	})
+	pulledBytes = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pulled_bytes_total",
+		Help:      "Artifact content sent by pulls.",
+	})
+
+	storageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of storage backend operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation", "result"})
+	storageBytes = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_bytes_total",
+		Help:      "Bytes written to and read from the storage backend.",
+	}, []string{"backend", "direction"})
+
+	dbQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database queries by operation and table.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "table", "result"})
+)
+
+// Storage usage is collected from the database on every scrape, see
+// MustRegister
+var (
+	NamespaceBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "namespace_storage_bytes"),
+		"Size of the versions in a namespace, excluding trashed ones.",
+		[]string{"namespace"},
+		nil,
+	)
+	NamespaceVersionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "namespace_versions"),
+		"Number of versions in a namespace, excluding trashed ones.",
+		[]string{"namespace"},
+		nil,
+	)
+	NamespaceTrashedBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "namespace_trashed_bytes"),
+		"Size of the trashed versions in a namespace.",
+		[]string{"namespace"},
+		nil,
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MustRegister adds collectors that are provided by other packages
+func MustRegister(cs ...prometheus.Collector) {
+	Registry.MustRegister(cs...)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveStorage records a single operation of a storage backend
+func ObserveStorage(
+	backend, operation string,
+	duration time.Duration,
+	err error,
+) {
+	storageDuration.
+		WithLabelValues(backend, operation, result(err)).
+		Observe(duration.Seconds())
+}
+
+// AddStorageBytes counts bytes written to a storage backend or read from it.
+// direction is either "write" or "read".
+func AddStorageBytes(backend, direction string, n int) {
+	storageBytes.WithLabelValues(backend, direction).Add(float64(n))
+}
+
+// ObserveDBQuery records a single database query
+func ObserveDBQuery(
+	operation, table string,
+	duration time.Duration,
+	err error,
+) {
+	dbQueryDuration.
+		WithLabelValues(operation, table, result(err)).
+		Observe(duration.Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "success"
+}
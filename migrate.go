@@ -0,0 +1,89 @@
+package main
+
+import (
+	"artifact-registry/config"
+	"artifact-registry/orm"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+var errMigrateUsage = errors.New(
+	"usage: artifact-registry migrate up | down [steps] | status",
+)
+
+// runMigrate runs the migrate subcommand. up applies all pending migrations,
+// down reverts the latest one or the given number of them and status lists
+// all migrations.
+func runMigrate(cfg *config.AppConfig, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	ctx := context.Background()
+	switch command := args[0]; {
+	case command == "up" && len(args) == 1:
+		db := orm.Connect(cfg)
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return fmt.Errorf("migrating up: %w", err)
+		}
+		fmt.Fprintf(out, "Applied %d migration(s)\n", applied)
+	case command == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+
+		db := orm.Connect(cfg)
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return fmt.Errorf("migrating down: %w", err)
+		}
+		fmt.Fprintf(out, "Reverted %d migration(s)\n", reverted)
+	case command == "status" && len(args) == 1:
+		db := orm.Connect(cfg)
+		statuses, err := db.GetMigrationStatus(ctx)
+		if err != nil {
+			return fmt.Errorf("getting migration status: %w", err)
+		}
+
+		return printMigrationStatus(out, statuses)
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
+
+func printMigrationStatus(
+	out io.Writer,
+	statuses []orm.MigrationStatus,
+) error {
+	//nolint:mnd // Two spaces between columns
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Unknown {
+			applied += " (unknown to this version)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("printing migration status: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// SystemActor is recorded for changes made without an AuditActor, e.g. by
+// background jobs of the registry
+const SystemActor = "system"
+
+// AuditActor identifies who made a change
+type AuditActor struct {
+	User          string
+	ClientAddress string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor attributes the changes made with the returned context to
+// actor
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActorFromContext(ctx context.Context) AuditActor {
+	actor, ok := ctx.Value(auditActorKey{}).(AuditActor)
+	if !ok {
+		return AuditActor{User: SystemActor}
+	}
+
+	return actor
+}
+
+// AuditFilter restricts the audit events that are returned. Empty fields
+// match everything.
+type AuditFilter struct {
+	Actor     string
+	Action    string
+	Namespace string
+	Name      string
+	Hash      string
+	// Only events recorded in [Since, Until)
+	Since time.Time
+	Until time.Time
+	// Only events older than the one with this ID, for paging
+	BeforeID uint
+	Limit    int
+}
+
+// audit runs change in a transaction and records it in the audit log. The
+// change may fill in the tags of the event. A failed change is recorded after
+// the rollback, if that fails as well it is only logged.
+func (db *DB) audit(
+	ctx context.Context,
+	event *AuditEvent,
+	change func(tx *gorm.DB) error,
+) error {
+	actor := auditActorFromContext(ctx)
+	event.Actor = actor.User
+	event.ClientAddress = actor.ClientAddress
+
+	err := db.dbGorm.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+
+		event.Succeeded = true
+
+		return wrapErrorWithDetails(
+			gorm.G[AuditEvent](tx).Create(ctx, event),
+			"record audit event",
+			auditDetails(event),
+		)
+	})
+	if err == nil {
+		return nil
+	}
+
+	event.ID = 0
+	event.Succeeded = false
+	event.Error = err.Error()
+	// Failures are recorded even if they were caused by the cancellation
+	recordErr := gorm.G[AuditEvent](db.dbGorm).
+		Create(context.WithoutCancel(ctx), event)
+	if recordErr != nil {
+		log.Warn().
+			Err(recordErr).
+			Str("event", auditDetails(event)).
+			Msg("Failed to record failed change in audit log")
+	}
+
+	//nolint:wrapcheck // Error already wrapped
+	return err
+}
+
+// RecordRejection records a change that was rejected before it reached the
+// database, e.g. an upload over the quota of its namespace
+func (db *DB) RecordRejection(
+	ctx context.Context,
+	event *AuditEvent,
+	reason error,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.RecordRejection")
+	defer span.End()
+
+	actor := auditActorFromContext(ctx)
+	event.Actor = actor.User
+	event.ClientAddress = actor.ClientAddress
+	event.Succeeded = false
+	event.Error = reason.Error()
+
+	// Rejections are recorded even if they were caused by the cancellation
+	return wrapErrorWithDetails(
+		gorm.G[AuditEvent](db.dbGorm).Create(context.WithoutCancel(ctx), event),
+		"record rejected change",
+		auditDetails(event),
+	)
+}
+
+func newAuditEvent(
+	action string,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) *AuditEvent {
+	return &AuditEvent{
+		Action:    action,
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+	}
+}
+
+func auditDetails(event *AuditEvent) string {
+	return fmt.Sprintf(
+		"action=%q, actor=%q, namespace=%q, name=%q, hash=%q",
+		event.Action,
+		event.Actor,
+		event.Namespace,
+		event.Name,
+		event.Hash,
+	)
+}
+
+// versionTags returns the tags currently pointing at a version
+func versionTags(
+	ctx context.Context,
+	tx *gorm.DB,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) ([]string, error) {
+	tags, err := gorm.G[Tag](tx).Where(&Tag{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+	}).Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get tags",
+			fmt.Sprintf(
+				"namespace=%q, name=%q, hash=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+			),
+		)
+	}
+
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.TagName
+	}
+
+	return names, nil
+}
+
+// GetAuditEvents returns the audit events matching the filter, newest first
+func (db *DB) GetAuditEvents(
+	ctx context.Context,
+	filter AuditFilter,
+) ([]AuditEvent, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetAuditEvents")
+	defer span.End()
+
+	query := gorm.G[AuditEvent](db.dbGorm).Where(&AuditEvent{
+		Actor:     filter.Actor,
+		Action:    filter.Action,
+		Namespace: filter.Namespace,
+		Name:      filter.Name,
+		Hash:      filter.Hash,
+	})
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	events, err := query.Order("id DESC").Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get audit events",
+			fmt.Sprintf("%+v", filter),
+		)
+	}
+
+	return events, nil
+}
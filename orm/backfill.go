@@ -0,0 +1,96 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GetArtifactMetasWithoutDetails returns up to limit artifacts that were
+// created before size and media type were recorded. Results are ordered by
+// primary key and start after the given artifact, so callers can page
+// through all rows even if some of them cannot be backfilled.
+func (db *DB) GetArtifactMetasWithoutDetails(
+	ctx context.Context,
+	after *Artifact,
+	limit int,
+) ([]Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetArtifactMetasWithoutDetails")
+	defer span.End()
+
+	query := gorm.G[Artifact](db.dbGorm).Where("media_type = ''")
+	if after != nil {
+		query = query.Where(
+			"(namespace, name, hash) > (?, ?, ?)",
+			after.Namespace,
+			after.Name,
+			after.Hash,
+		)
+	}
+
+	artifacts, err := query.
+		Order("namespace, name, hash").
+		Limit(limit).
+		Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get artifacts without details",
+			fmt.Sprintf("limit=%d", limit),
+		)
+	}
+
+	return artifacts, nil
+}
+
+// SetArtifactSizeAndMediaType updates the properties that can be derived from
+// the stored blob
+func (db *DB) SetArtifactSizeAndMediaType(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	sizeBytes int64,
+	mediaType string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.SetArtifactSizeAndMediaType")
+	defer span.End()
+
+	if pkg == nil {
+		return &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	if versionHash == "" || mediaType == "" || pkg.Namespace == "" ||
+		pkg.Name == "" {
+		return &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q, hash=%q, mediaType=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+				mediaType,
+			),
+		}
+	}
+
+	_, err := gorm.G[Artifact](db.dbGorm).Where(&Artifact{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+	}).Updates(ctx, Artifact{SizeBytes: sizeBytes, MediaType: mediaType})
+
+	return wrapErrorWithDetails(
+		err,
+		"set artifact size and media type",
+		fmt.Sprintf(
+			"namespace=%s, name=%s, hash=%s",
+			pkg.Namespace,
+			pkg.Name,
+			versionHash,
+		),
+	)
+}
@@ -0,0 +1,109 @@
+package orm
+
+import (
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ArtifactRef references a version of a package either by Hash or by Tag
+type ArtifactRef struct {
+	Namespace string
+	Name      string
+	Hash      string
+	Tag       string
+}
+
+// GetArtifactMetasByRefs resolves many references at once. The result has
+// the same length and order as refs, with nil for references that do not
+// match any version.
+func (db *DB) GetArtifactMetasByRefs(
+	ctx context.Context,
+	refs []ArtifactRef,
+) ([]*Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetArtifactMetasByRefs")
+	defer span.End()
+
+	byHash := make([]any, 0, len(refs))
+	byTag := make([]any, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Namespace == "" || ref.Name == "" ||
+			(ref.Hash == "") == (ref.Tag == "") {
+			return nil, &BadInputError{
+				Reason: fmt.Sprintf(
+					"Either hash or tag must be provided: "+
+						"namespace=%q, name=%q, hash=%q, tag=%q",
+					ref.Namespace,
+					ref.Name,
+					ref.Hash,
+					ref.Tag,
+				),
+			}
+		}
+
+		if ref.Hash != "" {
+			byHash = append(byHash, []any{ref.Namespace, ref.Name, ref.Hash})
+		} else {
+			byTag = append(byTag, []any{ref.Namespace, ref.Name, ref.Tag})
+		}
+	}
+
+	result := make([]*Artifact, len(refs))
+	if len(refs) == 0 {
+		return result, nil
+	}
+
+	// Empty lists must be left out, as they would be compared against NULL
+	matches := db.dbGorm.Session(&gorm.Session{NewDB: true})
+	if len(byHash) > 0 {
+		matches = matches.Or("(namespace, name, hash) IN ?", byHash)
+	}
+	if len(byTag) > 0 {
+		tagged := db.dbGorm.Session(&gorm.Session{NewDB: true}).
+			Model(&Tag{}).
+			Select("namespace, name, hash").
+			Where("(namespace, name, tag_name) IN ?", byTag)
+		matches = matches.Or("(namespace, name, hash) IN (?)", tagged)
+	}
+
+	artifacts, err := withArtifactAssociations(
+		gorm.G[Artifact](db.dbGorm).Where(matches),
+	).Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get artifacts by refs",
+			fmt.Sprintf("refs=%d", len(refs)),
+		)
+	}
+
+	for i, ref := range refs {
+		for j := range artifacts {
+			if artifacts[j].Namespace == ref.Namespace &&
+				artifacts[j].Name == ref.Name &&
+				artifacts[j].matches(ref) {
+				result[i] = &artifacts[j]
+
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (a *Artifact) matches(ref ArtifactRef) bool {
+	if ref.Hash != "" {
+		return a.Hash == ref.Hash
+	}
+
+	for _, tag := range a.Tags {
+		if tag.TagName == ref.Tag {
+			return true
+		}
+	}
+
+	return false
+}
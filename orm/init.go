@@ -2,9 +2,12 @@ package orm
 
 import (
 	"artifact-registry/config"
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/rs/zerolog/log"
 	"gorm.io/driver/postgres"
 
@@ -13,49 +16,117 @@ import (
 	"gorm.io/gorm"
 )
 
+// Supported values of the database.driver option
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqliteOptions enforce foreign keys and let concurrent writers wait for
+// each other. Transactions take the write lock right away, a read lock
+// cannot be upgraded while another connection is writing.
+const sqliteOptions = "?_pragma=foreign_keys(1)" +
+	"&_pragma=busy_timeout(10000)" +
+	"&_pragma=journal_mode(WAL)" +
+	"&_txlock=immediate"
+
 type DB struct {
 	dbGorm *gorm.DB
+	// Record changes in the replication outbox
+	outbox bool
 }
 
+// InitDB connects to the database and, unless disabled, applies pending
+// migrations
 func InitDB(cfg *config.AppConfig) DB {
-	dsn := fmt.Sprintf(
-		"host='%s' port='%d' user='%s' password='%s' dbname='%s' sslmode='%s'",
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Username,
-		cfg.Database.Password,
-		cfg.Database.Database,
-		cfg.Database.SSLMode,
-	)
-
-	dsn_redacted := strings.ReplaceAll(dsn, cfg.Database.Password, "*****")
-	log.Debug().
-		Msgf("Connecting to postgres using the following information: %s", dsn_redacted)
-
-	var err error
-	dbGorm, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db := Connect(cfg)
+
+	if !cfg.Database.AutoMigrate {
+		warnAboutPendingMigrations(&db)
+
+		return db
+	}
+
+	if _, err := db.MigrateUp(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to migrate database")
+	}
+
+	return db
+}
+
+// Connect connects to the database without changing the schema
+func Connect(cfg *config.AppConfig) DB {
+	gormConfig := &gorm.Config{
 		Logger:         logger.Default.LogMode(logger.Silent),
 		TranslateError: true,
-	})
+	}
+
+	var dialector gorm.Dialector
+	switch cfg.Database.Driver {
+	case DriverSQLite:
+		log.Debug().
+			Str("path", cfg.Database.Path).
+			Msg("Opening SQLite database")
+
+		dialector = sqlite.Open(cfg.Database.Path + sqliteOptions)
+		// SQLite compares times as text, which needs a single time zone
+		gormConfig.NowFunc = func() time.Time { return time.Now().UTC() }
+	default:
+		dsn := fmt.Sprintf(
+			"host='%s' port='%d' user='%s' password='%s' dbname='%s' sslmode='%s'",
+			cfg.Database.Host,
+			cfg.Database.Port,
+			cfg.Database.Username,
+			cfg.Database.Password,
+			cfg.Database.Database,
+			cfg.Database.SSLMode,
+		)
+
+		dsn_redacted := strings.ReplaceAll(dsn, cfg.Database.Password, "*****")
+		log.Debug().Msgf(
+			"Connecting to postgres using the following information: %s",
+			dsn_redacted,
+		)
+
+		dialector = postgres.Open(dsn)
+	}
+
+	dbGorm, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to the database")
 	}
 
 	log.Debug().Msg("Successfully connected to the database")
 
-	// Run database migrations
-	err = dbGorm.AutoMigrate(&Artifact{}, &Tag{})
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to migrate database")
+	if err := registerQueryMetrics(dbGorm); err != nil {
+		log.Fatal().Err(err).Msg("Failed to register query metrics")
 	}
 
 	return DB{dbGorm: dbGorm}
 }
 
+func warnAboutPendingMigrations(db *DB) {
+	statuses, err := db.GetMigrationStatus(context.Background())
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to check for pending migrations")
+
+		return
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			log.Warn().
+				Uint("version", status.Version).
+				Str("name", status.Name).
+				Msg("Migration is pending, run the migrate subcommand")
+		}
+	}
+}
+
 // UseTransaction returns a new DB instance that uses the provided gorm.DB
 // transaction.
 func (db *DB) UseTransaction(tx *gorm.DB) DB {
 	// By only allowing transactions to be set via this method,
 	// it is ensured that the function is called with an initialized db instance.
-	return DB{dbGorm: tx}
+	return DB{dbGorm: tx, outbox: db.outbox}
 }
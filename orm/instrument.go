@@ -0,0 +1,86 @@
+package orm
+
+import (
+	"artifact-registry/metrics"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+// registerQueryMetrics times every query through GORM callbacks that run
+// around all other callbacks of an operation and records it on the span of
+// the calling method
+func registerQueryMetrics(dbGorm *gorm.DB) error {
+	callback := dbGorm.Callback()
+
+	//nolint:wrapcheck // Registration only fails on conflicting names
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:start", startQuery),
+		callback.Create().After("*").
+			Register("metrics:observe", observeQuery("create")),
+		callback.Query().Before("*").Register("metrics:start", startQuery),
+		callback.Query().After("*").
+			Register("metrics:observe", observeQuery("query")),
+		callback.Update().Before("*").Register("metrics:start", startQuery),
+		callback.Update().After("*").
+			Register("metrics:observe", observeQuery("update")),
+		callback.Delete().Before("*").Register("metrics:start", startQuery),
+		callback.Delete().After("*").
+			Register("metrics:observe", observeQuery("delete")),
+		callback.Row().Before("*").Register("metrics:start", startQuery),
+		callback.Row().After("*").
+			Register("metrics:observe", observeQuery("row")),
+		callback.Raw().Before("*").Register("metrics:start", startQuery),
+		callback.Raw().After("*").
+			Register("metrics:observe", observeQuery("raw")),
+	)
+}
+
+func startQuery(tx *gorm.DB) {
+	tx.InstanceSet(queryStartKey, time.Now())
+}
+
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		elapsed := time.Since(start)
+
+		// Lookups that find nothing are answered by the database just fine
+		err := tx.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+
+		metrics.ObserveDBQuery(
+			operation,
+			tx.Statement.Table,
+			elapsed,
+			err,
+		)
+
+		span := trace.SpanFromContext(tx.Statement.Context)
+		span.AddEvent("db."+operation, trace.WithAttributes(
+			attribute.String("db.sql.table", tx.Statement.Table),
+			attribute.Int64("db.rows_affected", tx.RowsAffected),
+			attribute.Int64("db.duration_us", elapsed.Microseconds()),
+		))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
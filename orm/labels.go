@@ -0,0 +1,201 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// LabelOperator is the comparison a LabelSelector applies
+type LabelOperator int
+
+const (
+	LabelEquals LabelOperator = iota
+	LabelNotEquals
+	LabelExists
+	LabelDoesNotExist
+	LabelIn
+	LabelNotIn
+)
+
+// LabelSelector matches artifacts by one of their labels. Values holds a
+// single value for LabelEquals and LabelNotEquals, any number for LabelIn and
+// LabelNotIn and is ignored otherwise. Like Kubernetes label selectors, the
+// negated operators also match artifacts that do not have the label at all.
+type LabelSelector struct {
+	Key      string
+	Operator LabelOperator
+	Values   []string
+}
+
+func (db *DB) SetLabels(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	labels map[string]string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.SetLabels")
+	defer span.End()
+
+	if pkg == nil {
+		return &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	if versionHash == "" || pkg.Namespace == "" ||
+		pkg.Name == "" {
+		return &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q, hash=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+			),
+		}
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, hash=%q, labels=%v",
+		pkg.Namespace,
+		pkg.Name,
+		versionHash,
+		labels,
+	)
+
+	event := newAuditEvent(AuditSetLabels, pkg, versionHash)
+	event.Detail = formatLabels(labels)
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		_, err := gorm.G[ArtifactLabel](tx).Where(ArtifactLabel{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+		}).Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"delete existing labels",
+				detailString,
+			)
+		}
+
+		modelLabels := labelsToModel(pkg, versionHash, labels)
+		if len(modelLabels) == 0 {
+			return nil
+		}
+
+		//nolint:mnd // 100 is a reasonable batch size for label updates
+		err = gorm.G[ArtifactLabel](tx).
+			CreateInBatches(ctx, &modelLabels, 100)
+
+		return wrapErrorWithDetails(err, "set labels", detailString)
+	})
+}
+
+// labelsToModel converts a label map into rows, ordered by key
+func labelsToModel(
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	labels map[string]string,
+) []ArtifactLabel {
+	result := make([]ArtifactLabel, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		result = append(result, ArtifactLabel{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+			Key:       key,
+			Value:     labels[key],
+		})
+	}
+
+	return result
+}
+
+// formatLabels renders labels ordered by key, e.g. for the audit log
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+
+	return strings.Join(pairs, ", ")
+}
+
+// validateLabelSelector checks that a selector has a key and the number of
+// values its operator needs
+func validateLabelSelector(selector LabelSelector) error {
+	if selector.Key == "" {
+		return &BadInputError{Reason: "label selector without key"}
+	}
+
+	switch selector.Operator {
+	case LabelEquals, LabelNotEquals:
+		if len(selector.Values) != 1 {
+			return &BadInputError{
+				Reason: fmt.Sprintf(
+					"label selector on %q needs exactly one value",
+					selector.Key,
+				),
+			}
+		}
+	case LabelIn, LabelNotIn:
+		if len(selector.Values) == 0 {
+			return &BadInputError{
+				Reason: fmt.Sprintf(
+					"label selector on %q needs at least one value",
+					selector.Key,
+				),
+			}
+		}
+	case LabelExists, LabelDoesNotExist:
+	default:
+		return &BadInputError{
+			Reason: fmt.Sprintf("unknown label operator %d", selector.Operator),
+		}
+	}
+
+	return nil
+}
+
+// labelCondition builds the WHERE condition of a single label selector
+func (db *DB) labelCondition(selector LabelSelector) (string, any, error) {
+	if err := validateLabelSelector(selector); err != nil {
+		return "", nil, err
+	}
+
+	subQuery := db.dbGorm.Model(&ArtifactLabel{}).
+		Select("1").
+		Where("artifact_labels.namespace = artifacts.namespace").
+		Where("artifact_labels.name = artifacts.name").
+		Where("artifact_labels.hash = artifacts.hash").
+		Where("artifact_labels.key = ?", selector.Key)
+
+	switch selector.Operator {
+	case LabelEquals, LabelNotEquals:
+		subQuery = subQuery.Where(
+			"artifact_labels.value = ?",
+			selector.Values[0],
+		)
+	case LabelIn, LabelNotIn:
+		subQuery = subQuery.Where(
+			"artifact_labels.value IN ?",
+			selector.Values,
+		)
+	case LabelExists, LabelDoesNotExist:
+	}
+
+	switch selector.Operator {
+	case LabelNotEquals, LabelNotIn, LabelDoesNotExist:
+		return "NOT EXISTS (?)", subQuery, nil
+	default:
+		return "EXISTS (?)", subQuery, nil
+	}
+}
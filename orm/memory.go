@@ -0,0 +1,2027 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var _ MetadataStore = (*MemoryStore)(nil)
+
+// MemoryStore implements MetadataStore in memory. Used only for testing.
+//
+// It mirrors the constraints of the database: writing tags or labels of a
+// version that does not exist fails, but with a NotFoundError instead of a
+// DatabaseError for the violated foreign key.
+type MemoryStore struct {
+	*memoryData
+
+	// Record changes in the replication outbox
+	outbox bool
+}
+
+type memoryData struct {
+	mu sync.RWMutex
+
+	// Trashed artifacts stay in here with DeletedAt set
+	artifacts map[artifactKey]*Artifact
+	// Hash of the version each tag points at
+	tags       map[tagKey]string
+	packages   map[packageKey]*Package
+	namespaces map[string]*Namespace
+	// Ordered by ID
+	moves     []PackageMove
+	redirects map[packageKey]*PackageRedirect
+	// Ordered by ID
+	events      []ReplicationEvent
+	cursors     map[string]*ReplicationCursor
+	auditEvents []AuditEvent
+	pulls       map[pullBucketKey]*PullBucket
+
+	lastSymbolID uint
+	lastMoveID   uint
+	lastEventID  uint
+	lastAuditID  uint
+}
+
+type packageKey struct {
+	namespace, name string
+}
+
+type artifactKey struct {
+	namespace, name, hash string
+}
+
+type tagKey struct {
+	namespace, name, tag string
+}
+
+type pullBucketKey struct {
+	namespace, name, hash, tag, client, granularity string
+	start                                           time.Time
+}
+
+// NewMemoryStore creates an empty in-memory metadata store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		memoryData: &memoryData{
+			artifacts:  make(map[artifactKey]*Artifact),
+			tags:       make(map[tagKey]string),
+			packages:   make(map[packageKey]*Package),
+			namespaces: make(map[string]*Namespace),
+			redirects:  make(map[packageKey]*PackageRedirect),
+			cursors:    make(map[string]*ReplicationCursor),
+			pulls:      make(map[pullBucketKey]*PullBucket),
+		},
+	}
+}
+
+// WithReplicationOutbox returns a store on the same data that records
+// uploads, deletions and tag changes in the replication outbox
+func (m *MemoryStore) WithReplicationOutbox() MetadataStore {
+	return &MemoryStore{memoryData: m.memoryData, outbox: true}
+}
+
+func (m *MemoryStore) GetArtifactMetaByHash(
+	_ context.Context,
+	pkg *proto_gen.PackageName,
+	hash string,
+) (*Artifact, error) {
+	if err := validateArtifactKey(pkg, hash); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.artifactByHash(pkg, hash)
+}
+
+func (m *MemoryStore) GetArtifactMetaByTag(
+	_ context.Context,
+	pkg *proto_gen.PackageName,
+	tag string,
+) (*Artifact, error) {
+	if pkg == nil {
+		return nil, &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	if tag == "" || pkg.Namespace == "" || pkg.Name == "" {
+		return nil, &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q, tag=%q",
+				pkg.Namespace,
+				pkg.Name,
+				tag,
+			),
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hash, ok := m.tags[tagKey{pkg.Namespace, pkg.Name, tag}]
+	if !ok {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf(
+				"get tag (namespace=%s, name=%s, tag=%s)",
+				pkg.Namespace,
+				pkg.Name,
+				tag,
+			),
+		}
+	}
+
+	return m.artifactByHash(pkg, hash)
+}
+
+func (m *MemoryStore) GetArtifactMetasByFQN(
+	_ context.Context,
+	pkg *proto_gen.PackageName,
+) ([]Artifact, error) {
+	if pkg == nil {
+		return nil, &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findArtifacts(false, func(a *Artifact) bool {
+		return inPackage(a, pkg.Namespace, pkg.Name)
+	}), nil
+}
+
+func (m *MemoryStore) GetArtifactMetasByRefs(
+	_ context.Context,
+	refs []ArtifactRef,
+) ([]*Artifact, error) {
+	for _, ref := range refs {
+		if ref.Namespace == "" || ref.Name == "" ||
+			(ref.Hash == "") == (ref.Tag == "") {
+			return nil, &BadInputError{
+				Reason: fmt.Sprintf(
+					"Either hash or tag must be provided: "+
+						"namespace=%q, name=%q, hash=%q, tag=%q",
+					ref.Namespace,
+					ref.Name,
+					ref.Hash,
+					ref.Tag,
+				),
+			}
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Artifact, len(refs))
+	for i, ref := range refs {
+		hash := ref.Hash
+		if ref.Tag != "" {
+			hash = m.tags[tagKey{ref.Namespace, ref.Name, ref.Tag}]
+		}
+
+		row := m.liveArtifact(artifactKey{ref.Namespace, ref.Name, hash})
+		if row != nil {
+			artifact := m.load(row)
+			result[i] = &artifact
+		}
+	}
+
+	return result, nil
+}
+
+// GetArtifactHashes returns the hashes of all versions of a package, including
+// trashed ones
+func (m *MemoryStore) GetArtifactHashes(
+	_ context.Context,
+	name *proto_gen.PackageName,
+) ([]string, error) {
+	if err := validatePackageName(name); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hashes []string
+	for key := range m.artifacts {
+		if key.namespace == name.Namespace && key.name == name.Name {
+			hashes = append(hashes, key.hash)
+		}
+	}
+	slices.Sort(hashes)
+
+	return hashes, nil
+}
+
+func (m *MemoryStore) QueryArtifactMetas(
+	_ context.Context,
+	filter ArtifactFilter,
+) ([]Artifact, error) {
+	for _, symbol := range filter.Symbols {
+		if symbol.Name == "" ||
+			(symbol.Direction != SymbolImport && symbol.Direction != SymbolExport) {
+			return nil, &BadInputError{
+				Reason: fmt.Sprintf(
+					"symbol filter needs a name and a direction: direction=%q, name=%q",
+					symbol.Direction,
+					symbol.Name,
+				),
+			}
+		}
+	}
+
+	for _, selector := range filter.Labels {
+		if err := validateLabelSelector(selector); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findArtifacts(false, func(a *Artifact) bool {
+		if !inPackage(a, filter.Namespace, filter.Name) {
+			return false
+		}
+
+		for _, symbol := range filter.Symbols {
+			if !slices.ContainsFunc(a.Symbols, symbol.matches) {
+				return false
+			}
+		}
+
+		for _, selector := range filter.Labels {
+			if !selector.matches(a.Labels) {
+				return false
+			}
+		}
+
+		return true
+	}), nil
+}
+
+func (m *MemoryStore) CreateArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	details ArtifactDetails,
+	tags ...string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, hash=%q, tags=%v",
+		pkg.Namespace,
+		pkg.Name,
+		versionHash,
+		tags,
+	)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditUpload, pkg, versionHash)
+	event.TagsAfter = tags
+
+	return m.audit(ctx, event, func() error {
+		key := artifactKey{pkg.Namespace, pkg.Name, versionHash}
+		if _, ok := m.artifacts[key]; ok {
+			return &ConflictError{
+				Conflict: "create artifact metadata (" + detailString + ")",
+			}
+		}
+
+		symbols := details.Symbols
+		for i := range symbols {
+			m.lastSymbolID++
+			symbols[i].ID = m.lastSymbolID
+			symbols[i].Namespace = pkg.Namespace
+			symbols[i].Name = pkg.Name
+			symbols[i].Hash = versionHash
+		}
+
+		m.artifacts[key] = &Artifact{
+			Namespace:           pkg.Namespace,
+			Name:                pkg.Name,
+			Hash:                versionHash,
+			CreatedAt:           time.Now().UTC(),
+			SizeBytes:           details.SizeBytes,
+			MediaType:           details.MediaType,
+			Uploader:            details.Uploader,
+			UserAgent:           details.UserAgent,
+			CopiedFromNamespace: details.CopiedFrom.GetNamespace(),
+			CopiedFromName:      details.CopiedFrom.GetName(),
+			State:               ArtifactActive,
+			Symbols:             slices.Clone(symbols),
+			Labels:              labelsToModel(pkg, versionHash, details.Labels),
+		}
+
+		for _, tag := range tags {
+			m.tags[tagKey{pkg.Namespace, pkg.Name, tag}] = versionHash
+		}
+
+		m.recordChange(ReplicateUpload, pkg, versionHash)
+
+		return nil
+	})
+}
+
+func (m *MemoryStore) DeleteArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditDelete, pkg, versionHash)
+
+	return m.audit(ctx, event, func() error {
+		event.TagsBefore = m.versionTags(pkg, versionHash)
+		m.deleteArtifact(artifactKey{pkg.Namespace, pkg.Name, versionHash})
+		m.recordChange(ReplicateDelete, pkg, versionHash)
+
+		return nil
+	})
+}
+
+func (m *MemoryStore) SetTags(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	tags []string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditSetTags, pkg, versionHash)
+	event.TagsAfter = tags
+
+	return m.audit(ctx, event, func() error {
+		event.TagsBefore = m.versionTags(pkg, versionHash)
+
+		key := artifactKey{pkg.Namespace, pkg.Name, versionHash}
+		if _, ok := m.artifacts[key]; !ok && len(tags) > 0 {
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"set tags (namespace=%q, name=%q, hash=%q, tags=%v)",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+					tags,
+				),
+			}
+		}
+
+		for _, tag := range event.TagsBefore {
+			delete(m.tags, tagKey{pkg.Namespace, pkg.Name, tag})
+		}
+		for _, tag := range tags {
+			m.tags[tagKey{pkg.Namespace, pkg.Name, tag}] = versionHash
+		}
+
+		m.recordChange(ReplicateTags, pkg, versionHash)
+
+		return nil
+	})
+}
+
+func (m *MemoryStore) SetLabels(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	labels map[string]string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditSetLabels, pkg, versionHash)
+	event.Detail = formatLabels(labels)
+
+	return m.audit(ctx, event, func() error {
+		key := artifactKey{pkg.Namespace, pkg.Name, versionHash}
+		row, ok := m.artifacts[key]
+		if !ok {
+			if len(labels) == 0 {
+				return nil
+			}
+
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"set labels (namespace=%q, name=%q, hash=%q, labels=%v)",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+					labels,
+				),
+			}
+		}
+
+		row.Labels = labelsToModel(pkg, versionHash, labels)
+
+		return nil
+	})
+}
+
+func (m *MemoryStore) SetOCIManifest(
+	_ context.Context,
+	manifest *OCIManifest,
+) error {
+	if err := validateOCIManifest(manifest); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := artifactKey{manifest.Namespace, manifest.Name, manifest.Hash}
+	row, ok := m.artifacts[key]
+	if !ok {
+		return &NotFoundError{
+			Search: fmt.Sprintf(
+				"set OCI manifest (namespace=%q, name=%q, hash=%q)",
+				manifest.Namespace,
+				manifest.Name,
+				manifest.Hash,
+			),
+		}
+	}
+
+	stored := *manifest
+	stored.Content = slices.Clone(manifest.Content)
+	stored.Config = slices.Clone(manifest.Config)
+	row.OCIManifest = &stored
+
+	return nil
+}
+
+func (m *MemoryStore) GetOCIManifest(
+	_ context.Context,
+	pkg *proto_gen.PackageName,
+	filter OCIManifestFilter,
+) (*OCIManifest, error) {
+	if err := validateOCIManifestQuery(pkg, filter); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *OCIManifest
+	for key, row := range m.artifacts {
+		manifest := row.OCIManifest
+		if key.namespace != pkg.Namespace || key.name != pkg.Name ||
+			manifest == nil || row.DeletedAt.Valid ||
+			(filter.Hash != "" && filter.Hash != manifest.Hash) ||
+			(filter.Digest != "" && filter.Digest != manifest.Digest) ||
+			(filter.ConfigDigest != "" &&
+				filter.ConfigDigest != manifest.ConfigDigest) {
+			continue
+		}
+
+		if found == nil || manifest.Hash < found.Hash {
+			found = manifest
+		}
+	}
+	if found == nil {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf(
+				"get OCI manifest (namespace=%q, name=%q, %s)",
+				pkg.Namespace,
+				pkg.Name,
+				filter,
+			),
+		}
+	}
+
+	manifest := *found
+	manifest.Content = slices.Clone(found.Content)
+	manifest.Config = slices.Clone(found.Config)
+
+	return &manifest, nil
+}
+
+// SetArtifactState moves an artifact into the given lifecycle state. The
+// reason is stored as given, callers clear it when reactivating a version.
+func (m *MemoryStore) SetArtifactState(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	state string,
+	reason string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditSetState, pkg, versionHash)
+	event.Detail = state
+	if reason != "" {
+		event.Detail += ": " + reason
+	}
+
+	return m.audit(ctx, event, func() error {
+		row := m.liveArtifact(artifactKey{pkg.Namespace, pkg.Name, versionHash})
+		if row == nil {
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"set artifact state (namespace=%q, name=%q, hash=%q, state=%q)",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+					state,
+				),
+			}
+		}
+
+		row.State = state
+		row.StateReason = reason
+
+		return nil
+	})
+}
+
+// GetArtifactMetasWithoutDetails returns up to limit artifacts without a
+// media type, ordered by primary key and starting after the given artifact
+func (m *MemoryStore) GetArtifactMetasWithoutDetails(
+	_ context.Context,
+	after *Artifact,
+	limit int,
+) ([]Artifact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	artifacts := m.findArtifacts(false, func(a *Artifact) bool {
+		return a.MediaType == "" &&
+			(after == nil || compareArtifacts(a, after) > 0)
+	})
+	if limit >= 0 && len(artifacts) > limit {
+		artifacts = artifacts[:limit]
+	}
+
+	return artifacts, nil
+}
+
+// SetArtifactSizeAndMediaType updates the properties that can be derived from
+// the stored blob
+func (m *MemoryStore) SetArtifactSizeAndMediaType(
+	_ context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	sizeBytes int64,
+	mediaType string,
+) error {
+	if pkg == nil {
+		return &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	if versionHash == "" || mediaType == "" || pkg.Namespace == "" ||
+		pkg.Name == "" {
+		return &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q, hash=%q, mediaType=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+				mediaType,
+			),
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := m.liveArtifact(artifactKey{pkg.Namespace, pkg.Name, versionHash})
+	if row != nil {
+		row.SizeBytes = sizeBytes
+		row.MediaType = mediaType
+	}
+
+	return nil
+}
+
+// TrashArtifactMeta moves an artifact into the trash. Its tags are released,
+// so they can be reused, and remembered for a later restore.
+func (m *MemoryStore) TrashArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	deletedBy string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditTrash, pkg, versionHash)
+
+	return m.audit(ctx, event, func() error {
+		row := m.liveArtifact(
+			artifactKey{pkg.Namespace, pkg.Name, versionHash},
+		)
+		if row == nil {
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"trash artifact (namespace=%q, name=%q, hash=%q)",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+				),
+			}
+		}
+
+		row.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+		row.DeletedBy = deletedBy
+		event.TagsBefore = m.versionTags(pkg, versionHash)
+		m.recordChange(ReplicateDelete, pkg, versionHash)
+
+		row.DeletedTags = nil
+		for _, tag := range event.TagsBefore {
+			delete(m.tags, tagKey{pkg.Namespace, pkg.Name, tag})
+			row.DeletedTags = append(row.DeletedTags, DeletedTag{
+				Namespace: pkg.Namespace,
+				Name:      pkg.Name,
+				Hash:      versionHash,
+				TagName:   tag,
+			})
+		}
+
+		return nil
+	})
+}
+
+// GetDeletedArtifactMetas returns the trashed artifacts matching the filter,
+// oldest deletion first
+func (m *MemoryStore) GetDeletedArtifactMetas(
+	_ context.Context,
+	filter TrashFilter,
+) ([]Artifact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	artifacts := m.findArtifacts(true, func(a *Artifact) bool {
+		return inPackage(a, filter.Namespace, filter.Name) &&
+			(filter.DeletedBefore.IsZero() ||
+				a.DeletedAt.Time.Before(filter.DeletedBefore))
+	})
+	slices.SortStableFunc(artifacts, func(a, b Artifact) int {
+		return a.DeletedAt.Time.Compare(b.DeletedAt.Time)
+	})
+
+	return artifacts, nil
+}
+
+func (m *MemoryStore) GetDeletedArtifactMeta(
+	_ context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) (*Artifact, error) {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	row := m.trashedArtifact(artifactKey{pkg.Namespace, pkg.Name, versionHash})
+	if row == nil {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf(
+				"get deleted artifact (namespace=%q, name=%q, hash=%q)",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+			),
+		}
+	}
+
+	artifact := m.load(row)
+
+	return &artifact, nil
+}
+
+// RestoreArtifactMeta takes an artifact out of the trash. Tags that were
+// assigned to another version in the meantime stay there.
+func (m *MemoryStore) RestoreArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditRestore, pkg, versionHash)
+
+	return m.audit(ctx, event, func() error {
+		row := m.trashedArtifact(
+			artifactKey{pkg.Namespace, pkg.Name, versionHash},
+		)
+		if row == nil {
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"restore artifact (namespace=%q, name=%q, hash=%q)",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+				),
+			}
+		}
+
+		row.DeletedAt = gorm.DeletedAt{}
+		row.DeletedBy = ""
+		for _, t := range row.DeletedTags {
+			key := tagKey{t.Namespace, t.Name, t.TagName}
+			if _, taken := m.tags[key]; taken {
+				continue
+			}
+
+			m.tags[key] = t.Hash
+			event.TagsAfter = append(event.TagsAfter, t.TagName)
+		}
+		row.DeletedTags = nil
+		m.recordChange(ReplicateUpload, pkg, versionHash)
+
+		return nil
+	})
+}
+
+// PurgeArtifactMeta removes a trashed artifact for good
+func (m *MemoryStore) PurgeArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditPurge, pkg, versionHash)
+
+	return m.audit(ctx, event, func() error {
+		key := artifactKey{pkg.Namespace, pkg.Name, versionHash}
+		if m.trashedArtifact(key) == nil {
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"purge artifact (namespace=%q, name=%q, hash=%q)",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+				),
+			}
+		}
+
+		m.deleteArtifact(key)
+
+		return nil
+	})
+}
+
+func (m *MemoryStore) CreatePackage(_ context.Context, pkg *Package) error {
+	err := validatePackageName(&proto_gen.PackageName{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := packageKey{pkg.Namespace, pkg.Name}
+	if _, ok := m.packages[key]; ok {
+		return &ConflictError{
+			Conflict: fmt.Sprintf(
+				"create package (namespace=%q, name=%q)",
+				pkg.Namespace,
+				pkg.Name,
+			),
+		}
+	}
+
+	// Fill in the defaults like the database does
+	if pkg.Visibility == "" {
+		pkg.Visibility = VisibilityPublic
+	}
+	now := time.Now().UTC()
+	if pkg.CreatedAt.IsZero() {
+		pkg.CreatedAt = now
+	}
+	if pkg.UpdatedAt.IsZero() {
+		pkg.UpdatedAt = now
+	}
+	for i := range pkg.Owners {
+		pkg.Owners[i].Namespace = pkg.Namespace
+		pkg.Owners[i].Name = pkg.Name
+	}
+
+	m.packages[key] = clonePackage(pkg)
+
+	return nil
+}
+
+// EnsurePackage creates a package record with default settings if none exists
+// yet. The owner, if not empty, becomes the only owner of a newly created
+// package and is ignored for existing ones.
+func (m *MemoryStore) EnsurePackage(
+	_ context.Context,
+	name *proto_gen.PackageName,
+	owner string,
+) error {
+	if err := validatePackageName(name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := packageKey{name.Namespace, name.Name}
+	if _, ok := m.packages[key]; ok {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	pkg := &Package{
+		Namespace:  name.Namespace,
+		Name:       name.Name,
+		Visibility: VisibilityPublic,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if owner != "" {
+		pkg.Owners = []PackageOwner{
+			{Namespace: name.Namespace, Name: name.Name, Owner: owner},
+		}
+	}
+	m.packages[key] = pkg
+
+	return nil
+}
+
+func (m *MemoryStore) GetPackage(
+	_ context.Context,
+	name *proto_gen.PackageName,
+) (*Package, error) {
+	if err := validatePackageName(name); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pkg, ok := m.packages[packageKey{name.Namespace, name.Name}]
+	if !ok {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf(
+				"get package (namespace=%q, name=%q)",
+				name.Namespace,
+				name.Name,
+			),
+		}
+	}
+
+	return clonePackage(pkg), nil
+}
+
+// GetPackages returns the records of all given packages that have one.
+// Packages without a record are silently left out.
+func (m *MemoryStore) GetPackages(
+	_ context.Context,
+	names []*proto_gen.PackageName,
+) ([]Package, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findPackages(func(pkg *Package) bool {
+		return slices.ContainsFunc(names, func(name *proto_gen.PackageName) bool {
+			return pkg.Namespace == name.Namespace && pkg.Name == name.Name
+		})
+	}), nil
+}
+
+// ListPackages returns all package records, optionally restricted to a
+// namespace
+func (m *MemoryStore) ListPackages(
+	_ context.Context,
+	namespace string,
+) ([]Package, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findPackages(func(pkg *Package) bool {
+		return namespace == "" || pkg.Namespace == namespace
+	}), nil
+}
+
+// UpdatePackage replaces all mutable fields and the owners of an existing
+// package record
+func (m *MemoryStore) UpdatePackage(_ context.Context, pkg *Package) error {
+	err := validatePackageName(&proto_gen.PackageName{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.packages[packageKey{pkg.Namespace, pkg.Name}]
+	if !ok {
+		return &NotFoundError{
+			Search: fmt.Sprintf(
+				"update package (namespace=%q, name=%q)",
+				pkg.Namespace,
+				pkg.Name,
+			),
+		}
+	}
+
+	for i := range pkg.Owners {
+		pkg.Owners[i].Namespace = pkg.Namespace
+		pkg.Owners[i].Name = pkg.Name
+	}
+
+	stored.Description = pkg.Description
+	stored.Readme = pkg.Readme
+	stored.Homepage = pkg.Homepage
+	stored.Visibility = pkg.Visibility
+	stored.Deprecated = pkg.Deprecated
+	stored.DeprecationMessage = pkg.DeprecationMessage
+	stored.UpdatedAt = time.Now().UTC()
+	stored.Owners = slices.Clone(pkg.Owners)
+
+	return nil
+}
+
+func (m *MemoryStore) DeletePackage(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+) error {
+	if err := validatePackageName(name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := newAuditEvent(AuditDeletePackage, name, "")
+
+	return m.audit(ctx, event, func() error {
+		key := packageKey{name.Namespace, name.Name}
+		if _, ok := m.packages[key]; !ok {
+			return &NotFoundError{
+				Search: fmt.Sprintf(
+					"delete package (namespace=%q, name=%q)",
+					name.Namespace,
+					name.Name,
+				),
+			}
+		}
+		delete(m.packages, key)
+		m.recordChange(ReplicateDeletePackage, name, "")
+
+		return nil
+	})
+}
+
+func (m *MemoryStore) CreateNamespace(_ context.Context, ns *Namespace) error {
+	if err := validateNamespaceName(ns.Name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.namespaces[ns.Name]; ok {
+		return &ConflictError{
+			Conflict: fmt.Sprintf("create namespace (name=%q)", ns.Name),
+		}
+	}
+
+	if ns.CreatedAt.IsZero() {
+		ns.CreatedAt = time.Now().UTC()
+	}
+	stored := *ns
+	m.namespaces[ns.Name] = &stored
+
+	return nil
+}
+
+// EnsureNamespace creates the given namespace record if none exists yet and
+// returns the stored one
+func (m *MemoryStore) EnsureNamespace(
+	_ context.Context,
+	ns *Namespace,
+) (*Namespace, error) {
+	if err := validateNamespaceName(ns.Name); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.namespaces[ns.Name]
+	if !ok {
+		stored = new(Namespace)
+		*stored = *ns
+		if stored.CreatedAt.IsZero() {
+			stored.CreatedAt = time.Now().UTC()
+		}
+		m.namespaces[ns.Name] = stored
+	}
+	result := *stored
+
+	return &result, nil
+}
+
+func (m *MemoryStore) GetNamespace(
+	_ context.Context,
+	name string,
+) (*Namespace, error) {
+	if err := validateNamespaceName(name); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.namespaces[name]
+	if !ok {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf("get namespace (name=%q)", name),
+		}
+	}
+	result := *stored
+
+	return &result, nil
+}
+
+func (m *MemoryStore) ListNamespaces(_ context.Context) ([]Namespace, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	namespaces := make([]Namespace, 0, len(m.namespaces))
+	for _, name := range slices.Sorted(maps.Keys(m.namespaces)) {
+		namespaces = append(namespaces, *m.namespaces[name])
+	}
+
+	return namespaces, nil
+}
+
+// UpdateNamespace replaces the description and quotas of an existing
+// namespace record
+func (m *MemoryStore) UpdateNamespace(_ context.Context, ns *Namespace) error {
+	if err := validateNamespaceName(ns.Name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.namespaces[ns.Name]
+	if !ok {
+		return &NotFoundError{
+			Search: fmt.Sprintf("update namespace (name=%q)", ns.Name),
+		}
+	}
+
+	stored.Description = ns.Description
+	stored.QuotaBytes = ns.QuotaBytes
+	stored.QuotaVersions = ns.QuotaVersions
+
+	return nil
+}
+
+// DeleteNamespace removes the namespace record together with the metadata of
+// all versions, including trashed ones, and the package records it contains
+func (m *MemoryStore) DeleteNamespace(ctx context.Context, name string) error {
+	if err := validateNamespaceName(name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event := &AuditEvent{Action: AuditDeleteNamespace, Namespace: name}
+
+	return m.audit(ctx, event, func() error {
+		_, found := m.namespaces[name]
+		versions, packages := 0, 0
+		for key := range m.artifacts {
+			if key.namespace == name {
+				versions++
+			}
+		}
+		for key := range m.packages {
+			if key.namespace == name {
+				packages++
+			}
+		}
+		if !found && versions+packages == 0 {
+			return &NotFoundError{
+				Search: fmt.Sprintf("delete namespace (name=%q)", name),
+			}
+		}
+
+		m.recordNamespaceDeletion(name)
+		for key := range m.artifacts {
+			if key.namespace == name {
+				m.deleteArtifact(key)
+			}
+		}
+		maps.DeleteFunc(m.packages, func(key packageKey, _ *Package) bool {
+			return key.namespace == name
+		})
+		delete(m.namespaces, name)
+		event.Detail = namespaceDeletionDetail(versions, packages)
+
+		return nil
+	})
+}
+
+// recordNamespaceDeletion replicates the deletion of a namespace like
+// DB.recordNamespaceDeletion. The caller holds the write lock.
+func (m *MemoryStore) recordNamespaceDeletion(name string) {
+	var keys []artifactKey
+	for key, row := range m.artifacts {
+		if key.namespace == name && !row.DeletedAt.Valid {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b artifactKey) int {
+		return cmp.Or(cmp.Compare(a.name, b.name), cmp.Compare(a.hash, b.hash))
+	})
+	for _, key := range keys {
+		m.recordChange(
+			ReplicateDelete,
+			&proto_gen.PackageName{Namespace: name, Name: key.name},
+			key.hash,
+		)
+	}
+
+	var names []string
+	for key := range m.packages {
+		if key.namespace == name {
+			names = append(names, key.name)
+		}
+	}
+	slices.Sort(names)
+	for _, pkgName := range names {
+		m.recordChange(
+			ReplicateDeletePackage,
+			&proto_gen.PackageName{Namespace: name, Name: pkgName},
+			"",
+		)
+	}
+}
+
+// GetNamespaceUsages returns the usage of the given namespaces, or of all
+// namespaces with at least one version if none are given. Namespaces without
+// versions, including trashed ones, are left out.
+func (m *MemoryStore) GetNamespaceUsages(
+	_ context.Context,
+	names ...string,
+) ([]NamespaceUsage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usages := make(map[string]*NamespaceUsage)
+	for _, row := range m.artifacts {
+		if len(names) > 0 && !slices.Contains(names, row.Namespace) {
+			continue
+		}
+
+		usage, ok := usages[row.Namespace]
+		if !ok {
+			usage = &NamespaceUsage{Namespace: row.Namespace}
+			usages[row.Namespace] = usage
+		}
+		if row.DeletedAt.Valid {
+			usage.TrashedBytes += row.SizeBytes
+
+			continue
+		}
+		usage.Bytes += row.SizeBytes
+		usage.Versions++
+	}
+
+	result := make([]NamespaceUsage, 0, len(usages))
+	for _, name := range slices.Sorted(maps.Keys(usages)) {
+		result = append(result, *usages[name])
+	}
+
+	return result, nil
+}
+
+// CreatePackageMove journals a new move. Only one move per source package can
+// be in progress at a time.
+func (m *MemoryStore) CreatePackageMove(
+	_ context.Context,
+	move *PackageMove,
+) error {
+	for _, name := range []*proto_gen.PackageName{
+		{Namespace: move.FromNamespace, Name: move.FromName},
+		{Namespace: move.ToNamespace, Name: move.ToName},
+	} {
+		if err := validatePackageName(name); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slices.ContainsFunc(m.moves, func(other PackageMove) bool {
+		return other.FromNamespace == move.FromNamespace &&
+			other.FromName == move.FromName
+	}) {
+		return &ConflictError{
+			Conflict: "create package move (" + moveDetails(move) + ")",
+		}
+	}
+
+	m.lastMoveID++
+	move.ID = m.lastMoveID
+	if move.CreatedAt.IsZero() {
+		move.CreatedAt = time.Now().UTC()
+	}
+	m.moves = append(m.moves, *move)
+
+	return nil
+}
+
+// GetPackageMoves returns all moves that are still in progress
+func (m *MemoryStore) GetPackageMoves(
+	_ context.Context,
+) ([]PackageMove, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]PackageMove{}, m.moves...), nil
+}
+
+// CommitPackageMove switches all metadata of the source package over to the
+// target and puts the move into cleanup. The target must not have any
+// versions or a package record, and the source must have exactly the
+// versions of hashes.
+func (m *MemoryStore) CommitPackageMove(
+	ctx context.Context,
+	move *PackageMove,
+	hashes []string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.audit(ctx, newMoveAuditEvent(move), func() error {
+		if err := m.commitPackageMove(move, hashes); err != nil {
+			return err
+		}
+
+		// Replicated like DB.recordMove
+		from := &proto_gen.PackageName{
+			Namespace: move.FromNamespace,
+			Name:      move.FromName,
+		}
+		to := &proto_gen.PackageName{
+			Namespace: move.ToNamespace,
+			Name:      move.ToName,
+		}
+		for _, hash := range slices.Sorted(slices.Values(hashes)) {
+			row := m.artifacts[artifactKey{to.Namespace, to.Name, hash}]
+			if row == nil || row.DeletedAt.Valid {
+				continue
+			}
+			m.recordChange(ReplicateDelete, from, hash)
+			m.recordChange(ReplicateUpload, to, hash)
+		}
+
+		return nil
+	})
+}
+
+// commitPackageMove is CommitPackageMove with the write lock held
+func (m *memoryData) commitPackageMove(
+	move *PackageMove,
+	hashes []string,
+) error {
+	from := packageKey{move.FromNamespace, move.FromName}
+	to := packageKey{move.ToNamespace, move.ToName}
+
+	_, taken := m.packages[to]
+	for key := range m.artifacts {
+		taken = taken ||
+			(key.namespace == to.namespace && key.name == to.name)
+	}
+	if taken {
+		return &ConflictError{
+			Conflict: "move target already exists (" + moveDetails(move) + ")",
+		}
+	}
+
+	var versions []Artifact
+	for key, row := range m.artifacts {
+		if key.namespace == from.namespace && key.name == from.name {
+			versions = append(versions, *row)
+		}
+	}
+	if !sameVersions(versions, hashes) {
+		return &ConflictError{
+			Conflict: "versions of moved package changed (" +
+				moveDetails(move) + ")",
+		}
+	}
+
+	for key, row := range m.artifacts {
+		if key.namespace != from.namespace || key.name != from.name {
+			continue
+		}
+
+		row.Namespace, row.Name = to.namespace, to.name
+		for i := range row.Symbols {
+			row.Symbols[i].Namespace = to.namespace
+			row.Symbols[i].Name = to.name
+		}
+		for i := range row.Labels {
+			row.Labels[i].Namespace = to.namespace
+			row.Labels[i].Name = to.name
+		}
+		for i := range row.DeletedTags {
+			row.DeletedTags[i].Namespace = to.namespace
+			row.DeletedTags[i].Name = to.name
+		}
+		if row.OCIManifest != nil {
+			row.OCIManifest.Namespace = to.namespace
+			row.OCIManifest.Name = to.name
+		}
+		delete(m.artifacts, key)
+		m.artifacts[artifactKey{to.namespace, to.name, key.hash}] = row
+	}
+
+	for key, hash := range m.tags {
+		if key.namespace == from.namespace && key.name == from.name {
+			delete(m.tags, key)
+			m.tags[tagKey{to.namespace, to.name, key.tag}] = hash
+		}
+	}
+
+	if pkg, ok := m.packages[from]; ok {
+		pkg.Namespace, pkg.Name = to.namespace, to.name
+		for i := range pkg.Owners {
+			pkg.Owners[i].Namespace = to.namespace
+			pkg.Owners[i].Name = to.name
+		}
+		delete(m.packages, from)
+		m.packages[to] = pkg
+	}
+
+	// Redirects to the source keep pointing at the package, one away from
+	// the target is dropped, as the target name is taken now
+	delete(m.redirects, to)
+	for _, redirect := range m.redirects {
+		if redirect.TargetNamespace == from.namespace &&
+			redirect.TargetName == from.name {
+			redirect.TargetNamespace = to.namespace
+			redirect.TargetName = to.name
+		}
+	}
+	if move.RedirectTTL != 0 {
+		m.redirects[from] = &PackageRedirect{
+			Namespace:       from.namespace,
+			Name:            from.name,
+			TargetNamespace: to.namespace,
+			TargetName:      to.name,
+			ExpiresAt:       time.Now().UTC().Add(move.RedirectTTL),
+		}
+	}
+
+	move.State = MoveCleanup
+	for i := range m.moves {
+		if m.moves[i].ID == move.ID {
+			m.moves[i].State = MoveCleanup
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) DeletePackageMove(
+	_ context.Context,
+	move *PackageMove,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.moves = slices.DeleteFunc(m.moves, func(other PackageMove) bool {
+		return other.ID == move.ID
+	})
+
+	return nil
+}
+
+// GetPackageRedirect returns the redirect of a package that has been moved,
+// as long as it has not expired
+func (m *MemoryStore) GetPackageRedirect(
+	_ context.Context,
+	name *proto_gen.PackageName,
+) (*PackageRedirect, error) {
+	if err := validatePackageName(name); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	redirect, ok := m.redirects[packageKey{name.Namespace, name.Name}]
+	if !ok || !redirect.ExpiresAt.After(time.Now()) {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf(
+				"get package redirect (namespace=%q, name=%q)",
+				name.Namespace,
+				name.Name,
+			),
+		}
+	}
+	result := *redirect
+
+	return &result, nil
+}
+
+// GetReplicationEvents returns up to limit events following afterID, oldest
+// first. Only events recorded before the given time are returned.
+func (m *MemoryStore) GetReplicationEvents(
+	_ context.Context,
+	afterID uint,
+	before time.Time,
+	limit int,
+) ([]ReplicationEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []ReplicationEvent{}
+	for _, event := range m.events {
+		if limit >= 0 && len(events) >= limit {
+			break
+		}
+		if event.ID > afterID && event.CreatedAt.Before(before) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// CountReplicationEvents returns the number of events following afterID and
+// when the oldest of them was recorded. The time is zero if there are none.
+func (m *MemoryStore) CountReplicationEvents(
+	_ context.Context,
+	afterID uint,
+) (int64, time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var (
+		count  int64
+		oldest time.Time
+	)
+	for _, event := range m.events {
+		if event.ID <= afterID {
+			continue
+		}
+		if count == 0 {
+			oldest = event.CreatedAt
+		}
+		count++
+	}
+
+	return count, oldest, nil
+}
+
+// GetReplicationCursor returns the position of a peer in the outbox. Peers
+// that have not been seen before start at its beginning.
+func (m *MemoryStore) GetReplicationCursor(
+	_ context.Context,
+	peer string,
+) (*ReplicationCursor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursor, ok := m.cursors[peer]
+	if !ok {
+		return &ReplicationCursor{Peer: peer}, nil
+	}
+
+	return cloneCursor(cursor), nil
+}
+
+func (m *MemoryStore) SaveReplicationCursor(
+	_ context.Context,
+	cursor *ReplicationCursor,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cursors[cursor.Peer] = cloneCursor(cursor)
+
+	return nil
+}
+
+// PruneReplicationEvents deletes the events that have been replicated to all
+// of the given peers
+func (m *MemoryStore) PruneReplicationEvents(
+	_ context.Context,
+	peers []string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cursors []*ReplicationCursor
+	for peer, cursor := range m.cursors {
+		if slices.Contains(peers, peer) {
+			cursors = append(cursors, cursor)
+		}
+	}
+
+	// A peer without cursor has not received anything yet
+	if len(cursors) == 0 || len(cursors) < len(peers) {
+		return nil
+	}
+
+	replicated := slices.MinFunc(cursors, func(a, b *ReplicationCursor) int {
+		return cmp.Compare(a.LastEventID, b.LastEventID)
+	}).LastEventID
+	m.events = slices.DeleteFunc(m.events, func(event ReplicationEvent) bool {
+		return event.ID <= replicated
+	})
+
+	return nil
+}
+
+// AddPulls increments the pull counters of the versions and their buckets.
+// Pulls of versions that have been deleted in the meantime are dropped.
+func (m *MemoryStore) AddPulls(_ context.Context, pulls []Pull) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pull := range pulls {
+		row := m.liveArtifact(artifactKey{pull.Namespace, pull.Name, pull.Hash})
+		if row == nil {
+			continue
+		}
+
+		row.PullsCount += pull.Count
+		if row.LastPulledAt == nil || row.LastPulledAt.Before(pull.Time) {
+			lastPulledAt := pull.Time
+			row.LastPulledAt = &lastPulledAt
+		}
+
+		for _, bucket := range pullBuckets(pull) {
+			key := pullBucketKey{
+				bucket.Namespace,
+				bucket.Name,
+				bucket.Hash,
+				bucket.Tag,
+				bucket.Client,
+				bucket.Granularity,
+				bucket.Start,
+			}
+			if stored, ok := m.pulls[key]; ok {
+				stored.Pulls += bucket.Pulls
+			} else {
+				m.pulls[key] = &bucket
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetPullStats sums up the pulls matching the filter per series and bucket,
+// ordered by series and time
+func (m *MemoryStore) GetPullStats(
+	_ context.Context,
+	filter PullStatsFilter,
+) ([]PullStat, error) {
+	var series func(bucket *PullBucket) string
+	switch filter.GroupBy {
+	case "":
+		series = func(*PullBucket) string { return "" }
+	case PullsByVersion:
+		series = func(bucket *PullBucket) string { return bucket.Hash }
+	case PullsByTag:
+		series = func(bucket *PullBucket) string { return bucket.Tag }
+	case PullsByClient:
+		series = func(bucket *PullBucket) string { return bucket.Client }
+	default:
+		return nil, &BadInputError{
+			Reason: fmt.Sprintf("Unknown pull grouping %q", filter.GroupBy),
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type statKey struct {
+		series string
+		start  time.Time
+	}
+
+	sums := make(map[statKey]int64)
+	for _, bucket := range m.pulls {
+		if !matchesPullFilter(bucket, &filter) {
+			continue
+		}
+		sums[statKey{series(bucket), bucket.Start}] += bucket.Pulls
+	}
+
+	stats := make([]PullStat, 0, len(sums))
+	for key, pulls := range sums {
+		stats = append(stats, PullStat{
+			Series: key.series,
+			Start:  key.start,
+			Pulls:  pulls,
+		})
+	}
+	slices.SortFunc(stats, func(a, b PullStat) int {
+		return cmp.Or(cmp.Compare(a.Series, b.Series), a.Start.Compare(b.Start))
+	})
+
+	return stats, nil
+}
+
+// PrunePullBuckets deletes the buckets of the given granularity that started
+// before the given time
+func (m *MemoryStore) PrunePullBuckets(
+	_ context.Context,
+	granularity string,
+	before time.Time,
+) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pruned := 0
+	maps.DeleteFunc(m.pulls, func(_ pullBucketKey, bucket *PullBucket) bool {
+		prune := (granularity == "" || bucket.Granularity == granularity) &&
+			bucket.Start.Before(before)
+		if prune {
+			pruned++
+		}
+
+		return prune
+	})
+
+	return pruned, nil
+}
+
+// GetAuditEvents returns the audit events matching the filter, newest first
+func (m *MemoryStore) GetAuditEvents(
+	_ context.Context,
+	filter AuditFilter,
+) ([]AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []AuditEvent{}
+	for i := len(m.auditEvents) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+
+		event := &m.auditEvents[i]
+		if matchesAuditFilter(event, &filter) {
+			events = append(events, cloneAuditEvent(event))
+		}
+	}
+
+	return events, nil
+}
+
+// RecordRejection records a change that was rejected before it reached the
+// store, e.g. an upload over the quota of its namespace
+func (m *MemoryStore) RecordRejection(
+	ctx context.Context,
+	event *AuditEvent,
+	reason error,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The rejection is returned as the failure of the change
+	_ = m.audit(ctx, event, func() error { return reason })
+
+	return nil
+}
+
+// audit applies change and records it in the audit log, whether it succeeded
+// or not. There is no rollback, so changes must check everything before they
+// modify anything. The caller holds the write lock.
+func (m *MemoryStore) audit(
+	ctx context.Context,
+	event *AuditEvent,
+	change func() error,
+) error {
+	actor := auditActorFromContext(ctx)
+	event.Actor = actor.User
+	event.ClientAddress = actor.ClientAddress
+
+	err := change()
+	event.Succeeded = err == nil
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	m.lastAuditID++
+	event.ID = m.lastAuditID
+	event.CreatedAt = time.Now().UTC()
+	m.auditEvents = append(m.auditEvents, cloneAuditEvent(event))
+
+	return err
+}
+
+// recordChange appends a change to the replication outbox, if it is enabled.
+// The caller holds the write lock.
+func (m *MemoryStore) recordChange(
+	kind string,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) {
+	if !m.outbox {
+		return
+	}
+
+	m.lastEventID++
+	m.events = append(m.events, ReplicationEvent{
+		ID:        m.lastEventID,
+		Kind:      kind,
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+func (m *memoryData) artifactByHash(
+	pkg *proto_gen.PackageName,
+	hash string,
+) (*Artifact, error) {
+	row := m.liveArtifact(artifactKey{pkg.Namespace, pkg.Name, hash})
+	if row == nil {
+		return nil, &NotFoundError{
+			Search: fmt.Sprintf(
+				"get artifact by hash (namespace=%s, name=%s, hash=%s)",
+				pkg.Namespace,
+				pkg.Name,
+				hash,
+			),
+		}
+	}
+
+	artifact := m.load(row)
+
+	return &artifact, nil
+}
+
+// liveArtifact returns the stored artifact unless it is missing or trashed
+func (m *memoryData) liveArtifact(key artifactKey) *Artifact {
+	row, ok := m.artifacts[key]
+	if !ok || row.DeletedAt.Valid {
+		return nil
+	}
+
+	return row
+}
+
+// trashedArtifact returns the stored artifact if it is in the trash
+func (m *memoryData) trashedArtifact(key artifactKey) *Artifact {
+	row, ok := m.artifacts[key]
+	if !ok || !row.DeletedAt.Valid {
+		return nil
+	}
+
+	return row
+}
+
+// findArtifacts returns copies of the live or trashed artifacts that match,
+// ordered by primary key
+func (m *memoryData) findArtifacts(
+	trashed bool,
+	match func(a *Artifact) bool,
+) []Artifact {
+	artifacts := []Artifact{}
+	for _, row := range m.artifacts {
+		if row.DeletedAt.Valid == trashed && match(row) {
+			artifacts = append(artifacts, m.load(row))
+		}
+	}
+	slices.SortFunc(artifacts, func(a, b Artifact) int {
+		return compareArtifacts(&a, &b)
+	})
+
+	return artifacts
+}
+
+// load returns a copy of a stored artifact with its tags
+func (m *memoryData) load(row *Artifact) Artifact {
+	artifact := *row
+	if row.LastPulledAt != nil {
+		lastPulledAt := *row.LastPulledAt
+		artifact.LastPulledAt = &lastPulledAt
+	}
+	artifact.Symbols = slices.Clone(row.Symbols)
+	artifact.Labels = slices.Clone(row.Labels)
+	artifact.DeletedTags = slices.Clone(row.DeletedTags)
+	artifact.OCIManifest = nil
+
+	pkg := &proto_gen.PackageName{Namespace: row.Namespace, Name: row.Name}
+	artifact.Tags = nil
+	for _, tag := range m.versionTags(pkg, row.Hash) {
+		artifact.Tags = append(artifact.Tags, Tag{
+			Namespace: row.Namespace,
+			Name:      row.Name,
+			TagName:   tag,
+			Hash:      row.Hash,
+		})
+	}
+
+	return artifact
+}
+
+// versionTags returns the tags currently pointing at a version, sorted
+func (m *memoryData) versionTags(
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) []string {
+	var tags []string
+	for key, hash := range m.tags {
+		if key.namespace == pkg.Namespace && key.name == pkg.Name &&
+			hash == versionHash {
+			tags = append(tags, key.tag)
+		}
+	}
+	slices.Sort(tags)
+
+	return tags
+}
+
+// deleteArtifact removes an artifact together with its tags
+func (m *memoryData) deleteArtifact(key artifactKey) {
+	delete(m.artifacts, key)
+	maps.DeleteFunc(m.tags, func(tag tagKey, hash string) bool {
+		return tag.namespace == key.namespace && tag.name == key.name &&
+			hash == key.hash
+	})
+}
+
+// findPackages returns copies of the matching package records, ordered by
+// namespace and name
+func (m *memoryData) findPackages(match func(pkg *Package) bool) []Package {
+	packages := []Package{}
+	for _, pkg := range m.packages {
+		if match(pkg) {
+			packages = append(packages, *clonePackage(pkg))
+		}
+	}
+	slices.SortFunc(packages, func(a, b Package) int {
+		return cmp.Or(
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	return packages
+}
+
+func (f SymbolFilter) matches(symbol ArtifactSymbol) bool {
+	return symbol.Direction == f.Direction &&
+		symbol.SymbolName == f.Name &&
+		(f.Module == "" || symbol.Module == f.Module)
+}
+
+// matches reports whether the labels of an artifact satisfy the selector
+func (s LabelSelector) matches(labels []ArtifactLabel) bool {
+	found := false
+	for _, label := range labels {
+		if label.Key != s.Key {
+			continue
+		}
+
+		switch s.Operator {
+		case LabelEquals, LabelNotEquals:
+			found = label.Value == s.Values[0]
+		case LabelIn, LabelNotIn:
+			found = slices.Contains(s.Values, label.Value)
+		case LabelExists, LabelDoesNotExist:
+			found = true
+		}
+	}
+
+	switch s.Operator {
+	case LabelNotEquals, LabelNotIn, LabelDoesNotExist:
+		return !found
+	default:
+		return found
+	}
+}
+
+// inPackage matches artifacts like a query on namespace and name, which
+// ignores empty values
+func inPackage(a *Artifact, namespace, name string) bool {
+	return (namespace == "" || a.Namespace == namespace) &&
+		(name == "" || a.Name == name)
+}
+
+func compareArtifacts(a, b *Artifact) int {
+	return cmp.Or(
+		cmp.Compare(a.Namespace, b.Namespace),
+		cmp.Compare(a.Name, b.Name),
+		cmp.Compare(a.Hash, b.Hash),
+	)
+}
+
+func matchesPullFilter(bucket *PullBucket, filter *PullStatsFilter) bool {
+	return (filter.Namespace == "" || bucket.Namespace == filter.Namespace) &&
+		(filter.Name == "" || bucket.Name == filter.Name) &&
+		(filter.Hash == "" || bucket.Hash == filter.Hash) &&
+		(filter.Granularity == "" ||
+			bucket.Granularity == filter.Granularity) &&
+		(filter.Since.IsZero() || !bucket.Start.Before(filter.Since)) &&
+		(filter.Until.IsZero() || bucket.Start.Before(filter.Until))
+}
+
+func matchesAuditFilter(event *AuditEvent, filter *AuditFilter) bool {
+	return (filter.Actor == "" || event.Actor == filter.Actor) &&
+		(filter.Action == "" || event.Action == filter.Action) &&
+		(filter.Namespace == "" || event.Namespace == filter.Namespace) &&
+		(filter.Name == "" || event.Name == filter.Name) &&
+		(filter.Hash == "" || event.Hash == filter.Hash) &&
+		(filter.Since.IsZero() || !event.CreatedAt.Before(filter.Since)) &&
+		(filter.Until.IsZero() || event.CreatedAt.Before(filter.Until)) &&
+		(filter.BeforeID == 0 || event.ID < filter.BeforeID)
+}
+
+func clonePackage(pkg *Package) *Package {
+	result := *pkg
+	result.Owners = slices.Clone(pkg.Owners)
+
+	return &result
+}
+
+func cloneCursor(cursor *ReplicationCursor) *ReplicationCursor {
+	result := *cursor
+	if cursor.LastReplicatedAt != nil {
+		lastReplicatedAt := *cursor.LastReplicatedAt
+		result.LastReplicatedAt = &lastReplicatedAt
+	}
+
+	return &result
+}
+
+func cloneAuditEvent(event *AuditEvent) AuditEvent {
+	result := *event
+	result.TagsBefore = slices.Clone(event.TagsBefore)
+	result.TagsAfter = slices.Clone(event.TagsAfter)
+
+	return result
+}
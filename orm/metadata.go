@@ -2,6 +2,7 @@ package orm
 
 import (
 	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
 	"context"
 	"errors"
 	"fmt"
@@ -10,11 +11,28 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// withArtifactAssociations preloads all relations that are returned together
+// with an artifact's metadata
+func withArtifactAssociations(
+	query gorm.ChainInterface[Artifact],
+) gorm.ChainInterface[Artifact] {
+	return query.Preload("Tags", nil).
+		Preload("Symbols", func(db gorm.PreloadBuilder) error {
+			db.Order("id")
+
+			return nil
+		}).
+		Preload("Labels", nil)
+}
+
 func (db *DB) GetArtifactMetaByHash(
 	ctx context.Context,
 	pkg *proto_gen.PackageName,
 	hash string,
 ) (*Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetArtifactMetaByHash")
+	defer span.End()
+
 	if pkg == nil {
 		return nil, &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -34,13 +52,13 @@ func (db *DB) GetArtifactMetaByHash(
 
 	var artifact Artifact
 
-	artifact, err := gorm.G[Artifact](
+	artifact, err := withArtifactAssociations(gorm.G[Artifact](
 		db.dbGorm,
-	).Preload("Tags", nil).Where(&Artifact{
+	).Where(&Artifact{
 		Namespace: pkg.Namespace,
 		Name:      pkg.Name,
 		Hash:      hash,
-	}).First(ctx)
+	})).First(ctx)
 	if err != nil {
 		return nil, wrapErrorWithDetails(
 			err,
@@ -62,6 +80,9 @@ func (db *DB) GetArtifactMetaByTag(
 	pkg *proto_gen.PackageName,
 	tag string,
 ) (*Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetArtifactMetaByTag")
+	defer span.End()
+
 	if pkg == nil {
 		return nil, &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -100,46 +121,25 @@ func (db *DB) GetArtifactMetaByTag(
 	return db.GetArtifactMetaByHash(ctx, pkg, tagQuery.Hash)
 }
 
-func (db *DB) IncreasePullCount(
-	ctx context.Context,
-	pkg *proto_gen.PackageName,
-	hash string,
-) error {
-	artifact, err := db.GetArtifactMetaByHash(ctx, pkg, hash)
-	if err != nil {
-		return err
-	}
-
-	artifact.PullsCount += 1
-
-	return wrapErrorWithDetails(
-		db.dbGorm.Save(&artifact).Error,
-		"increase pull count - save artifact",
-		fmt.Sprintf(
-			"namespace=%s, name=%s, hash=%s",
-			pkg.Namespace,
-			pkg.Name,
-			hash,
-		),
-	)
-}
-
 func (db *DB) GetArtifactMetasByFQN(
 	ctx context.Context,
 	pkg *proto_gen.PackageName,
 ) ([]Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetArtifactMetasByFQN")
+	defer span.End()
+
 	if pkg == nil {
 		return nil, &BadInputError{
 			Reason: "artifact with nil PackageName",
 		}
 	}
 
-	artifacts, err := gorm.G[Artifact](
+	artifacts, err := withArtifactAssociations(gorm.G[Artifact](
 		db.dbGorm,
-	).Preload("Tags", nil).Where(&Artifact{
+	).Where(&Artifact{
 		Namespace: pkg.Namespace,
 		Name:      pkg.Name,
-	}).Find(ctx)
+	})).Find(ctx)
 	if err != nil {
 		return nil, wrapErrorWithDetails(
 			err,
@@ -155,12 +155,29 @@ func (db *DB) GetArtifactMetasByFQN(
 	return artifacts, nil
 }
 
+// ArtifactDetails are the properties of an artifact that are recorded once on
+// upload
+type ArtifactDetails struct {
+	SizeBytes int64
+	MediaType string
+	Uploader  string
+	UserAgent string
+	Symbols   []ArtifactSymbol
+	Labels    map[string]string
+	// Set if the content was copied from another package
+	CopiedFrom *proto_gen.PackageName
+}
+
 func (db *DB) CreateArtifactMeta(
 	ctx context.Context,
 	pkg *proto_gen.PackageName,
 	versionHash string,
+	details ArtifactDetails,
 	tags ...string,
 ) error {
+	ctx, span := tracing.Start(ctx, "orm.CreateArtifactMeta")
+	defer span.End()
+
 	if pkg == nil {
 		return &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -187,12 +204,30 @@ func (db *DB) CreateArtifactMeta(
 		tags,
 	)
 
-	err := db.dbGorm.Transaction(func(tx *gorm.DB) error {
+	event := newAuditEvent(AuditUpload, pkg, versionHash)
+	event.TagsAfter = tags
+	err := db.audit(ctx, event, func(tx *gorm.DB) error {
 		dbTx := db.UseTransaction(tx)
+		symbols := details.Symbols
+		for i := range symbols {
+			symbols[i].Namespace = pkg.Namespace
+			symbols[i].Name = pkg.Name
+			symbols[i].Hash = versionHash
+		}
+
 		err := gorm.G[Artifact](tx).Create(ctx, &Artifact{
-			Namespace: pkg.Namespace,
-			Name:      pkg.Name,
-			Hash:      versionHash,
+			Namespace:           pkg.Namespace,
+			Name:                pkg.Name,
+			Hash:                versionHash,
+			SizeBytes:           details.SizeBytes,
+			MediaType:           details.MediaType,
+			Uploader:            details.Uploader,
+			UserAgent:           details.UserAgent,
+			CopiedFromNamespace: details.CopiedFrom.GetNamespace(),
+			CopiedFromName:      details.CopiedFrom.GetName(),
+			State:               ArtifactActive,
+			Symbols:             symbols,
+			Labels:              labelsToModel(pkg, versionHash, details.Labels),
 		})
 		if err != nil {
 			return wrapErrorWithDetails(
@@ -209,17 +244,20 @@ func (db *DB) CreateArtifactMeta(
 			}
 		}
 
-		return nil
+		return db.recordChange(ctx, tx, ReplicateUpload, pkg, versionHash)
 	})
 
-	//nolint:wrapcheck // Error already wrapped
 	return err
 }
 
 func (db *DB) DeleteArtifactMeta(
+	ctx context.Context,
 	pkg *proto_gen.PackageName,
 	versionHash string,
 ) error {
+	ctx, span := tracing.Start(ctx, "orm.DeleteArtifactMeta")
+	defer span.End()
+
 	if pkg == nil {
 		return &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -238,22 +276,38 @@ func (db *DB) DeleteArtifactMeta(
 		}
 	}
 
-	return wrapErrorWithDetails(
-		db.dbGorm.Delete(
+	event := newAuditEvent(AuditDelete, pkg, versionHash)
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		tags, err := versionTags(ctx, tx, pkg, versionHash)
+		if err != nil {
+			return err
+		}
+		event.TagsBefore = tags
+
+		// Unscoped, so trashed artifacts are removed for good as well
+		err = tx.WithContext(ctx).Unscoped().Delete(
 			&Artifact{
 				Namespace: pkg.Namespace,
 				Name:      pkg.Name,
 				Hash:      versionHash,
 			},
-		).Error,
-		"delete artifact metadata",
-		fmt.Sprintf(
-			"namespace=%s, name=%s, hash=%s",
-			pkg.Namespace,
-			pkg.Name,
-			versionHash,
-		),
-	)
+		).Error
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"delete artifact metadata",
+				fmt.Sprintf(
+					"namespace=%s, name=%s, hash=%s",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+				),
+			)
+		}
+
+		return db.recordChange(ctx, tx, ReplicateDelete, pkg, versionHash)
+	})
 }
 
 func (db *DB) AddTag(
@@ -261,6 +315,9 @@ func (db *DB) AddTag(
 	pkg *proto_gen.PackageName,
 	versionHash, tag string,
 ) error {
+	ctx, span := tracing.Start(ctx, "orm.AddTag")
+	defer span.End()
+
 	if pkg == nil {
 		return &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -364,6 +421,9 @@ func (db *DB) RemoveTag(
 	pkg *proto_gen.PackageName,
 	tag string,
 ) error {
+	ctx, span := tracing.Start(ctx, "orm.RemoveTag")
+	defer span.End()
+
 	if pkg == nil {
 		return &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -382,7 +442,7 @@ func (db *DB) RemoveTag(
 	}
 
 	return wrapErrorWithDetails(
-		db.dbGorm.Delete(Tag{
+		db.dbGorm.WithContext(ctx).Delete(Tag{
 			Namespace: pkg.Namespace,
 			Name:      pkg.Name,
 			TagName:   tag,
@@ -403,6 +463,9 @@ func (db *DB) SetTags(
 	versionHash string,
 	tags []string,
 ) error {
+	ctx, span := tracing.Start(ctx, "orm.SetTags")
+	defer span.End()
+
 	if pkg == nil {
 		return &BadInputError{
 			Reason: "artifact with nil PackageName",
@@ -431,9 +494,17 @@ func (db *DB) SetTags(
 		}
 	}
 
-	//nolint:wrapcheck // Error already wrapped
-	return db.dbGorm.Transaction(func(tx *gorm.DB) error {
-		_, err := gorm.G[Tag](
+	event := newAuditEvent(AuditSetTags, pkg, versionHash)
+	event.TagsAfter = tags
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		before, err := versionTags(ctx, tx, pkg, versionHash)
+		if err != nil {
+			return err
+		}
+		event.TagsBefore = before
+
+		_, err = gorm.G[Tag](
 			tx,
 		).Where(Tag{
 			Namespace: pkg.Namespace,
@@ -455,23 +526,26 @@ func (db *DB) SetTags(
 		}
 
 		if len(modelTags) == 0 {
-			return nil
+			return db.recordChange(ctx, tx, ReplicateTags, pkg, versionHash)
 		}
 
 		//nolint:mnd // 100 is a reasonable batch size for tag updates
 		err = tx.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).
 			CreateInBatches(modelTags, 100).Error
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"set tags",
+				fmt.Sprintf(
+					"namespace=%q, name=%q, hash=%q, tags=%v",
+					pkg.Namespace,
+					pkg.Name,
+					versionHash,
+					tags,
+				),
+			)
+		}
 
-		return wrapErrorWithDetails(
-			err,
-			"set tags",
-			fmt.Sprintf(
-				"namespace=%q, name=%q, hash=%q, tags=%v",
-				pkg.Namespace,
-				pkg.Name,
-				versionHash,
-				tags,
-			),
-		)
+		return db.recordChange(ctx, tx, ReplicateTags, pkg, versionHash)
 	})
 }
@@ -0,0 +1,272 @@
+package orm
+
+import (
+	"artifact-registry/tracing"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// migrationLockID is the key of the advisory lock held while migrating, so
+// only one replica changes the schema at a time
+const migrationLockID = 0x61727469666163 // "artifac"
+
+// ErrIrreversibleMigration is returned by the Down of migrations that cannot
+// be reverted without losing data
+var ErrIrreversibleMigration = errors.New("migration cannot be reverted")
+
+// Migration is a numbered change of the schema. Down reverts Up, both run in
+// one transaction with the update of the schema version.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus describes a migration and whether it has been applied.
+// Unknown migrations have been applied by a newer version of the registry.
+type MigrationStatus struct {
+	Version uint
+	Name    string
+	// Unset if the migration is pending
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+func migrationDetails(version uint, name string) string {
+	return fmt.Sprintf("version=%d, name=%q", version, name)
+}
+
+// withMigrationLock runs fn on a single connection that holds the migration
+// lock and has the schema version table. On SQLite, the lock is the write
+// lock of a transaction around fn, which keeps out other processes using the
+// same file, e.g. the migrate subcommand.
+func (db *DB) withMigrationLock(
+	ctx context.Context,
+	fn func(conn *gorm.DB) error,
+) error {
+	//nolint:wrapcheck // Error already wrapped
+	return db.dbGorm.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == DriverSQLite {
+			return conn.Transaction(func(tx *gorm.DB) error {
+				err := tx.Migrator().AutoMigrate(&SchemaMigration{})
+				if err != nil {
+					return wrapErrorWithDetails(
+						err,
+						"create schema version table",
+						"",
+					)
+				}
+
+				return fn(tx)
+			})
+		}
+
+		if conn.Dialector.Name() == DriverPostgres {
+			err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error
+			if err != nil {
+				return wrapErrorWithDetails(err, "acquire migration lock", "")
+			}
+			defer func() {
+				// The lock belongs to the session, which outlives the request
+				err := conn.WithContext(context.WithoutCancel(ctx)).
+					Exec("SELECT pg_advisory_unlock(?)", migrationLockID).Error
+				if err != nil {
+					log.Warn().Err(err).Msg("Failed to release migration lock")
+				}
+			}()
+		}
+
+		err := conn.Migrator().AutoMigrate(&SchemaMigration{})
+		if err != nil {
+			return wrapErrorWithDetails(err, "create schema version table", "")
+		}
+
+		return fn(conn)
+	})
+}
+
+// appliedMigrations returns the applied migrations, newest first
+func appliedMigrations(
+	ctx context.Context,
+	conn *gorm.DB,
+) ([]SchemaMigration, error) {
+	applied, err := gorm.G[SchemaMigration](conn).
+		Order("version DESC").
+		Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(err, "get applied migrations", "")
+	}
+
+	return applied, nil
+}
+
+// MigrateUp applies all pending migrations in order and returns how many
+// were applied
+func (db *DB) MigrateUp(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "orm.MigrateUp")
+	defer span.End()
+
+	count := 0
+	err := db.withMigrationLock(ctx, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if slices.ContainsFunc(applied, func(m SchemaMigration) bool {
+				return m.Version == migration.Version
+			}) {
+				continue
+			}
+
+			details := migrationDetails(migration.Version, migration.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return wrapErrorWithDetails(err, "migrate up", details)
+				}
+
+				return wrapErrorWithDetails(
+					gorm.G[SchemaMigration](tx).Create(ctx, &SchemaMigration{
+						Version: migration.Version,
+						Name:    migration.Name,
+					}),
+					"record migration",
+					details,
+				)
+			})
+			if err != nil {
+				//nolint:wrapcheck // Error already wrapped
+				return err
+			}
+
+			log.Info().
+				Uint("version", migration.Version).
+				Str("name", migration.Name).
+				Msg("Applied migration")
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// MigrateDown reverts the given number of migrations, newest first, and
+// returns how many were reverted
+func (db *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	ctx, span := tracing.Start(ctx, "orm.MigrateDown")
+	defer span.End()
+
+	count := 0
+	err := db.withMigrationLock(ctx, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range applied[:min(steps, len(applied))] {
+			details := migrationDetails(record.Version, record.Name)
+			i := slices.IndexFunc(migrations, func(m Migration) bool {
+				return m.Version == record.Version
+			})
+			if i < 0 {
+				return &BadInputError{
+					Reason: "Migration is unknown to this version of the " +
+						"registry and cannot be reverted: " + details,
+				}
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				err := migrations[i].Down(tx)
+				if errors.Is(err, ErrIrreversibleMigration) {
+					return &BadInputError{
+						Reason: "Migration cannot be reverted: " + details,
+					}
+				}
+				if err != nil {
+					return wrapErrorWithDetails(err, "migrate down", details)
+				}
+
+				_, err = gorm.G[SchemaMigration](tx).
+					Where(&SchemaMigration{Version: record.Version}).
+					Delete(ctx)
+
+				return wrapErrorWithDetails(err, "remove migration", details)
+			})
+			if err != nil {
+				//nolint:wrapcheck // Error already wrapped
+				return err
+			}
+
+			log.Info().
+				Uint("version", record.Version).
+				Str("name", record.Name).
+				Msg("Reverted migration")
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// GetMigrationStatus returns all known migrations and the unknown applied
+// ones, ordered by version
+func (db *DB) GetMigrationStatus(
+	ctx context.Context,
+) ([]MigrationStatus, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetMigrationStatus")
+	defer span.End()
+
+	var applied []SchemaMigration
+	conn := db.dbGorm.WithContext(ctx)
+	if conn.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		applied, err = appliedMigrations(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		i := slices.IndexFunc(applied, func(m SchemaMigration) bool {
+			return m.Version == migration.Version
+		})
+		if i >= 0 {
+			appliedAt := applied[i].AppliedAt
+			status.AppliedAt = &appliedAt
+			applied = slices.Delete(applied, i, i+1)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for i := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Version:   applied[i].Version,
+			Name:      applied[i].Name,
+			AppliedAt: &applied[i].AppliedAt,
+			Unknown:   true,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return statuses, nil
+}
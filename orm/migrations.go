@@ -0,0 +1,304 @@
+package orm
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations are applied in the order of their versions. Applied migrations
+// must never change, the schema is changed by appending a new one that runs
+// explicit DDL, as AutoMigrate neither drops nor changes existing columns.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		// Databases created by AutoMigrate before versioned migrations were
+		// introduced already have these tables, only missing parts are added
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(baselineTables()...)
+		},
+		// Reverting would drop all data
+		Down: func(*gorm.DB) error {
+			return ErrIrreversibleMigration
+		},
+	},
+	{
+		Version: 2,
+		Name:    "audit_event_detail",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE audit_events ADD COLUMN detail TEXT").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE audit_events DROP COLUMN detail").Error
+		},
+	},
+	{
+		Version: 3,
+		Name:    "oci_manifests",
+		Up: func(tx *gorm.DB) error {
+			binary := "BYTEA"
+			if tx.Dialector.Name() == DriverSQLite {
+				binary = "BLOB"
+			}
+
+			for _, statement := range []string{
+				`CREATE TABLE oci_manifests (
+					namespace VARCHAR(255) NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					hash VARCHAR(64) NOT NULL,
+					digest VARCHAR(71) NOT NULL,
+					media_type VARCHAR(255) NOT NULL,
+					content ` + binary + ` NOT NULL,
+					config_digest VARCHAR(71) NOT NULL,
+					config ` + binary + ` NOT NULL,
+					PRIMARY KEY (namespace, name, hash),
+					CONSTRAINT fk_artifacts_oci_manifest
+						FOREIGN KEY (namespace, name, hash)
+						REFERENCES artifacts (namespace, name, hash)
+						ON DELETE CASCADE
+				)`,
+				"CREATE INDEX idx_oci_manifests_digest ON oci_manifests (digest)",
+				"CREATE INDEX idx_oci_manifests_config_digest " +
+					"ON oci_manifests (config_digest)",
+			} {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE oci_manifests").Error
+		},
+	},
+}
+
+// The baseline schema is defined by copies of the models at the time, so
+// later changes to the models do not change this migration
+
+func baselineTables() []any {
+	return []any{
+		&baselineArtifact{},
+		&baselineTag{},
+		&baselineDeletedTag{},
+		&baselineArtifactSymbol{},
+		&baselineArtifactLabel{},
+		&baselinePackage{},
+		&baselinePackageOwner{},
+		&baselineNamespace{},
+		&baselinePackageMove{},
+		&baselinePackageRedirect{},
+		&baselineReplicationEvent{},
+		&baselineReplicationCursor{},
+		&baselineAuditEvent{},
+		&baselinePullBucket{},
+	}
+}
+
+type baselineArtifact struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+	Hash      string `gorm:"primaryKey;size:64;not null"`
+
+	CreatedAt    time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	PullsCount   int64     `gorm:"default:0"`
+	LastPulledAt *time.Time
+
+	SizeBytes int64  `gorm:"not null;default:0"`
+	MediaType string `gorm:"size:128;not null;default:''"`
+	Uploader  string `gorm:"size:255;not null;default:''"`
+	UserAgent string `gorm:"size:512;not null;default:''"`
+
+	CopiedFromNamespace string `gorm:"size:255;not null;default:''"`
+	CopiedFromName      string `gorm:"size:255;not null;default:''"`
+
+	State       string `gorm:"size:16;not null;default:'active'"`
+	StateReason string `gorm:"type:text"`
+
+	DeletedAt   gorm.DeletedAt       `gorm:"index"`
+	DeletedBy   string               `gorm:"size:255;not null;default:''"`
+	DeletedTags []baselineDeletedTag `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE"`
+
+	Tags    []baselineTag            `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE"`
+	Symbols []baselineArtifactSymbol `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE"`
+	Labels  []baselineArtifactLabel  `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE"`
+}
+
+func (baselineArtifact) TableName() string { return "artifacts" }
+
+type baselineTag struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+	TagName   string `gorm:"primaryKey;size:255;not null"`
+	Hash      string `gorm:"size:64;not null"`
+}
+
+func (baselineTag) TableName() string { return "tags" }
+
+type baselineDeletedTag struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+	Hash      string `gorm:"primaryKey;size:64;not null"`
+	TagName   string `gorm:"primaryKey;size:255;not null"`
+}
+
+func (baselineDeletedTag) TableName() string { return "deleted_tags" }
+
+type baselineArtifactSymbol struct {
+	ID uint `gorm:"primaryKey"`
+
+	Namespace string `gorm:"size:255;not null;index:idx_symbol_artifact"`
+	Name      string `gorm:"size:255;not null;index:idx_symbol_artifact"`
+	Hash      string `gorm:"size:64;not null;index:idx_symbol_artifact"`
+
+	Direction  string `gorm:"size:16;not null;index:idx_symbol_lookup"`
+	Module     string `gorm:"size:255;not null;default:''"`
+	SymbolName string `gorm:"size:1024;not null;index:idx_symbol_lookup"`
+	Kind       string `gorm:"size:32;not null"`
+	Signature  string `gorm:"type:text"`
+}
+
+func (baselineArtifactSymbol) TableName() string { return "artifact_symbols" }
+
+type baselineArtifactLabel struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+	Hash      string `gorm:"primaryKey;size:64;not null"`
+	Key       string `gorm:"primaryKey;size:255;not null;index"`
+	Value     string `gorm:"size:1024;not null"`
+}
+
+func (baselineArtifactLabel) TableName() string { return "artifact_labels" }
+
+type baselinePackage struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+
+	Description        string `gorm:"type:text"`
+	Readme             string `gorm:"type:text"`
+	Homepage           string `gorm:"size:2048;not null;default:''"`
+	Visibility         string `gorm:"size:16;not null;default:'public'"`
+	Deprecated         bool   `gorm:"not null;default:false"`
+	DeprecationMessage string `gorm:"type:text"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+
+	Owners []baselinePackageOwner `gorm:"foreignKey:Namespace,Name;references:Namespace,Name;constraint:OnDelete:CASCADE"`
+}
+
+func (baselinePackage) TableName() string { return "packages" }
+
+type baselinePackageOwner struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+	Owner     string `gorm:"primaryKey;size:255;not null"`
+}
+
+func (baselinePackageOwner) TableName() string { return "package_owners" }
+
+type baselineNamespace struct {
+	Name        string `gorm:"primaryKey;size:255;not null"`
+	Description string `gorm:"type:text"`
+
+	QuotaBytes    int64 `gorm:"not null;default:0"`
+	QuotaVersions int64 `gorm:"not null;default:0"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (baselineNamespace) TableName() string { return "namespaces" }
+
+type baselinePackageMove struct {
+	ID uint `gorm:"primaryKey"`
+
+	FromNamespace string `gorm:"size:255;not null;uniqueIndex:idx_move_source"`
+	FromName      string `gorm:"size:255;not null;uniqueIndex:idx_move_source"`
+	ToNamespace   string `gorm:"size:255;not null"`
+	ToName        string `gorm:"size:255;not null"`
+
+	RedirectTTL time.Duration `gorm:"not null;default:0"`
+	State       string        `gorm:"size:16;not null"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (baselinePackageMove) TableName() string { return "package_moves" }
+
+type baselinePackageRedirect struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+
+	TargetNamespace string    `gorm:"size:255;not null"`
+	TargetName      string    `gorm:"size:255;not null"`
+	ExpiresAt       time.Time `gorm:"not null;index"`
+}
+
+func (baselinePackageRedirect) TableName() string {
+	return "package_redirects"
+}
+
+type baselineReplicationEvent struct {
+	ID uint `gorm:"primaryKey"`
+
+	Kind      string `gorm:"size:16;not null"`
+	Namespace string `gorm:"size:255;not null"`
+	Name      string `gorm:"size:255;not null"`
+	Hash      string `gorm:"size:64;not null"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (baselineReplicationEvent) TableName() string {
+	return "replication_events"
+}
+
+type baselineReplicationCursor struct {
+	Peer        string `gorm:"primaryKey;size:255;not null"`
+	LastEventID uint   `gorm:"not null;default:0"`
+	Failures    int64  `gorm:"not null;default:0"`
+	LastError   string `gorm:"type:text"`
+
+	LastReplicatedAt *time.Time
+}
+
+func (baselineReplicationCursor) TableName() string {
+	return "replication_cursors"
+}
+
+type baselineAuditEvent struct {
+	ID uint `gorm:"primaryKey"`
+
+	Actor         string `gorm:"size:255;not null;index"`
+	ClientAddress string `gorm:"size:255;not null"`
+
+	Action     string   `gorm:"size:16;not null;index"`
+	Namespace  string   `gorm:"size:255;not null;index:idx_audit_pkg"`
+	Name       string   `gorm:"size:255;not null;index:idx_audit_pkg"`
+	Hash       string   `gorm:"size:64;not null"`
+	TagsBefore []string `gorm:"serializer:json"`
+	TagsAfter  []string `gorm:"serializer:json"`
+
+	Succeeded bool   `gorm:"not null"`
+	Error     string `gorm:"type:text"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
+}
+
+func (baselineAuditEvent) TableName() string { return "audit_events" }
+
+type baselinePullBucket struct {
+	Namespace string `gorm:"primaryKey;size:255;not null"`
+	Name      string `gorm:"primaryKey;size:255;not null"`
+	Hash      string `gorm:"primaryKey;size:64;not null"`
+	Tag       string `gorm:"primaryKey;size:255;not null"`
+	Client    string `gorm:"primaryKey;size:255;not null"`
+
+	Granularity string    `gorm:"primaryKey;size:8;not null"`
+	Start       time.Time `gorm:"primaryKey;not null;index"`
+	Pulls       int64     `gorm:"not null;default:0"`
+}
+
+func (baselinePullBucket) TableName() string { return "pull_buckets" }
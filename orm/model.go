@@ -2,6 +2,8 @@ package orm
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Artifact struct {
@@ -11,11 +13,53 @@ type Artifact struct {
 
 	CreatedAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
 	PullsCount int64     `gorm:"default:0"                          json:"pullsCount"`
+	// Unset if the version has never been pulled
+	LastPulledAt *time.Time `json:"lastPulledAt,omitempty"`
+
+	// Recorded on upload. MediaType is empty for rows created before these
+	// columns existed until they are backfilled.
+	SizeBytes int64  `gorm:"not null;default:0"           json:"sizeBytes"`
+	MediaType string `gorm:"size:128;not null;default:''" json:"mediaType"`
+	Uploader  string `gorm:"size:255;not null;default:''" json:"uploader"`
+	UserAgent string `gorm:"size:512;not null;default:''" json:"userAgent"`
+
+	// Package the version was copied from with CopyArtifact, empty for
+	// uploaded versions
+	CopiedFromNamespace string `gorm:"size:255;not null;default:''" json:"copiedFromNamespace,omitempty"`
+	CopiedFromName      string `gorm:"size:255;not null;default:''" json:"copiedFromName,omitempty"`
+
+	// Lifecycle state, one of ArtifactActive, ArtifactDeprecated and
+	// ArtifactYanked
+	State       string `gorm:"size:16;not null;default:'active'" json:"state"`
+	StateReason string `gorm:"type:text"                         json:"stateReason,omitempty"`
+
+	// Set while the artifact is in the trash, which hides it from all regular
+	// queries. DeletedTags holds the tags it had when it was trashed.
+	DeletedAt   gorm.DeletedAt `gorm:"index"                        json:"deletedAt,omitempty"`
+	DeletedBy   string         `gorm:"size:255;not null;default:''" json:"deletedBy,omitempty"`
+	DeletedTags []DeletedTag   `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE" json:"deletedTags,omitempty"`
 
 	// Reverse relationship to tags with cascading deletion
 	Tags []Tag `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE" json:"tags,omitempty"`
+
+	// Imports and exports of the Wasm binary, empty if it could not be parsed
+	Symbols []ArtifactSymbol `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE" json:"symbols,omitempty"`
+
+	// Free-form key/value annotations
+	Labels []ArtifactLabel `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE" json:"labels,omitempty"`
+
+	// Never loaded with the artifact, see GetOCIManifest
+	OCIManifest *OCIManifest `gorm:"foreignKey:Namespace,Name,Hash;references:Namespace,Name,Hash;constraint:OnDelete:CASCADE" json:"-"`
 }
 
+const (
+	ArtifactActive     = "active"
+	ArtifactDeprecated = "deprecated"
+	// Yanked versions are no longer resolved through tags, but remain
+	// available by hash for workloads that pinned them
+	ArtifactYanked = "yanked"
+)
+
 type Tag struct {
 	// Composite primary key that also serves as foreign key to Artifact
 	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
@@ -23,3 +67,246 @@ type Tag struct {
 	TagName   string `gorm:"primaryKey;size:255;not null" json:"tagName"`
 	Hash      string `gorm:"size:64;not null"             json:"hash"`
 }
+
+// DeletedTag is a tag of a trashed artifact, kept to restore it
+type DeletedTag struct {
+	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null" json:"name"`
+	Hash      string `gorm:"primaryKey;size:64;not null"  json:"hash"`
+	TagName   string `gorm:"primaryKey;size:255;not null" json:"tagName"`
+}
+
+// OCIManifest is the manifest pushed for a version through the OCI
+// distribution API, together with its config blob. Both are served as they
+// were pushed, so their digests match the ones the client computed. A version
+// has at most one manifest, a later push replaces it.
+type OCIManifest struct {
+	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null" json:"name"`
+	Hash      string `gorm:"primaryKey;size:64;not null"  json:"hash"`
+
+	Digest       string `gorm:"size:71;not null;index"  json:"digest"`
+	MediaType    string `gorm:"size:255;not null"       json:"mediaType"`
+	Content      []byte `gorm:"not null"                json:"content"`
+	ConfigDigest string `gorm:"size:71;not null;index"  json:"configDigest"`
+	Config       []byte `gorm:"not null"                json:"config"`
+}
+
+const (
+	SymbolImport = "import"
+	SymbolExport = "export"
+)
+
+// ArtifactSymbol is a single import or export of an artifact's Wasm binary
+type ArtifactSymbol struct {
+	ID uint `gorm:"primaryKey" json:"-"`
+
+	Namespace string `gorm:"size:255;not null;index:idx_symbol_artifact" json:"namespace"`
+	Name      string `gorm:"size:255;not null;index:idx_symbol_artifact" json:"name"`
+	Hash      string `gorm:"size:64;not null;index:idx_symbol_artifact"  json:"hash"`
+
+	// Direction is either SymbolImport or SymbolExport
+	Direction  string `gorm:"size:16;not null;index:idx_symbol_lookup"   json:"direction"`
+	Module     string `gorm:"size:255;not null;default:''"               json:"module,omitempty"`
+	SymbolName string `gorm:"size:1024;not null;index:idx_symbol_lookup" json:"symbolName"`
+	Kind       string `gorm:"size:32;not null"                           json:"kind"`
+	Signature  string `gorm:"type:text"                                  json:"signature,omitempty"`
+}
+
+type ArtifactLabel struct {
+	// Composite primary key, the first three columns reference the Artifact
+	Namespace string `gorm:"primaryKey;size:255;not null"        json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null"        json:"name"`
+	Hash      string `gorm:"primaryKey;size:64;not null"         json:"hash"`
+	Key       string `gorm:"primaryKey;size:255;not null;index"  json:"key"`
+	Value     string `gorm:"size:1024;not null"                  json:"value"`
+}
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// Package holds the metadata shared by all versions of a package. It is not
+// tied to the artifacts by a foreign key, so it outlives its last version.
+type Package struct {
+	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null" json:"name"`
+
+	Description        string `gorm:"type:text"                               json:"description"`
+	Readme             string `gorm:"type:text"                               json:"readme"`
+	Homepage           string `gorm:"size:2048;not null;default:''"           json:"homepage"`
+	Visibility         string `gorm:"size:16;not null;default:'public'"       json:"visibility"`
+	Deprecated         bool   `gorm:"not null;default:false"                  json:"deprecated"`
+	DeprecationMessage string `gorm:"type:text"                               json:"deprecationMessage"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+
+	Owners []PackageOwner `gorm:"foreignKey:Namespace,Name;references:Namespace,Name;constraint:OnDelete:CASCADE" json:"owners,omitempty"`
+}
+
+type PackageOwner struct {
+	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null" json:"name"`
+	Owner     string `gorm:"primaryKey;size:255;not null" json:"owner"`
+}
+
+// Namespace groups packages and carries their storage quotas. Zero quotas
+// are unlimited.
+type Namespace struct {
+	Name        string `gorm:"primaryKey;size:255;not null" json:"name"`
+	Description string `gorm:"type:text"                    json:"description"`
+
+	QuotaBytes    int64 `gorm:"not null;default:0" json:"quotaBytes"`
+	QuotaVersions int64 `gorm:"not null;default:0" json:"quotaVersions"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// NamespaceUsage is the storage consumed by all versions in a namespace.
+// Bytes and Versions leave out trashed versions, whose size is TrashedBytes.
+type NamespaceUsage struct {
+	Namespace    string
+	Bytes        int64
+	Versions     int64
+	TrashedBytes int64
+}
+
+const (
+	MoveCopying = "copying"
+	MoveCleanup = "cleanup"
+)
+
+// PackageMove journals a package move while it is in progress, so it can be
+// resumed after a crash. Blobs are linked to the target while the move is
+// copying, the metadata is switched over in a single transaction that enters
+// cleanup, which removes the source blobs.
+type PackageMove struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	FromNamespace string `gorm:"size:255;not null;uniqueIndex:idx_move_source" json:"fromNamespace"`
+	FromName      string `gorm:"size:255;not null;uniqueIndex:idx_move_source" json:"fromName"`
+	ToNamespace   string `gorm:"size:255;not null"                             json:"toNamespace"`
+	ToName        string `gorm:"size:255;not null"                             json:"toName"`
+
+	// How long the source keeps resolving to the target, zero for no redirect
+	RedirectTTL time.Duration `gorm:"not null;default:0"  json:"redirectTtl"`
+	State       string        `gorm:"size:16;not null"    json:"state"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// PackageRedirect lets reads of a moved package resolve to its new name
+type PackageRedirect struct {
+	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null" json:"name"`
+
+	TargetNamespace string    `gorm:"size:255;not null" json:"targetNamespace"`
+	TargetName      string    `gorm:"size:255;not null" json:"targetName"`
+	ExpiresAt       time.Time `gorm:"not null;index"    json:"expiresAt"`
+}
+
+const (
+	ReplicateUpload = "upload"
+	ReplicateDelete = "delete"
+	ReplicateTags   = "tags"
+	// Removes the package record, Hash is empty
+	ReplicateDeletePackage = "delete_package"
+)
+
+// ReplicationEvent is an entry of the replication outbox. It is written in
+// the transaction of the change it records and only references the version,
+// or the package for ReplicateDeletePackage. The version and its tags are
+// sent to the peers as they are at the time of replication.
+type ReplicationEvent struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Kind is one of the Replicate constants
+	Kind      string `gorm:"size:16;not null"  json:"kind"`
+	Namespace string `gorm:"size:255;not null" json:"namespace"`
+	Name      string `gorm:"size:255;not null" json:"name"`
+	Hash      string `gorm:"size:64;not null"  json:"hash"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+// ReplicationCursor is the position of a peer in the replication outbox.
+// Failures counts the attempts since the last success, LastError is kept
+// after the peer recovers.
+type ReplicationCursor struct {
+	Peer        string `gorm:"primaryKey;size:255;not null" json:"peer"`
+	LastEventID uint   `gorm:"not null;default:0"           json:"lastEventId"`
+	Failures    int64  `gorm:"not null;default:0"           json:"failures"`
+	LastError   string `gorm:"type:text"                    json:"lastError,omitempty"`
+
+	LastReplicatedAt *time.Time `json:"lastReplicatedAt,omitempty"`
+}
+
+const (
+	AuditUpload          = "upload"
+	AuditDelete          = "delete"
+	AuditTrash           = "trash"
+	AuditRestore         = "restore"
+	AuditPurge           = "purge"
+	AuditSetTags         = "set_tags"
+	AuditSetLabels       = "set_labels"
+	AuditSetState        = "set_state"
+	AuditMovePackage     = "move_package"
+	AuditDeletePackage   = "delete_package"
+	AuditDeleteNamespace = "delete_namespace"
+)
+
+// AuditEvent is an entry of the append-only audit log. Successful changes are
+// recorded in the transaction that makes them, failed ones after it was
+// rolled back.
+// Detail describes what else changed, e.g. the target of a move.
+type AuditEvent struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	Actor         string `gorm:"size:255;not null;index" json:"actor"`
+	ClientAddress string `gorm:"size:255;not null"       json:"clientAddress"`
+
+	// Action is one of the Audit constants
+	Action     string   `gorm:"size:16;not null;index"                json:"action"`
+	Namespace  string   `gorm:"size:255;not null;index:idx_audit_pkg" json:"namespace"`
+	Name       string   `gorm:"size:255;not null;index:idx_audit_pkg" json:"name"`
+	Hash       string   `gorm:"size:64;not null"                      json:"hash"`
+	TagsBefore []string `gorm:"serializer:json"                       json:"tagsBefore,omitempty"`
+	TagsAfter  []string `gorm:"serializer:json"                       json:"tagsAfter,omitempty"`
+	Detail     string   `gorm:"type:text"                             json:"detail,omitempty"`
+
+	Succeeded bool   `gorm:"not null"  json:"succeeded"`
+	Error     string `gorm:"type:text" json:"error,omitempty"`
+
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"createdAt"`
+}
+
+const (
+	PullsHourly = "hour"
+	PullsDaily  = "day"
+)
+
+// PullBucket counts the pulls of a version by one client through one tag
+// within an hour or a day. Each pull is counted in both granularities, so
+// the hourly buckets can be pruned sooner.
+type PullBucket struct {
+	Namespace string `gorm:"primaryKey;size:255;not null" json:"namespace"`
+	Name      string `gorm:"primaryKey;size:255;not null" json:"name"`
+	Hash      string `gorm:"primaryKey;size:64;not null"  json:"hash"`
+	// Empty for pulls by version hash
+	Tag    string `gorm:"primaryKey;size:255;not null" json:"tag"`
+	Client string `gorm:"primaryKey;size:255;not null" json:"client"`
+
+	// Granularity is one of the Pulls constants
+	Granularity string    `gorm:"primaryKey;size:8;not null" json:"granularity"`
+	Start       time.Time `gorm:"primaryKey;not null;index"  json:"start"`
+	Pulls       int64     `gorm:"not null;default:0"         json:"pulls"`
+}
+
+// SchemaMigration records a migration that has been applied to the schema
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"     json:"version"`
+	Name      string    `gorm:"size:255;not null"                  json:"name"`
+	AppliedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"appliedAt"`
+}
@@ -0,0 +1,409 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreatePackageMove journals a new move. Only one move per source package can
+// be in progress at a time.
+func (db *DB) CreatePackageMove(ctx context.Context, move *PackageMove) error {
+	ctx, span := tracing.Start(ctx, "orm.CreatePackageMove")
+	defer span.End()
+
+	for _, name := range []*proto_gen.PackageName{
+		{Namespace: move.FromNamespace, Name: move.FromName},
+		{Namespace: move.ToNamespace, Name: move.ToName},
+	} {
+		if err := validatePackageName(name); err != nil {
+			return err
+		}
+	}
+
+	return wrapErrorWithDetails(
+		gorm.G[PackageMove](db.dbGorm).Create(ctx, move),
+		"create package move",
+		moveDetails(move),
+	)
+}
+
+// GetPackageMoves returns all moves that are still in progress
+func (db *DB) GetPackageMoves(ctx context.Context) ([]PackageMove, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetPackageMoves")
+	defer span.End()
+
+	moves, err := gorm.G[PackageMove](db.dbGorm).Order("id").Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(err, "get package moves", "")
+	}
+
+	return moves, nil
+}
+
+func (db *DB) DeletePackageMove(ctx context.Context, move *PackageMove) error {
+	ctx, span := tracing.Start(ctx, "orm.DeletePackageMove")
+	defer span.End()
+
+	_, err := gorm.G[PackageMove](db.dbGorm).
+		Where(&PackageMove{ID: move.ID}).
+		Delete(ctx)
+
+	return wrapErrorWithDetails(err, "delete package move", moveDetails(move))
+}
+
+// GetArtifactHashes returns the hashes of all versions of a package, including
+// trashed ones
+func (db *DB) GetArtifactHashes(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetArtifactHashes")
+	defer span.End()
+
+	if err := validatePackageName(name); err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	err := db.dbGorm.WithContext(ctx).
+		Unscoped().
+		Model(&Artifact{}).
+		Where(&Artifact{Namespace: name.Namespace, Name: name.Name}).
+		Order("hash").
+		Pluck("hash", &hashes).Error
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get artifact hashes",
+			fmt.Sprintf("namespace=%q, name=%q", name.Namespace, name.Name),
+		)
+	}
+
+	return hashes, nil
+}
+
+// CommitPackageMove switches all metadata of the source package over to the
+// target in a single transaction and puts the move into cleanup. This
+// includes trashed versions, tags, symbols, labels, the package record and
+// redirects pointing to the source. The target must not have any versions or
+// a package record. hashes are the versions whose blobs were copied to the
+// target, if the source has others by now, e.g. after a concurrent upload,
+// the move fails with a ConflictError.
+func (db *DB) CommitPackageMove(
+	ctx context.Context,
+	move *PackageMove,
+	hashes []string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.CommitPackageMove")
+	defer span.End()
+
+	detailString := moveDetails(move)
+	from := &Artifact{Namespace: move.FromNamespace, Name: move.FromName}
+	to := map[string]any{"namespace": move.ToNamespace, "name": move.ToName}
+
+	return db.audit(ctx, newMoveAuditEvent(move), func(tx *gorm.DB) error {
+		if err := checkMoveTarget(ctx, tx, move); err != nil {
+			return err
+		}
+
+		artifacts, err := gorm.G[Artifact](tx).
+			Scopes(unscoped).
+			Where(from).
+			Find(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "get artifacts to move", detailString)
+		}
+		if !sameVersions(artifacts, hashes) {
+			return &ConflictError{
+				Conflict: "versions of moved package changed (" + detailString + ")",
+			}
+		}
+
+		if len(artifacts) > 0 {
+			for i := range artifacts {
+				artifacts[i].Namespace = move.ToNamespace
+				artifacts[i].Name = move.ToName
+			}
+
+			//nolint:mnd // 100 is a reasonable batch size for versions
+			err = tx.WithContext(ctx).
+				Omit(clause.Associations).
+				CreateInBatches(&artifacts, 100).Error
+			if err != nil {
+				return wrapErrorWithDetails(err, "copy artifacts", detailString)
+			}
+		}
+
+		// The new artifact rows exist now, so the references can be switched.
+		// Only rows of the copied versions are touched, others may have been
+		// inserted since they were listed.
+		for _, model := range []any{
+			&Tag{},
+			&DeletedTag{},
+			&ArtifactSymbol{},
+			&ArtifactLabel{},
+			&OCIManifest{},
+		} {
+			err := tx.WithContext(ctx).
+				Model(model).
+				Where("namespace = ? AND name = ?", move.FromNamespace, move.FromName).
+				Where("hash IN ?", hashes).
+				Updates(to).Error
+			if err != nil {
+				return wrapErrorWithDetails(
+					err,
+					fmt.Sprintf("move %T rows", model),
+					detailString,
+				)
+			}
+		}
+
+		_, err = gorm.G[Artifact](tx).
+			Scopes(unscoped).
+			Where(from).
+			Where("hash IN ?", hashes).
+			Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "delete moved artifacts", detailString)
+		}
+
+		if err := movePackageRecord(ctx, tx, move); err != nil {
+			return err
+		}
+
+		if err := moveRedirects(ctx, tx, move); err != nil {
+			return err
+		}
+
+		if err := db.recordMove(ctx, tx, move, artifacts); err != nil {
+			return err
+		}
+
+		move.State = MoveCleanup
+
+		return wrapErrorWithDetails(
+			tx.WithContext(ctx).
+				Model(&PackageMove{ID: move.ID}).
+				Update("state", MoveCleanup).Error,
+			"update package move",
+			detailString,
+		)
+	})
+}
+
+// recordMove replicates a move as the deletion of the moved versions under
+// the old name and their upload under the new one. Trashed versions stay in
+// the trash of the peers.
+func (db *DB) recordMove(
+	ctx context.Context,
+	tx *gorm.DB,
+	move *PackageMove,
+	artifacts []Artifact,
+) error {
+	from := &proto_gen.PackageName{
+		Namespace: move.FromNamespace,
+		Name:      move.FromName,
+	}
+	to := &proto_gen.PackageName{Namespace: move.ToNamespace, Name: move.ToName}
+
+	for i := range artifacts {
+		if artifacts[i].DeletedAt.Valid {
+			continue
+		}
+
+		hash := artifacts[i].Hash
+		err := db.recordChange(ctx, tx, ReplicateDelete, from, hash)
+		if err != nil {
+			return err
+		}
+		if err := db.recordChange(ctx, tx, ReplicateUpload, to, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newMoveAuditEvent records a move under the source package
+func newMoveAuditEvent(move *PackageMove) *AuditEvent {
+	event := newAuditEvent(
+		AuditMovePackage,
+		&proto_gen.PackageName{
+			Namespace: move.FromNamespace,
+			Name:      move.FromName,
+		},
+		"",
+	)
+	event.Detail = "to " + move.ToNamespace + "/" + move.ToName
+
+	return event
+}
+
+// GetPackageRedirect returns the redirect of a package that has been moved,
+// as long as it has not expired
+func (db *DB) GetPackageRedirect(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+) (*PackageRedirect, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetPackageRedirect")
+	defer span.End()
+
+	if err := validatePackageName(name); err != nil {
+		return nil, err
+	}
+
+	redirect, err := gorm.G[PackageRedirect](db.dbGorm).
+		Where(&PackageRedirect{Namespace: name.Namespace, Name: name.Name}).
+		Where("expires_at > ?", time.Now()).
+		First(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get package redirect",
+			fmt.Sprintf("namespace=%q, name=%q", name.Namespace, name.Name),
+		)
+	}
+
+	return &redirect, nil
+}
+
+// sameVersions reports whether artifacts are exactly the versions of hashes
+func sameVersions(artifacts []Artifact, hashes []string) bool {
+	if len(artifacts) != len(hashes) {
+		return false
+	}
+	for i := range artifacts {
+		if !slices.Contains(hashes, artifacts[i].Hash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func checkMoveTarget(
+	ctx context.Context,
+	tx *gorm.DB,
+	move *PackageMove,
+) error {
+	versions, err := gorm.G[Artifact](tx).Scopes(unscoped).
+		Where(&Artifact{Namespace: move.ToNamespace, Name: move.ToName}).
+		Count(ctx, "*")
+	if err != nil {
+		return wrapErrorWithDetails(err, "check move target", moveDetails(move))
+	}
+
+	records, err := gorm.G[Package](tx).
+		Where(&Package{Namespace: move.ToNamespace, Name: move.ToName}).
+		Count(ctx, "*")
+	if err != nil {
+		return wrapErrorWithDetails(err, "check move target", moveDetails(move))
+	}
+
+	if versions > 0 || records > 0 {
+		return &ConflictError{
+			Conflict: "move target already exists (" + moveDetails(move) + ")",
+		}
+	}
+
+	return nil
+}
+
+func movePackageRecord(
+	ctx context.Context,
+	tx *gorm.DB,
+	move *PackageMove,
+) error {
+	detailString := moveDetails(move)
+	from := &Package{Namespace: move.FromNamespace, Name: move.FromName}
+
+	pkgs, err := gorm.G[Package](tx).Where(from).Find(ctx)
+	if err != nil || len(pkgs) == 0 {
+		return wrapErrorWithDetails(err, "get package to move", detailString)
+	}
+
+	pkg := pkgs[0]
+	pkg.Namespace = move.ToNamespace
+	pkg.Name = move.ToName
+	err = tx.WithContext(ctx).Omit(clause.Associations).Create(&pkg).Error
+	if err != nil {
+		return wrapErrorWithDetails(err, "copy package record", detailString)
+	}
+
+	err = tx.WithContext(ctx).
+		Model(&PackageOwner{}).
+		Where("namespace = ? AND name = ?", move.FromNamespace, move.FromName).
+		Updates(map[string]any{
+			"namespace": move.ToNamespace,
+			"name":      move.ToName,
+		}).Error
+	if err != nil {
+		return wrapErrorWithDetails(err, "move package owners", detailString)
+	}
+
+	_, err = gorm.G[Package](tx).Where(from).Delete(ctx)
+
+	return wrapErrorWithDetails(err, "delete moved package record", detailString)
+}
+
+// moveRedirects keeps redirects to the source pointing at the package and
+// leaves a new one behind if requested. A redirect away from the target is
+// dropped, as the target name is taken now.
+func moveRedirects(ctx context.Context, tx *gorm.DB, move *PackageMove) error {
+	detailString := moveDetails(move)
+
+	_, err := gorm.G[PackageRedirect](tx).
+		Where(&PackageRedirect{Namespace: move.ToNamespace, Name: move.ToName}).
+		Delete(ctx)
+	if err != nil {
+		return wrapErrorWithDetails(err, "drop target redirect", detailString)
+	}
+
+	err = tx.WithContext(ctx).
+		Model(&PackageRedirect{}).
+		Where(
+			"target_namespace = ? AND target_name = ?",
+			move.FromNamespace,
+			move.FromName,
+		).
+		Updates(map[string]any{
+			"target_namespace": move.ToNamespace,
+			"target_name":      move.ToName,
+		}).Error
+	if err != nil {
+		return wrapErrorWithDetails(err, "update redirects", detailString)
+	}
+
+	if move.RedirectTTL == 0 {
+		return nil
+	}
+
+	return wrapErrorWithDetails(
+		tx.WithContext(ctx).
+			Clauses(clause.OnConflict{UpdateAll: true}).
+			Create(&PackageRedirect{
+				Namespace:       move.FromNamespace,
+				Name:            move.FromName,
+				TargetNamespace: move.ToNamespace,
+				TargetName:      move.ToName,
+				ExpiresAt:       time.Now().Add(move.RedirectTTL),
+			}).Error,
+		"create redirect",
+		detailString,
+	)
+}
+
+func moveDetails(move *PackageMove) string {
+	return fmt.Sprintf(
+		"from=%q, to=%q",
+		move.FromNamespace+"/"+move.FromName,
+		move.ToNamespace+"/"+move.ToName,
+	)
+}
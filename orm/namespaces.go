@@ -0,0 +1,279 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func validateNamespaceName(name string) error {
+	if name == "" {
+		return &BadInputError{Reason: "namespace name must be provided"}
+	}
+
+	return nil
+}
+
+func (db *DB) CreateNamespace(ctx context.Context, ns *Namespace) error {
+	ctx, span := tracing.Start(ctx, "orm.CreateNamespace")
+	defer span.End()
+
+	if err := validateNamespaceName(ns.Name); err != nil {
+		return err
+	}
+
+	return wrapErrorWithDetails(
+		gorm.G[Namespace](db.dbGorm).Create(ctx, ns),
+		"create namespace",
+		fmt.Sprintf("name=%q", ns.Name),
+	)
+}
+
+// EnsureNamespace creates the given namespace record if none exists yet and
+// returns the stored one
+func (db *DB) EnsureNamespace(
+	ctx context.Context,
+	ns *Namespace,
+) (*Namespace, error) {
+	ctx, span := tracing.Start(ctx, "orm.EnsureNamespace")
+	defer span.End()
+
+	if err := validateNamespaceName(ns.Name); err != nil {
+		return nil, err
+	}
+
+	err := db.dbGorm.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(ns).Error
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"ensure namespace",
+			fmt.Sprintf("name=%q", ns.Name),
+		)
+	}
+
+	return db.GetNamespace(ctx, ns.Name)
+}
+
+func (db *DB) GetNamespace(
+	ctx context.Context,
+	name string,
+) (*Namespace, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetNamespace")
+	defer span.End()
+
+	if err := validateNamespaceName(name); err != nil {
+		return nil, err
+	}
+
+	ns, err := gorm.G[Namespace](db.dbGorm).
+		Where(&Namespace{Name: name}).
+		First(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get namespace",
+			fmt.Sprintf("name=%q", name),
+		)
+	}
+
+	return &ns, nil
+}
+
+func (db *DB) ListNamespaces(ctx context.Context) ([]Namespace, error) {
+	ctx, span := tracing.Start(ctx, "orm.ListNamespaces")
+	defer span.End()
+
+	namespaces, err := gorm.G[Namespace](db.dbGorm).Order("name").Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(err, "list namespaces", "")
+	}
+
+	return namespaces, nil
+}
+
+// UpdateNamespace replaces the description and quotas of an existing
+// namespace record
+func (db *DB) UpdateNamespace(ctx context.Context, ns *Namespace) error {
+	ctx, span := tracing.Start(ctx, "orm.UpdateNamespace")
+	defer span.End()
+
+	if err := validateNamespaceName(ns.Name); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf("name=%q", ns.Name)
+
+	result := db.dbGorm.WithContext(ctx).
+		Model(&Namespace{Name: ns.Name}).
+		Select("Description", "QuotaBytes", "QuotaVersions").
+		Updates(ns)
+	if result.Error != nil {
+		return wrapErrorWithDetails(
+			result.Error,
+			"update namespace",
+			detailString,
+		)
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{Search: "update namespace (" + detailString + ")"}
+	}
+
+	return nil
+}
+
+// DeleteNamespace removes the namespace record together with the metadata of
+// all versions, including trashed ones, and the package records it contains.
+// Namespaces without a record are deleted as long as they contain anything.
+// The blobs have to be removed by the caller.
+func (db *DB) DeleteNamespace(ctx context.Context, name string) error {
+	ctx, span := tracing.Start(ctx, "orm.DeleteNamespace")
+	defer span.End()
+
+	if err := validateNamespaceName(name); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf("name=%q", name)
+
+	event := &AuditEvent{Action: AuditDeleteNamespace, Namespace: name}
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		if err := db.recordNamespaceDeletion(ctx, tx, name); err != nil {
+			return err
+		}
+
+		artifacts, err := gorm.G[Artifact](tx).Scopes(unscoped).
+			Where(&Artifact{Namespace: name}).
+			Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"delete namespace artifacts",
+				detailString,
+			)
+		}
+
+		packages, err := gorm.G[Package](tx).
+			Where(&Package{Namespace: name}).
+			Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"delete namespace packages",
+				detailString,
+			)
+		}
+
+		rows, err := gorm.G[Namespace](tx).
+			Where(&Namespace{Name: name}).
+			Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "delete namespace", detailString)
+		}
+		if rows+artifacts+packages == 0 {
+			return &NotFoundError{Search: "delete namespace (" + detailString + ")"}
+		}
+		event.Detail = namespaceDeletionDetail(artifacts, packages)
+
+		return nil
+	})
+}
+
+// namespaceDeletionDetail describes what the deletion of a namespace removed
+// in its audit event. Trashed versions are included.
+func namespaceDeletionDetail(versions, packages int) string {
+	return fmt.Sprintf("versions=%d, packages=%d", versions, packages)
+}
+
+// recordNamespaceDeletion replicates the deletion of a namespace as the
+// deletion of its versions, which were not trashed yet, and package records
+func (db *DB) recordNamespaceDeletion(
+	ctx context.Context,
+	tx *gorm.DB,
+	name string,
+) error {
+	detailString := fmt.Sprintf("name=%q", name)
+
+	artifacts, err := gorm.G[Artifact](tx).
+		Where(&Artifact{Namespace: name}).
+		Order("name, hash").
+		Find(ctx)
+	if err != nil {
+		return wrapErrorWithDetails(
+			err,
+			"get namespace artifacts",
+			detailString,
+		)
+	}
+	for i := range artifacts {
+		pkg := &proto_gen.PackageName{
+			Namespace: name,
+			Name:      artifacts[i].Name,
+		}
+		err := db.recordChange(ctx, tx, ReplicateDelete, pkg, artifacts[i].Hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	packages, err := gorm.G[Package](tx).
+		Where(&Package{Namespace: name}).
+		Order("name").
+		Find(ctx)
+	if err != nil {
+		return wrapErrorWithDetails(err, "get namespace packages", detailString)
+	}
+	for i := range packages {
+		pkg := &proto_gen.PackageName{Namespace: name, Name: packages[i].Name}
+		err := db.recordChange(ctx, tx, ReplicateDeletePackage, pkg, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetNamespaceUsages returns the usage of the given namespaces, or of all
+// namespaces with at least one version if none are given. Namespaces without
+// versions, including trashed ones, are left out.
+func (db *DB) GetNamespaceUsages(
+	ctx context.Context,
+	names ...string,
+) ([]NamespaceUsage, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetNamespaceUsages")
+	defer span.End()
+
+	query := db.dbGorm.WithContext(ctx).
+		Unscoped().
+		Model(&Artifact{}).
+		Select(
+			"namespace, " +
+				"COALESCE(SUM(CASE WHEN deleted_at IS NULL " +
+				"THEN size_bytes ELSE 0 END), 0) AS bytes, " +
+				"COUNT(CASE WHEN deleted_at IS NULL THEN 1 END) AS versions, " +
+				"COALESCE(SUM(CASE WHEN deleted_at IS NOT NULL " +
+				"THEN size_bytes ELSE 0 END), 0) AS trashed_bytes",
+		).
+		Group("namespace")
+	if len(names) > 0 {
+		query = query.Where("namespace IN ?", names)
+	}
+
+	var usages []NamespaceUsage
+	if err := query.Scan(&usages).Error; err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get namespace usages",
+			fmt.Sprintf("namespaces=%q", names),
+		)
+	}
+
+	return usages, nil
+}
@@ -0,0 +1,141 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OCIManifestFilter selects the OCI manifest of a package by the version it
+// belongs to, its own digest or the digest of its config blob. Empty fields
+// match everything, but at least one has to be set.
+type OCIManifestFilter struct {
+	Hash         string
+	Digest       string
+	ConfigDigest string
+}
+
+func (f OCIManifestFilter) String() string {
+	return fmt.Sprintf(
+		"hash=%q, digest=%q, configDigest=%q",
+		f.Hash,
+		f.Digest,
+		f.ConfigDigest,
+	)
+}
+
+func validateOCIManifest(manifest *OCIManifest) error {
+	if manifest == nil {
+		return &BadInputError{Reason: "nil OCI manifest"}
+	}
+
+	if manifest.Digest == "" || manifest.ConfigDigest == "" {
+		return &BadInputError{
+			Reason: "OCI manifest without digest or config digest",
+		}
+	}
+
+	return validateArtifactKey(
+		&proto_gen.PackageName{
+			Namespace: manifest.Namespace,
+			Name:      manifest.Name,
+		},
+		manifest.Hash,
+	)
+}
+
+func validateOCIManifestQuery(
+	pkg *proto_gen.PackageName,
+	filter OCIManifestFilter,
+) error {
+	if err := validatePackageName(pkg); err != nil {
+		return err
+	}
+
+	if filter == (OCIManifestFilter{}) {
+		return &BadInputError{Reason: "OCI manifest filter without criteria"}
+	}
+
+	return nil
+}
+
+// SetOCIManifest stores the OCI manifest of a version, replacing the one
+// pushed before
+func (db *DB) SetOCIManifest(
+	ctx context.Context,
+	manifest *OCIManifest,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.SetOCIManifest")
+	defer span.End()
+
+	if err := validateOCIManifest(manifest); err != nil {
+		return err
+	}
+
+	err := db.dbGorm.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(manifest).Error
+
+	return wrapErrorWithDetails(
+		err,
+		"set OCI manifest",
+		fmt.Sprintf(
+			"namespace=%q, name=%q, hash=%q, digest=%q",
+			manifest.Namespace,
+			manifest.Name,
+			manifest.Hash,
+			manifest.Digest,
+		),
+	)
+}
+
+// GetOCIManifest returns the first OCI manifest of a package that matches
+// the filter. Manifests of trashed versions are not found.
+func (db *DB) GetOCIManifest(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	filter OCIManifestFilter,
+) (*OCIManifest, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetOCIManifest")
+	defer span.End()
+
+	if err := validateOCIManifestQuery(pkg, filter); err != nil {
+		return nil, err
+	}
+
+	active := db.dbGorm.Model(&Artifact{}).
+		Select("1").
+		Where("artifacts.namespace = oci_manifests.namespace").
+		Where("artifacts.name = oci_manifests.name").
+		Where("artifacts.hash = oci_manifests.hash")
+
+	manifest, err := gorm.G[OCIManifest](db.dbGorm).
+		Where(&OCIManifest{
+			Namespace:    pkg.Namespace,
+			Name:         pkg.Name,
+			Hash:         filter.Hash,
+			Digest:       filter.Digest,
+			ConfigDigest: filter.ConfigDigest,
+		}).
+		Where("EXISTS (?)", active).
+		Order("hash").
+		First(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get OCI manifest",
+			fmt.Sprintf(
+				"namespace=%q, name=%q, %s",
+				pkg.Namespace,
+				pkg.Name,
+				filter,
+			),
+		)
+	}
+
+	return &manifest, nil
+}
@@ -0,0 +1,298 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func validatePackageName(pkg *proto_gen.PackageName) error {
+	if pkg == nil {
+		return &BadInputError{
+			Reason: "package with nil PackageName",
+		}
+	}
+
+	if pkg.Namespace == "" || pkg.Name == "" {
+		return &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q",
+				pkg.Namespace,
+				pkg.Name,
+			),
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) CreatePackage(ctx context.Context, pkg *Package) error {
+	ctx, span := tracing.Start(ctx, "orm.CreatePackage")
+	defer span.End()
+
+	err := validatePackageName(&proto_gen.PackageName{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	return wrapErrorWithDetails(
+		gorm.G[Package](db.dbGorm).Create(ctx, pkg),
+		"create package",
+		fmt.Sprintf("namespace=%q, name=%q", pkg.Namespace, pkg.Name),
+	)
+}
+
+// EnsurePackage creates a package record with default settings if none exists
+// yet. The owner, if not empty, becomes the only owner of a newly created
+// package and is ignored for existing ones.
+func (db *DB) EnsurePackage(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+	owner string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.EnsurePackage")
+	defer span.End()
+
+	if err := validatePackageName(name); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, owner=%q",
+		name.Namespace,
+		name.Name,
+		owner,
+	)
+
+	//nolint:wrapcheck // Error already wrapped
+	return db.dbGorm.Transaction(func(tx *gorm.DB) error {
+		result := tx.WithContext(ctx).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Package{
+				Namespace:  name.Namespace,
+				Name:       name.Name,
+				Visibility: VisibilityPublic,
+			})
+		if result.Error != nil {
+			return wrapErrorWithDetails(
+				result.Error,
+				"ensure package",
+				detailString,
+			)
+		}
+
+		if result.RowsAffected == 0 || owner == "" {
+			return nil
+		}
+
+		return wrapErrorWithDetails(
+			gorm.G[PackageOwner](tx).Create(ctx, &PackageOwner{
+				Namespace: name.Namespace,
+				Name:      name.Name,
+				Owner:     owner,
+			}),
+			"add initial package owner",
+			detailString,
+		)
+	})
+}
+
+func (db *DB) GetPackage(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+) (*Package, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetPackage")
+	defer span.End()
+
+	if err := validatePackageName(name); err != nil {
+		return nil, err
+	}
+
+	pkg, err := gorm.G[Package](db.dbGorm).
+		Preload("Owners", nil).
+		Where(&Package{Namespace: name.Namespace, Name: name.Name}).
+		First(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get package",
+			fmt.Sprintf("namespace=%q, name=%q", name.Namespace, name.Name),
+		)
+	}
+
+	return &pkg, nil
+}
+
+// GetPackages returns the records of all given packages that have one.
+// Packages without a record are silently left out.
+func (db *DB) GetPackages(
+	ctx context.Context,
+	names []*proto_gen.PackageName,
+) ([]Package, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetPackages")
+	defer span.End()
+
+	if len(names) == 0 {
+		return []Package{}, nil
+	}
+
+	keys := make([][]any, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, []any{name.Namespace, name.Name})
+	}
+
+	packages, err := gorm.G[Package](db.dbGorm).
+		Preload("Owners", nil).
+		Where("(namespace, name) IN ?", keys).
+		Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get packages",
+			fmt.Sprintf("count=%d", len(names)),
+		)
+	}
+
+	return packages, nil
+}
+
+// ListPackages returns all package records, optionally restricted to a
+// namespace
+func (db *DB) ListPackages(
+	ctx context.Context,
+	namespace string,
+) ([]Package, error) {
+	ctx, span := tracing.Start(ctx, "orm.ListPackages")
+	defer span.End()
+
+	packages, err := gorm.G[Package](db.dbGorm).
+		Preload("Owners", nil).
+		Where(&Package{Namespace: namespace}).
+		Order("namespace, name").
+		Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"list packages",
+			fmt.Sprintf("namespace=%q", namespace),
+		)
+	}
+
+	return packages, nil
+}
+
+// UpdatePackage replaces all mutable fields and the owners of an existing
+// package record
+func (db *DB) UpdatePackage(ctx context.Context, pkg *Package) error {
+	ctx, span := tracing.Start(ctx, "orm.UpdatePackage")
+	defer span.End()
+
+	err := validatePackageName(&proto_gen.PackageName{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q",
+		pkg.Namespace,
+		pkg.Name,
+	)
+	key := &Package{Namespace: pkg.Namespace, Name: pkg.Name}
+
+	//nolint:wrapcheck // Error already wrapped
+	return db.dbGorm.Transaction(func(tx *gorm.DB) error {
+		result := tx.WithContext(ctx).
+			Model(key).
+			Select(
+				"Description",
+				"Readme",
+				"Homepage",
+				"Visibility",
+				"Deprecated",
+				"DeprecationMessage",
+				"UpdatedAt",
+			).
+			Updates(pkg)
+		if result.Error != nil {
+			return wrapErrorWithDetails(
+				result.Error,
+				"update package",
+				detailString,
+			)
+		}
+		if result.RowsAffected == 0 {
+			return &NotFoundError{Search: "update package (" + detailString + ")"}
+		}
+
+		_, err := gorm.G[PackageOwner](tx).Where(&PackageOwner{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+		}).Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"delete package owners",
+				detailString,
+			)
+		}
+
+		if len(pkg.Owners) == 0 {
+			return nil
+		}
+		for i := range pkg.Owners {
+			pkg.Owners[i].Namespace = pkg.Namespace
+			pkg.Owners[i].Name = pkg.Name
+		}
+
+		//nolint:mnd // 100 is a reasonable batch size for owner updates
+		return wrapErrorWithDetails(
+			gorm.G[PackageOwner](tx).CreateInBatches(ctx, &pkg.Owners, 100),
+			"set package owners",
+			detailString,
+		)
+	})
+}
+
+func (db *DB) DeletePackage(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.DeletePackage")
+	defer span.End()
+
+	if err := validatePackageName(name); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q",
+		name.Namespace,
+		name.Name,
+	)
+
+	event := newAuditEvent(AuditDeletePackage, name, "")
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		rows, err := gorm.G[Package](tx).
+			Where(&Package{Namespace: name.Namespace, Name: name.Name}).
+			Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "delete package", detailString)
+		}
+		if rows == 0 {
+			return &NotFoundError{Search: "delete package (" + detailString + ")"}
+		}
+
+		return db.recordChange(ctx, tx, ReplicateDeletePackage, name, "")
+	})
+}
@@ -0,0 +1,269 @@
+package orm
+
+import (
+	"artifact-registry/tracing"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Columns pull statistics can be grouped by
+const (
+	PullsByVersion = "hash"
+	PullsByTag     = "tag"
+	PullsByClient  = "client"
+)
+
+// Pull counts the pulls of a version by one client through one tag. Pulls
+// that are added together are counted in the hour of Time.
+type Pull struct {
+	Namespace string
+	Name      string
+	Hash      string
+	// Empty if the version was pulled by hash
+	Tag    string
+	Client string
+	// Time of the latest pull
+	Time  time.Time
+	Count int64
+}
+
+// PullStatsFilter selects the pull buckets that are summed up. Empty fields
+// match everything.
+type PullStatsFilter struct {
+	Namespace   string
+	Name        string
+	Hash        string
+	Granularity string
+	// Only buckets starting in [Since, Until)
+	Since time.Time
+	Until time.Time
+	// One of the PullsBy constants, empty for a single series
+	GroupBy string
+}
+
+// PullStat is the number of pulls in one bucket of a series
+type PullStat struct {
+	// Value of the grouping column, empty if the pulls are not grouped
+	Series string
+	Start  time.Time
+	Pulls  int64
+}
+
+// AddPulls increments the pull counters of the versions and their buckets
+// in one transaction. The counters are incremented in SQL, so concurrent
+// additions are never lost. Pulls of versions that have been deleted in the
+// meantime are dropped.
+func (db *DB) AddPulls(ctx context.Context, pulls []Pull) error {
+	ctx, span := tracing.Start(ctx, "orm.AddPulls")
+	defer span.End()
+
+	// A consistent locking order keeps concurrent batches from deadlocking
+	pulls = slices.Clone(pulls)
+	slices.SortFunc(pulls, func(a, b Pull) int {
+		return cmp.Or(
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Hash, b.Hash),
+		)
+	})
+
+	//nolint:wrapcheck // Error already wrapped
+	return db.dbGorm.Transaction(func(tx *gorm.DB) error {
+		buckets := make([]PullBucket, 0, 2*len(pulls))
+		for _, pull := range pulls {
+			detailString := fmt.Sprintf(
+				"namespace=%q, name=%q, hash=%q",
+				pull.Namespace,
+				pull.Name,
+				pull.Hash,
+			)
+
+			result := tx.WithContext(ctx).
+				Model(&Artifact{}).
+				Where(&Artifact{
+					Namespace: pull.Namespace,
+					Name:      pull.Name,
+					Hash:      pull.Hash,
+				}).
+				UpdateColumns(map[string]any{
+					"pulls_count": gorm.Expr("pulls_count + ?", pull.Count),
+					"last_pulled_at": gorm.Expr(
+						"CASE WHEN last_pulled_at IS NULL OR last_pulled_at < ? "+
+							"THEN ? ELSE last_pulled_at END",
+						pull.Time,
+						pull.Time,
+					),
+				})
+			if result.Error != nil {
+				return wrapErrorWithDetails(
+					result.Error,
+					"increase pull count",
+					detailString,
+				)
+			}
+			if result.RowsAffected > 0 {
+				buckets = append(buckets, pullBuckets(pull)...)
+			}
+		}
+
+		if len(buckets) == 0 {
+			return nil
+		}
+
+		//nolint:mnd // 100 is a reasonable batch size for buckets
+		return wrapErrorWithDetails(
+			tx.WithContext(ctx).
+				Clauses(clause.OnConflict{
+					Columns: []clause.Column{
+						{Name: "namespace"},
+						{Name: "name"},
+						{Name: "hash"},
+						{Name: "tag"},
+						{Name: "client"},
+						{Name: "granularity"},
+						{Name: "start"},
+					},
+					DoUpdates: clause.Assignments(map[string]any{
+						"pulls": gorm.Expr("pull_buckets.pulls + excluded.pulls"),
+					}),
+				}).
+				CreateInBatches(mergePullBuckets(buckets), 100).Error,
+			"record pulls",
+			fmt.Sprintf("buckets=%d", len(buckets)),
+		)
+	})
+}
+
+// pullBuckets returns the hourly and daily bucket a pull is counted in
+func pullBuckets(pull Pull) []PullBucket {
+	bucket := PullBucket{
+		Namespace: pull.Namespace,
+		Name:      pull.Name,
+		Hash:      pull.Hash,
+		Tag:       pull.Tag,
+		Client:    pull.Client,
+		Pulls:     pull.Count,
+	}
+	hourly, daily := bucket, bucket
+	hourly.Granularity = PullsHourly
+	hourly.Start = pull.Time.UTC().Truncate(time.Hour)
+	daily.Granularity = PullsDaily
+	//nolint:mnd // Days are aligned to midnight UTC
+	daily.Start = pull.Time.UTC().Truncate(24 * time.Hour)
+
+	return []PullBucket{hourly, daily}
+}
+
+// mergePullBuckets sums up buckets with the same key, which a single insert
+// must not contain more than once
+func mergePullBuckets(buckets []PullBucket) []PullBucket {
+	type key struct {
+		namespace, name, hash, tag, client, granularity string
+		start                                           time.Time
+	}
+
+	merged := make([]PullBucket, 0, len(buckets))
+	indices := make(map[key]int, len(buckets))
+	for _, bucket := range buckets {
+		k := key{
+			bucket.Namespace,
+			bucket.Name,
+			bucket.Hash,
+			bucket.Tag,
+			bucket.Client,
+			bucket.Granularity,
+			bucket.Start,
+		}
+		if i, ok := indices[k]; ok {
+			merged[i].Pulls += bucket.Pulls
+
+			continue
+		}
+		indices[k] = len(merged)
+		merged = append(merged, bucket)
+	}
+
+	return merged
+}
+
+// GetPullStats sums up the pulls matching the filter per series and bucket,
+// ordered by series and time
+func (db *DB) GetPullStats(
+	ctx context.Context,
+	filter PullStatsFilter,
+) ([]PullStat, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetPullStats")
+	defer span.End()
+
+	series := "''"
+	groupBy := "start"
+	switch filter.GroupBy {
+	case "":
+	case PullsByVersion, PullsByTag, PullsByClient:
+		series = filter.GroupBy
+		groupBy = filter.GroupBy + ", start"
+	default:
+		return nil, &BadInputError{
+			Reason: fmt.Sprintf("Unknown pull grouping %q", filter.GroupBy),
+		}
+	}
+
+	query := db.dbGorm.WithContext(ctx).
+		Model(&PullBucket{}).
+		Select(series + " AS series, start, SUM(pulls) AS pulls").
+		Where(&PullBucket{
+			Namespace:   filter.Namespace,
+			Name:        filter.Name,
+			Hash:        filter.Hash,
+			Granularity: filter.Granularity,
+		})
+	if !filter.Since.IsZero() {
+		query = query.Where("start >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("start < ?", filter.Until)
+	}
+
+	var stats []PullStat
+	err := query.Group(groupBy).Order(groupBy).Scan(&stats).Error
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get pull stats",
+			fmt.Sprintf("%+v", filter),
+		)
+	}
+
+	return stats, nil
+}
+
+// PrunePullBuckets deletes the buckets of the given granularity that started
+// before the given time
+func (db *DB) PrunePullBuckets(
+	ctx context.Context,
+	granularity string,
+	before time.Time,
+) (int, error) {
+	ctx, span := tracing.Start(ctx, "orm.PrunePullBuckets")
+	defer span.End()
+
+	rows, err := gorm.G[PullBucket](db.dbGorm).
+		Where(&PullBucket{Granularity: granularity}).
+		Where("start < ?", before).
+		Delete(ctx)
+	if err != nil {
+		return 0, wrapErrorWithDetails(
+			err,
+			"prune pull buckets",
+			fmt.Sprintf("granularity=%q, before=%s", granularity, before),
+		)
+	}
+
+	return rows, nil
+}
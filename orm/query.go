@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SymbolFilter matches artifacts that import or export a symbol. Module is
+// optional and only meaningful for core module imports.
+type SymbolFilter struct {
+	Direction string
+	Module    string
+	Name      string
+}
+
+// ArtifactFilter narrows down QueryArtifactMetas. Empty fields are ignored,
+// all given criteria must match.
+type ArtifactFilter struct {
+	Namespace string
+	Name      string
+	Symbols   []SymbolFilter
+	Labels    []LabelSelector
+}
+
+func (db *DB) QueryArtifactMetas(
+	ctx context.Context,
+	filter ArtifactFilter,
+) ([]Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.QueryArtifactMetas")
+	defer span.End()
+
+	query := gorm.G[Artifact](db.dbGorm).Where(&Artifact{
+		Namespace: filter.Namespace,
+		Name:      filter.Name,
+	})
+
+	for _, symbol := range filter.Symbols {
+		if symbol.Name == "" ||
+			(symbol.Direction != SymbolImport && symbol.Direction != SymbolExport) {
+			return nil, &BadInputError{
+				Reason: fmt.Sprintf(
+					"symbol filter needs a name and a direction: direction=%q, name=%q",
+					symbol.Direction,
+					symbol.Name,
+				),
+			}
+		}
+
+		subQuery := db.dbGorm.Model(&ArtifactSymbol{}).
+			Select("1").
+			Where("artifact_symbols.namespace = artifacts.namespace").
+			Where("artifact_symbols.name = artifacts.name").
+			Where("artifact_symbols.hash = artifacts.hash").
+			Where(&ArtifactSymbol{
+				Direction:  symbol.Direction,
+				Module:     symbol.Module,
+				SymbolName: symbol.Name,
+			})
+		query = query.Where("EXISTS (?)", subQuery)
+	}
+
+	for _, selector := range filter.Labels {
+		condition, subQuery, err := db.labelCondition(selector)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, subQuery)
+	}
+
+	artifacts, err := withArtifactAssociations(query).Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"query artifacts",
+			fmt.Sprintf("filter=%+v", filter),
+		)
+	}
+
+	return artifacts, nil
+}
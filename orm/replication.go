@@ -0,0 +1,197 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WithReplicationOutbox returns a DB instance that records uploads, deletions
+// and tag changes in the replication outbox
+func (db *DB) WithReplicationOutbox() MetadataStore {
+	return &DB{dbGorm: db.dbGorm, outbox: true}
+}
+
+// recordChange appends a change to the replication outbox, if it is enabled.
+// tx has to be the transaction that makes the change.
+func (db *DB) recordChange(
+	ctx context.Context,
+	tx *gorm.DB,
+	kind string,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	if !db.outbox {
+		return nil
+	}
+
+	return wrapErrorWithDetails(
+		gorm.G[ReplicationEvent](tx).Create(ctx, &ReplicationEvent{
+			Kind:      kind,
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+		}),
+		"record replication event",
+		fmt.Sprintf(
+			"kind=%q, namespace=%q, name=%q, hash=%q",
+			kind,
+			pkg.Namespace,
+			pkg.Name,
+			versionHash,
+		),
+	)
+}
+
+// GetReplicationEvents returns up to limit events following afterID, oldest
+// first. Only events recorded before the given time are returned.
+func (db *DB) GetReplicationEvents(
+	ctx context.Context,
+	afterID uint,
+	before time.Time,
+	limit int,
+) ([]ReplicationEvent, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetReplicationEvents")
+	defer span.End()
+
+	events, err := gorm.G[ReplicationEvent](db.dbGorm).
+		Where("id > ? AND created_at < ?", afterID, before).
+		Order("id").
+		Limit(limit).
+		Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get replication events",
+			fmt.Sprintf("afterID=%d", afterID),
+		)
+	}
+
+	return events, nil
+}
+
+// CountReplicationEvents returns the number of events following afterID and
+// when the oldest of them was recorded. The time is zero if there are none.
+func (db *DB) CountReplicationEvents(
+	ctx context.Context,
+	afterID uint,
+) (int64, time.Time, error) {
+	ctx, span := tracing.Start(ctx, "orm.CountReplicationEvents")
+	defer span.End()
+
+	count, err := gorm.G[ReplicationEvent](db.dbGorm).
+		Where("id > ?", afterID).
+		Count(ctx, "*")
+	if err != nil || count == 0 {
+		return 0, time.Time{}, wrapErrorWithDetails(
+			err,
+			"count replication events",
+			fmt.Sprintf("afterID=%d", afterID),
+		)
+	}
+
+	// The events are recorded in order, the first one is the oldest
+	oldest, err := gorm.G[ReplicationEvent](db.dbGorm).
+		Where("id > ?", afterID).
+		Order("id").
+		First(ctx)
+	if err != nil {
+		return 0, time.Time{}, wrapErrorWithDetails(
+			err,
+			"get oldest replication event",
+			fmt.Sprintf("afterID=%d", afterID),
+		)
+	}
+
+	return count, oldest.CreatedAt, nil
+}
+
+// GetReplicationCursor returns the position of a peer in the outbox. Peers
+// that have not been seen before start at its beginning.
+func (db *DB) GetReplicationCursor(
+	ctx context.Context,
+	peer string,
+) (*ReplicationCursor, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetReplicationCursor")
+	defer span.End()
+
+	cursor, err := gorm.G[ReplicationCursor](db.dbGorm).
+		Where(&ReplicationCursor{Peer: peer}).
+		First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &ReplicationCursor{Peer: peer}, nil
+	}
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get replication cursor",
+			fmt.Sprintf("peer=%q", peer),
+		)
+	}
+
+	return &cursor, nil
+}
+
+func (db *DB) SaveReplicationCursor(
+	ctx context.Context,
+	cursor *ReplicationCursor,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.SaveReplicationCursor")
+	defer span.End()
+
+	return wrapErrorWithDetails(
+		db.dbGorm.WithContext(ctx).
+			Clauses(clause.OnConflict{UpdateAll: true}).
+			Create(cursor).Error,
+		"save replication cursor",
+		fmt.Sprintf("peer=%q, lastEventID=%d", cursor.Peer, cursor.LastEventID),
+	)
+}
+
+// PruneReplicationEvents deletes the events that have been replicated to all
+// of the given peers
+func (db *DB) PruneReplicationEvents(
+	ctx context.Context,
+	peers []string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.PruneReplicationEvents")
+	defer span.End()
+
+	cursors, err := gorm.G[ReplicationCursor](db.dbGorm).
+		Where("peer IN ?", peers).
+		Find(ctx)
+	if err != nil {
+		return wrapErrorWithDetails(
+			err,
+			"get replication cursors",
+			fmt.Sprintf("peers=%v", peers),
+		)
+	}
+
+	// A peer without cursor has not received anything yet
+	if len(cursors) == 0 || len(cursors) < len(peers) {
+		return nil
+	}
+
+	replicated := slices.MinFunc(cursors, func(a, b ReplicationCursor) int {
+		return cmp.Compare(a.LastEventID, b.LastEventID)
+	}).LastEventID
+
+	_, err = gorm.G[ReplicationEvent](db.dbGorm).
+		Where("id <= ?", replicated).
+		Delete(ctx)
+
+	return wrapErrorWithDetails(
+		err,
+		"prune replication events",
+		fmt.Sprintf("replicated=%d", replicated),
+	)
+}
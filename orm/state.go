@@ -0,0 +1,79 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SetArtifactState moves an artifact into the given lifecycle state. The
+// reason is stored as given, callers clear it when reactivating a version.
+func (db *DB) SetArtifactState(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	state string,
+	reason string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.SetArtifactState")
+	defer span.End()
+
+	if pkg == nil {
+		return &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	if versionHash == "" || pkg.Namespace == "" || pkg.Name == "" {
+		return &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q, hash=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+			),
+		}
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, hash=%q, state=%q",
+		pkg.Namespace,
+		pkg.Name,
+		versionHash,
+		state,
+	)
+
+	event := newAuditEvent(AuditSetState, pkg, versionHash)
+	event.Detail = state
+	if reason != "" {
+		event.Detail += ": " + reason
+	}
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		result := tx.WithContext(ctx).
+			Model(&Artifact{
+				Namespace: pkg.Namespace,
+				Name:      pkg.Name,
+				Hash:      versionHash,
+			}).
+			Select("State", "StateReason").
+			Updates(&Artifact{State: state, StateReason: reason})
+		if result.Error != nil {
+			return wrapErrorWithDetails(
+				result.Error,
+				"set artifact state",
+				detailString,
+			)
+		}
+		if result.RowsAffected == 0 {
+			return &NotFoundError{
+				Search: "set artifact state (" + detailString + ")",
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,192 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"context"
+	"time"
+)
+
+var _ MetadataStore = (*DB)(nil)
+
+// MetadataStore is the metadata storage used by the registry server. DB keeps
+// it in a database, implementations for tests must report failures with the
+// same error types: BadInputError for invalid arguments, NotFoundError for
+// missing records and ConflictError for duplicates.
+type MetadataStore interface {
+	// WithReplicationOutbox returns a store that records uploads, deletions
+	// and tag changes in the replication outbox
+	WithReplicationOutbox() MetadataStore
+
+	GetArtifactMetaByHash(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		hash string,
+	) (*Artifact, error)
+	GetArtifactMetaByTag(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		tag string,
+	) (*Artifact, error)
+	GetArtifactMetasByFQN(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+	) ([]Artifact, error)
+	GetArtifactMetasByRefs(
+		ctx context.Context,
+		refs []ArtifactRef,
+	) ([]*Artifact, error)
+	GetArtifactHashes(
+		ctx context.Context,
+		name *proto_gen.PackageName,
+	) ([]string, error)
+	QueryArtifactMetas(
+		ctx context.Context,
+		filter ArtifactFilter,
+	) ([]Artifact, error)
+	CreateArtifactMeta(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+		details ArtifactDetails,
+		tags ...string,
+	) error
+	DeleteArtifactMeta(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+	) error
+	SetTags(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+		tags []string,
+	) error
+	SetLabels(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+		labels map[string]string,
+	) error
+	SetArtifactState(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+		state string,
+		reason string,
+	) error
+	GetArtifactMetasWithoutDetails(
+		ctx context.Context,
+		after *Artifact,
+		limit int,
+	) ([]Artifact, error)
+	SetArtifactSizeAndMediaType(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+		sizeBytes int64,
+		mediaType string,
+	) error
+
+	SetOCIManifest(ctx context.Context, manifest *OCIManifest) error
+	GetOCIManifest(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		filter OCIManifestFilter,
+	) (*OCIManifest, error)
+
+	TrashArtifactMeta(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+		deletedBy string,
+	) error
+	GetDeletedArtifactMetas(
+		ctx context.Context,
+		filter TrashFilter,
+	) ([]Artifact, error)
+	GetDeletedArtifactMeta(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+	) (*Artifact, error)
+	RestoreArtifactMeta(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+	) error
+	PurgeArtifactMeta(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		versionHash string,
+	) error
+
+	CreatePackage(ctx context.Context, pkg *Package) error
+	EnsurePackage(
+		ctx context.Context,
+		name *proto_gen.PackageName,
+		owner string,
+	) error
+	GetPackage(
+		ctx context.Context,
+		name *proto_gen.PackageName,
+	) (*Package, error)
+	GetPackages(
+		ctx context.Context,
+		names []*proto_gen.PackageName,
+	) ([]Package, error)
+	ListPackages(ctx context.Context, namespace string) ([]Package, error)
+	UpdatePackage(ctx context.Context, pkg *Package) error
+	DeletePackage(ctx context.Context, name *proto_gen.PackageName) error
+
+	CreateNamespace(ctx context.Context, ns *Namespace) error
+	EnsureNamespace(ctx context.Context, ns *Namespace) (*Namespace, error)
+	GetNamespace(ctx context.Context, name string) (*Namespace, error)
+	ListNamespaces(ctx context.Context) ([]Namespace, error)
+	UpdateNamespace(ctx context.Context, ns *Namespace) error
+	DeleteNamespace(ctx context.Context, name string) error
+	GetNamespaceUsages(
+		ctx context.Context,
+		names ...string,
+	) ([]NamespaceUsage, error)
+
+	CreatePackageMove(ctx context.Context, move *PackageMove) error
+	GetPackageMoves(ctx context.Context) ([]PackageMove, error)
+	CommitPackageMove(
+		ctx context.Context,
+		move *PackageMove,
+		hashes []string,
+	) error
+	DeletePackageMove(ctx context.Context, move *PackageMove) error
+	GetPackageRedirect(
+		ctx context.Context,
+		name *proto_gen.PackageName,
+	) (*PackageRedirect, error)
+
+	GetReplicationEvents(
+		ctx context.Context,
+		afterID uint,
+		before time.Time,
+		limit int,
+	) ([]ReplicationEvent, error)
+	CountReplicationEvents(
+		ctx context.Context,
+		afterID uint,
+	) (int64, time.Time, error)
+	GetReplicationCursor(
+		ctx context.Context,
+		peer string,
+	) (*ReplicationCursor, error)
+	SaveReplicationCursor(ctx context.Context, cursor *ReplicationCursor) error
+	PruneReplicationEvents(ctx context.Context, peers []string) error
+
+	AddPulls(ctx context.Context, pulls []Pull) error
+	GetPullStats(ctx context.Context, filter PullStatsFilter) ([]PullStat, error)
+	PrunePullBuckets(
+		ctx context.Context,
+		granularity string,
+		before time.Time,
+	) (int, error)
+
+	GetAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+	RecordRejection(ctx context.Context, event *AuditEvent, reason error) error
+}
@@ -0,0 +1,33 @@
+package orm_test
+
+import (
+	"artifact-registry/config"
+	"artifact-registry/orm"
+	"artifact-registry/orm/storetest"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	storetest.RunTests(t, func(*testing.T) orm.MetadataStore {
+		return orm.NewMemoryStore()
+	})
+}
+
+func TestDB(t *testing.T) {
+	t.Parallel()
+
+	storetest.RunTests(t, func(t *testing.T) orm.MetadataStore {
+		t.Helper()
+
+		cfg := &config.AppConfig{}
+		cfg.Database.Driver = orm.DriverSQLite
+		cfg.Database.Path = filepath.Join(t.TempDir(), "registry.db")
+		cfg.Database.AutoMigrate = true
+		db := orm.InitDB(cfg)
+
+		return &db
+	})
+}
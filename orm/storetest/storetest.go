@@ -0,0 +1,1450 @@
+// Package storetest is a conformance test suite for implementations of
+// orm.MetadataStore. It only checks behavior that callers rely on, so it
+// leaves out the order of results that are documented as unordered.
+package storetest
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTests runs the suite against stores created by newStore. Every test
+// gets a store of its own.
+func RunTests(
+	t *testing.T,
+	newStore func(t *testing.T) orm.MetadataStore,
+) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store orm.MetadataStore)
+	}{
+		{"CreateArtifact", testCreateArtifact},
+		{"BadInput", testBadInput},
+		{"NotFound", testNotFound},
+		{"Tags", testTags},
+		{"Labels", testLabels},
+		{"OCIManifests", testOCIManifests},
+		{"QueryArtifacts", testQueryArtifacts},
+		{"ArtifactRefs", testArtifactRefs},
+		{"ArtifactState", testArtifactState},
+		{"ArtifactDetails", testArtifactDetails},
+		{"DeleteArtifact", testDeleteArtifact},
+		{"Trash", testTrash},
+		{"Packages", testPackages},
+		{"Namespaces", testNamespaces},
+		{"PackageMoves", testPackageMoves},
+		{"Replication", testReplication},
+		{"ReplicatedChanges", testReplicatedChanges},
+		{"Pulls", testPulls},
+		{"Audit", testAudit},
+		{"AuditedChanges", testAuditedChanges},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			test.run(t, newStore(t))
+		})
+	}
+}
+
+func pkgName(namespace, name string) *proto_gen.PackageName {
+	return &proto_gen.PackageName{Namespace: namespace, Name: name}
+}
+
+// create stores a version with the given tags and fails the test otherwise
+func create(
+	t *testing.T,
+	store orm.MetadataStore,
+	pkg *proto_gen.PackageName,
+	hash string,
+	tags ...string,
+) {
+	t.Helper()
+
+	err := store.CreateArtifactMeta(
+		t.Context(),
+		pkg,
+		hash,
+		orm.ArtifactDetails{SizeBytes: 10, MediaType: "application/wasm"},
+		tags...,
+	)
+	require.NoError(t, err)
+}
+
+func tagNames(artifact *orm.Artifact) []string {
+	names := make([]string, 0, len(artifact.Tags))
+	for _, tag := range artifact.Tags {
+		names = append(names, tag.TagName)
+	}
+
+	return names
+}
+
+func hashes(artifacts []orm.Artifact) []string {
+	result := make([]string, 0, len(artifacts))
+	for i := range artifacts {
+		result = append(result, artifacts[i].Hash)
+	}
+
+	return result
+}
+
+func requireNotFound(t *testing.T, err error) {
+	t.Helper()
+
+	var notFound *orm.NotFoundError
+	require.ErrorAs(t, err, &notFound)
+}
+
+func requireConflict(t *testing.T, err error) {
+	t.Helper()
+
+	var conflict *orm.ConflictError
+	require.ErrorAs(t, err, &conflict)
+}
+
+func requireBadInput(t *testing.T, err error) {
+	t.Helper()
+
+	var badInput *orm.BadInputError
+	require.ErrorAs(t, err, &badInput)
+}
+
+func testCreateArtifact(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+
+	err := store.CreateArtifactMeta(ctx, pkg, "h1", orm.ArtifactDetails{
+		SizeBytes: 42,
+		MediaType: "application/wasm",
+		Uploader:  "alice",
+		UserAgent: "test",
+		Symbols: []orm.ArtifactSymbol{{
+			Direction:  orm.SymbolExport,
+			SymbolName: "run",
+			Kind:       "func",
+		}},
+		Labels:     map[string]string{"team": "a"},
+		CopiedFrom: pkgName("other", "app"),
+	}, "latest", "v1")
+	require.NoError(t, err)
+
+	artifact, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, "ns", artifact.Namespace)
+	assert.Equal(t, "app", artifact.Name)
+	assert.Equal(t, int64(42), artifact.SizeBytes)
+	assert.Equal(t, "application/wasm", artifact.MediaType)
+	assert.Equal(t, "alice", artifact.Uploader)
+	assert.Equal(t, "test", artifact.UserAgent)
+	assert.Equal(t, "other", artifact.CopiedFromNamespace)
+	assert.Equal(t, orm.ArtifactActive, artifact.State)
+	assert.False(t, artifact.CreatedAt.IsZero())
+	assert.ElementsMatch(t, []string{"latest", "v1"}, tagNames(artifact))
+	require.Len(t, artifact.Symbols, 1)
+	assert.Equal(t, "run", artifact.Symbols[0].SymbolName)
+	require.Len(t, artifact.Labels, 1)
+	assert.Equal(t, "team", artifact.Labels[0].Key)
+	assert.Equal(t, "a", artifact.Labels[0].Value)
+
+	tagged, err := store.GetArtifactMetaByTag(ctx, pkg, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, "h1", tagged.Hash)
+
+	create(t, store, pkg, "h2")
+	create(t, store, pkgName("ns", "other"), "h1")
+	versions, err := store.GetArtifactMetasByFQN(ctx, pkg)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"h1", "h2"}, hashes(versions))
+
+	versionHashes, err := store.GetArtifactHashes(ctx, pkg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1", "h2"}, versionHashes)
+
+	err = store.CreateArtifactMeta(ctx, pkg, "h1", orm.ArtifactDetails{})
+	requireConflict(t, err)
+}
+
+func testBadInput(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+
+	_, err := store.GetArtifactMetaByHash(ctx, nil, "h1")
+	requireBadInput(t, err)
+	_, err = store.GetArtifactMetaByHash(ctx, pkg, "")
+	requireBadInput(t, err)
+	_, err = store.GetArtifactMetaByTag(ctx, pkg, "")
+	requireBadInput(t, err)
+	_, err = store.GetArtifactMetasByFQN(ctx, nil)
+	requireBadInput(t, err)
+	_, err = store.GetArtifactHashes(ctx, pkgName("ns", ""))
+	requireBadInput(t, err)
+	err = store.CreateArtifactMeta(
+		ctx,
+		pkgName("", "app"),
+		"h1",
+		orm.ArtifactDetails{},
+	)
+	requireBadInput(t, err)
+	requireBadInput(t, store.DeleteArtifactMeta(ctx, pkg, ""))
+	requireBadInput(t, store.SetTags(ctx, nil, "h1", nil))
+	requireBadInput(t, store.SetLabels(ctx, pkg, "", nil))
+	requireBadInput(t, store.SetArtifactState(ctx, pkg, "", "", ""))
+	requireBadInput(
+		t,
+		store.SetArtifactSizeAndMediaType(ctx, pkg, "h1", 1, ""),
+	)
+	requireBadInput(t, store.TrashArtifactMeta(ctx, pkg, "", ""))
+	requireBadInput(t, store.RestoreArtifactMeta(ctx, nil, "h1"))
+	requireBadInput(t, store.PurgeArtifactMeta(ctx, pkg, ""))
+	requireBadInput(t, store.CreatePackage(ctx, &orm.Package{Name: "app"}))
+	requireBadInput(t, store.EnsurePackage(ctx, pkgName("", ""), ""))
+	requireBadInput(t, store.CreateNamespace(ctx, &orm.Namespace{}))
+	_, err = store.GetNamespace(ctx, "")
+	requireBadInput(t, err)
+	requireBadInput(t, store.CreatePackageMove(ctx, &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+	}))
+
+	_, err = store.GetArtifactMetasByRefs(ctx, []orm.ArtifactRef{
+		{Namespace: "ns", Name: "app", Hash: "h1", Tag: "latest"},
+	})
+	requireBadInput(t, err)
+
+	_, err = store.QueryArtifactMetas(ctx, orm.ArtifactFilter{
+		Symbols: []orm.SymbolFilter{{Name: "run"}},
+	})
+	requireBadInput(t, err)
+	_, err = store.QueryArtifactMetas(ctx, orm.ArtifactFilter{
+		Labels: []orm.LabelSelector{{Key: "team", Operator: orm.LabelIn}},
+	})
+	requireBadInput(t, err)
+
+	_, err = store.GetPullStats(ctx, orm.PullStatsFilter{GroupBy: "unknown"})
+	requireBadInput(t, err)
+}
+
+func testNotFound(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+
+	_, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	requireNotFound(t, err)
+	_, err = store.GetArtifactMetaByTag(ctx, pkg, "latest")
+	requireNotFound(t, err)
+	requireNotFound(
+		t,
+		store.SetArtifactState(ctx, pkg, "h1", orm.ArtifactYanked, ""),
+	)
+	requireNotFound(t, store.TrashArtifactMeta(ctx, pkg, "h1", "alice"))
+	_, err = store.GetDeletedArtifactMeta(ctx, pkg, "h1")
+	requireNotFound(t, err)
+	requireNotFound(t, store.RestoreArtifactMeta(ctx, pkg, "h1"))
+	requireNotFound(t, store.PurgeArtifactMeta(ctx, pkg, "h1"))
+
+	_, err = store.GetPackage(ctx, pkg)
+	requireNotFound(t, err)
+	requireNotFound(t, store.UpdatePackage(ctx, &orm.Package{
+		Namespace: "ns",
+		Name:      "app",
+	}))
+	requireNotFound(t, store.DeletePackage(ctx, pkg))
+
+	_, err = store.GetNamespace(ctx, "ns")
+	requireNotFound(t, err)
+	requireNotFound(t, store.UpdateNamespace(ctx, &orm.Namespace{Name: "ns"}))
+	requireNotFound(t, store.DeleteNamespace(ctx, "ns"))
+
+	_, err = store.GetPackageRedirect(ctx, pkg)
+	requireNotFound(t, err)
+
+	// Tagging the version of a tag that does not exist must not create it
+	create(t, store, pkg, "h1", "latest")
+	_, err = store.GetArtifactMetaByTag(ctx, pkg, "stable")
+	requireNotFound(t, err)
+}
+
+func testTags(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1", "latest", "v1")
+	create(t, store, pkg, "h2")
+
+	// Tags are unique within a package and move between versions
+	require.NoError(t, store.SetTags(ctx, pkg, "h2", []string{"latest", "v2"}))
+
+	h1, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1"}, tagNames(h1))
+
+	h2, err := store.GetArtifactMetaByTag(ctx, pkg, "latest")
+	require.NoError(t, err)
+	assert.Equal(t, "h2", h2.Hash)
+	assert.ElementsMatch(t, []string{"latest", "v2"}, tagNames(h2))
+
+	// Tags of other packages are independent
+	create(t, store, pkgName("ns", "other"), "h1", "latest")
+	h2, err = store.GetArtifactMetaByTag(ctx, pkg, "latest")
+	require.NoError(t, err)
+	assert.Equal(t, "h2", h2.Hash)
+
+	require.NoError(t, store.SetTags(ctx, pkg, "h1", nil))
+	h1, err = store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Empty(t, h1.Tags)
+
+	require.Error(t, store.SetTags(ctx, pkg, "missing", []string{"v3"}))
+	_, err = store.GetArtifactMetaByTag(ctx, pkg, "v3")
+	requireNotFound(t, err)
+}
+
+func testLabels(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1")
+
+	labels := map[string]string{"team": "a", "tier": "gold"}
+	require.NoError(t, store.SetLabels(ctx, pkg, "h1", labels))
+	artifact, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	require.Len(t, artifact.Labels, 2)
+
+	// Labels are replaced as a whole
+	require.NoError(t, store.SetLabels(ctx, pkg, "h1", map[string]string{
+		"team": "b",
+	}))
+	artifact, err = store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	require.Len(t, artifact.Labels, 1)
+	assert.Equal(t, "b", artifact.Labels[0].Value)
+
+	require.NoError(t, store.SetLabels(ctx, pkg, "h1", nil))
+	artifact, err = store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Empty(t, artifact.Labels)
+
+	require.Error(t, store.SetLabels(ctx, pkg, "missing", labels))
+}
+
+func testOCIManifests(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1")
+	create(t, store, pkg, "h2")
+
+	manifest := &orm.OCIManifest{
+		Namespace:    "ns",
+		Name:         "app",
+		Hash:         "h1",
+		Digest:       "sha256:m1",
+		MediaType:    "application/vnd.oci.image.manifest.v1+json",
+		Content:      []byte(`{"schemaVersion":2}`),
+		ConfigDigest: "sha256:c1",
+		Config:       []byte("{}"),
+	}
+	require.NoError(t, store.SetOCIManifest(ctx, manifest))
+
+	// Versions share config blobs, but each has its own manifest
+	second := *manifest
+	second.Hash, second.Digest = "h2", "sha256:m2"
+	require.NoError(t, store.SetOCIManifest(ctx, &second))
+
+	for _, filter := range []orm.OCIManifestFilter{
+		{Hash: "h1"},
+		{Digest: "sha256:m1"},
+		{ConfigDigest: "sha256:c1"},
+	} {
+		found, err := store.GetOCIManifest(ctx, pkg, filter)
+		require.NoError(t, err)
+		assert.Equal(t, manifest, found)
+	}
+
+	// A later push replaces the manifest of a version
+	manifest.Digest = "sha256:m3"
+	require.NoError(t, store.SetOCIManifest(ctx, manifest))
+	_, err := store.GetOCIManifest(
+		ctx,
+		pkg,
+		orm.OCIManifestFilter{Digest: "sha256:m1"},
+	)
+	requireNotFound(t, err)
+
+	_, err = store.GetOCIManifest(ctx, pkg, orm.OCIManifestFilter{})
+	requireBadInput(t, err)
+	_, err = store.GetOCIManifest(
+		ctx,
+		pkgName("ns", "other"),
+		orm.OCIManifestFilter{Hash: "h1"},
+	)
+	requireNotFound(t, err)
+
+	// Manifests of trashed versions are hidden and purged with them
+	require.NoError(t, store.TrashArtifactMeta(ctx, pkg, "h1", "alice"))
+	found, err := store.GetOCIManifest(
+		ctx,
+		pkg,
+		orm.OCIManifestFilter{ConfigDigest: "sha256:c1"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "h2", found.Hash)
+	require.NoError(t, store.RestoreArtifactMeta(ctx, pkg, "h1"))
+	_, err = store.GetOCIManifest(ctx, pkg, orm.OCIManifestFilter{Hash: "h1"})
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteArtifactMeta(ctx, pkg, "h1"))
+	create(t, store, pkg, "h1")
+	_, err = store.GetOCIManifest(ctx, pkg, orm.OCIManifestFilter{Hash: "h1"})
+	requireNotFound(t, err)
+
+	// Manifests move with their package
+	move := &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+		ToName:        "renamed",
+		State:         orm.MoveCopying,
+	}
+	require.NoError(t, store.CreatePackageMove(ctx, move))
+	require.NoError(t, store.CommitPackageMove(ctx, move, []string{"h1", "h2"}))
+	found, err = store.GetOCIManifest(
+		ctx,
+		pkgName("ns", "renamed"),
+		orm.OCIManifestFilter{Digest: "sha256:m2"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", found.Name)
+
+	second.Hash = "missing"
+	require.Error(t, store.SetOCIManifest(ctx, &second))
+}
+
+func testQueryArtifacts(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	for hash, labels := range map[string]map[string]string{
+		"h1": {"team": "a", "tier": "gold"},
+		"h2": {"team": "b"},
+		"h3": {},
+	} {
+		err := store.CreateArtifactMeta(ctx, pkg, hash, orm.ArtifactDetails{
+			Symbols: []orm.ArtifactSymbol{{
+				Direction:  orm.SymbolImport,
+				Module:     "env",
+				SymbolName: "log_" + hash,
+				Kind:       "func",
+			}},
+			Labels: labels,
+		})
+		require.NoError(t, err)
+	}
+	create(t, store, pkgName("other", "app"), "h4")
+
+	tests := []struct {
+		name   string
+		filter orm.ArtifactFilter
+		want   []string
+	}{
+		{"All", orm.ArtifactFilter{}, []string{"h1", "h2", "h3", "h4"}},
+		{
+			"Namespace",
+			orm.ArtifactFilter{Namespace: "ns"},
+			[]string{"h1", "h2", "h3"},
+		},
+		{
+			"Symbol",
+			orm.ArtifactFilter{Symbols: []orm.SymbolFilter{
+				{Direction: orm.SymbolImport, Name: "log_h2"},
+			}},
+			[]string{"h2"},
+		},
+		{
+			"SymbolModule",
+			orm.ArtifactFilter{Symbols: []orm.SymbolFilter{
+				{Direction: orm.SymbolImport, Module: "wasi", Name: "log_h2"},
+			}},
+			[]string{},
+		},
+		{
+			"SymbolDirection",
+			orm.ArtifactFilter{Symbols: []orm.SymbolFilter{
+				{Direction: orm.SymbolExport, Name: "log_h2"},
+			}},
+			[]string{},
+		},
+		{
+			"LabelEquals",
+			orm.ArtifactFilter{Labels: []orm.LabelSelector{
+				{Key: "team", Operator: orm.LabelEquals, Values: []string{"a"}},
+			}},
+			[]string{"h1"},
+		},
+		{
+			// Negated selectors match versions without the label
+			"LabelNotEquals",
+			orm.ArtifactFilter{Namespace: "ns", Labels: []orm.LabelSelector{
+				{Key: "team", Operator: orm.LabelNotEquals, Values: []string{"a"}},
+			}},
+			[]string{"h2", "h3"},
+		},
+		{
+			"LabelIn",
+			orm.ArtifactFilter{Labels: []orm.LabelSelector{
+				{Key: "team", Operator: orm.LabelIn, Values: []string{"a", "b"}},
+			}},
+			[]string{"h1", "h2"},
+		},
+		{
+			"LabelNotIn",
+			orm.ArtifactFilter{Namespace: "ns", Labels: []orm.LabelSelector{
+				{Key: "team", Operator: orm.LabelNotIn, Values: []string{"a", "b"}},
+			}},
+			[]string{"h3"},
+		},
+		{
+			"LabelExists",
+			orm.ArtifactFilter{Labels: []orm.LabelSelector{
+				{Key: "tier", Operator: orm.LabelExists},
+			}},
+			[]string{"h1"},
+		},
+		{
+			"LabelDoesNotExist",
+			orm.ArtifactFilter{Namespace: "ns", Labels: []orm.LabelSelector{
+				{Key: "tier", Operator: orm.LabelDoesNotExist},
+			}},
+			[]string{"h2", "h3"},
+		},
+		{
+			"Combined",
+			orm.ArtifactFilter{
+				Labels: []orm.LabelSelector{
+					{Key: "team", Operator: orm.LabelExists},
+				},
+				Symbols: []orm.SymbolFilter{
+					{Direction: orm.SymbolImport, Module: "env", Name: "log_h1"},
+				},
+			},
+			[]string{"h1"},
+		},
+	}
+
+	for _, test := range tests {
+		artifacts, err := store.QueryArtifactMetas(ctx, test.filter)
+		require.NoError(t, err, test.name)
+		assert.ElementsMatch(t, test.want, hashes(artifacts), test.name)
+	}
+}
+
+func testArtifactRefs(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	create(t, store, pkgName("ns", "app"), "h1", "latest")
+	create(t, store, pkgName("ns", "lib"), "h2", "stable")
+
+	artifacts, err := store.GetArtifactMetasByRefs(ctx, []orm.ArtifactRef{
+		{Namespace: "ns", Name: "lib", Tag: "stable"},
+		{Namespace: "ns", Name: "app", Hash: "h2"},
+		{Namespace: "ns", Name: "app", Hash: "h1"},
+		{Namespace: "ns", Name: "app", Tag: "stable"},
+	})
+	require.NoError(t, err)
+	require.Len(t, artifacts, 4)
+	require.NotNil(t, artifacts[0])
+	assert.Equal(t, "h2", artifacts[0].Hash)
+	assert.Nil(t, artifacts[1])
+	require.NotNil(t, artifacts[2])
+	assert.Equal(t, "h1", artifacts[2].Hash)
+	assert.Equal(t, []string{"latest"}, tagNames(artifacts[2]))
+	assert.Nil(t, artifacts[3])
+
+	artifacts, err = store.GetArtifactMetasByRefs(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, artifacts)
+}
+
+func testArtifactState(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1")
+
+	err := store.SetArtifactState(ctx, pkg, "h1", orm.ArtifactYanked, "broken")
+	require.NoError(t, err)
+	artifact, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, orm.ArtifactYanked, artifact.State)
+	assert.Equal(t, "broken", artifact.StateReason)
+
+	err = store.SetArtifactState(ctx, pkg, "h1", orm.ArtifactActive, "")
+	require.NoError(t, err)
+	artifact, err = store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, orm.ArtifactActive, artifact.State)
+	assert.Empty(t, artifact.StateReason)
+}
+
+func testArtifactDetails(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	for _, hash := range []string{"h3", "h1", "h2"} {
+		err := store.CreateArtifactMeta(ctx, pkg, hash, orm.ArtifactDetails{})
+		require.NoError(t, err)
+	}
+	create(t, store, pkg, "h4")
+
+	page, err := store.GetArtifactMetasWithoutDetails(ctx, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1", "h2"}, hashes(page))
+
+	page, err = store.GetArtifactMetasWithoutDetails(ctx, &page[1], 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h3"}, hashes(page))
+
+	err = store.SetArtifactSizeAndMediaType(ctx, pkg, "h1", 7, "text/plain")
+	require.NoError(t, err)
+	artifact, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), artifact.SizeBytes)
+	assert.Equal(t, "text/plain", artifact.MediaType)
+
+	page, err = store.GetArtifactMetasWithoutDetails(ctx, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h2", "h3"}, hashes(page))
+}
+
+func testDeleteArtifact(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1", "latest")
+	create(t, store, pkg, "h2")
+
+	require.NoError(t, store.DeleteArtifactMeta(ctx, pkg, "h1"))
+	_, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	requireNotFound(t, err)
+	_, err = store.GetArtifactMetaByTag(ctx, pkg, "latest")
+	requireNotFound(t, err)
+
+	// Deleting is idempotent
+	require.NoError(t, store.DeleteArtifactMeta(ctx, pkg, "h1"))
+
+	// The version can be uploaded again
+	create(t, store, pkg, "h1")
+
+	// Trashed versions are deleted for good as well
+	require.NoError(t, store.TrashArtifactMeta(ctx, pkg, "h2", "alice"))
+	require.NoError(t, store.DeleteArtifactMeta(ctx, pkg, "h2"))
+	_, err = store.GetDeletedArtifactMeta(ctx, pkg, "h2")
+	requireNotFound(t, err)
+}
+
+func testTrash(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1", "latest", "v1")
+	create(t, store, pkg, "h2")
+
+	require.NoError(t, store.TrashArtifactMeta(ctx, pkg, "h1", "alice"))
+
+	_, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	requireNotFound(t, err)
+	versions, err := store.GetArtifactMetasByFQN(ctx, pkg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h2"}, hashes(versions))
+	versionHashes, err := store.GetArtifactHashes(ctx, pkg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1", "h2"}, versionHashes)
+	requireNotFound(t, store.TrashArtifactMeta(ctx, pkg, "h1", "alice"))
+
+	trashed, err := store.GetDeletedArtifactMeta(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", trashed.DeletedBy)
+	assert.True(t, trashed.DeletedAt.Valid)
+	assert.Empty(t, trashed.Tags)
+	deletedTags := make([]string, 0, len(trashed.DeletedTags))
+	for _, tag := range trashed.DeletedTags {
+		deletedTags = append(deletedTags, tag.TagName)
+	}
+	assert.ElementsMatch(t, []string{"latest", "v1"}, deletedTags)
+
+	all, err := store.GetDeletedArtifactMetas(ctx, orm.TrashFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1"}, hashes(all))
+	old, err := store.GetDeletedArtifactMetas(ctx, orm.TrashFilter{
+		DeletedBefore: trashed.DeletedAt.Time.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, old)
+	other, err := store.GetDeletedArtifactMetas(ctx, orm.TrashFilter{
+		Namespace: "other",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, other)
+
+	// The released tags can be reused, restoring leaves them there
+	require.NoError(t, store.SetTags(ctx, pkg, "h2", []string{"latest"}))
+	require.NoError(t, store.RestoreArtifactMeta(ctx, pkg, "h1"))
+	requireNotFound(t, store.RestoreArtifactMeta(ctx, pkg, "h1"))
+
+	restored, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1"}, tagNames(restored))
+	latest, err := store.GetArtifactMetaByTag(ctx, pkg, "latest")
+	require.NoError(t, err)
+	assert.Equal(t, "h2", latest.Hash)
+	_, err = store.GetDeletedArtifactMeta(ctx, pkg, "h1")
+	requireNotFound(t, err)
+
+	// Only trashed versions can be purged
+	requireNotFound(t, store.PurgeArtifactMeta(ctx, pkg, "h1"))
+	require.NoError(t, store.TrashArtifactMeta(ctx, pkg, "h1", "bob"))
+	require.NoError(t, store.PurgeArtifactMeta(ctx, pkg, "h1"))
+	versionHashes, err = store.GetArtifactHashes(ctx, pkg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h2"}, versionHashes)
+}
+
+func testPackages(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	app := pkgName("ns", "app")
+
+	err := store.CreatePackage(ctx, &orm.Package{
+		Namespace:   "ns",
+		Name:        "app",
+		Description: "An app",
+		Owners:      []orm.PackageOwner{{Owner: "alice"}, {Owner: "bob"}},
+	})
+	require.NoError(t, err)
+	requireConflict(t, store.CreatePackage(ctx, &orm.Package{
+		Namespace: "ns",
+		Name:      "app",
+	}))
+
+	pkg, err := store.GetPackage(ctx, app)
+	require.NoError(t, err)
+	assert.Equal(t, "An app", pkg.Description)
+	assert.Equal(t, orm.VisibilityPublic, pkg.Visibility)
+	owners := make([]string, 0, len(pkg.Owners))
+	for _, owner := range pkg.Owners {
+		owners = append(owners, owner.Owner)
+	}
+	assert.ElementsMatch(t, []string{"alice", "bob"}, owners)
+
+	// Existing packages keep their owners
+	require.NoError(t, store.EnsurePackage(ctx, app, "carol"))
+	pkg, err = store.GetPackage(ctx, app)
+	require.NoError(t, err)
+	assert.Len(t, pkg.Owners, 2)
+
+	require.NoError(t, store.EnsurePackage(ctx, pkgName("ns", "lib"), "carol"))
+	lib, err := store.GetPackage(ctx, pkgName("ns", "lib"))
+	require.NoError(t, err)
+	assert.Equal(t, orm.VisibilityPublic, lib.Visibility)
+	require.Len(t, lib.Owners, 1)
+	assert.Equal(t, "carol", lib.Owners[0].Owner)
+
+	require.NoError(t, store.EnsurePackage(ctx, pkgName("other", "app"), ""))
+	other, err := store.GetPackage(ctx, pkgName("other", "app"))
+	require.NoError(t, err)
+	assert.Empty(t, other.Owners)
+
+	err = store.UpdatePackage(ctx, &orm.Package{
+		Namespace:          "ns",
+		Name:               "app",
+		Description:        "Still an app",
+		Visibility:         orm.VisibilityPrivate,
+		Deprecated:         true,
+		DeprecationMessage: "Use lib",
+		Owners:             []orm.PackageOwner{{Owner: "dave"}},
+	})
+	require.NoError(t, err)
+	pkg, err = store.GetPackage(ctx, app)
+	require.NoError(t, err)
+	assert.Equal(t, "Still an app", pkg.Description)
+	assert.Equal(t, orm.VisibilityPrivate, pkg.Visibility)
+	assert.True(t, pkg.Deprecated)
+	assert.Equal(t, "Use lib", pkg.DeprecationMessage)
+	require.Len(t, pkg.Owners, 1)
+	assert.Equal(t, "dave", pkg.Owners[0].Owner)
+
+	packages, err := store.ListPackages(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, packages, 3)
+	assert.Equal(t, "ns", packages[0].Namespace)
+	assert.Equal(t, "app", packages[0].Name)
+	assert.Equal(t, "lib", packages[1].Name)
+	assert.Equal(t, "other", packages[2].Namespace)
+
+	packages, err = store.ListPackages(ctx, "other")
+	require.NoError(t, err)
+	assert.Len(t, packages, 1)
+
+	packages, err = store.GetPackages(ctx, []*proto_gen.PackageName{
+		app,
+		pkgName("ns", "missing"),
+		pkgName("other", "app"),
+	})
+	require.NoError(t, err)
+	assert.Len(t, packages, 2)
+
+	packages, err = store.GetPackages(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, packages)
+
+	require.NoError(t, store.DeletePackage(ctx, app))
+	_, err = store.GetPackage(ctx, app)
+	requireNotFound(t, err)
+}
+
+func testNamespaces(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+
+	err := store.CreateNamespace(ctx, &orm.Namespace{
+		Name:        "ns",
+		Description: "Mine",
+		QuotaBytes:  100,
+	})
+	require.NoError(t, err)
+	requireConflict(t, store.CreateNamespace(ctx, &orm.Namespace{Name: "ns"}))
+
+	// Ensuring an existing namespace returns it unchanged
+	ns, err := store.EnsureNamespace(ctx, &orm.Namespace{
+		Name:       "ns",
+		QuotaBytes: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Mine", ns.Description)
+	assert.Equal(t, int64(100), ns.QuotaBytes)
+
+	ns, err = store.EnsureNamespace(ctx, &orm.Namespace{
+		Name:          "a",
+		QuotaVersions: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), ns.QuotaVersions)
+
+	require.NoError(t, store.UpdateNamespace(ctx, &orm.Namespace{
+		Name:          "ns",
+		Description:   "Ours",
+		QuotaVersions: 10,
+	}))
+	ns, err = store.GetNamespace(ctx, "ns")
+	require.NoError(t, err)
+	assert.Equal(t, "Ours", ns.Description)
+	assert.Equal(t, int64(0), ns.QuotaBytes)
+	assert.Equal(t, int64(10), ns.QuotaVersions)
+
+	namespaces, err := store.ListNamespaces(ctx)
+	require.NoError(t, err)
+	require.Len(t, namespaces, 2)
+	assert.Equal(t, "a", namespaces[0].Name)
+	assert.Equal(t, "ns", namespaces[1].Name)
+
+	app := pkgName("ns", "app")
+	create(t, store, app, "h1")
+	create(t, store, app, "h2")
+	create(t, store, app, "h3")
+	create(t, store, pkgName("a", "app"), "h1")
+	require.NoError(t, store.TrashArtifactMeta(ctx, app, "h3", "alice"))
+	require.NoError(t, store.EnsurePackage(ctx, app, "alice"))
+
+	// Trashed versions are reported on their own
+	usages, err := store.GetNamespaceUsages(ctx, "ns", "empty")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, orm.NamespaceUsage{
+		Namespace:    "ns",
+		Bytes:        20,
+		Versions:     2,
+		TrashedBytes: 10,
+	}, usages[0])
+
+	usages, err = store.GetNamespaceUsages(ctx)
+	require.NoError(t, err)
+	assert.Len(t, usages, 2)
+
+	require.NoError(t, store.DeleteNamespace(ctx, "ns"))
+	_, err = store.GetNamespace(ctx, "ns")
+	requireNotFound(t, err)
+	versionHashes, err := store.GetArtifactHashes(ctx, app)
+	require.NoError(t, err)
+	assert.Empty(t, versionHashes)
+	_, err = store.GetPackage(ctx, app)
+	requireNotFound(t, err)
+
+	_, err = store.GetArtifactMetaByHash(ctx, pkgName("a", "app"), "h1")
+	require.NoError(t, err)
+
+	// Namespaces without a record are deleted by their contents
+	require.NoError(t, store.DeleteNamespace(ctx, "a"))
+	versionHashes, err = store.GetArtifactHashes(ctx, pkgName("a", "app"))
+	require.NoError(t, err)
+	assert.Empty(t, versionHashes)
+	requireNotFound(t, store.DeleteNamespace(ctx, "a"))
+}
+
+func testPackageMoves(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	from := pkgName("ns", "app")
+	to := pkgName("ns", "renamed")
+	create(t, store, from, "h1", "latest")
+	create(t, store, from, "h2", "v2")
+	require.NoError(t, store.TrashArtifactMeta(ctx, from, "h2", "alice"))
+	require.NoError(t, store.SetLabels(ctx, from, "h1", map[string]string{
+		"team": "a",
+	}))
+	require.NoError(t, store.EnsurePackage(ctx, from, "alice"))
+
+	move := &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+		ToName:        "renamed",
+		RedirectTTL:   time.Hour,
+		State:         orm.MoveCopying,
+	}
+	require.NoError(t, store.CreatePackageMove(ctx, move))
+	assert.NotZero(t, move.ID)
+	requireConflict(t, store.CreatePackageMove(ctx, &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+		ToName:        "elsewhere",
+		State:         orm.MoveCopying,
+	}))
+
+	moves, err := store.GetPackageMoves(ctx)
+	require.NoError(t, err)
+	require.Len(t, moves, 1)
+	assert.Equal(t, move.ID, moves[0].ID)
+	assert.Equal(t, orm.MoveCopying, moves[0].State)
+
+	// Versions added since the blobs were copied make the commit fail
+	versions := []string{"h1", "h2"}
+	create(t, store, from, "h0")
+	requireConflict(t, store.CommitPackageMove(ctx, move, versions))
+	versionHashes, err := store.GetArtifactHashes(ctx, to)
+	require.NoError(t, err)
+	assert.Empty(t, versionHashes)
+	require.NoError(t, store.DeleteArtifactMeta(ctx, from, "h0"))
+
+	require.NoError(t, store.CommitPackageMove(ctx, move, versions))
+	assert.Equal(t, orm.MoveCleanup, move.State)
+	moves, err = store.GetPackageMoves(ctx)
+	require.NoError(t, err)
+	require.Len(t, moves, 1)
+	assert.Equal(t, orm.MoveCleanup, moves[0].State)
+
+	versionHashes, err = store.GetArtifactHashes(ctx, from)
+	require.NoError(t, err)
+	assert.Empty(t, versionHashes)
+	versionHashes, err = store.GetArtifactHashes(ctx, to)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1", "h2"}, versionHashes)
+
+	moved, err := store.GetArtifactMetaByTag(ctx, to, "latest")
+	require.NoError(t, err)
+	assert.Equal(t, "h1", moved.Hash)
+	require.Len(t, moved.Labels, 1)
+	assert.Equal(t, "renamed", moved.Labels[0].Name)
+
+	require.NoError(t, store.RestoreArtifactMeta(ctx, to, "h2"))
+	restored, err := store.GetArtifactMetaByTag(ctx, to, "v2")
+	require.NoError(t, err)
+	assert.Equal(t, "h2", restored.Hash)
+
+	pkg, err := store.GetPackage(ctx, to)
+	require.NoError(t, err)
+	require.Len(t, pkg.Owners, 1)
+	assert.Equal(t, "alice", pkg.Owners[0].Owner)
+	_, err = store.GetPackage(ctx, from)
+	requireNotFound(t, err)
+
+	redirect, err := store.GetPackageRedirect(ctx, from)
+	require.NoError(t, err)
+	assert.Equal(t, "ns", redirect.TargetNamespace)
+	assert.Equal(t, "renamed", redirect.TargetName)
+
+	require.NoError(t, store.DeletePackageMove(ctx, move))
+	moves, err = store.GetPackageMoves(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, moves)
+
+	// Moving back onto the old name replaces its redirect, moving onto an
+	// existing package fails
+	back := &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "renamed",
+		ToNamespace:   "ns",
+		ToName:        "app",
+		State:         orm.MoveCopying,
+	}
+	require.NoError(t, store.CreatePackageMove(ctx, back))
+	require.NoError(t, store.CommitPackageMove(ctx, back, versions))
+	_, err = store.GetPackageRedirect(ctx, from)
+	requireNotFound(t, err)
+	_, err = store.GetPackageRedirect(ctx, to)
+	requireNotFound(t, err)
+	require.NoError(t, store.DeletePackageMove(ctx, back))
+
+	create(t, store, to, "h3")
+	onto := &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+		ToName:        "renamed",
+		State:         orm.MoveCopying,
+	}
+	require.NoError(t, store.CreatePackageMove(ctx, onto))
+	requireConflict(t, store.CommitPackageMove(ctx, onto, versions))
+	versionHashes, err = store.GetArtifactHashes(ctx, from)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1", "h2"}, versionHashes)
+}
+
+func testReplication(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+
+	// Changes are only recorded with the outbox
+	create(t, store, pkg, "h0")
+	count, oldest, err := store.CountReplicationEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.True(t, oldest.IsZero())
+
+	outbox := store.WithReplicationOutbox()
+	create(t, outbox, pkg, "h1", "latest")
+	require.NoError(t, outbox.SetTags(ctx, pkg, "h1", []string{"stable"}))
+	require.NoError(t, outbox.TrashArtifactMeta(ctx, pkg, "h0", "alice"))
+	require.NoError(t, outbox.DeleteArtifactMeta(ctx, pkg, "h1"))
+
+	// The outbox is shared with the store it was created from
+	events, err := store.GetReplicationEvents(
+		ctx,
+		0,
+		time.Now().Add(time.Hour),
+		10,
+	)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	kinds := make([]string, 0, len(events))
+	for i := range events {
+		kinds = append(kinds, events[i].Kind)
+	}
+	assert.Equal(t, []string{
+		orm.ReplicateUpload,
+		orm.ReplicateTags,
+		orm.ReplicateDelete,
+		orm.ReplicateDelete,
+	}, kinds)
+	assert.Equal(t, "h0", events[2].Hash)
+	assert.Less(t, events[0].ID, events[1].ID)
+
+	page, err := store.GetReplicationEvents(
+		ctx,
+		events[0].ID,
+		time.Now().Add(time.Hour),
+		2,
+	)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, events[1].ID, page[0].ID)
+
+	page, err = store.GetReplicationEvents(
+		ctx,
+		0,
+		events[0].CreatedAt.Add(-time.Minute),
+		10,
+	)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	count, oldest, err = store.CountReplicationEvents(ctx, events[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.WithinDuration(t, events[2].CreatedAt, oldest, 0)
+
+	cursor, err := store.GetReplicationCursor(ctx, "peer-a")
+	require.NoError(t, err)
+	assert.Equal(t, "peer-a", cursor.Peer)
+	assert.Zero(t, cursor.LastEventID)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.SaveReplicationCursor(ctx, &orm.ReplicationCursor{
+		Peer:             "peer-a",
+		LastEventID:      events[2].ID,
+		LastReplicatedAt: &now,
+	}))
+	cursor, err = store.GetReplicationCursor(ctx, "peer-a")
+	require.NoError(t, err)
+	assert.Equal(t, events[2].ID, cursor.LastEventID)
+	require.NotNil(t, cursor.LastReplicatedAt)
+	assert.WithinDuration(t, now, *cursor.LastReplicatedAt, 0)
+
+	// Events are kept until every peer has them
+	peers := []string{"peer-a", "peer-b"}
+	require.NoError(t, store.PruneReplicationEvents(ctx, peers))
+	count, _, err = store.CountReplicationEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	require.NoError(t, store.SaveReplicationCursor(ctx, &orm.ReplicationCursor{
+		Peer:        "peer-b",
+		LastEventID: events[1].ID,
+		Failures:    2,
+		LastError:   "unavailable",
+	}))
+	require.NoError(t, store.PruneReplicationEvents(ctx, peers))
+	events, err = store.GetReplicationEvents(
+		ctx,
+		0,
+		time.Now().Add(time.Hour),
+		10,
+	)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "h0", events[0].Hash)
+}
+
+func testReplicatedChanges(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	app := pkgName("ns", "app")
+	renamed := pkgName("ns", "renamed")
+	store = store.WithReplicationOutbox()
+
+	type change struct{ kind, name, hash string }
+	changesSince := func(afterID uint) ([]change, uint) {
+		t.Helper()
+
+		events, err := store.GetReplicationEvents(
+			ctx,
+			afterID,
+			time.Now().Add(time.Hour),
+			100,
+		)
+		require.NoError(t, err)
+		changes := make([]change, 0, len(events))
+		for _, e := range events {
+			changes = append(changes, change{e.Kind, e.Name, e.Hash})
+			afterID = e.ID
+		}
+
+		return changes, afterID
+	}
+
+	create(t, store, app, "h1")
+	create(t, store, app, "h2")
+	require.NoError(t, store.EnsurePackage(ctx, app, "alice"))
+	require.NoError(t, store.TrashArtifactMeta(ctx, app, "h2", "alice"))
+	_, last := changesSince(0)
+
+	// Restored versions are uploaded again
+	require.NoError(t, store.RestoreArtifactMeta(ctx, app, "h2"))
+	changes, last := changesSince(last)
+	assert.Equal(t, []change{{orm.ReplicateUpload, "app", "h2"}}, changes)
+
+	// Moved versions are deleted under the old name and uploaded under the
+	// new one, trashed versions stay in the trash
+	require.NoError(t, store.TrashArtifactMeta(ctx, app, "h2", "alice"))
+	_, last = changesSince(last)
+	move := &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+		ToName:        "renamed",
+		State:         orm.MoveCopying,
+	}
+	require.NoError(t, store.CreatePackageMove(ctx, move))
+	require.NoError(t, store.CommitPackageMove(ctx, move, []string{"h1", "h2"}))
+	changes, last = changesSince(last)
+	assert.Equal(t, []change{
+		{orm.ReplicateDelete, "app", "h1"},
+		{orm.ReplicateUpload, "renamed", "h1"},
+	}, changes)
+
+	require.NoError(t, store.DeletePackage(ctx, renamed))
+	changes, last = changesSince(last)
+	assert.Equal(
+		t,
+		[]change{{orm.ReplicateDeletePackage, "renamed", ""}},
+		changes,
+	)
+
+	// Namespaces are deleted version by version, then package by package
+	lib := pkgName("ns", "lib")
+	create(t, store, lib, "h3")
+	create(t, store, lib, "h4")
+	require.NoError(t, store.TrashArtifactMeta(ctx, lib, "h4", "alice"))
+	require.NoError(t, store.EnsurePackage(ctx, lib, "alice"))
+	_, err := store.EnsureNamespace(ctx, &orm.Namespace{Name: "ns"})
+	require.NoError(t, err)
+	_, last = changesSince(last)
+	require.NoError(t, store.DeleteNamespace(ctx, "ns"))
+	changes, _ = changesSince(last)
+	assert.Equal(t, []change{
+		{orm.ReplicateDelete, "lib", "h3"},
+		{orm.ReplicateDelete, "renamed", "h1"},
+		{orm.ReplicateDeletePackage, "lib", ""},
+	}, changes)
+}
+
+func testPulls(t *testing.T, store orm.MetadataStore) {
+	ctx := t.Context()
+	pkg := pkgName("ns", "app")
+	create(t, store, pkg, "h1")
+	create(t, store, pkg, "h2")
+
+	day := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	pull := func(hash, tag, client string, at time.Duration) orm.Pull {
+		return orm.Pull{
+			Namespace: "ns",
+			Name:      "app",
+			Hash:      hash,
+			Tag:       tag,
+			Client:    client,
+			Time:      day.Add(at),
+			Count:     1,
+		}
+	}
+
+	require.NoError(t, store.AddPulls(ctx, []orm.Pull{
+		pull("h1", "latest", "a", time.Hour),
+		pull("h1", "latest", "a", time.Hour+time.Minute),
+		pull("h1", "", "b", 2*time.Hour),
+		pull("h2", "latest", "a", 3*time.Hour),
+		// Pulls of missing versions are dropped
+		pull("h3", "latest", "a", time.Hour),
+	}))
+	require.NoError(t, store.AddPulls(ctx, []orm.Pull{
+		pull("h1", "stable", "c", 25*time.Hour),
+	}))
+
+	artifact, err := store.GetArtifactMetaByHash(ctx, pkg, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), artifact.PullsCount)
+	require.NotNil(t, artifact.LastPulledAt)
+	assert.WithinDuration(t, day.Add(25*time.Hour), *artifact.LastPulledAt, 0)
+
+	stats, err := store.GetPullStats(ctx, orm.PullStatsFilter{
+		Granularity: orm.PullsDaily,
+	})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.WithinDuration(t, day, stats[0].Start, 0)
+	assert.Equal(t, int64(4), stats[0].Pulls)
+	assert.Empty(t, stats[0].Series)
+	assert.Equal(t, int64(1), stats[1].Pulls)
+
+	stats, err = store.GetPullStats(ctx, orm.PullStatsFilter{
+		Hash:        "h1",
+		Granularity: orm.PullsHourly,
+		Since:       day,
+		Until:       day.Add(24 * time.Hour),
+		GroupBy:     orm.PullsByClient,
+	})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "a", stats[0].Series)
+	assert.Equal(t, int64(2), stats[0].Pulls)
+	assert.WithinDuration(t, day.Add(time.Hour), stats[0].Start, 0)
+	assert.Equal(t, "b", stats[1].Series)
+	assert.Equal(t, int64(1), stats[1].Pulls)
+
+	stats, err = store.GetPullStats(ctx, orm.PullStatsFilter{
+		Granularity: orm.PullsDaily,
+		GroupBy:     orm.PullsByTag,
+	})
+	require.NoError(t, err)
+	series := make(map[string]int64)
+	for _, stat := range stats {
+		series[stat.Series] += stat.Pulls
+	}
+	assert.Equal(t, map[string]int64{"": 1, "latest": 3, "stable": 1}, series)
+
+	pruned, err := store.PrunePullBuckets(
+		ctx,
+		orm.PullsHourly,
+		day.Add(3*time.Hour),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+
+	stats, err = store.GetPullStats(ctx, orm.PullStatsFilter{
+		Granularity: orm.PullsHourly,
+	})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.WithinDuration(t, day.Add(3*time.Hour), stats[0].Start, 0)
+}
+
+func testAudit(t *testing.T, store orm.MetadataStore) {
+	ctx := orm.WithAuditActor(t.Context(), orm.AuditActor{
+		User:          "alice",
+		ClientAddress: "192.0.2.1",
+	})
+	pkg := pkgName("ns", "app")
+
+	create(t, store, pkg, "h1", "latest")
+	require.NoError(t, store.SetTags(ctx, pkg, "h1", []string{"stable"}))
+	require.NoError(t, store.TrashArtifactMeta(ctx, pkg, "h1", "alice"))
+	requireNotFound(t, store.TrashArtifactMeta(ctx, pkg, "h1", "alice"))
+	require.NoError(t, store.RestoreArtifactMeta(ctx, pkg, "h1"))
+
+	events, err := store.GetAuditEvents(ctx, orm.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+
+	actions := make([]string, 0, len(events))
+	for i := range events {
+		actions = append(actions, events[i].Action)
+	}
+	assert.Equal(t, []string{
+		orm.AuditRestore,
+		orm.AuditTrash,
+		orm.AuditTrash,
+		orm.AuditSetTags,
+		orm.AuditUpload,
+	}, actions)
+
+	upload := events[4]
+	assert.Equal(t, orm.SystemActor, upload.Actor)
+	assert.True(t, upload.Succeeded)
+	assert.Equal(t, []string{"latest"}, upload.TagsAfter)
+
+	setTags := events[3]
+	assert.Equal(t, "alice", setTags.Actor)
+	assert.Equal(t, "192.0.2.1", setTags.ClientAddress)
+	assert.Equal(t, []string{"latest"}, setTags.TagsBefore)
+	assert.Equal(t, []string{"stable"}, setTags.TagsAfter)
+
+	assert.True(t, events[2].Succeeded)
+	assert.Equal(t, []string{"stable"}, events[2].TagsBefore)
+	assert.False(t, events[1].Succeeded)
+	assert.NotEmpty(t, events[1].Error)
+	assert.Equal(t, []string{"stable"}, events[0].TagsAfter)
+
+	filtered, err := store.GetAuditEvents(ctx, orm.AuditFilter{
+		Actor:  "alice",
+		Action: orm.AuditTrash,
+		Limit:  1,
+	})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, events[1].ID, filtered[0].ID)
+
+	filtered, err = store.GetAuditEvents(ctx, orm.AuditFilter{
+		BeforeID: events[1].ID,
+		Until:    time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Len(t, filtered, 3)
+
+	filtered, err = store.GetAuditEvents(ctx, orm.AuditFilter{
+		Since: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+
+	filtered, err = store.GetAuditEvents(ctx, orm.AuditFilter{Hash: "h2"})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+
+}
+
+// testAuditedChanges checks that changes other than those to versions and
+// tags, and changes rejected before they reach the store are audited
+func testAuditedChanges(t *testing.T, store orm.MetadataStore) {
+	ctx := orm.WithAuditActor(t.Context(), orm.AuditActor{User: "bob"})
+	pkg := pkgName("ns", "app")
+	moved := pkgName("ns", "renamed")
+
+	create(t, store, pkg, "h1")
+	require.NoError(t, store.SetLabels(ctx, pkg, "h1", map[string]string{
+		"team": "a",
+		"env":  "prod",
+	}))
+	require.NoError(t, store.SetArtifactState(
+		ctx,
+		pkg,
+		"h1",
+		orm.ArtifactDeprecated,
+		"use h2",
+	))
+	require.NoError(t, store.EnsurePackage(ctx, pkg, "bob"))
+
+	move := &orm.PackageMove{
+		FromNamespace: "ns",
+		FromName:      "app",
+		ToNamespace:   "ns",
+		ToName:        "renamed",
+		State:         orm.MoveCopying,
+	}
+	require.NoError(t, store.CreatePackageMove(ctx, move))
+	requireConflict(t, store.CommitPackageMove(ctx, move, nil))
+	require.NoError(t, store.CommitPackageMove(ctx, move, []string{"h1"}))
+	require.NoError(t, store.DeletePackage(ctx, moved))
+
+	rejection := errors.New("namespace quota exceeded")
+	require.NoError(t, store.RecordRejection(ctx, &orm.AuditEvent{
+		Action:    orm.AuditUpload,
+		Namespace: "ns",
+		Name:      "renamed",
+		TagsAfter: []string{"latest"},
+	}, rejection))
+
+	events, err := store.GetAuditEvents(ctx, orm.AuditFilter{Actor: "bob"})
+	require.NoError(t, err)
+	require.Len(t, events, 6)
+
+	rejected := events[0]
+	assert.Equal(t, orm.AuditUpload, rejected.Action)
+	assert.False(t, rejected.Succeeded)
+	assert.Equal(t, rejection.Error(), rejected.Error)
+	assert.Equal(t, []string{"latest"}, rejected.TagsAfter)
+
+	assert.Equal(t, orm.AuditDeletePackage, events[1].Action)
+	assert.Equal(t, "renamed", events[1].Name)
+	assert.True(t, events[1].Succeeded)
+
+	for i, event := range events[2:4] {
+		assert.Equal(t, orm.AuditMovePackage, event.Action)
+		assert.Equal(t, "app", event.Name)
+		assert.Equal(t, "to ns/renamed", event.Detail)
+		// The attempt with outdated versions failed
+		assert.Equal(t, i == 0, event.Succeeded)
+	}
+
+	assert.Equal(t, orm.AuditSetState, events[4].Action)
+	assert.Equal(t, "deprecated: use h2", events[4].Detail)
+	assert.Equal(t, "h1", events[4].Hash)
+
+	assert.Equal(t, orm.AuditSetLabels, events[5].Action)
+	assert.Equal(t, "env=prod, team=a", events[5].Detail)
+
+	// Namespaces are deleted with everything in them
+	create(t, store, pkg, "h2")
+	require.NoError(t, store.TrashArtifactMeta(ctx, pkg, "h2", "bob"))
+	require.NoError(t, store.EnsurePackage(ctx, pkg, "bob"))
+	require.NoError(t, store.DeleteNamespace(ctx, "ns"))
+	requireNotFound(t, store.DeleteNamespace(ctx, "ns"))
+	events, err = store.GetAuditEvents(ctx, orm.AuditFilter{
+		Action: orm.AuditDeleteNamespace,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.False(t, events[0].Succeeded)
+	assert.True(t, events[1].Succeeded)
+	assert.Equal(t, "bob", events[1].Actor)
+	assert.Equal(t, "ns", events[1].Namespace)
+	assert.Equal(t, "versions=2, packages=1", events[1].Detail)
+}
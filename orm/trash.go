@@ -0,0 +1,342 @@
+package orm
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrashFilter restricts the trashed artifacts that are returned. Empty fields
+// match everything.
+type TrashFilter struct {
+	Namespace string
+	Name      string
+	// Only artifacts trashed before this point in time
+	DeletedBefore time.Time
+}
+
+// unscoped includes trashed artifacts in a query. gorm.G starts a new session,
+// which drops Unscoped from the DB it was given.
+func unscoped(stmt *gorm.Statement) {
+	stmt.Unscoped = true
+}
+
+func validateArtifactKey(pkg *proto_gen.PackageName, versionHash string) error {
+	if pkg == nil {
+		return &BadInputError{
+			Reason: "artifact with nil PackageName",
+		}
+	}
+
+	if versionHash == "" || pkg.Namespace == "" || pkg.Name == "" {
+		return &BadInputError{
+			Reason: fmt.Sprintf(
+				"All parameters must be provided: namespace=%q, name=%q, hash=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+			),
+		}
+	}
+
+	return nil
+}
+
+// TrashArtifactMeta moves an artifact into the trash. Its tags are released,
+// so they can be reused, and remembered for a later restore.
+func (db *DB) TrashArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	deletedBy string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.TrashArtifactMeta")
+	defer span.End()
+
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, hash=%q",
+		pkg.Namespace,
+		pkg.Name,
+		versionHash,
+	)
+	key := &Artifact{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+	}
+
+	event := newAuditEvent(AuditTrash, pkg, versionHash)
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		tags, err := gorm.G[Tag](tx).Where(&Tag{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+		}).Find(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "get tags to trash", detailString)
+		}
+
+		result := tx.WithContext(ctx).
+			Model(key).
+			Update("deleted_by", deletedBy)
+		if result.Error != nil {
+			return wrapErrorWithDetails(
+				result.Error,
+				"record artifact deleter",
+				detailString,
+			)
+		}
+		if result.RowsAffected == 0 {
+			return &NotFoundError{Search: "trash artifact (" + detailString + ")"}
+		}
+
+		if err := tx.WithContext(ctx).Delete(key).Error; err != nil {
+			return wrapErrorWithDetails(err, "trash artifact", detailString)
+		}
+		for _, t := range tags {
+			event.TagsBefore = append(event.TagsBefore, t.TagName)
+		}
+
+		err = db.recordChange(ctx, tx, ReplicateDelete, pkg, versionHash)
+		if err != nil {
+			return err
+		}
+
+		if len(tags) == 0 {
+			return nil
+		}
+
+		_, err = gorm.G[Tag](tx).Where(&Tag{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+		}).Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "release tags", detailString)
+		}
+
+		deletedTags := make([]DeletedTag, 0, len(tags))
+		for _, t := range tags {
+			deletedTags = append(deletedTags, DeletedTag{
+				Namespace: t.Namespace,
+				Name:      t.Name,
+				Hash:      t.Hash,
+				TagName:   t.TagName,
+			})
+		}
+
+		//nolint:mnd // 100 is a reasonable batch size for tags
+		return wrapErrorWithDetails(
+			gorm.G[DeletedTag](tx).CreateInBatches(ctx, &deletedTags, 100),
+			"remember trashed tags",
+			detailString,
+		)
+	})
+}
+
+// GetDeletedArtifactMetas returns the trashed artifacts matching the filter,
+// oldest deletion first
+func (db *DB) GetDeletedArtifactMetas(
+	ctx context.Context,
+	filter TrashFilter,
+) ([]Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetDeletedArtifactMetas")
+	defer span.End()
+
+	query := withArtifactAssociations(
+		gorm.G[Artifact](db.dbGorm).
+			Scopes(unscoped).
+			Where("deleted_at IS NOT NULL"),
+	).
+		Preload("DeletedTags", nil).
+		Where(&Artifact{Namespace: filter.Namespace, Name: filter.Name})
+	if !filter.DeletedBefore.IsZero() {
+		query = query.Where("deleted_at < ?", filter.DeletedBefore)
+	}
+
+	artifacts, err := query.Order("deleted_at").Find(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get deleted artifacts",
+			fmt.Sprintf("%+v", filter),
+		)
+	}
+
+	return artifacts, nil
+}
+
+func (db *DB) GetDeletedArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) (*Artifact, error) {
+	ctx, span := tracing.Start(ctx, "orm.GetDeletedArtifactMeta")
+	defer span.End()
+
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return nil, err
+	}
+
+	artifact, err := withArtifactAssociations(
+		gorm.G[Artifact](db.dbGorm).
+			Scopes(unscoped).
+			Where("deleted_at IS NOT NULL"),
+	).
+		Preload("DeletedTags", nil).
+		Where(&Artifact{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+		}).
+		First(ctx)
+	if err != nil {
+		return nil, wrapErrorWithDetails(
+			err,
+			"get deleted artifact",
+			fmt.Sprintf(
+				"namespace=%q, name=%q, hash=%q",
+				pkg.Namespace,
+				pkg.Name,
+				versionHash,
+			),
+		)
+	}
+
+	return &artifact, nil
+}
+
+// RestoreArtifactMeta takes an artifact out of the trash. Tags that were
+// assigned to another version in the meantime stay there.
+func (db *DB) RestoreArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.RestoreArtifactMeta")
+	defer span.End()
+
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, hash=%q",
+		pkg.Namespace,
+		pkg.Name,
+		versionHash,
+	)
+
+	event := newAuditEvent(AuditRestore, pkg, versionHash)
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		result := tx.WithContext(ctx).
+			Unscoped().
+			Model(&Artifact{
+				Namespace: pkg.Namespace,
+				Name:      pkg.Name,
+				Hash:      versionHash,
+			}).
+			Where("deleted_at IS NOT NULL").
+			Updates(map[string]any{"deleted_at": nil, "deleted_by": ""})
+		if result.Error != nil {
+			return wrapErrorWithDetails(
+				result.Error,
+				"restore artifact",
+				detailString,
+			)
+		}
+		if result.RowsAffected == 0 {
+			return &NotFoundError{Search: "restore artifact (" + detailString + ")"}
+		}
+
+		deletedTag := &DeletedTag{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+			Hash:      versionHash,
+		}
+		deletedTags, err := gorm.G[DeletedTag](tx).Where(deletedTag).Find(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(
+				err,
+				"get trashed tags",
+				detailString,
+			)
+		}
+
+		for _, t := range deletedTags {
+			tag := &Tag{Namespace: t.Namespace, Name: t.Name, TagName: t.TagName}
+			taken, err := gorm.G[Tag](tx).Where(tag).Count(ctx, "*")
+			if err != nil {
+				return wrapErrorWithDetails(err, "check tag", detailString)
+			}
+			if taken > 0 {
+				continue
+			}
+
+			tag.Hash = t.Hash
+			if err := gorm.G[Tag](tx).Create(ctx, tag); err != nil {
+				return wrapErrorWithDetails(err, "restore tag", detailString)
+			}
+			event.TagsAfter = append(event.TagsAfter, t.TagName)
+		}
+
+		_, err = gorm.G[DeletedTag](tx).Where(deletedTag).Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "clear trashed tags", detailString)
+		}
+
+		return db.recordChange(ctx, tx, ReplicateUpload, pkg, versionHash)
+	})
+}
+
+// PurgeArtifactMeta removes a trashed artifact for good
+func (db *DB) PurgeArtifactMeta(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	ctx, span := tracing.Start(ctx, "orm.PurgeArtifactMeta")
+	defer span.End()
+
+	if err := validateArtifactKey(pkg, versionHash); err != nil {
+		return err
+	}
+
+	detailString := fmt.Sprintf(
+		"namespace=%q, name=%q, hash=%q",
+		pkg.Namespace,
+		pkg.Name,
+		versionHash,
+	)
+
+	event := newAuditEvent(AuditPurge, pkg, versionHash)
+
+	return db.audit(ctx, event, func(tx *gorm.DB) error {
+		rows, err := gorm.G[Artifact](tx).Scopes(unscoped).
+			Where("deleted_at IS NOT NULL").
+			Where(&Artifact{
+				Namespace: pkg.Namespace,
+				Name:      pkg.Name,
+				Hash:      versionHash,
+			}).
+			Delete(ctx)
+		if err != nil {
+			return wrapErrorWithDetails(err, "purge artifact", detailString)
+		}
+		if rows == 0 {
+			return &NotFoundError{Search: "purge artifact (" + detailString + ")"}
+		}
+
+		return nil
+	})
+}
@@ -9,6 +9,7 @@ package proto_gen
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -22,6 +23,390 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Serving a deprecated or yanked version adds its reason to the
+// x-enclave-warning response header
+type VersionState int32
+
+const (
+	VersionState_ACTIVE     VersionState = 0
+	VersionState_DEPRECATED VersionState = 1
+	// Excluded from tag resolution, but still available by version hash
+	VersionState_YANKED VersionState = 2
+)
+
+// Enum value maps for VersionState.
+var (
+	VersionState_name = map[int32]string{
+		0: "ACTIVE",
+		1: "DEPRECATED",
+		2: "YANKED",
+	}
+	VersionState_value = map[string]int32{
+		"ACTIVE":     0,
+		"DEPRECATED": 1,
+		"YANKED":     2,
+	}
+)
+
+func (x VersionState) Enum() *VersionState {
+	p := new(VersionState)
+	*p = x
+	return p
+}
+
+func (x VersionState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (VersionState) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[0].Descriptor()
+}
+
+func (VersionState) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[0]
+}
+
+func (x VersionState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use VersionState.Descriptor instead.
+func (VersionState) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{0}
+}
+
+type Visibility int32
+
+const (
+	Visibility_PUBLIC  Visibility = 0
+	Visibility_PRIVATE Visibility = 1
+)
+
+// Enum value maps for Visibility.
+var (
+	Visibility_name = map[int32]string{
+		0: "PUBLIC",
+		1: "PRIVATE",
+	}
+	Visibility_value = map[string]int32{
+		"PUBLIC":  0,
+		"PRIVATE": 1,
+	}
+)
+
+func (x Visibility) Enum() *Visibility {
+	p := new(Visibility)
+	*p = x
+	return p
+}
+
+func (x Visibility) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Visibility) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[1].Descriptor()
+}
+
+func (Visibility) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[1]
+}
+
+func (x Visibility) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Visibility.Descriptor instead.
+func (Visibility) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{1}
+}
+
+type AuditAction int32
+
+const (
+	AuditAction_UPLOAD AuditAction = 0
+	// Removed for good while the trash is disabled
+	AuditAction_DELETE     AuditAction = 1
+	AuditAction_TRASH      AuditAction = 2
+	AuditAction_RESTORE    AuditAction = 3
+	AuditAction_PURGE      AuditAction = 4
+	AuditAction_SET_TAGS   AuditAction = 5
+	AuditAction_SET_LABELS AuditAction = 6
+	AuditAction_SET_STATE  AuditAction = 7
+	// Recorded under the source package
+	AuditAction_MOVE_PACKAGE   AuditAction = 8
+	AuditAction_DELETE_PACKAGE AuditAction = 9
+	// Recorded under the namespace, with the number of removed versions and
+	// packages as detail
+	AuditAction_DELETE_NAMESPACE AuditAction = 10
+)
+
+// Enum value maps for AuditAction.
+var (
+	AuditAction_name = map[int32]string{
+		0:  "UPLOAD",
+		1:  "DELETE",
+		2:  "TRASH",
+		3:  "RESTORE",
+		4:  "PURGE",
+		5:  "SET_TAGS",
+		6:  "SET_LABELS",
+		7:  "SET_STATE",
+		8:  "MOVE_PACKAGE",
+		9:  "DELETE_PACKAGE",
+		10: "DELETE_NAMESPACE",
+	}
+	AuditAction_value = map[string]int32{
+		"UPLOAD":           0,
+		"DELETE":           1,
+		"TRASH":            2,
+		"RESTORE":          3,
+		"PURGE":            4,
+		"SET_TAGS":         5,
+		"SET_LABELS":       6,
+		"SET_STATE":        7,
+		"MOVE_PACKAGE":     8,
+		"DELETE_PACKAGE":   9,
+		"DELETE_NAMESPACE": 10,
+	}
+)
+
+func (x AuditAction) Enum() *AuditAction {
+	p := new(AuditAction)
+	*p = x
+	return p
+}
+
+func (x AuditAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AuditAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[2].Descriptor()
+}
+
+func (AuditAction) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[2]
+}
+
+func (x AuditAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AuditAction.Descriptor instead.
+func (AuditAction) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{2}
+}
+
+// Hourly buckets are kept for a shorter time than daily ones
+type PullStatsInterval int32
+
+const (
+	PullStatsInterval_DAILY  PullStatsInterval = 0
+	PullStatsInterval_HOURLY PullStatsInterval = 1
+)
+
+// Enum value maps for PullStatsInterval.
+var (
+	PullStatsInterval_name = map[int32]string{
+		0: "DAILY",
+		1: "HOURLY",
+	}
+	PullStatsInterval_value = map[string]int32{
+		"DAILY":  0,
+		"HOURLY": 1,
+	}
+)
+
+func (x PullStatsInterval) Enum() *PullStatsInterval {
+	p := new(PullStatsInterval)
+	*p = x
+	return p
+}
+
+func (x PullStatsInterval) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PullStatsInterval) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[3].Descriptor()
+}
+
+func (PullStatsInterval) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[3]
+}
+
+func (x PullStatsInterval) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PullStatsInterval.Descriptor instead.
+func (PullStatsInterval) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{3}
+}
+
+type PullStatsGrouping int32
+
+const (
+	PullStatsGrouping_TOTAL      PullStatsGrouping = 0
+	PullStatsGrouping_BY_VERSION PullStatsGrouping = 1
+	PullStatsGrouping_BY_TAG     PullStatsGrouping = 2
+	PullStatsGrouping_BY_CLIENT  PullStatsGrouping = 3
+)
+
+// Enum value maps for PullStatsGrouping.
+var (
+	PullStatsGrouping_name = map[int32]string{
+		0: "TOTAL",
+		1: "BY_VERSION",
+		2: "BY_TAG",
+		3: "BY_CLIENT",
+	}
+	PullStatsGrouping_value = map[string]int32{
+		"TOTAL":      0,
+		"BY_VERSION": 1,
+		"BY_TAG":     2,
+		"BY_CLIENT":  3,
+	}
+)
+
+func (x PullStatsGrouping) Enum() *PullStatsGrouping {
+	p := new(PullStatsGrouping)
+	*p = x
+	return p
+}
+
+func (x PullStatsGrouping) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PullStatsGrouping) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[4].Descriptor()
+}
+
+func (PullStatsGrouping) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[4]
+}
+
+func (x PullStatsGrouping) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PullStatsGrouping.Descriptor instead.
+func (PullStatsGrouping) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{4}
+}
+
+type LabelSelector_Operator int32
+
+const (
+	LabelSelector_EQUALS         LabelSelector_Operator = 0
+	LabelSelector_NOT_EQUALS     LabelSelector_Operator = 1
+	LabelSelector_EXISTS         LabelSelector_Operator = 2
+	LabelSelector_DOES_NOT_EXIST LabelSelector_Operator = 3
+	LabelSelector_IN             LabelSelector_Operator = 4
+	LabelSelector_NOT_IN         LabelSelector_Operator = 5
+)
+
+// Enum value maps for LabelSelector_Operator.
+var (
+	LabelSelector_Operator_name = map[int32]string{
+		0: "EQUALS",
+		1: "NOT_EQUALS",
+		2: "EXISTS",
+		3: "DOES_NOT_EXIST",
+		4: "IN",
+		5: "NOT_IN",
+	}
+	LabelSelector_Operator_value = map[string]int32{
+		"EQUALS":         0,
+		"NOT_EQUALS":     1,
+		"EXISTS":         2,
+		"DOES_NOT_EXIST": 3,
+		"IN":             4,
+		"NOT_IN":         5,
+	}
+)
+
+func (x LabelSelector_Operator) Enum() *LabelSelector_Operator {
+	p := new(LabelSelector_Operator)
+	*p = x
+	return p
+}
+
+func (x LabelSelector_Operator) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LabelSelector_Operator) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[5].Descriptor()
+}
+
+func (LabelSelector_Operator) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[5]
+}
+
+func (x LabelSelector_Operator) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LabelSelector_Operator.Descriptor instead.
+func (LabelSelector_Operator) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type InterfaceChange_Type int32
+
+const (
+	InterfaceChange_TYPE_UNSPECIFIED InterfaceChange_Type = 0
+	InterfaceChange_ADDED            InterfaceChange_Type = 1
+	InterfaceChange_REMOVED          InterfaceChange_Type = 2
+	InterfaceChange_CHANGED          InterfaceChange_Type = 3
+)
+
+// Enum value maps for InterfaceChange_Type.
+var (
+	InterfaceChange_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "ADDED",
+		2: "REMOVED",
+		3: "CHANGED",
+	}
+	InterfaceChange_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"ADDED":            1,
+		"REMOVED":          2,
+		"CHANGED":          3,
+	}
+)
+
+func (x InterfaceChange_Type) Enum() *InterfaceChange_Type {
+	p := new(InterfaceChange_Type)
+	*p = x
+	return p
+}
+
+func (x InterfaceChange_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (InterfaceChange_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_registry_proto_enumTypes[6].Descriptor()
+}
+
+func (InterfaceChange_Type) Type() protoreflect.EnumType {
+	return &file_registry_proto_enumTypes[6]
+}
+
+func (x InterfaceChange_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use InterfaceChange_Type.Descriptor instead.
+func (InterfaceChange_Type) EnumDescriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{23, 0}
+}
+
 type PackageName struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
@@ -165,11 +550,19 @@ func (*ArtifactIdentifier_VersionHash) isArtifactIdentifier_Identifier() {}
 func (*ArtifactIdentifier_Tag) isArtifactIdentifier_Identifier() {}
 
 type Artifact struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Package       *PackageName           `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
-	VersionHash   string                 `protobuf:"bytes,2,opt,name=version_hash,json=versionHash,proto3" json:"version_hash,omitempty"`
-	Tags          []string               `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
-	Metadata      *MetaData              `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Package     *PackageName           `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	VersionHash string                 `protobuf:"bytes,2,opt,name=version_hash,json=versionHash,proto3" json:"version_hash,omitempty"`
+	Tags        []string               `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	Metadata    *MetaData              `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Interface   *WasmInterface         `protobuf:"bytes,5,opt,name=interface,proto3" json:"interface,omitempty"`
+	Labels      map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Unset if the package has no record yet
+	PackageInfo *Package     `protobuf:"bytes,7,opt,name=package_info,json=packageInfo,proto3" json:"package_info,omitempty"`
+	State       VersionState `protobuf:"varint,8,opt,name=state,proto3,enum=registry.VersionState" json:"state,omitempty"`
+	StateReason string       `protobuf:"bytes,9,opt,name=state_reason,json=stateReason,proto3" json:"state_reason,omitempty"`
+	// Package the version was copied from, unset for uploaded versions
+	CopiedFrom    *PackageName `protobuf:"bytes,10,opt,name=copied_from,json=copiedFrom,proto3" json:"copied_from,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -232,28 +625,73 @@ func (x *Artifact) GetMetadata() *MetaData {
 	return nil
 }
 
-type MetaData struct {
+func (x *Artifact) GetInterface() *WasmInterface {
+	if x != nil {
+		return x.Interface
+	}
+	return nil
+}
+
+func (x *Artifact) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Artifact) GetPackageInfo() *Package {
+	if x != nil {
+		return x.PackageInfo
+	}
+	return nil
+}
+
+func (x *Artifact) GetState() VersionState {
+	if x != nil {
+		return x.State
+	}
+	return VersionState_ACTIVE
+}
+
+func (x *Artifact) GetStateReason() string {
+	if x != nil {
+		return x.StateReason
+	}
+	return ""
+}
+
+func (x *Artifact) GetCopiedFrom() *PackageName {
+	if x != nil {
+		return x.CopiedFrom
+	}
+	return nil
+}
+
+// Imports and exports of the artifact's Wasm binary. For components these are
+// the top-level imports and exports, i.e. the WIT world it targets. Unset if
+// the artifact could not be parsed as Wasm.
+type WasmInterface struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Created       *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=created,proto3" json:"created,omitempty"`
-	Pulls         int64                  `protobuf:"varint,2,opt,name=pulls,proto3" json:"pulls,omitempty"`
+	Imports       []*WasmSymbol          `protobuf:"bytes,1,rep,name=imports,proto3" json:"imports,omitempty"`
+	Exports       []*WasmSymbol          `protobuf:"bytes,2,rep,name=exports,proto3" json:"exports,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MetaData) Reset() {
-	*x = MetaData{}
+func (x *WasmInterface) Reset() {
+	*x = WasmInterface{}
 	mi := &file_registry_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MetaData) String() string {
+func (x *WasmInterface) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MetaData) ProtoMessage() {}
+func (*WasmInterface) ProtoMessage() {}
 
-func (x *MetaData) ProtoReflect() protoreflect.Message {
+func (x *WasmInterface) ProtoReflect() protoreflect.Message {
 	mi := &file_registry_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -265,47 +703,51 @@ func (x *MetaData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MetaData.ProtoReflect.Descriptor instead.
-func (*MetaData) Descriptor() ([]byte, []int) {
+// Deprecated: Use WasmInterface.ProtoReflect.Descriptor instead.
+func (*WasmInterface) Descriptor() ([]byte, []int) {
 	return file_registry_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *MetaData) GetCreated() *timestamppb.Timestamp {
+func (x *WasmInterface) GetImports() []*WasmSymbol {
 	if x != nil {
-		return x.Created
+		return x.Imports
 	}
 	return nil
 }
 
-func (x *MetaData) GetPulls() int64 {
+func (x *WasmInterface) GetExports() []*WasmSymbol {
 	if x != nil {
-		return x.Pulls
+		return x.Exports
 	}
-	return 0
+	return nil
 }
 
-type ArtifactQuery struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Namespace     *string                `protobuf:"bytes,1,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
-	Name          *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+type WasmSymbol struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only set for core module imports
+	Module string `protobuf:"bytes,1,opt,name=module,proto3" json:"module,omitempty"`
+	Name   string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// func, table, memory, global, tag, module, value, type, component, instance
+	Kind          string `protobuf:"bytes,3,opt,name=kind,proto3" json:"kind,omitempty"`
+	Signature     string `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ArtifactQuery) Reset() {
-	*x = ArtifactQuery{}
+func (x *WasmSymbol) Reset() {
+	*x = WasmSymbol{}
 	mi := &file_registry_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ArtifactQuery) String() string {
+func (x *WasmSymbol) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ArtifactQuery) ProtoMessage() {}
+func (*WasmSymbol) ProtoMessage() {}
 
-func (x *ArtifactQuery) ProtoReflect() protoreflect.Message {
+func (x *WasmSymbol) ProtoReflect() protoreflect.Message {
 	mi := &file_registry_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -317,46 +759,70 @@ func (x *ArtifactQuery) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ArtifactQuery.ProtoReflect.Descriptor instead.
-func (*ArtifactQuery) Descriptor() ([]byte, []int) {
+// Deprecated: Use WasmSymbol.ProtoReflect.Descriptor instead.
+func (*WasmSymbol) Descriptor() ([]byte, []int) {
 	return file_registry_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *ArtifactQuery) GetNamespace() string {
-	if x != nil && x.Namespace != nil {
-		return *x.Namespace
+func (x *WasmSymbol) GetModule() string {
+	if x != nil {
+		return x.Module
 	}
 	return ""
 }
 
-func (x *ArtifactQuery) GetName() string {
-	if x != nil && x.Name != nil {
-		return *x.Name
+func (x *WasmSymbol) GetName() string {
+	if x != nil {
+		return x.Name
 	}
 	return ""
 }
 
-type ArtifactListResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Artifacts     []*Artifact            `protobuf:"bytes,1,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+func (x *WasmSymbol) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *WasmSymbol) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+type MetaData struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Created   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=created,proto3" json:"created,omitempty"`
+	Pulls     int64                  `protobuf:"varint,2,opt,name=pulls,proto3" json:"pulls,omitempty"`
+	SizeBytes int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	// application/wasm for core modules, application/vnd.wasm.component for
+	// components and application/octet-stream for anything else
+	MediaType string `protobuf:"bytes,4,opt,name=media_type,json=mediaType,proto3" json:"media_type,omitempty"`
+	// Identity of the uploading user as forwarded in the x-enclave-user header
+	Uploader  string `protobuf:"bytes,5,opt,name=uploader,proto3" json:"uploader,omitempty"`
+	UserAgent string `protobuf:"bytes,6,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	// Unset if the version has never been pulled
+	LastPulledAt  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_pulled_at,json=lastPulledAt,proto3" json:"last_pulled_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ArtifactListResponse) Reset() {
-	*x = ArtifactListResponse{}
+func (x *MetaData) Reset() {
+	*x = MetaData{}
 	mi := &file_registry_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ArtifactListResponse) String() string {
+func (x *MetaData) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ArtifactListResponse) ProtoMessage() {}
+func (*MetaData) ProtoMessage() {}
 
-func (x *ArtifactListResponse) ProtoReflect() protoreflect.Message {
+func (x *MetaData) ProtoReflect() protoreflect.Message {
 	mi := &file_registry_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -368,28 +834,306 @@ func (x *ArtifactListResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ArtifactListResponse.ProtoReflect.Descriptor instead.
-func (*ArtifactListResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use MetaData.ProtoReflect.Descriptor instead.
+func (*MetaData) Descriptor() ([]byte, []int) {
 	return file_registry_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *ArtifactListResponse) GetArtifacts() []*Artifact {
+func (x *MetaData) GetCreated() *timestamppb.Timestamp {
 	if x != nil {
-		return x.Artifacts
+		return x.Created
 	}
 	return nil
 }
 
-type ArtifactContent struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	unknownFields protoimpl.UnknownFields
+func (x *MetaData) GetPulls() int64 {
+	if x != nil {
+		return x.Pulls
+	}
+	return 0
+}
+
+func (x *MetaData) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *MetaData) GetMediaType() string {
+	if x != nil {
+		return x.MediaType
+	}
+	return ""
+}
+
+func (x *MetaData) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+func (x *MetaData) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *MetaData) GetLastPulledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastPulledAt
+	}
+	return nil
+}
+
+type ArtifactQuery struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace *string                `protobuf:"bytes,1,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	Name      *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	// All filters have to match
+	Imports        []*SymbolFilter  `protobuf:"bytes,3,rep,name=imports,proto3" json:"imports,omitempty"`
+	Exports        []*SymbolFilter  `protobuf:"bytes,4,rep,name=exports,proto3" json:"exports,omitempty"`
+	LabelSelectors []*LabelSelector `protobuf:"bytes,5,rep,name=label_selectors,json=labelSelectors,proto3" json:"label_selectors,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ArtifactQuery) Reset() {
+	*x = ArtifactQuery{}
+	mi := &file_registry_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArtifactQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArtifactQuery) ProtoMessage() {}
+
+func (x *ArtifactQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArtifactQuery.ProtoReflect.Descriptor instead.
+func (*ArtifactQuery) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ArtifactQuery) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+func (x *ArtifactQuery) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *ArtifactQuery) GetImports() []*SymbolFilter {
+	if x != nil {
+		return x.Imports
+	}
+	return nil
+}
+
+func (x *ArtifactQuery) GetExports() []*SymbolFilter {
+	if x != nil {
+		return x.Exports
+	}
+	return nil
+}
+
+func (x *ArtifactQuery) GetLabelSelectors() []*LabelSelector {
+	if x != nil {
+		return x.LabelSelectors
+	}
+	return nil
+}
+
+type SymbolFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Module        *string                `protobuf:"bytes,1,opt,name=module,proto3,oneof" json:"module,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SymbolFilter) Reset() {
+	*x = SymbolFilter{}
+	mi := &file_registry_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SymbolFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SymbolFilter) ProtoMessage() {}
+
+func (x *SymbolFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SymbolFilter.ProtoReflect.Descriptor instead.
+func (*SymbolFilter) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SymbolFilter) GetModule() string {
+	if x != nil && x.Module != nil {
+		return *x.Module
+	}
+	return ""
+}
+
+func (x *SymbolFilter) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Selects artifacts by label. The negated operators also match artifacts that
+// do not carry the label at all.
+type LabelSelector struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Key      string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Operator LabelSelector_Operator `protobuf:"varint,2,opt,name=operator,proto3,enum=registry.LabelSelector_Operator" json:"operator,omitempty"`
+	// One value for EQUALS and NOT_EQUALS, at least one for IN and NOT_IN
+	Values        []string `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelSelector) Reset() {
+	*x = LabelSelector{}
+	mi := &file_registry_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelSelector) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelSelector) ProtoMessage() {}
+
+func (x *LabelSelector) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelSelector.ProtoReflect.Descriptor instead.
+func (*LabelSelector) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LabelSelector) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *LabelSelector) GetOperator() LabelSelector_Operator {
+	if x != nil {
+		return x.Operator
+	}
+	return LabelSelector_EQUALS
+}
+
+func (x *LabelSelector) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type ArtifactListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Artifacts     []*Artifact            `protobuf:"bytes,1,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArtifactListResponse) Reset() {
+	*x = ArtifactListResponse{}
+	mi := &file_registry_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArtifactListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArtifactListResponse) ProtoMessage() {}
+
+func (x *ArtifactListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArtifactListResponse.ProtoReflect.Descriptor instead.
+func (*ArtifactListResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ArtifactListResponse) GetArtifacts() []*Artifact {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+type ArtifactContent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ArtifactContent) Reset() {
 	*x = ArtifactContent{}
-	mi := &file_registry_proto_msgTypes[6]
+	mi := &file_registry_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -401,7 +1145,7 @@ func (x *ArtifactContent) String() string {
 func (*ArtifactContent) ProtoMessage() {}
 
 func (x *ArtifactContent) ProtoReflect() protoreflect.Message {
-	mi := &file_registry_proto_msgTypes[6]
+	mi := &file_registry_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -414,7 +1158,7 @@ func (x *ArtifactContent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ArtifactContent.ProtoReflect.Descriptor instead.
 func (*ArtifactContent) Descriptor() ([]byte, []int) {
-	return file_registry_proto_rawDescGZIP(), []int{6}
+	return file_registry_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ArtifactContent) GetData() []byte {
@@ -437,7 +1181,7 @@ type UploadArtifactRequest struct {
 
 func (x *UploadArtifactRequest) Reset() {
 	*x = UploadArtifactRequest{}
-	mi := &file_registry_proto_msgTypes[7]
+	mi := &file_registry_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -449,7 +1193,7 @@ func (x *UploadArtifactRequest) String() string {
 func (*UploadArtifactRequest) ProtoMessage() {}
 
 func (x *UploadArtifactRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_registry_proto_msgTypes[7]
+	mi := &file_registry_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -462,7 +1206,7 @@ func (x *UploadArtifactRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UploadArtifactRequest.ProtoReflect.Descriptor instead.
 func (*UploadArtifactRequest) Descriptor() ([]byte, []int) {
-	return file_registry_proto_rawDescGZIP(), []int{7}
+	return file_registry_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *UploadArtifactRequest) GetRequest() isUploadArtifactRequest_Request {
@@ -510,13 +1254,14 @@ type UploadMetadata struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Fqn           *PackageName           `protobuf:"bytes,1,opt,name=fqn,proto3" json:"fqn,omitempty"`
 	Tags          []string               `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UploadMetadata) Reset() {
 	*x = UploadMetadata{}
-	mi := &file_registry_proto_msgTypes[8]
+	mi := &file_registry_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -528,7 +1273,7 @@ func (x *UploadMetadata) String() string {
 func (*UploadMetadata) ProtoMessage() {}
 
 func (x *UploadMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_registry_proto_msgTypes[8]
+	mi := &file_registry_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -541,7 +1286,7 @@ func (x *UploadMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UploadMetadata.ProtoReflect.Descriptor instead.
 func (*UploadMetadata) Descriptor() ([]byte, []int) {
-	return file_registry_proto_rawDescGZIP(), []int{8}
+	return file_registry_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *UploadMetadata) GetFqn() *PackageName {
@@ -558,6 +1303,13 @@ func (x *UploadMetadata) GetTags() []string {
 	return nil
 }
 
+func (x *UploadMetadata) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 type SetTagsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Artifact      *ArtifactIdentifier    `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
@@ -568,7 +1320,7 @@ type SetTagsRequest struct {
 
 func (x *SetTagsRequest) Reset() {
 	*x = SetTagsRequest{}
-	mi := &file_registry_proto_msgTypes[9]
+	mi := &file_registry_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -580,7 +1332,7 @@ func (x *SetTagsRequest) String() string {
 func (*SetTagsRequest) ProtoMessage() {}
 
 func (x *SetTagsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_registry_proto_msgTypes[9]
+	mi := &file_registry_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -593,7 +1345,7 @@ func (x *SetTagsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetTagsRequest.ProtoReflect.Descriptor instead.
 func (*SetTagsRequest) Descriptor() ([]byte, []int) {
-	return file_registry_proto_rawDescGZIP(), []int{9}
+	return file_registry_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *SetTagsRequest) GetArtifact() *ArtifactIdentifier {
@@ -610,34 +1362,2271 @@ func (x *SetTagsRequest) GetTags() []string {
 	return nil
 }
 
-var File_registry_proto protoreflect.FileDescriptor
+// Replaces all labels of an artifact
+type SetLabelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Artifact      *ArtifactIdentifier    `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_registry_proto_rawDesc = "" +
-	"\n" +
-	"\x0eregistry.proto\x12\bregistry\x1a\x1fgoogle/protobuf/timestamp.proto\"?\n" +
-	"\vPackageName\x12\x1c\n" +
-	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
-	"\x04name\x18\x02 \x01(\tR\x04name\"\x8c\x01\n" +
-	"\x12ArtifactIdentifier\x12/\n" +
-	"\apackage\x18\x01 \x01(\v2\x15.registry.PackageNameR\apackage\x12#\n" +
-	"\fversion_hash\x18\x02 \x01(\tH\x00R\vversionHash\x12\x12\n" +
-	"\x03tag\x18\x03 \x01(\tH\x00R\x03tagB\f\n" +
-	"\n" +
-	"identifier\"\xa2\x01\n" +
-	"\bArtifact\x12/\n" +
+func (x *SetLabelsRequest) Reset() {
+	*x = SetLabelsRequest{}
+	mi := &file_registry_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLabelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLabelsRequest) ProtoMessage() {}
+
+func (x *SetLabelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLabelsRequest.ProtoReflect.Descriptor instead.
+func (*SetLabelsRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SetLabelsRequest) GetArtifact() *ArtifactIdentifier {
+	if x != nil {
+		return x.Artifact
+	}
+	return nil
+}
+
+func (x *SetLabelsRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// Setting a version back to ACTIVE clears its reason. Yanked versions can
+// only be addressed by version hash.
+type SetVersionStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Artifact      *ArtifactIdentifier    `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	State         VersionState           `protobuf:"varint,2,opt,name=state,proto3,enum=registry.VersionState" json:"state,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetVersionStateRequest) Reset() {
+	*x = SetVersionStateRequest{}
+	mi := &file_registry_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetVersionStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetVersionStateRequest) ProtoMessage() {}
+
+func (x *SetVersionStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetVersionStateRequest.ProtoReflect.Descriptor instead.
+func (*SetVersionStateRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SetVersionStateRequest) GetArtifact() *ArtifactIdentifier {
+	if x != nil {
+		return x.Artifact
+	}
+	return nil
+}
+
+func (x *SetVersionStateRequest) GetState() VersionState {
+	if x != nil {
+		return x.State
+	}
+	return VersionState_ACTIVE
+}
+
+func (x *SetVersionStateRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type BatchGetArtifactsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Identifiers   []*ArtifactIdentifier  `protobuf:"bytes,1,rep,name=identifiers,proto3" json:"identifiers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetArtifactsRequest) Reset() {
+	*x = BatchGetArtifactsRequest{}
+	mi := &file_registry_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetArtifactsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetArtifactsRequest) ProtoMessage() {}
+
+func (x *BatchGetArtifactsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetArtifactsRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetArtifactsRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *BatchGetArtifactsRequest) GetIdentifiers() []*ArtifactIdentifier {
+	if x != nil {
+		return x.Identifiers
+	}
+	return nil
+}
+
+type BatchGetArtifactsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// In the order of the requested identifiers
+	Results       []*ArtifactResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetArtifactsResponse) Reset() {
+	*x = BatchGetArtifactsResponse{}
+	mi := &file_registry_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetArtifactsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetArtifactsResponse) ProtoMessage() {}
+
+func (x *BatchGetArtifactsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetArtifactsResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetArtifactsResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *BatchGetArtifactsResponse) GetResults() []*ArtifactResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ArtifactResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*ArtifactResult_Artifact
+	//	*ArtifactResult_Error
+	Result        isArtifactResult_Result `protobuf_oneof:"result"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArtifactResult) Reset() {
+	*x = ArtifactResult{}
+	mi := &file_registry_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArtifactResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArtifactResult) ProtoMessage() {}
+
+func (x *ArtifactResult) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArtifactResult.ProtoReflect.Descriptor instead.
+func (*ArtifactResult) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ArtifactResult) GetResult() isArtifactResult_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *ArtifactResult) GetArtifact() *Artifact {
+	if x != nil {
+		if x, ok := x.Result.(*ArtifactResult_Artifact); ok {
+			return x.Artifact
+		}
+	}
+	return nil
+}
+
+func (x *ArtifactResult) GetError() *ErrorStatus {
+	if x != nil {
+		if x, ok := x.Result.(*ArtifactResult_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isArtifactResult_Result interface {
+	isArtifactResult_Result()
+}
+
+type ArtifactResult_Artifact struct {
+	Artifact *Artifact `protobuf:"bytes,1,opt,name=artifact,proto3,oneof"`
+}
+
+type ArtifactResult_Error struct {
+	Error *ErrorStatus `protobuf:"bytes,2,opt,name=error,proto3,oneof"`
+}
+
+func (*ArtifactResult_Artifact) isArtifactResult_Result() {}
+
+func (*ArtifactResult_Error) isArtifactResult_Result() {}
+
+// Error of a single item of a batch request
+type ErrorStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// gRPC status code
+	Code          uint32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorStatus) Reset() {
+	*x = ErrorStatus{}
+	mi := &file_registry_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorStatus) ProtoMessage() {}
+
+func (x *ErrorStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorStatus.ProtoReflect.Descriptor instead.
+func (*ErrorStatus) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ErrorStatus) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ErrorStatus) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type CopyArtifactRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Source *ArtifactIdentifier    `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Target *PackageName           `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	// Tags to assign to the copy, the tags of the source are not copied
+	Tags          []string `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyArtifactRequest) Reset() {
+	*x = CopyArtifactRequest{}
+	mi := &file_registry_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyArtifactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyArtifactRequest) ProtoMessage() {}
+
+func (x *CopyArtifactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyArtifactRequest.ProtoReflect.Descriptor instead.
+func (*CopyArtifactRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CopyArtifactRequest) GetSource() *ArtifactIdentifier {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *CopyArtifactRequest) GetTarget() *PackageName {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *CopyArtifactRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type CompareVersionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Both identifiers have to refer to the same package
+	Base          *ArtifactIdentifier `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	Candidate     *ArtifactIdentifier `protobuf:"bytes,2,opt,name=candidate,proto3" json:"candidate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompareVersionsRequest) Reset() {
+	*x = CompareVersionsRequest{}
+	mi := &file_registry_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareVersionsRequest) ProtoMessage() {}
+
+func (x *CompareVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareVersionsRequest.ProtoReflect.Descriptor instead.
+func (*CompareVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CompareVersionsRequest) GetBase() *ArtifactIdentifier {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+func (x *CompareVersionsRequest) GetCandidate() *ArtifactIdentifier {
+	if x != nil {
+		return x.Candidate
+	}
+	return nil
+}
+
+type CompareVersionsResponse struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	BaseVersionHash      string                 `protobuf:"bytes,1,opt,name=base_version_hash,json=baseVersionHash,proto3" json:"base_version_hash,omitempty"`
+	CandidateVersionHash string                 `protobuf:"bytes,2,opt,name=candidate_version_hash,json=candidateVersionHash,proto3" json:"candidate_version_hash,omitempty"`
+	// False if any of the changes is breaking
+	Compatible    bool               `protobuf:"varint,3,opt,name=compatible,proto3" json:"compatible,omitempty"`
+	Changes       []*InterfaceChange `protobuf:"bytes,4,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompareVersionsResponse) Reset() {
+	*x = CompareVersionsResponse{}
+	mi := &file_registry_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareVersionsResponse) ProtoMessage() {}
+
+func (x *CompareVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareVersionsResponse.ProtoReflect.Descriptor instead.
+func (*CompareVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CompareVersionsResponse) GetBaseVersionHash() string {
+	if x != nil {
+		return x.BaseVersionHash
+	}
+	return ""
+}
+
+func (x *CompareVersionsResponse) GetCandidateVersionHash() string {
+	if x != nil {
+		return x.CandidateVersionHash
+	}
+	return ""
+}
+
+func (x *CompareVersionsResponse) GetCompatible() bool {
+	if x != nil {
+		return x.Compatible
+	}
+	return false
+}
+
+func (x *CompareVersionsResponse) GetChanges() []*InterfaceChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type InterfaceChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  InterfaceChange_Type   `protobuf:"varint,1,opt,name=type,proto3,enum=registry.InterfaceChange_Type" json:"type,omitempty"`
+	// import or export
+	Direction string `protobuf:"bytes,2,opt,name=direction,proto3" json:"direction,omitempty"`
+	// Unset for added symbols
+	Before *WasmSymbol `protobuf:"bytes,3,opt,name=before,proto3" json:"before,omitempty"`
+	// Unset for removed symbols
+	After         *WasmSymbol `protobuf:"bytes,4,opt,name=after,proto3" json:"after,omitempty"`
+	Breaking      bool        `protobuf:"varint,5,opt,name=breaking,proto3" json:"breaking,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InterfaceChange) Reset() {
+	*x = InterfaceChange{}
+	mi := &file_registry_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InterfaceChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InterfaceChange) ProtoMessage() {}
+
+func (x *InterfaceChange) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InterfaceChange.ProtoReflect.Descriptor instead.
+func (*InterfaceChange) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *InterfaceChange) GetType() InterfaceChange_Type {
+	if x != nil {
+		return x.Type
+	}
+	return InterfaceChange_TYPE_UNSPECIFIED
+}
+
+func (x *InterfaceChange) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *InterfaceChange) GetBefore() *WasmSymbol {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *InterfaceChange) GetAfter() *WasmSymbol {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+func (x *InterfaceChange) GetBreaking() bool {
+	if x != nil {
+		return x.Breaking
+	}
+	return false
+}
+
+// Metadata shared by all versions of a package. A record is created on the
+// first upload and kept when the last version is deleted. Visibility is
+// recorded for clients that enforce access control, the registry itself does
+// not restrict access.
+type Package struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Name               *PackageName           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description        string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Readme             string                 `protobuf:"bytes,3,opt,name=readme,proto3" json:"readme,omitempty"`
+	Homepage           string                 `protobuf:"bytes,4,opt,name=homepage,proto3" json:"homepage,omitempty"`
+	Owners             []string               `protobuf:"bytes,5,rep,name=owners,proto3" json:"owners,omitempty"`
+	Visibility         Visibility             `protobuf:"varint,6,opt,name=visibility,proto3,enum=registry.Visibility" json:"visibility,omitempty"`
+	Deprecated         bool                   `protobuf:"varint,7,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	DeprecationMessage string                 `protobuf:"bytes,8,opt,name=deprecation_message,json=deprecationMessage,proto3" json:"deprecation_message,omitempty"`
+	// Output only
+	Created       *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created,proto3" json:"created,omitempty"`
+	Updated       *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Package) Reset() {
+	*x = Package{}
+	mi := &file_registry_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Package) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Package) ProtoMessage() {}
+
+func (x *Package) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Package.ProtoReflect.Descriptor instead.
+func (*Package) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Package) GetName() *PackageName {
+	if x != nil {
+		return x.Name
+	}
+	return nil
+}
+
+func (x *Package) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Package) GetReadme() string {
+	if x != nil {
+		return x.Readme
+	}
+	return ""
+}
+
+func (x *Package) GetHomepage() string {
+	if x != nil {
+		return x.Homepage
+	}
+	return ""
+}
+
+func (x *Package) GetOwners() []string {
+	if x != nil {
+		return x.Owners
+	}
+	return nil
+}
+
+func (x *Package) GetVisibility() Visibility {
+	if x != nil {
+		return x.Visibility
+	}
+	return Visibility_PUBLIC
+}
+
+func (x *Package) GetDeprecated() bool {
+	if x != nil {
+		return x.Deprecated
+	}
+	return false
+}
+
+func (x *Package) GetDeprecationMessage() string {
+	if x != nil {
+		return x.DeprecationMessage
+	}
+	return ""
+}
+
+func (x *Package) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *Package) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+type DeletePackageRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Package *PackageName           `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	// Packages with versions can only be deleted together with them. The
+	// versions are moved to the trash unless it is disabled.
+	DeleteVersions bool `protobuf:"varint,2,opt,name=delete_versions,json=deleteVersions,proto3" json:"delete_versions,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DeletePackageRequest) Reset() {
+	*x = DeletePackageRequest{}
+	mi := &file_registry_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePackageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePackageRequest) ProtoMessage() {}
+
+func (x *DeletePackageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePackageRequest.ProtoReflect.Descriptor instead.
+func (*DeletePackageRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DeletePackageRequest) GetPackage() *PackageName {
+	if x != nil {
+		return x.Package
+	}
+	return nil
+}
+
+func (x *DeletePackageRequest) GetDeleteVersions() bool {
+	if x != nil {
+		return x.DeleteVersions
+	}
+	return false
+}
+
+type MovePackageRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	From  *PackageName           `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To    *PackageName           `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	// Lets reads of versions under the old name resolve to the new one for the
+	// configured time
+	Redirect      bool `protobuf:"varint,3,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MovePackageRequest) Reset() {
+	*x = MovePackageRequest{}
+	mi := &file_registry_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MovePackageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MovePackageRequest) ProtoMessage() {}
+
+func (x *MovePackageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MovePackageRequest.ProtoReflect.Descriptor instead.
+func (*MovePackageRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *MovePackageRequest) GetFrom() *PackageName {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *MovePackageRequest) GetTo() *PackageName {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *MovePackageRequest) GetRedirect() bool {
+	if x != nil {
+		return x.Redirect
+	}
+	return false
+}
+
+type PackageQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     *string                `protobuf:"bytes,1,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackageQuery) Reset() {
+	*x = PackageQuery{}
+	mi := &file_registry_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackageQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackageQuery) ProtoMessage() {}
+
+func (x *PackageQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackageQuery.ProtoReflect.Descriptor instead.
+func (*PackageQuery) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *PackageQuery) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+type PackageListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Packages      []*Package             `protobuf:"bytes,1,rep,name=packages,proto3" json:"packages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackageListResponse) Reset() {
+	*x = PackageListResponse{}
+	mi := &file_registry_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackageListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackageListResponse) ProtoMessage() {}
+
+func (x *PackageListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackageListResponse.ProtoReflect.Descriptor instead.
+func (*PackageListResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *PackageListResponse) GetPackages() []*Package {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+type NamespaceName struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceName) Reset() {
+	*x = NamespaceName{}
+	mi := &file_registry_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceName) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceName) ProtoMessage() {}
+
+func (x *NamespaceName) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceName.ProtoReflect.Descriptor instead.
+func (*NamespaceName) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *NamespaceName) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Namespace struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Name        string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// Defaults to the configured quota if unset on creation
+	Quota *NamespaceQuota `protobuf:"bytes,3,opt,name=quota,proto3" json:"quota,omitempty"`
+	// Output only
+	Usage         *NamespaceUsage        `protobuf:"bytes,4,opt,name=usage,proto3" json:"usage,omitempty"`
+	Created       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Namespace) Reset() {
+	*x = Namespace{}
+	mi := &file_registry_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Namespace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Namespace) ProtoMessage() {}
+
+func (x *Namespace) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Namespace.ProtoReflect.Descriptor instead.
+func (*Namespace) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Namespace) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Namespace) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Namespace) GetQuota() *NamespaceQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *Namespace) GetUsage() *NamespaceUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *Namespace) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+// Limits are enforced on upload, zero means unlimited
+type NamespaceQuota struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxBytes      int64                  `protobuf:"varint,1,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxVersions   int64                  `protobuf:"varint,2,opt,name=max_versions,json=maxVersions,proto3" json:"max_versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceQuota) Reset() {
+	*x = NamespaceQuota{}
+	mi := &file_registry_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceQuota) ProtoMessage() {}
+
+func (x *NamespaceQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceQuota.ProtoReflect.Descriptor instead.
+func (*NamespaceQuota) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *NamespaceQuota) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *NamespaceQuota) GetMaxVersions() int64 {
+	if x != nil {
+		return x.MaxVersions
+	}
+	return 0
+}
+
+type NamespaceUsage struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Bytes    int64                  `protobuf:"varint,1,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Versions int64                  `protobuf:"varint,2,opt,name=versions,proto3" json:"versions,omitempty"`
+	// Size of the versions in the trash, which counts towards max_bytes
+	TrashedBytes  int64 `protobuf:"varint,3,opt,name=trashed_bytes,json=trashedBytes,proto3" json:"trashed_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceUsage) Reset() {
+	*x = NamespaceUsage{}
+	mi := &file_registry_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceUsage) ProtoMessage() {}
+
+func (x *NamespaceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceUsage.ProtoReflect.Descriptor instead.
+func (*NamespaceUsage) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *NamespaceUsage) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *NamespaceUsage) GetVersions() int64 {
+	if x != nil {
+		return x.Versions
+	}
+	return 0
+}
+
+func (x *NamespaceUsage) GetTrashedBytes() int64 {
+	if x != nil {
+		return x.TrashedBytes
+	}
+	return 0
+}
+
+type NamespaceQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceQuery) Reset() {
+	*x = NamespaceQuery{}
+	mi := &file_registry_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceQuery) ProtoMessage() {}
+
+func (x *NamespaceQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceQuery.ProtoReflect.Descriptor instead.
+func (*NamespaceQuery) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{33}
+}
+
+type NamespaceListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespaces    []*Namespace           `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceListResponse) Reset() {
+	*x = NamespaceListResponse{}
+	mi := &file_registry_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceListResponse) ProtoMessage() {}
+
+func (x *NamespaceListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceListResponse.ProtoReflect.Descriptor instead.
+func (*NamespaceListResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *NamespaceListResponse) GetNamespaces() []*Namespace {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+type DeletedQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     *string                `protobuf:"bytes,1,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	Name          *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedQuery) Reset() {
+	*x = DeletedQuery{}
+	mi := &file_registry_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedQuery) ProtoMessage() {}
+
+func (x *DeletedQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedQuery.ProtoReflect.Descriptor instead.
+func (*DeletedQuery) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DeletedQuery) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+func (x *DeletedQuery) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+type DeletedIdentifier struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Package       *PackageName           `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	VersionHash   string                 `protobuf:"bytes,2,opt,name=version_hash,json=versionHash,proto3" json:"version_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedIdentifier) Reset() {
+	*x = DeletedIdentifier{}
+	mi := &file_registry_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedIdentifier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedIdentifier) ProtoMessage() {}
+
+func (x *DeletedIdentifier) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedIdentifier.ProtoReflect.Descriptor instead.
+func (*DeletedIdentifier) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *DeletedIdentifier) GetPackage() *PackageName {
+	if x != nil {
+		return x.Package
+	}
+	return nil
+}
+
+func (x *DeletedIdentifier) GetVersionHash() string {
+	if x != nil {
+		return x.VersionHash
+	}
+	return ""
+}
+
+type DeletedArtifact struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Artifact *Artifact              `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	// Tags the version had when it was deleted
+	Tags      []string               `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	DeletedBy string                 `protobuf:"bytes,3,opt,name=deleted_by,json=deletedBy,proto3" json:"deleted_by,omitempty"`
+	Deleted   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	// When the version is purged automatically
+	Expires       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires,proto3" json:"expires,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedArtifact) Reset() {
+	*x = DeletedArtifact{}
+	mi := &file_registry_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedArtifact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedArtifact) ProtoMessage() {}
+
+func (x *DeletedArtifact) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedArtifact.ProtoReflect.Descriptor instead.
+func (*DeletedArtifact) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *DeletedArtifact) GetArtifact() *Artifact {
+	if x != nil {
+		return x.Artifact
+	}
+	return nil
+}
+
+func (x *DeletedArtifact) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *DeletedArtifact) GetDeletedBy() string {
+	if x != nil {
+		return x.DeletedBy
+	}
+	return ""
+}
+
+func (x *DeletedArtifact) GetDeleted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deleted
+	}
+	return nil
+}
+
+func (x *DeletedArtifact) GetExpires() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Expires
+	}
+	return nil
+}
+
+type DeletedListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Artifacts     []*DeletedArtifact     `protobuf:"bytes,1,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedListResponse) Reset() {
+	*x = DeletedListResponse{}
+	mi := &file_registry_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedListResponse) ProtoMessage() {}
+
+func (x *DeletedListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedListResponse.ProtoReflect.Descriptor instead.
+func (*DeletedListResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *DeletedListResponse) GetArtifacts() []*DeletedArtifact {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+type ReplicationStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicationStatusRequest) Reset() {
+	*x = ReplicationStatusRequest{}
+	mi := &file_registry_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusRequest) ProtoMessage() {}
+
+func (x *ReplicationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusRequest.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{39}
+}
+
+type ReplicationStatus struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Peers         []*PeerReplicationStatus `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicationStatus) Reset() {
+	*x = ReplicationStatus{}
+	mi := &file_registry_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatus) ProtoMessage() {}
+
+func (x *ReplicationStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatus.ProtoReflect.Descriptor instead.
+func (*ReplicationStatus) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ReplicationStatus) GetPeers() []*PeerReplicationStatus {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type PeerReplicationStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Address of the peer as configured
+	Peer string `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	// Changes that have not been replicated to the peer yet
+	PendingChanges int64 `protobuf:"varint,2,opt,name=pending_changes,json=pendingChanges,proto3" json:"pending_changes,omitempty"`
+	// Age of the oldest pending change, zero if the peer is up to date
+	Lag *durationpb.Duration `protobuf:"bytes,3,opt,name=lag,proto3" json:"lag,omitempty"`
+	// Consecutive failed attempts, reset once a change is replicated
+	Failures int64 `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"`
+	// Most recent error, including changes that were skipped because the peer
+	// rejected them
+	LastError string `protobuf:"bytes,5,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	// Unset if nothing has been replicated to the peer yet
+	LastReplicated *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_replicated,json=lastReplicated,proto3" json:"last_replicated,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PeerReplicationStatus) Reset() {
+	*x = PeerReplicationStatus{}
+	mi := &file_registry_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerReplicationStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerReplicationStatus) ProtoMessage() {}
+
+func (x *PeerReplicationStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerReplicationStatus.ProtoReflect.Descriptor instead.
+func (*PeerReplicationStatus) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *PeerReplicationStatus) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+func (x *PeerReplicationStatus) GetPendingChanges() int64 {
+	if x != nil {
+		return x.PendingChanges
+	}
+	return 0
+}
+
+func (x *PeerReplicationStatus) GetLag() *durationpb.Duration {
+	if x != nil {
+		return x.Lag
+	}
+	return nil
+}
+
+func (x *PeerReplicationStatus) GetFailures() int64 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *PeerReplicationStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *PeerReplicationStatus) GetLastReplicated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastReplicated
+	}
+	return nil
+}
+
+// All filters have to match
+type AuditQuery struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Actor       *string                `protobuf:"bytes,1,opt,name=actor,proto3,oneof" json:"actor,omitempty"`
+	Action      *AuditAction           `protobuf:"varint,2,opt,name=action,proto3,enum=registry.AuditAction,oneof" json:"action,omitempty"`
+	Namespace   *string                `protobuf:"bytes,3,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	Name        *string                `protobuf:"bytes,4,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	VersionHash *string                `protobuf:"bytes,5,opt,name=version_hash,json=versionHash,proto3,oneof" json:"version_hash,omitempty"`
+	// Only events recorded at or after since and before until
+	Since *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=since,proto3" json:"since,omitempty"`
+	Until *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=until,proto3" json:"until,omitempty"`
+	// Defaults to 100, at most 1000 events are returned
+	PageSize int32 `protobuf:"varint,8,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page
+	PageToken     string `protobuf:"bytes,9,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditQuery) Reset() {
+	*x = AuditQuery{}
+	mi := &file_registry_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditQuery) ProtoMessage() {}
+
+func (x *AuditQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditQuery.ProtoReflect.Descriptor instead.
+func (*AuditQuery) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *AuditQuery) GetActor() string {
+	if x != nil && x.Actor != nil {
+		return *x.Actor
+	}
+	return ""
+}
+
+func (x *AuditQuery) GetAction() AuditAction {
+	if x != nil && x.Action != nil {
+		return *x.Action
+	}
+	return AuditAction_UPLOAD
+}
+
+func (x *AuditQuery) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+func (x *AuditQuery) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *AuditQuery) GetVersionHash() string {
+	if x != nil && x.VersionHash != nil {
+		return *x.VersionHash
+	}
+	return ""
+}
+
+func (x *AuditQuery) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *AuditQuery) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *AuditQuery) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *AuditQuery) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type AuditEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	// User forwarded in the x-enclave-user header, "system" for changes made
+	// by the registry itself, e.g. purging expired versions
+	Actor         string       `protobuf:"bytes,3,opt,name=actor,proto3" json:"actor,omitempty"`
+	ClientAddress string       `protobuf:"bytes,4,opt,name=client_address,json=clientAddress,proto3" json:"client_address,omitempty"`
+	Action        AuditAction  `protobuf:"varint,5,opt,name=action,proto3,enum=registry.AuditAction" json:"action,omitempty"`
+	Package       *PackageName `protobuf:"bytes,6,opt,name=package,proto3" json:"package,omitempty"`
+	VersionHash   string       `protobuf:"bytes,7,opt,name=version_hash,json=versionHash,proto3" json:"version_hash,omitempty"`
+	TagsBefore    []string     `protobuf:"bytes,8,rep,name=tags_before,json=tagsBefore,proto3" json:"tags_before,omitempty"`
+	TagsAfter     []string     `protobuf:"bytes,9,rep,name=tags_after,json=tagsAfter,proto3" json:"tags_after,omitempty"`
+	Succeeded     bool         `protobuf:"varint,10,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	// Why the change failed
+	Error string `protobuf:"bytes,11,opt,name=error,proto3" json:"error,omitempty"`
+	// What else changed: the labels or state that were set, or the target of
+	// a move
+	Detail        string `protobuf:"bytes,12,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditEvent) Reset() {
+	*x = AuditEvent{}
+	mi := &file_registry_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEvent) ProtoMessage() {}
+
+func (x *AuditEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEvent.ProtoReflect.Descriptor instead.
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *AuditEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetClientAddress() string {
+	if x != nil {
+		return x.ClientAddress
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetAction() AuditAction {
+	if x != nil {
+		return x.Action
+	}
+	return AuditAction_UPLOAD
+}
+
+func (x *AuditEvent) GetPackage() *PackageName {
+	if x != nil {
+		return x.Package
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetVersionHash() string {
+	if x != nil {
+		return x.VersionHash
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetTagsBefore() []string {
+	if x != nil {
+		return x.TagsBefore
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetTagsAfter() []string {
+	if x != nil {
+		return x.TagsAfter
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetSucceeded() bool {
+	if x != nil {
+		return x.Succeeded
+	}
+	return false
+}
+
+func (x *AuditEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type AuditEventListResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Events []*AuditEvent          `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	// Empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditEventListResponse) Reset() {
+	*x = AuditEventListResponse{}
+	mi := &file_registry_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEventListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEventListResponse) ProtoMessage() {}
+
+func (x *AuditEventListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEventListResponse.ProtoReflect.Descriptor instead.
+func (*AuditEventListResponse) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *AuditEventListResponse) GetEvents() []*AuditEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *AuditEventListResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type PullStatsRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Package     *PackageName           `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	VersionHash *string                `protobuf:"bytes,2,opt,name=version_hash,json=versionHash,proto3,oneof" json:"version_hash,omitempty"`
+	Interval    PullStatsInterval      `protobuf:"varint,3,opt,name=interval,proto3,enum=registry.PullStatsInterval" json:"interval,omitempty"`
+	// Only buckets starting at or after since and before until
+	Since         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=since,proto3" json:"since,omitempty"`
+	Until         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=until,proto3" json:"until,omitempty"`
+	GroupBy       PullStatsGrouping      `protobuf:"varint,6,opt,name=group_by,json=groupBy,proto3,enum=registry.PullStatsGrouping" json:"group_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullStatsRequest) Reset() {
+	*x = PullStatsRequest{}
+	mi := &file_registry_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullStatsRequest) ProtoMessage() {}
+
+func (x *PullStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullStatsRequest.ProtoReflect.Descriptor instead.
+func (*PullStatsRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *PullStatsRequest) GetPackage() *PackageName {
+	if x != nil {
+		return x.Package
+	}
+	return nil
+}
+
+func (x *PullStatsRequest) GetVersionHash() string {
+	if x != nil && x.VersionHash != nil {
+		return *x.VersionHash
+	}
+	return ""
+}
+
+func (x *PullStatsRequest) GetInterval() PullStatsInterval {
+	if x != nil {
+		return x.Interval
+	}
+	return PullStatsInterval_DAILY
+}
+
+func (x *PullStatsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *PullStatsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *PullStatsRequest) GetGroupBy() PullStatsGrouping {
+	if x != nil {
+		return x.GroupBy
+	}
+	return PullStatsGrouping_TOTAL
+}
+
+type PullStats struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One series per version, tag or client, a single one for TOTAL
+	Series        []*PullSeries `protobuf:"bytes,1,rep,name=series,proto3" json:"series,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullStats) Reset() {
+	*x = PullStats{}
+	mi := &file_registry_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullStats) ProtoMessage() {}
+
+func (x *PullStats) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullStats.ProtoReflect.Descriptor instead.
+func (*PullStats) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *PullStats) GetSeries() []*PullSeries {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+type PullSeries struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Version hash, tag or client whose pulls are counted, empty for TOTAL.
+	// Pulls by version hash are counted under an empty tag.
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Total int64  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	// Oldest first, buckets without pulls are left out
+	Buckets       []*PullCount `protobuf:"bytes,3,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullSeries) Reset() {
+	*x = PullSeries{}
+	mi := &file_registry_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullSeries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullSeries) ProtoMessage() {}
+
+func (x *PullSeries) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullSeries.ProtoReflect.Descriptor instead.
+func (*PullSeries) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *PullSeries) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PullSeries) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *PullSeries) GetBuckets() []*PullCount {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+type PullCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	Pulls         int64                  `protobuf:"varint,2,opt,name=pulls,proto3" json:"pulls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullCount) Reset() {
+	*x = PullCount{}
+	mi := &file_registry_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullCount) ProtoMessage() {}
+
+func (x *PullCount) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullCount.ProtoReflect.Descriptor instead.
+func (*PullCount) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *PullCount) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *PullCount) GetPulls() int64 {
+	if x != nil {
+		return x.Pulls
+	}
+	return 0
+}
+
+var File_registry_proto protoreflect.FileDescriptor
+
+const file_registry_proto_rawDesc = "" +
+	"\n" +
+	"\x0eregistry.proto\x12\bregistry\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"?\n" +
+	"\vPackageName\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"\x8c\x01\n" +
+	"\x12ArtifactIdentifier\x12/\n" +
+	"\apackage\x18\x01 \x01(\v2\x15.registry.PackageNameR\apackage\x12#\n" +
+	"\fversion_hash\x18\x02 \x01(\tH\x00R\vversionHash\x12\x12\n" +
+	"\x03tag\x18\x03 \x01(\tH\x00R\x03tagB\f\n" +
+	"\n" +
+	"identifier\"\x8b\x04\n" +
+	"\bArtifact\x12/\n" +
 	"\apackage\x18\x01 \x01(\v2\x15.registry.PackageNameR\apackage\x12!\n" +
 	"\fversion_hash\x18\x02 \x01(\tR\vversionHash\x12\x12\n" +
 	"\x04tags\x18\x03 \x03(\tR\x04tags\x12.\n" +
-	"\bmetadata\x18\x04 \x01(\v2\x12.registry.MetaDataR\bmetadata\"V\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x12.registry.MetaDataR\bmetadata\x125\n" +
+	"\tinterface\x18\x05 \x01(\v2\x17.registry.WasmInterfaceR\tinterface\x126\n" +
+	"\x06labels\x18\x06 \x03(\v2\x1e.registry.Artifact.LabelsEntryR\x06labels\x124\n" +
+	"\fpackage_info\x18\a \x01(\v2\x11.registry.PackageR\vpackageInfo\x12,\n" +
+	"\x05state\x18\b \x01(\x0e2\x16.registry.VersionStateR\x05state\x12!\n" +
+	"\fstate_reason\x18\t \x01(\tR\vstateReason\x126\n" +
+	"\vcopied_from\x18\n" +
+	" \x01(\v2\x15.registry.PackageNameR\n" +
+	"copiedFrom\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"o\n" +
+	"\rWasmInterface\x12.\n" +
+	"\aimports\x18\x01 \x03(\v2\x14.registry.WasmSymbolR\aimports\x12.\n" +
+	"\aexports\x18\x02 \x03(\v2\x14.registry.WasmSymbolR\aexports\"j\n" +
+	"\n" +
+	"WasmSymbol\x12\x16\n" +
+	"\x06module\x18\x01 \x01(\tR\x06module\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04kind\x18\x03 \x01(\tR\x04kind\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\tR\tsignature\"\x91\x02\n" +
 	"\bMetaData\x124\n" +
 	"\acreated\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x12\x14\n" +
-	"\x05pulls\x18\x02 \x01(\x03R\x05pulls\"b\n" +
+	"\x05pulls\x18\x02 \x01(\x03R\x05pulls\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12\x1d\n" +
+	"\n" +
+	"media_type\x18\x04 \x01(\tR\tmediaType\x12\x1a\n" +
+	"\buploader\x18\x05 \x01(\tR\buploader\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\x06 \x01(\tR\tuserAgent\x12@\n" +
+	"\x0elast_pulled_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\flastPulledAt\"\x88\x02\n" +
 	"\rArtifactQuery\x12!\n" +
 	"\tnamespace\x18\x01 \x01(\tH\x00R\tnamespace\x88\x01\x01\x12\x17\n" +
-	"\x04name\x18\x02 \x01(\tH\x01R\x04name\x88\x01\x01B\f\n" +
+	"\x04name\x18\x02 \x01(\tH\x01R\x04name\x88\x01\x01\x120\n" +
+	"\aimports\x18\x03 \x03(\v2\x16.registry.SymbolFilterR\aimports\x120\n" +
+	"\aexports\x18\x04 \x03(\v2\x16.registry.SymbolFilterR\aexports\x12@\n" +
+	"\x0flabel_selectors\x18\x05 \x03(\v2\x17.registry.LabelSelectorR\x0elabelSelectorsB\f\n" +
 	"\n" +
 	"_namespaceB\a\n" +
-	"\x05_name\"H\n" +
+	"\x05_name\"J\n" +
+	"\fSymbolFilter\x12\x1b\n" +
+	"\x06module\x18\x01 \x01(\tH\x00R\x06module\x88\x01\x01\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04nameB\t\n" +
+	"\a_module\"\xd3\x01\n" +
+	"\rLabelSelector\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12<\n" +
+	"\boperator\x18\x02 \x01(\x0e2 .registry.LabelSelector.OperatorR\boperator\x12\x16\n" +
+	"\x06values\x18\x03 \x03(\tR\x06values\"Z\n" +
+	"\bOperator\x12\n" +
+	"\n" +
+	"\x06EQUALS\x10\x00\x12\x0e\n" +
+	"\n" +
+	"NOT_EQUALS\x10\x01\x12\n" +
+	"\n" +
+	"\x06EXISTS\x10\x02\x12\x12\n" +
+	"\x0eDOES_NOT_EXIST\x10\x03\x12\x06\n" +
+	"\x02IN\x10\x04\x12\n" +
+	"\n" +
+	"\x06NOT_IN\x10\x05\"H\n" +
 	"\x14ArtifactListResponse\x120\n" +
 	"\tartifacts\x18\x01 \x03(\v2\x12.registry.ArtifactR\tartifacts\"%\n" +
 	"\x0fArtifactContent\x12\x12\n" +
@@ -645,20 +3634,266 @@ const file_registry_proto_rawDesc = "" +
 	"\x15UploadArtifactRequest\x126\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x18.registry.UploadMetadataH\x00R\bmetadata\x125\n" +
 	"\acontent\x18\x02 \x01(\v2\x19.registry.ArtifactContentH\x00R\acontentB\t\n" +
-	"\arequest\"M\n" +
+	"\arequest\"\xc6\x01\n" +
 	"\x0eUploadMetadata\x12'\n" +
 	"\x03fqn\x18\x01 \x01(\v2\x15.registry.PackageNameR\x03fqn\x12\x12\n" +
-	"\x04tags\x18\x02 \x03(\tR\x04tags\"^\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\x12<\n" +
+	"\x06labels\x18\x03 \x03(\v2$.registry.UploadMetadata.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"^\n" +
 	"\x0eSetTagsRequest\x128\n" +
 	"\bartifact\x18\x01 \x01(\v2\x1c.registry.ArtifactIdentifierR\bartifact\x12\x12\n" +
-	"\x04tags\x18\x02 \x03(\tR\x04tags2\xae\x03\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\"\xc7\x01\n" +
+	"\x10SetLabelsRequest\x128\n" +
+	"\bartifact\x18\x01 \x01(\v2\x1c.registry.ArtifactIdentifierR\bartifact\x12>\n" +
+	"\x06labels\x18\x02 \x03(\v2&.registry.SetLabelsRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x98\x01\n" +
+	"\x16SetVersionStateRequest\x128\n" +
+	"\bartifact\x18\x01 \x01(\v2\x1c.registry.ArtifactIdentifierR\bartifact\x12,\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x16.registry.VersionStateR\x05state\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"Z\n" +
+	"\x18BatchGetArtifactsRequest\x12>\n" +
+	"\videntifiers\x18\x01 \x03(\v2\x1c.registry.ArtifactIdentifierR\videntifiers\"O\n" +
+	"\x19BatchGetArtifactsResponse\x122\n" +
+	"\aresults\x18\x01 \x03(\v2\x18.registry.ArtifactResultR\aresults\"{\n" +
+	"\x0eArtifactResult\x120\n" +
+	"\bartifact\x18\x01 \x01(\v2\x12.registry.ArtifactH\x00R\bartifact\x12-\n" +
+	"\x05error\x18\x02 \x01(\v2\x15.registry.ErrorStatusH\x00R\x05errorB\b\n" +
+	"\x06result\";\n" +
+	"\vErrorStatus\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\rR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x8e\x01\n" +
+	"\x13CopyArtifactRequest\x124\n" +
+	"\x06source\x18\x01 \x01(\v2\x1c.registry.ArtifactIdentifierR\x06source\x12-\n" +
+	"\x06target\x18\x02 \x01(\v2\x15.registry.PackageNameR\x06target\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\"\x86\x01\n" +
+	"\x16CompareVersionsRequest\x120\n" +
+	"\x04base\x18\x01 \x01(\v2\x1c.registry.ArtifactIdentifierR\x04base\x12:\n" +
+	"\tcandidate\x18\x02 \x01(\v2\x1c.registry.ArtifactIdentifierR\tcandidate\"\xd0\x01\n" +
+	"\x17CompareVersionsResponse\x12*\n" +
+	"\x11base_version_hash\x18\x01 \x01(\tR\x0fbaseVersionHash\x124\n" +
+	"\x16candidate_version_hash\x18\x02 \x01(\tR\x14candidateVersionHash\x12\x1e\n" +
+	"\n" +
+	"compatible\x18\x03 \x01(\bR\n" +
+	"compatible\x123\n" +
+	"\achanges\x18\x04 \x03(\v2\x19.registry.InterfaceChangeR\achanges\"\x9c\x02\n" +
+	"\x0fInterfaceChange\x122\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1e.registry.InterfaceChange.TypeR\x04type\x12\x1c\n" +
+	"\tdirection\x18\x02 \x01(\tR\tdirection\x12,\n" +
+	"\x06before\x18\x03 \x01(\v2\x14.registry.WasmSymbolR\x06before\x12*\n" +
+	"\x05after\x18\x04 \x01(\v2\x14.registry.WasmSymbolR\x05after\x12\x1a\n" +
+	"\bbreaking\x18\x05 \x01(\bR\bbreaking\"A\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\t\n" +
+	"\x05ADDED\x10\x01\x12\v\n" +
+	"\aREMOVED\x10\x02\x12\v\n" +
+	"\aCHANGED\x10\x03\"\x95\x03\n" +
+	"\aPackage\x12)\n" +
+	"\x04name\x18\x01 \x01(\v2\x15.registry.PackageNameR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06readme\x18\x03 \x01(\tR\x06readme\x12\x1a\n" +
+	"\bhomepage\x18\x04 \x01(\tR\bhomepage\x12\x16\n" +
+	"\x06owners\x18\x05 \x03(\tR\x06owners\x124\n" +
+	"\n" +
+	"visibility\x18\x06 \x01(\x0e2\x14.registry.VisibilityR\n" +
+	"visibility\x12\x1e\n" +
+	"\n" +
+	"deprecated\x18\a \x01(\bR\n" +
+	"deprecated\x12/\n" +
+	"\x13deprecation_message\x18\b \x01(\tR\x12deprecationMessage\x124\n" +
+	"\acreated\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x124\n" +
+	"\aupdated\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\"p\n" +
+	"\x14DeletePackageRequest\x12/\n" +
+	"\apackage\x18\x01 \x01(\v2\x15.registry.PackageNameR\apackage\x12'\n" +
+	"\x0fdelete_versions\x18\x02 \x01(\bR\x0edeleteVersions\"\x82\x01\n" +
+	"\x12MovePackageRequest\x12)\n" +
+	"\x04from\x18\x01 \x01(\v2\x15.registry.PackageNameR\x04from\x12%\n" +
+	"\x02to\x18\x02 \x01(\v2\x15.registry.PackageNameR\x02to\x12\x1a\n" +
+	"\bredirect\x18\x03 \x01(\bR\bredirect\"?\n" +
+	"\fPackageQuery\x12!\n" +
+	"\tnamespace\x18\x01 \x01(\tH\x00R\tnamespace\x88\x01\x01B\f\n" +
+	"\n" +
+	"_namespace\"D\n" +
+	"\x13PackageListResponse\x12-\n" +
+	"\bpackages\x18\x01 \x03(\v2\x11.registry.PackageR\bpackages\"#\n" +
+	"\rNamespaceName\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\xd7\x01\n" +
+	"\tNamespace\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12.\n" +
+	"\x05quota\x18\x03 \x01(\v2\x18.registry.NamespaceQuotaR\x05quota\x12.\n" +
+	"\x05usage\x18\x04 \x01(\v2\x18.registry.NamespaceUsageR\x05usage\x124\n" +
+	"\acreated\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\"P\n" +
+	"\x0eNamespaceQuota\x12\x1b\n" +
+	"\tmax_bytes\x18\x01 \x01(\x03R\bmaxBytes\x12!\n" +
+	"\fmax_versions\x18\x02 \x01(\x03R\vmaxVersions\"g\n" +
+	"\x0eNamespaceUsage\x12\x14\n" +
+	"\x05bytes\x18\x01 \x01(\x03R\x05bytes\x12\x1a\n" +
+	"\bversions\x18\x02 \x01(\x03R\bversions\x12#\n" +
+	"\rtrashed_bytes\x18\x03 \x01(\x03R\ftrashedBytes\"\x10\n" +
+	"\x0eNamespaceQuery\"L\n" +
+	"\x15NamespaceListResponse\x123\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\v2\x13.registry.NamespaceR\n" +
+	"namespaces\"a\n" +
+	"\fDeletedQuery\x12!\n" +
+	"\tnamespace\x18\x01 \x01(\tH\x00R\tnamespace\x88\x01\x01\x12\x17\n" +
+	"\x04name\x18\x02 \x01(\tH\x01R\x04name\x88\x01\x01B\f\n" +
+	"\n" +
+	"_namespaceB\a\n" +
+	"\x05_name\"g\n" +
+	"\x11DeletedIdentifier\x12/\n" +
+	"\apackage\x18\x01 \x01(\v2\x15.registry.PackageNameR\apackage\x12!\n" +
+	"\fversion_hash\x18\x02 \x01(\tR\vversionHash\"\xe0\x01\n" +
+	"\x0fDeletedArtifact\x12.\n" +
+	"\bartifact\x18\x01 \x01(\v2\x12.registry.ArtifactR\bartifact\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\x12\x1d\n" +
+	"\n" +
+	"deleted_by\x18\x03 \x01(\tR\tdeletedBy\x124\n" +
+	"\adeleted\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\adeleted\x124\n" +
+	"\aexpires\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\aexpires\"N\n" +
+	"\x13DeletedListResponse\x127\n" +
+	"\tartifacts\x18\x01 \x03(\v2\x19.registry.DeletedArtifactR\tartifacts\"\x1a\n" +
+	"\x18ReplicationStatusRequest\"J\n" +
+	"\x11ReplicationStatus\x125\n" +
+	"\x05peers\x18\x01 \x03(\v2\x1f.registry.PeerReplicationStatusR\x05peers\"\x81\x02\n" +
+	"\x15PeerReplicationStatus\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\tR\x04peer\x12'\n" +
+	"\x0fpending_changes\x18\x02 \x01(\x03R\x0ependingChanges\x12+\n" +
+	"\x03lag\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x03lag\x12\x1a\n" +
+	"\bfailures\x18\x04 \x01(\x03R\bfailures\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x05 \x01(\tR\tlastError\x12C\n" +
+	"\x0flast_replicated\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0elastReplicated\"\x9c\x03\n" +
+	"\n" +
+	"AuditQuery\x12\x19\n" +
+	"\x05actor\x18\x01 \x01(\tH\x00R\x05actor\x88\x01\x01\x122\n" +
+	"\x06action\x18\x02 \x01(\x0e2\x15.registry.AuditActionH\x01R\x06action\x88\x01\x01\x12!\n" +
+	"\tnamespace\x18\x03 \x01(\tH\x02R\tnamespace\x88\x01\x01\x12\x17\n" +
+	"\x04name\x18\x04 \x01(\tH\x03R\x04name\x88\x01\x01\x12&\n" +
+	"\fversion_hash\x18\x05 \x01(\tH\x04R\vversionHash\x88\x01\x01\x120\n" +
+	"\x05since\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1b\n" +
+	"\tpage_size\x18\b \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\t \x01(\tR\tpageTokenB\b\n" +
+	"\x06_actorB\t\n" +
+	"\a_actionB\f\n" +
+	"\n" +
+	"_namespaceB\a\n" +
+	"\x05_nameB\x0f\n" +
+	"\r_version_hash\"\x98\x03\n" +
+	"\n" +
+	"AuditEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x14\n" +
+	"\x05actor\x18\x03 \x01(\tR\x05actor\x12%\n" +
+	"\x0eclient_address\x18\x04 \x01(\tR\rclientAddress\x12-\n" +
+	"\x06action\x18\x05 \x01(\x0e2\x15.registry.AuditActionR\x06action\x12/\n" +
+	"\apackage\x18\x06 \x01(\v2\x15.registry.PackageNameR\apackage\x12!\n" +
+	"\fversion_hash\x18\a \x01(\tR\vversionHash\x12\x1f\n" +
+	"\vtags_before\x18\b \x03(\tR\n" +
+	"tagsBefore\x12\x1d\n" +
+	"\n" +
+	"tags_after\x18\t \x03(\tR\ttagsAfter\x12\x1c\n" +
+	"\tsucceeded\x18\n" +
+	" \x01(\bR\tsucceeded\x12\x14\n" +
+	"\x05error\x18\v \x01(\tR\x05error\x12\x16\n" +
+	"\x06detail\x18\f \x01(\tR\x06detail\"n\n" +
+	"\x16AuditEventListResponse\x12,\n" +
+	"\x06events\x18\x01 \x03(\v2\x14.registry.AuditEventR\x06events\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xd1\x02\n" +
+	"\x10PullStatsRequest\x12/\n" +
+	"\apackage\x18\x01 \x01(\v2\x15.registry.PackageNameR\apackage\x12&\n" +
+	"\fversion_hash\x18\x02 \x01(\tH\x00R\vversionHash\x88\x01\x01\x127\n" +
+	"\binterval\x18\x03 \x01(\x0e2\x1b.registry.PullStatsIntervalR\binterval\x120\n" +
+	"\x05since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x126\n" +
+	"\bgroup_by\x18\x06 \x01(\x0e2\x1b.registry.PullStatsGroupingR\agroupByB\x0f\n" +
+	"\r_version_hash\"9\n" +
+	"\tPullStats\x12,\n" +
+	"\x06series\x18\x01 \x03(\v2\x14.registry.PullSeriesR\x06series\"c\n" +
+	"\n" +
+	"PullSeries\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12-\n" +
+	"\abuckets\x18\x03 \x03(\v2\x13.registry.PullCountR\abuckets\"S\n" +
+	"\tPullCount\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12\x14\n" +
+	"\x05pulls\x18\x02 \x01(\x03R\x05pulls*6\n" +
+	"\fVersionState\x12\n" +
+	"\n" +
+	"\x06ACTIVE\x10\x00\x12\x0e\n" +
+	"\n" +
+	"DEPRECATED\x10\x01\x12\n" +
+	"\n" +
+	"\x06YANKED\x10\x02*%\n" +
+	"\n" +
+	"Visibility\x12\n" +
+	"\n" +
+	"\x06PUBLIC\x10\x00\x12\v\n" +
+	"\aPRIVATE\x10\x01*\xb1\x01\n" +
+	"\vAuditAction\x12\n" +
+	"\n" +
+	"\x06UPLOAD\x10\x00\x12\n" +
+	"\n" +
+	"\x06DELETE\x10\x01\x12\t\n" +
+	"\x05TRASH\x10\x02\x12\v\n" +
+	"\aRESTORE\x10\x03\x12\t\n" +
+	"\x05PURGE\x10\x04\x12\f\n" +
+	"\bSET_TAGS\x10\x05\x12\x0e\n" +
+	"\n" +
+	"SET_LABELS\x10\x06\x12\r\n" +
+	"\tSET_STATE\x10\a\x12\x10\n" +
+	"\fMOVE_PACKAGE\x10\b\x12\x12\n" +
+	"\x0eDELETE_PACKAGE\x10\t\x12\x14\n" +
+	"\x10DELETE_NAMESPACE\x10\n" +
+	"**\n" +
+	"\x11PullStatsInterval\x12\t\n" +
+	"\x05DAILY\x10\x00\x12\n" +
+	"\n" +
+	"\x06HOURLY\x10\x01*I\n" +
+	"\x11PullStatsGrouping\x12\t\n" +
+	"\x05TOTAL\x10\x00\x12\x0e\n" +
+	"\n" +
+	"BY_VERSION\x10\x01\x12\n" +
+	"\n" +
+	"\x06BY_TAG\x10\x02\x12\r\n" +
+	"\tBY_CLIENT\x10\x032\x9c\x0f\n" +
 	"\x0fRegistryService\x12I\n" +
 	"\x0eQueryArtifacts\x12\x17.registry.ArtifactQuery\x1a\x1e.registry.ArtifactListResponse\x12I\n" +
 	"\fPullArtifact\x12\x1c.registry.ArtifactIdentifier\x1a\x19.registry.ArtifactContent0\x01\x12G\n" +
 	"\x0eUploadArtifact\x12\x1f.registry.UploadArtifactRequest\x1a\x12.registry.Artifact(\x01\x12B\n" +
 	"\x0eDeleteArtifact\x12\x1c.registry.ArtifactIdentifier\x1a\x12.registry.Artifact\x12?\n" +
-	"\vGetArtifact\x12\x1c.registry.ArtifactIdentifier\x1a\x12.registry.Artifact\x127\n" +
-	"\aSetTags\x12\x18.registry.SetTagsRequest\x1a\x12.registry.ArtifactB\fZ\n" +
+	"\vGetArtifact\x12\x1c.registry.ArtifactIdentifier\x1a\x12.registry.Artifact\x12\\\n" +
+	"\x11BatchGetArtifacts\x12\".registry.BatchGetArtifactsRequest\x1a#.registry.BatchGetArtifactsResponse\x127\n" +
+	"\aSetTags\x12\x18.registry.SetTagsRequest\x1a\x12.registry.Artifact\x12V\n" +
+	"\x0fCompareVersions\x12 .registry.CompareVersionsRequest\x1a!.registry.CompareVersionsResponse\x12;\n" +
+	"\tSetLabels\x12\x1a.registry.SetLabelsRequest\x1a\x12.registry.Artifact\x12G\n" +
+	"\x0fSetVersionState\x12 .registry.SetVersionStateRequest\x1a\x12.registry.Artifact\x12A\n" +
+	"\fCopyArtifact\x12\x1d.registry.CopyArtifactRequest\x1a\x12.registry.Artifact\x12D\n" +
+	"\vListDeleted\x12\x16.registry.DeletedQuery\x1a\x1d.registry.DeletedListResponse\x12B\n" +
+	"\x0fRestoreArtifact\x12\x1b.registry.DeletedIdentifier\x1a\x12.registry.Artifact\x12G\n" +
+	"\rPurgeArtifact\x12\x1b.registry.DeletedIdentifier\x1a\x19.registry.DeletedArtifact\x125\n" +
+	"\rCreatePackage\x12\x11.registry.Package\x1a\x11.registry.Package\x126\n" +
+	"\n" +
+	"GetPackage\x12\x15.registry.PackageName\x1a\x11.registry.Package\x12E\n" +
+	"\fListPackages\x12\x16.registry.PackageQuery\x1a\x1d.registry.PackageListResponse\x125\n" +
+	"\rUpdatePackage\x12\x11.registry.Package\x1a\x11.registry.Package\x12B\n" +
+	"\rDeletePackage\x12\x1e.registry.DeletePackageRequest\x1a\x11.registry.Package\x12>\n" +
+	"\vMovePackage\x12\x1c.registry.MovePackageRequest\x1a\x11.registry.Package\x12;\n" +
+	"\x0fCreateNamespace\x12\x13.registry.Namespace\x1a\x13.registry.Namespace\x12<\n" +
+	"\fGetNamespace\x12\x17.registry.NamespaceName\x1a\x13.registry.Namespace\x12K\n" +
+	"\x0eListNamespaces\x12\x18.registry.NamespaceQuery\x1a\x1f.registry.NamespaceListResponse\x12;\n" +
+	"\x0fUpdateNamespace\x12\x13.registry.Namespace\x1a\x13.registry.Namespace\x12?\n" +
+	"\x0fDeleteNamespace\x12\x17.registry.NamespaceName\x1a\x13.registry.Namespace\x12W\n" +
+	"\x14GetReplicationStatus\x12\".registry.ReplicationStatusRequest\x1a\x1b.registry.ReplicationStatus\x12I\n" +
+	"\x0fListAuditEvents\x12\x14.registry.AuditQuery\x1a .registry.AuditEventListResponse\x12?\n" +
+	"\fGetPullStats\x12\x1a.registry.PullStatsRequest\x1a\x13.registry.PullStatsB\fZ\n" +
 	"proto_gen/b\x06proto3"
 
 var (
@@ -673,47 +3908,206 @@ func file_registry_proto_rawDescGZIP() []byte {
 	return file_registry_proto_rawDescData
 }
 
-var file_registry_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_registry_proto_enumTypes = make([]protoimpl.EnumInfo, 7)
+var file_registry_proto_msgTypes = make([]protoimpl.MessageInfo, 52)
 var file_registry_proto_goTypes = []any{
-	(*PackageName)(nil),           // 0: registry.PackageName
-	(*ArtifactIdentifier)(nil),    // 1: registry.ArtifactIdentifier
-	(*Artifact)(nil),              // 2: registry.Artifact
-	(*MetaData)(nil),              // 3: registry.MetaData
-	(*ArtifactQuery)(nil),         // 4: registry.ArtifactQuery
-	(*ArtifactListResponse)(nil),  // 5: registry.ArtifactListResponse
-	(*ArtifactContent)(nil),       // 6: registry.ArtifactContent
-	(*UploadArtifactRequest)(nil), // 7: registry.UploadArtifactRequest
-	(*UploadMetadata)(nil),        // 8: registry.UploadMetadata
-	(*SetTagsRequest)(nil),        // 9: registry.SetTagsRequest
-	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+	(VersionState)(0),                 // 0: registry.VersionState
+	(Visibility)(0),                   // 1: registry.Visibility
+	(AuditAction)(0),                  // 2: registry.AuditAction
+	(PullStatsInterval)(0),            // 3: registry.PullStatsInterval
+	(PullStatsGrouping)(0),            // 4: registry.PullStatsGrouping
+	(LabelSelector_Operator)(0),       // 5: registry.LabelSelector.Operator
+	(InterfaceChange_Type)(0),         // 6: registry.InterfaceChange.Type
+	(*PackageName)(nil),               // 7: registry.PackageName
+	(*ArtifactIdentifier)(nil),        // 8: registry.ArtifactIdentifier
+	(*Artifact)(nil),                  // 9: registry.Artifact
+	(*WasmInterface)(nil),             // 10: registry.WasmInterface
+	(*WasmSymbol)(nil),                // 11: registry.WasmSymbol
+	(*MetaData)(nil),                  // 12: registry.MetaData
+	(*ArtifactQuery)(nil),             // 13: registry.ArtifactQuery
+	(*SymbolFilter)(nil),              // 14: registry.SymbolFilter
+	(*LabelSelector)(nil),             // 15: registry.LabelSelector
+	(*ArtifactListResponse)(nil),      // 16: registry.ArtifactListResponse
+	(*ArtifactContent)(nil),           // 17: registry.ArtifactContent
+	(*UploadArtifactRequest)(nil),     // 18: registry.UploadArtifactRequest
+	(*UploadMetadata)(nil),            // 19: registry.UploadMetadata
+	(*SetTagsRequest)(nil),            // 20: registry.SetTagsRequest
+	(*SetLabelsRequest)(nil),          // 21: registry.SetLabelsRequest
+	(*SetVersionStateRequest)(nil),    // 22: registry.SetVersionStateRequest
+	(*BatchGetArtifactsRequest)(nil),  // 23: registry.BatchGetArtifactsRequest
+	(*BatchGetArtifactsResponse)(nil), // 24: registry.BatchGetArtifactsResponse
+	(*ArtifactResult)(nil),            // 25: registry.ArtifactResult
+	(*ErrorStatus)(nil),               // 26: registry.ErrorStatus
+	(*CopyArtifactRequest)(nil),       // 27: registry.CopyArtifactRequest
+	(*CompareVersionsRequest)(nil),    // 28: registry.CompareVersionsRequest
+	(*CompareVersionsResponse)(nil),   // 29: registry.CompareVersionsResponse
+	(*InterfaceChange)(nil),           // 30: registry.InterfaceChange
+	(*Package)(nil),                   // 31: registry.Package
+	(*DeletePackageRequest)(nil),      // 32: registry.DeletePackageRequest
+	(*MovePackageRequest)(nil),        // 33: registry.MovePackageRequest
+	(*PackageQuery)(nil),              // 34: registry.PackageQuery
+	(*PackageListResponse)(nil),       // 35: registry.PackageListResponse
+	(*NamespaceName)(nil),             // 36: registry.NamespaceName
+	(*Namespace)(nil),                 // 37: registry.Namespace
+	(*NamespaceQuota)(nil),            // 38: registry.NamespaceQuota
+	(*NamespaceUsage)(nil),            // 39: registry.NamespaceUsage
+	(*NamespaceQuery)(nil),            // 40: registry.NamespaceQuery
+	(*NamespaceListResponse)(nil),     // 41: registry.NamespaceListResponse
+	(*DeletedQuery)(nil),              // 42: registry.DeletedQuery
+	(*DeletedIdentifier)(nil),         // 43: registry.DeletedIdentifier
+	(*DeletedArtifact)(nil),           // 44: registry.DeletedArtifact
+	(*DeletedListResponse)(nil),       // 45: registry.DeletedListResponse
+	(*ReplicationStatusRequest)(nil),  // 46: registry.ReplicationStatusRequest
+	(*ReplicationStatus)(nil),         // 47: registry.ReplicationStatus
+	(*PeerReplicationStatus)(nil),     // 48: registry.PeerReplicationStatus
+	(*AuditQuery)(nil),                // 49: registry.AuditQuery
+	(*AuditEvent)(nil),                // 50: registry.AuditEvent
+	(*AuditEventListResponse)(nil),    // 51: registry.AuditEventListResponse
+	(*PullStatsRequest)(nil),          // 52: registry.PullStatsRequest
+	(*PullStats)(nil),                 // 53: registry.PullStats
+	(*PullSeries)(nil),                // 54: registry.PullSeries
+	(*PullCount)(nil),                 // 55: registry.PullCount
+	nil,                               // 56: registry.Artifact.LabelsEntry
+	nil,                               // 57: registry.UploadMetadata.LabelsEntry
+	nil,                               // 58: registry.SetLabelsRequest.LabelsEntry
+	(*timestamppb.Timestamp)(nil),     // 59: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),       // 60: google.protobuf.Duration
 }
 var file_registry_proto_depIdxs = []int32{
-	0,  // 0: registry.ArtifactIdentifier.package:type_name -> registry.PackageName
-	0,  // 1: registry.Artifact.package:type_name -> registry.PackageName
-	3,  // 2: registry.Artifact.metadata:type_name -> registry.MetaData
-	10, // 3: registry.MetaData.created:type_name -> google.protobuf.Timestamp
-	2,  // 4: registry.ArtifactListResponse.artifacts:type_name -> registry.Artifact
-	8,  // 5: registry.UploadArtifactRequest.metadata:type_name -> registry.UploadMetadata
-	6,  // 6: registry.UploadArtifactRequest.content:type_name -> registry.ArtifactContent
-	0,  // 7: registry.UploadMetadata.fqn:type_name -> registry.PackageName
-	1,  // 8: registry.SetTagsRequest.artifact:type_name -> registry.ArtifactIdentifier
-	4,  // 9: registry.RegistryService.QueryArtifacts:input_type -> registry.ArtifactQuery
-	1,  // 10: registry.RegistryService.PullArtifact:input_type -> registry.ArtifactIdentifier
-	7,  // 11: registry.RegistryService.UploadArtifact:input_type -> registry.UploadArtifactRequest
-	1,  // 12: registry.RegistryService.DeleteArtifact:input_type -> registry.ArtifactIdentifier
-	1,  // 13: registry.RegistryService.GetArtifact:input_type -> registry.ArtifactIdentifier
-	9,  // 14: registry.RegistryService.SetTags:input_type -> registry.SetTagsRequest
-	5,  // 15: registry.RegistryService.QueryArtifacts:output_type -> registry.ArtifactListResponse
-	6,  // 16: registry.RegistryService.PullArtifact:output_type -> registry.ArtifactContent
-	2,  // 17: registry.RegistryService.UploadArtifact:output_type -> registry.Artifact
-	2,  // 18: registry.RegistryService.DeleteArtifact:output_type -> registry.Artifact
-	2,  // 19: registry.RegistryService.GetArtifact:output_type -> registry.Artifact
-	2,  // 20: registry.RegistryService.SetTags:output_type -> registry.Artifact
-	15, // [15:21] is the sub-list for method output_type
-	9,  // [9:15] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+	7,   // 0: registry.ArtifactIdentifier.package:type_name -> registry.PackageName
+	7,   // 1: registry.Artifact.package:type_name -> registry.PackageName
+	12,  // 2: registry.Artifact.metadata:type_name -> registry.MetaData
+	10,  // 3: registry.Artifact.interface:type_name -> registry.WasmInterface
+	56,  // 4: registry.Artifact.labels:type_name -> registry.Artifact.LabelsEntry
+	31,  // 5: registry.Artifact.package_info:type_name -> registry.Package
+	0,   // 6: registry.Artifact.state:type_name -> registry.VersionState
+	7,   // 7: registry.Artifact.copied_from:type_name -> registry.PackageName
+	11,  // 8: registry.WasmInterface.imports:type_name -> registry.WasmSymbol
+	11,  // 9: registry.WasmInterface.exports:type_name -> registry.WasmSymbol
+	59,  // 10: registry.MetaData.created:type_name -> google.protobuf.Timestamp
+	59,  // 11: registry.MetaData.last_pulled_at:type_name -> google.protobuf.Timestamp
+	14,  // 12: registry.ArtifactQuery.imports:type_name -> registry.SymbolFilter
+	14,  // 13: registry.ArtifactQuery.exports:type_name -> registry.SymbolFilter
+	15,  // 14: registry.ArtifactQuery.label_selectors:type_name -> registry.LabelSelector
+	5,   // 15: registry.LabelSelector.operator:type_name -> registry.LabelSelector.Operator
+	9,   // 16: registry.ArtifactListResponse.artifacts:type_name -> registry.Artifact
+	19,  // 17: registry.UploadArtifactRequest.metadata:type_name -> registry.UploadMetadata
+	17,  // 18: registry.UploadArtifactRequest.content:type_name -> registry.ArtifactContent
+	7,   // 19: registry.UploadMetadata.fqn:type_name -> registry.PackageName
+	57,  // 20: registry.UploadMetadata.labels:type_name -> registry.UploadMetadata.LabelsEntry
+	8,   // 21: registry.SetTagsRequest.artifact:type_name -> registry.ArtifactIdentifier
+	8,   // 22: registry.SetLabelsRequest.artifact:type_name -> registry.ArtifactIdentifier
+	58,  // 23: registry.SetLabelsRequest.labels:type_name -> registry.SetLabelsRequest.LabelsEntry
+	8,   // 24: registry.SetVersionStateRequest.artifact:type_name -> registry.ArtifactIdentifier
+	0,   // 25: registry.SetVersionStateRequest.state:type_name -> registry.VersionState
+	8,   // 26: registry.BatchGetArtifactsRequest.identifiers:type_name -> registry.ArtifactIdentifier
+	25,  // 27: registry.BatchGetArtifactsResponse.results:type_name -> registry.ArtifactResult
+	9,   // 28: registry.ArtifactResult.artifact:type_name -> registry.Artifact
+	26,  // 29: registry.ArtifactResult.error:type_name -> registry.ErrorStatus
+	8,   // 30: registry.CopyArtifactRequest.source:type_name -> registry.ArtifactIdentifier
+	7,   // 31: registry.CopyArtifactRequest.target:type_name -> registry.PackageName
+	8,   // 32: registry.CompareVersionsRequest.base:type_name -> registry.ArtifactIdentifier
+	8,   // 33: registry.CompareVersionsRequest.candidate:type_name -> registry.ArtifactIdentifier
+	30,  // 34: registry.CompareVersionsResponse.changes:type_name -> registry.InterfaceChange
+	6,   // 35: registry.InterfaceChange.type:type_name -> registry.InterfaceChange.Type
+	11,  // 36: registry.InterfaceChange.before:type_name -> registry.WasmSymbol
+	11,  // 37: registry.InterfaceChange.after:type_name -> registry.WasmSymbol
+	7,   // 38: registry.Package.name:type_name -> registry.PackageName
+	1,   // 39: registry.Package.visibility:type_name -> registry.Visibility
+	59,  // 40: registry.Package.created:type_name -> google.protobuf.Timestamp
+	59,  // 41: registry.Package.updated:type_name -> google.protobuf.Timestamp
+	7,   // 42: registry.DeletePackageRequest.package:type_name -> registry.PackageName
+	7,   // 43: registry.MovePackageRequest.from:type_name -> registry.PackageName
+	7,   // 44: registry.MovePackageRequest.to:type_name -> registry.PackageName
+	31,  // 45: registry.PackageListResponse.packages:type_name -> registry.Package
+	38,  // 46: registry.Namespace.quota:type_name -> registry.NamespaceQuota
+	39,  // 47: registry.Namespace.usage:type_name -> registry.NamespaceUsage
+	59,  // 48: registry.Namespace.created:type_name -> google.protobuf.Timestamp
+	37,  // 49: registry.NamespaceListResponse.namespaces:type_name -> registry.Namespace
+	7,   // 50: registry.DeletedIdentifier.package:type_name -> registry.PackageName
+	9,   // 51: registry.DeletedArtifact.artifact:type_name -> registry.Artifact
+	59,  // 52: registry.DeletedArtifact.deleted:type_name -> google.protobuf.Timestamp
+	59,  // 53: registry.DeletedArtifact.expires:type_name -> google.protobuf.Timestamp
+	44,  // 54: registry.DeletedListResponse.artifacts:type_name -> registry.DeletedArtifact
+	48,  // 55: registry.ReplicationStatus.peers:type_name -> registry.PeerReplicationStatus
+	60,  // 56: registry.PeerReplicationStatus.lag:type_name -> google.protobuf.Duration
+	59,  // 57: registry.PeerReplicationStatus.last_replicated:type_name -> google.protobuf.Timestamp
+	2,   // 58: registry.AuditQuery.action:type_name -> registry.AuditAction
+	59,  // 59: registry.AuditQuery.since:type_name -> google.protobuf.Timestamp
+	59,  // 60: registry.AuditQuery.until:type_name -> google.protobuf.Timestamp
+	59,  // 61: registry.AuditEvent.time:type_name -> google.protobuf.Timestamp
+	2,   // 62: registry.AuditEvent.action:type_name -> registry.AuditAction
+	7,   // 63: registry.AuditEvent.package:type_name -> registry.PackageName
+	50,  // 64: registry.AuditEventListResponse.events:type_name -> registry.AuditEvent
+	7,   // 65: registry.PullStatsRequest.package:type_name -> registry.PackageName
+	3,   // 66: registry.PullStatsRequest.interval:type_name -> registry.PullStatsInterval
+	59,  // 67: registry.PullStatsRequest.since:type_name -> google.protobuf.Timestamp
+	59,  // 68: registry.PullStatsRequest.until:type_name -> google.protobuf.Timestamp
+	4,   // 69: registry.PullStatsRequest.group_by:type_name -> registry.PullStatsGrouping
+	54,  // 70: registry.PullStats.series:type_name -> registry.PullSeries
+	55,  // 71: registry.PullSeries.buckets:type_name -> registry.PullCount
+	59,  // 72: registry.PullCount.start:type_name -> google.protobuf.Timestamp
+	13,  // 73: registry.RegistryService.QueryArtifacts:input_type -> registry.ArtifactQuery
+	8,   // 74: registry.RegistryService.PullArtifact:input_type -> registry.ArtifactIdentifier
+	18,  // 75: registry.RegistryService.UploadArtifact:input_type -> registry.UploadArtifactRequest
+	8,   // 76: registry.RegistryService.DeleteArtifact:input_type -> registry.ArtifactIdentifier
+	8,   // 77: registry.RegistryService.GetArtifact:input_type -> registry.ArtifactIdentifier
+	23,  // 78: registry.RegistryService.BatchGetArtifacts:input_type -> registry.BatchGetArtifactsRequest
+	20,  // 79: registry.RegistryService.SetTags:input_type -> registry.SetTagsRequest
+	28,  // 80: registry.RegistryService.CompareVersions:input_type -> registry.CompareVersionsRequest
+	21,  // 81: registry.RegistryService.SetLabels:input_type -> registry.SetLabelsRequest
+	22,  // 82: registry.RegistryService.SetVersionState:input_type -> registry.SetVersionStateRequest
+	27,  // 83: registry.RegistryService.CopyArtifact:input_type -> registry.CopyArtifactRequest
+	42,  // 84: registry.RegistryService.ListDeleted:input_type -> registry.DeletedQuery
+	43,  // 85: registry.RegistryService.RestoreArtifact:input_type -> registry.DeletedIdentifier
+	43,  // 86: registry.RegistryService.PurgeArtifact:input_type -> registry.DeletedIdentifier
+	31,  // 87: registry.RegistryService.CreatePackage:input_type -> registry.Package
+	7,   // 88: registry.RegistryService.GetPackage:input_type -> registry.PackageName
+	34,  // 89: registry.RegistryService.ListPackages:input_type -> registry.PackageQuery
+	31,  // 90: registry.RegistryService.UpdatePackage:input_type -> registry.Package
+	32,  // 91: registry.RegistryService.DeletePackage:input_type -> registry.DeletePackageRequest
+	33,  // 92: registry.RegistryService.MovePackage:input_type -> registry.MovePackageRequest
+	37,  // 93: registry.RegistryService.CreateNamespace:input_type -> registry.Namespace
+	36,  // 94: registry.RegistryService.GetNamespace:input_type -> registry.NamespaceName
+	40,  // 95: registry.RegistryService.ListNamespaces:input_type -> registry.NamespaceQuery
+	37,  // 96: registry.RegistryService.UpdateNamespace:input_type -> registry.Namespace
+	36,  // 97: registry.RegistryService.DeleteNamespace:input_type -> registry.NamespaceName
+	46,  // 98: registry.RegistryService.GetReplicationStatus:input_type -> registry.ReplicationStatusRequest
+	49,  // 99: registry.RegistryService.ListAuditEvents:input_type -> registry.AuditQuery
+	52,  // 100: registry.RegistryService.GetPullStats:input_type -> registry.PullStatsRequest
+	16,  // 101: registry.RegistryService.QueryArtifacts:output_type -> registry.ArtifactListResponse
+	17,  // 102: registry.RegistryService.PullArtifact:output_type -> registry.ArtifactContent
+	9,   // 103: registry.RegistryService.UploadArtifact:output_type -> registry.Artifact
+	9,   // 104: registry.RegistryService.DeleteArtifact:output_type -> registry.Artifact
+	9,   // 105: registry.RegistryService.GetArtifact:output_type -> registry.Artifact
+	24,  // 106: registry.RegistryService.BatchGetArtifacts:output_type -> registry.BatchGetArtifactsResponse
+	9,   // 107: registry.RegistryService.SetTags:output_type -> registry.Artifact
+	29,  // 108: registry.RegistryService.CompareVersions:output_type -> registry.CompareVersionsResponse
+	9,   // 109: registry.RegistryService.SetLabels:output_type -> registry.Artifact
+	9,   // 110: registry.RegistryService.SetVersionState:output_type -> registry.Artifact
+	9,   // 111: registry.RegistryService.CopyArtifact:output_type -> registry.Artifact
+	45,  // 112: registry.RegistryService.ListDeleted:output_type -> registry.DeletedListResponse
+	9,   // 113: registry.RegistryService.RestoreArtifact:output_type -> registry.Artifact
+	44,  // 114: registry.RegistryService.PurgeArtifact:output_type -> registry.DeletedArtifact
+	31,  // 115: registry.RegistryService.CreatePackage:output_type -> registry.Package
+	31,  // 116: registry.RegistryService.GetPackage:output_type -> registry.Package
+	35,  // 117: registry.RegistryService.ListPackages:output_type -> registry.PackageListResponse
+	31,  // 118: registry.RegistryService.UpdatePackage:output_type -> registry.Package
+	31,  // 119: registry.RegistryService.DeletePackage:output_type -> registry.Package
+	31,  // 120: registry.RegistryService.MovePackage:output_type -> registry.Package
+	37,  // 121: registry.RegistryService.CreateNamespace:output_type -> registry.Namespace
+	37,  // 122: registry.RegistryService.GetNamespace:output_type -> registry.Namespace
+	41,  // 123: registry.RegistryService.ListNamespaces:output_type -> registry.NamespaceListResponse
+	37,  // 124: registry.RegistryService.UpdateNamespace:output_type -> registry.Namespace
+	37,  // 125: registry.RegistryService.DeleteNamespace:output_type -> registry.Namespace
+	47,  // 126: registry.RegistryService.GetReplicationStatus:output_type -> registry.ReplicationStatus
+	51,  // 127: registry.RegistryService.ListAuditEvents:output_type -> registry.AuditEventListResponse
+	53,  // 128: registry.RegistryService.GetPullStats:output_type -> registry.PullStats
+	101, // [101:129] is the sub-list for method output_type
+	73,  // [73:101] is the sub-list for method input_type
+	73,  // [73:73] is the sub-list for extension type_name
+	73,  // [73:73] is the sub-list for extension extendee
+	0,   // [0:73] is the sub-list for field type_name
 }
 
 func init() { file_registry_proto_init() }
@@ -725,23 +4119,33 @@ func file_registry_proto_init() {
 		(*ArtifactIdentifier_VersionHash)(nil),
 		(*ArtifactIdentifier_Tag)(nil),
 	}
-	file_registry_proto_msgTypes[4].OneofWrappers = []any{}
-	file_registry_proto_msgTypes[7].OneofWrappers = []any{
+	file_registry_proto_msgTypes[6].OneofWrappers = []any{}
+	file_registry_proto_msgTypes[7].OneofWrappers = []any{}
+	file_registry_proto_msgTypes[11].OneofWrappers = []any{
 		(*UploadArtifactRequest_Metadata)(nil),
 		(*UploadArtifactRequest_Content)(nil),
 	}
+	file_registry_proto_msgTypes[18].OneofWrappers = []any{
+		(*ArtifactResult_Artifact)(nil),
+		(*ArtifactResult_Error)(nil),
+	}
+	file_registry_proto_msgTypes[27].OneofWrappers = []any{}
+	file_registry_proto_msgTypes[35].OneofWrappers = []any{}
+	file_registry_proto_msgTypes[42].OneofWrappers = []any{}
+	file_registry_proto_msgTypes[45].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_registry_proto_rawDesc), len(file_registry_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   10,
+			NumEnums:      7,
+			NumMessages:   52,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_registry_proto_goTypes,
 		DependencyIndexes: file_registry_proto_depIdxs,
+		EnumInfos:         file_registry_proto_enumTypes,
 		MessageInfos:      file_registry_proto_msgTypes,
 	}.Build()
 	File_registry_proto = out.File
@@ -19,12 +19,34 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	RegistryService_QueryArtifacts_FullMethodName = "/registry.RegistryService/QueryArtifacts"
-	RegistryService_PullArtifact_FullMethodName   = "/registry.RegistryService/PullArtifact"
-	RegistryService_UploadArtifact_FullMethodName = "/registry.RegistryService/UploadArtifact"
-	RegistryService_DeleteArtifact_FullMethodName = "/registry.RegistryService/DeleteArtifact"
-	RegistryService_GetArtifact_FullMethodName    = "/registry.RegistryService/GetArtifact"
-	RegistryService_SetTags_FullMethodName        = "/registry.RegistryService/SetTags"
+	RegistryService_QueryArtifacts_FullMethodName       = "/registry.RegistryService/QueryArtifacts"
+	RegistryService_PullArtifact_FullMethodName         = "/registry.RegistryService/PullArtifact"
+	RegistryService_UploadArtifact_FullMethodName       = "/registry.RegistryService/UploadArtifact"
+	RegistryService_DeleteArtifact_FullMethodName       = "/registry.RegistryService/DeleteArtifact"
+	RegistryService_GetArtifact_FullMethodName          = "/registry.RegistryService/GetArtifact"
+	RegistryService_BatchGetArtifacts_FullMethodName    = "/registry.RegistryService/BatchGetArtifacts"
+	RegistryService_SetTags_FullMethodName              = "/registry.RegistryService/SetTags"
+	RegistryService_CompareVersions_FullMethodName      = "/registry.RegistryService/CompareVersions"
+	RegistryService_SetLabels_FullMethodName            = "/registry.RegistryService/SetLabels"
+	RegistryService_SetVersionState_FullMethodName      = "/registry.RegistryService/SetVersionState"
+	RegistryService_CopyArtifact_FullMethodName         = "/registry.RegistryService/CopyArtifact"
+	RegistryService_ListDeleted_FullMethodName          = "/registry.RegistryService/ListDeleted"
+	RegistryService_RestoreArtifact_FullMethodName      = "/registry.RegistryService/RestoreArtifact"
+	RegistryService_PurgeArtifact_FullMethodName        = "/registry.RegistryService/PurgeArtifact"
+	RegistryService_CreatePackage_FullMethodName        = "/registry.RegistryService/CreatePackage"
+	RegistryService_GetPackage_FullMethodName           = "/registry.RegistryService/GetPackage"
+	RegistryService_ListPackages_FullMethodName         = "/registry.RegistryService/ListPackages"
+	RegistryService_UpdatePackage_FullMethodName        = "/registry.RegistryService/UpdatePackage"
+	RegistryService_DeletePackage_FullMethodName        = "/registry.RegistryService/DeletePackage"
+	RegistryService_MovePackage_FullMethodName          = "/registry.RegistryService/MovePackage"
+	RegistryService_CreateNamespace_FullMethodName      = "/registry.RegistryService/CreateNamespace"
+	RegistryService_GetNamespace_FullMethodName         = "/registry.RegistryService/GetNamespace"
+	RegistryService_ListNamespaces_FullMethodName       = "/registry.RegistryService/ListNamespaces"
+	RegistryService_UpdateNamespace_FullMethodName      = "/registry.RegistryService/UpdateNamespace"
+	RegistryService_DeleteNamespace_FullMethodName      = "/registry.RegistryService/DeleteNamespace"
+	RegistryService_GetReplicationStatus_FullMethodName = "/registry.RegistryService/GetReplicationStatus"
+	RegistryService_ListAuditEvents_FullMethodName      = "/registry.RegistryService/ListAuditEvents"
+	RegistryService_GetPullStats_FullMethodName         = "/registry.RegistryService/GetPullStats"
 )
 
 // RegistryServiceClient is the client API for RegistryService service.
@@ -34,9 +56,46 @@ type RegistryServiceClient interface {
 	QueryArtifacts(ctx context.Context, in *ArtifactQuery, opts ...grpc.CallOption) (*ArtifactListResponse, error)
 	PullArtifact(ctx context.Context, in *ArtifactIdentifier, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArtifactContent], error)
 	UploadArtifact(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadArtifactRequest, Artifact], error)
+	// Moves the version into the trash, unless the trash is disabled
 	DeleteArtifact(ctx context.Context, in *ArtifactIdentifier, opts ...grpc.CallOption) (*Artifact, error)
 	GetArtifact(ctx context.Context, in *ArtifactIdentifier, opts ...grpc.CallOption) (*Artifact, error)
+	// Resolves many identifiers in one call. Failures are reported per item.
+	BatchGetArtifacts(ctx context.Context, in *BatchGetArtifactsRequest, opts ...grpc.CallOption) (*BatchGetArtifactsResponse, error)
 	SetTags(ctx context.Context, in *SetTagsRequest, opts ...grpc.CallOption) (*Artifact, error)
+	CompareVersions(ctx context.Context, in *CompareVersionsRequest, opts ...grpc.CallOption) (*CompareVersionsResponse, error)
+	SetLabels(ctx context.Context, in *SetLabelsRequest, opts ...grpc.CallOption) (*Artifact, error)
+	SetVersionState(ctx context.Context, in *SetVersionStateRequest, opts ...grpc.CallOption) (*Artifact, error)
+	// Copies a version into another package, e.g. to promote it to another
+	// namespace. The content is shared with the source where the storage
+	// backend allows it.
+	CopyArtifact(ctx context.Context, in *CopyArtifactRequest, opts ...grpc.CallOption) (*Artifact, error)
+	ListDeleted(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (*DeletedListResponse, error)
+	// Tags that were assigned to another version in the meantime stay there
+	RestoreArtifact(ctx context.Context, in *DeletedIdentifier, opts ...grpc.CallOption) (*Artifact, error)
+	// Removes a version from the trash for good
+	PurgeArtifact(ctx context.Context, in *DeletedIdentifier, opts ...grpc.CallOption) (*DeletedArtifact, error)
+	CreatePackage(ctx context.Context, in *Package, opts ...grpc.CallOption) (*Package, error)
+	GetPackage(ctx context.Context, in *PackageName, opts ...grpc.CallOption) (*Package, error)
+	ListPackages(ctx context.Context, in *PackageQuery, opts ...grpc.CallOption) (*PackageListResponse, error)
+	// Replaces all mutable fields of an existing package
+	UpdatePackage(ctx context.Context, in *Package, opts ...grpc.CallOption) (*Package, error)
+	DeletePackage(ctx context.Context, in *DeletePackageRequest, opts ...grpc.CallOption) (*Package, error)
+	// Moves all versions, tags and the package record to another name, which
+	// may be in another namespace. The target must not exist yet.
+	MovePackage(ctx context.Context, in *MovePackageRequest, opts ...grpc.CallOption) (*Package, error)
+	CreateNamespace(ctx context.Context, in *Namespace, opts ...grpc.CallOption) (*Namespace, error)
+	GetNamespace(ctx context.Context, in *NamespaceName, opts ...grpc.CallOption) (*Namespace, error)
+	ListNamespaces(ctx context.Context, in *NamespaceQuery, opts ...grpc.CallOption) (*NamespaceListResponse, error)
+	// Replaces the description and quota of an existing namespace
+	UpdateNamespace(ctx context.Context, in *Namespace, opts ...grpc.CallOption) (*Namespace, error)
+	// Deletes the namespace with all of its packages and versions
+	DeleteNamespace(ctx context.Context, in *NamespaceName, opts ...grpc.CallOption) (*Namespace, error)
+	// Shows how far each configured peer lags behind this registry
+	GetReplicationStatus(ctx context.Context, in *ReplicationStatusRequest, opts ...grpc.CallOption) (*ReplicationStatus, error)
+	// Uploads, deletions and tag changes of versions, newest first
+	ListAuditEvents(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditEventListResponse, error)
+	// Pulls of a package or a single version over time
+	GetPullStats(ctx context.Context, in *PullStatsRequest, opts ...grpc.CallOption) (*PullStats, error)
 }
 
 type registryServiceClient struct {
@@ -109,6 +168,16 @@ func (c *registryServiceClient) GetArtifact(ctx context.Context, in *ArtifactIde
 	return out, nil
 }
 
+func (c *registryServiceClient) BatchGetArtifacts(ctx context.Context, in *BatchGetArtifactsRequest, opts ...grpc.CallOption) (*BatchGetArtifactsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetArtifactsResponse)
+	err := c.cc.Invoke(ctx, RegistryService_BatchGetArtifacts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *registryServiceClient) SetTags(ctx context.Context, in *SetTagsRequest, opts ...grpc.CallOption) (*Artifact, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Artifact)
@@ -119,6 +188,216 @@ func (c *registryServiceClient) SetTags(ctx context.Context, in *SetTagsRequest,
 	return out, nil
 }
 
+func (c *registryServiceClient) CompareVersions(ctx context.Context, in *CompareVersionsRequest, opts ...grpc.CallOption) (*CompareVersionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompareVersionsResponse)
+	err := c.cc.Invoke(ctx, RegistryService_CompareVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) SetLabels(ctx context.Context, in *SetLabelsRequest, opts ...grpc.CallOption) (*Artifact, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Artifact)
+	err := c.cc.Invoke(ctx, RegistryService_SetLabels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) SetVersionState(ctx context.Context, in *SetVersionStateRequest, opts ...grpc.CallOption) (*Artifact, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Artifact)
+	err := c.cc.Invoke(ctx, RegistryService_SetVersionState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) CopyArtifact(ctx context.Context, in *CopyArtifactRequest, opts ...grpc.CallOption) (*Artifact, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Artifact)
+	err := c.cc.Invoke(ctx, RegistryService_CopyArtifact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) ListDeleted(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (*DeletedListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletedListResponse)
+	err := c.cc.Invoke(ctx, RegistryService_ListDeleted_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) RestoreArtifact(ctx context.Context, in *DeletedIdentifier, opts ...grpc.CallOption) (*Artifact, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Artifact)
+	err := c.cc.Invoke(ctx, RegistryService_RestoreArtifact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) PurgeArtifact(ctx context.Context, in *DeletedIdentifier, opts ...grpc.CallOption) (*DeletedArtifact, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletedArtifact)
+	err := c.cc.Invoke(ctx, RegistryService_PurgeArtifact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) CreatePackage(ctx context.Context, in *Package, opts ...grpc.CallOption) (*Package, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Package)
+	err := c.cc.Invoke(ctx, RegistryService_CreatePackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) GetPackage(ctx context.Context, in *PackageName, opts ...grpc.CallOption) (*Package, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Package)
+	err := c.cc.Invoke(ctx, RegistryService_GetPackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) ListPackages(ctx context.Context, in *PackageQuery, opts ...grpc.CallOption) (*PackageListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PackageListResponse)
+	err := c.cc.Invoke(ctx, RegistryService_ListPackages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) UpdatePackage(ctx context.Context, in *Package, opts ...grpc.CallOption) (*Package, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Package)
+	err := c.cc.Invoke(ctx, RegistryService_UpdatePackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) DeletePackage(ctx context.Context, in *DeletePackageRequest, opts ...grpc.CallOption) (*Package, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Package)
+	err := c.cc.Invoke(ctx, RegistryService_DeletePackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) MovePackage(ctx context.Context, in *MovePackageRequest, opts ...grpc.CallOption) (*Package, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Package)
+	err := c.cc.Invoke(ctx, RegistryService_MovePackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) CreateNamespace(ctx context.Context, in *Namespace, opts ...grpc.CallOption) (*Namespace, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, RegistryService_CreateNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) GetNamespace(ctx context.Context, in *NamespaceName, opts ...grpc.CallOption) (*Namespace, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, RegistryService_GetNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) ListNamespaces(ctx context.Context, in *NamespaceQuery, opts ...grpc.CallOption) (*NamespaceListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NamespaceListResponse)
+	err := c.cc.Invoke(ctx, RegistryService_ListNamespaces_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) UpdateNamespace(ctx context.Context, in *Namespace, opts ...grpc.CallOption) (*Namespace, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, RegistryService_UpdateNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) DeleteNamespace(ctx context.Context, in *NamespaceName, opts ...grpc.CallOption) (*Namespace, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Namespace)
+	err := c.cc.Invoke(ctx, RegistryService_DeleteNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) GetReplicationStatus(ctx context.Context, in *ReplicationStatusRequest, opts ...grpc.CallOption) (*ReplicationStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReplicationStatus)
+	err := c.cc.Invoke(ctx, RegistryService_GetReplicationStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) ListAuditEvents(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditEventListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuditEventListResponse)
+	err := c.cc.Invoke(ctx, RegistryService_ListAuditEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryServiceClient) GetPullStats(ctx context.Context, in *PullStatsRequest, opts ...grpc.CallOption) (*PullStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PullStats)
+	err := c.cc.Invoke(ctx, RegistryService_GetPullStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RegistryServiceServer is the server API for RegistryService service.
 // All implementations must embed UnimplementedRegistryServiceServer
 // for forward compatibility.
@@ -126,9 +405,46 @@ type RegistryServiceServer interface {
 	QueryArtifacts(context.Context, *ArtifactQuery) (*ArtifactListResponse, error)
 	PullArtifact(*ArtifactIdentifier, grpc.ServerStreamingServer[ArtifactContent]) error
 	UploadArtifact(grpc.ClientStreamingServer[UploadArtifactRequest, Artifact]) error
+	// Moves the version into the trash, unless the trash is disabled
 	DeleteArtifact(context.Context, *ArtifactIdentifier) (*Artifact, error)
 	GetArtifact(context.Context, *ArtifactIdentifier) (*Artifact, error)
+	// Resolves many identifiers in one call. Failures are reported per item.
+	BatchGetArtifacts(context.Context, *BatchGetArtifactsRequest) (*BatchGetArtifactsResponse, error)
 	SetTags(context.Context, *SetTagsRequest) (*Artifact, error)
+	CompareVersions(context.Context, *CompareVersionsRequest) (*CompareVersionsResponse, error)
+	SetLabels(context.Context, *SetLabelsRequest) (*Artifact, error)
+	SetVersionState(context.Context, *SetVersionStateRequest) (*Artifact, error)
+	// Copies a version into another package, e.g. to promote it to another
+	// namespace. The content is shared with the source where the storage
+	// backend allows it.
+	CopyArtifact(context.Context, *CopyArtifactRequest) (*Artifact, error)
+	ListDeleted(context.Context, *DeletedQuery) (*DeletedListResponse, error)
+	// Tags that were assigned to another version in the meantime stay there
+	RestoreArtifact(context.Context, *DeletedIdentifier) (*Artifact, error)
+	// Removes a version from the trash for good
+	PurgeArtifact(context.Context, *DeletedIdentifier) (*DeletedArtifact, error)
+	CreatePackage(context.Context, *Package) (*Package, error)
+	GetPackage(context.Context, *PackageName) (*Package, error)
+	ListPackages(context.Context, *PackageQuery) (*PackageListResponse, error)
+	// Replaces all mutable fields of an existing package
+	UpdatePackage(context.Context, *Package) (*Package, error)
+	DeletePackage(context.Context, *DeletePackageRequest) (*Package, error)
+	// Moves all versions, tags and the package record to another name, which
+	// may be in another namespace. The target must not exist yet.
+	MovePackage(context.Context, *MovePackageRequest) (*Package, error)
+	CreateNamespace(context.Context, *Namespace) (*Namespace, error)
+	GetNamespace(context.Context, *NamespaceName) (*Namespace, error)
+	ListNamespaces(context.Context, *NamespaceQuery) (*NamespaceListResponse, error)
+	// Replaces the description and quota of an existing namespace
+	UpdateNamespace(context.Context, *Namespace) (*Namespace, error)
+	// Deletes the namespace with all of its packages and versions
+	DeleteNamespace(context.Context, *NamespaceName) (*Namespace, error)
+	// Shows how far each configured peer lags behind this registry
+	GetReplicationStatus(context.Context, *ReplicationStatusRequest) (*ReplicationStatus, error)
+	// Uploads, deletions and tag changes of versions, newest first
+	ListAuditEvents(context.Context, *AuditQuery) (*AuditEventListResponse, error)
+	// Pulls of a package or a single version over time
+	GetPullStats(context.Context, *PullStatsRequest) (*PullStats, error)
 	mustEmbedUnimplementedRegistryServiceServer()
 }
 
@@ -154,9 +470,75 @@ func (UnimplementedRegistryServiceServer) DeleteArtifact(context.Context, *Artif
 func (UnimplementedRegistryServiceServer) GetArtifact(context.Context, *ArtifactIdentifier) (*Artifact, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetArtifact not implemented")
 }
+func (UnimplementedRegistryServiceServer) BatchGetArtifacts(context.Context, *BatchGetArtifactsRequest) (*BatchGetArtifactsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetArtifacts not implemented")
+}
 func (UnimplementedRegistryServiceServer) SetTags(context.Context, *SetTagsRequest) (*Artifact, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetTags not implemented")
 }
+func (UnimplementedRegistryServiceServer) CompareVersions(context.Context, *CompareVersionsRequest) (*CompareVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareVersions not implemented")
+}
+func (UnimplementedRegistryServiceServer) SetLabels(context.Context, *SetLabelsRequest) (*Artifact, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLabels not implemented")
+}
+func (UnimplementedRegistryServiceServer) SetVersionState(context.Context, *SetVersionStateRequest) (*Artifact, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetVersionState not implemented")
+}
+func (UnimplementedRegistryServiceServer) CopyArtifact(context.Context, *CopyArtifactRequest) (*Artifact, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CopyArtifact not implemented")
+}
+func (UnimplementedRegistryServiceServer) ListDeleted(context.Context, *DeletedQuery) (*DeletedListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeleted not implemented")
+}
+func (UnimplementedRegistryServiceServer) RestoreArtifact(context.Context, *DeletedIdentifier) (*Artifact, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreArtifact not implemented")
+}
+func (UnimplementedRegistryServiceServer) PurgeArtifact(context.Context, *DeletedIdentifier) (*DeletedArtifact, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeArtifact not implemented")
+}
+func (UnimplementedRegistryServiceServer) CreatePackage(context.Context, *Package) (*Package, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePackage not implemented")
+}
+func (UnimplementedRegistryServiceServer) GetPackage(context.Context, *PackageName) (*Package, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPackage not implemented")
+}
+func (UnimplementedRegistryServiceServer) ListPackages(context.Context, *PackageQuery) (*PackageListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPackages not implemented")
+}
+func (UnimplementedRegistryServiceServer) UpdatePackage(context.Context, *Package) (*Package, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePackage not implemented")
+}
+func (UnimplementedRegistryServiceServer) DeletePackage(context.Context, *DeletePackageRequest) (*Package, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePackage not implemented")
+}
+func (UnimplementedRegistryServiceServer) MovePackage(context.Context, *MovePackageRequest) (*Package, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MovePackage not implemented")
+}
+func (UnimplementedRegistryServiceServer) CreateNamespace(context.Context, *Namespace) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateNamespace not implemented")
+}
+func (UnimplementedRegistryServiceServer) GetNamespace(context.Context, *NamespaceName) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNamespace not implemented")
+}
+func (UnimplementedRegistryServiceServer) ListNamespaces(context.Context, *NamespaceQuery) (*NamespaceListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNamespaces not implemented")
+}
+func (UnimplementedRegistryServiceServer) UpdateNamespace(context.Context, *Namespace) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateNamespace not implemented")
+}
+func (UnimplementedRegistryServiceServer) DeleteNamespace(context.Context, *NamespaceName) (*Namespace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNamespace not implemented")
+}
+func (UnimplementedRegistryServiceServer) GetReplicationStatus(context.Context, *ReplicationStatusRequest) (*ReplicationStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReplicationStatus not implemented")
+}
+func (UnimplementedRegistryServiceServer) ListAuditEvents(context.Context, *AuditQuery) (*AuditEventListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedRegistryServiceServer) GetPullStats(context.Context, *PullStatsRequest) (*PullStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPullStats not implemented")
+}
 func (UnimplementedRegistryServiceServer) mustEmbedUnimplementedRegistryServiceServer() {}
 func (UnimplementedRegistryServiceServer) testEmbeddedByValue()                         {}
 
@@ -250,6 +632,24 @@ func _RegistryService_GetArtifact_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegistryService_BatchGetArtifacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetArtifactsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).BatchGetArtifacts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_BatchGetArtifacts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).BatchGetArtifacts(ctx, req.(*BatchGetArtifactsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RegistryService_SetTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetTagsRequest)
 	if err := dec(in); err != nil {
@@ -268,6 +668,384 @@ func _RegistryService_SetTags_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegistryService_CompareVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).CompareVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_CompareVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).CompareVersions(ctx, req.(*CompareVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_SetLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLabelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).SetLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_SetLabels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).SetLabels(ctx, req.(*SetLabelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_SetVersionState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetVersionStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).SetVersionState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_SetVersionState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).SetVersionState(ctx, req.(*SetVersionStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_CopyArtifact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyArtifactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).CopyArtifact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_CopyArtifact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).CopyArtifact(ctx, req.(*CopyArtifactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_ListDeleted_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletedQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).ListDeleted(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_ListDeleted_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).ListDeleted(ctx, req.(*DeletedQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_RestoreArtifact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletedIdentifier)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).RestoreArtifact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_RestoreArtifact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).RestoreArtifact(ctx, req.(*DeletedIdentifier))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_PurgeArtifact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletedIdentifier)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).PurgeArtifact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_PurgeArtifact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).PurgeArtifact(ctx, req.(*DeletedIdentifier))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_CreatePackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Package)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).CreatePackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_CreatePackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).CreatePackage(ctx, req.(*Package))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_GetPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PackageName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).GetPackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_GetPackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).GetPackage(ctx, req.(*PackageName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_ListPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PackageQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).ListPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_ListPackages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).ListPackages(ctx, req.(*PackageQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_UpdatePackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Package)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).UpdatePackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_UpdatePackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).UpdatePackage(ctx, req.(*Package))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_DeletePackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).DeletePackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_DeletePackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).DeletePackage(ctx, req.(*DeletePackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_MovePackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MovePackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).MovePackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_MovePackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).MovePackage(ctx, req.(*MovePackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_CreateNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Namespace)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).CreateNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_CreateNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).CreateNamespace(ctx, req.(*Namespace))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_GetNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).GetNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_GetNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).GetNamespace(ctx, req.(*NamespaceName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_ListNamespaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).ListNamespaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_ListNamespaces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).ListNamespaces(ctx, req.(*NamespaceQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_UpdateNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Namespace)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).UpdateNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_UpdateNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).UpdateNamespace(ctx, req.(*Namespace))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_DeleteNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NamespaceName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).DeleteNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_DeleteNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).DeleteNamespace(ctx, req.(*NamespaceName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_GetReplicationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplicationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).GetReplicationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_GetReplicationStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).GetReplicationStatus(ctx, req.(*ReplicationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_ListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).ListAuditEvents(ctx, req.(*AuditQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistryService_GetPullStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PullStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServiceServer).GetPullStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegistryService_GetPullStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServiceServer).GetPullStats(ctx, req.(*PullStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RegistryService_ServiceDesc is the grpc.ServiceDesc for RegistryService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -287,10 +1065,98 @@ var RegistryService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetArtifact",
 			Handler:    _RegistryService_GetArtifact_Handler,
 		},
+		{
+			MethodName: "BatchGetArtifacts",
+			Handler:    _RegistryService_BatchGetArtifacts_Handler,
+		},
 		{
 			MethodName: "SetTags",
 			Handler:    _RegistryService_SetTags_Handler,
 		},
+		{
+			MethodName: "CompareVersions",
+			Handler:    _RegistryService_CompareVersions_Handler,
+		},
+		{
+			MethodName: "SetLabels",
+			Handler:    _RegistryService_SetLabels_Handler,
+		},
+		{
+			MethodName: "SetVersionState",
+			Handler:    _RegistryService_SetVersionState_Handler,
+		},
+		{
+			MethodName: "CopyArtifact",
+			Handler:    _RegistryService_CopyArtifact_Handler,
+		},
+		{
+			MethodName: "ListDeleted",
+			Handler:    _RegistryService_ListDeleted_Handler,
+		},
+		{
+			MethodName: "RestoreArtifact",
+			Handler:    _RegistryService_RestoreArtifact_Handler,
+		},
+		{
+			MethodName: "PurgeArtifact",
+			Handler:    _RegistryService_PurgeArtifact_Handler,
+		},
+		{
+			MethodName: "CreatePackage",
+			Handler:    _RegistryService_CreatePackage_Handler,
+		},
+		{
+			MethodName: "GetPackage",
+			Handler:    _RegistryService_GetPackage_Handler,
+		},
+		{
+			MethodName: "ListPackages",
+			Handler:    _RegistryService_ListPackages_Handler,
+		},
+		{
+			MethodName: "UpdatePackage",
+			Handler:    _RegistryService_UpdatePackage_Handler,
+		},
+		{
+			MethodName: "DeletePackage",
+			Handler:    _RegistryService_DeletePackage_Handler,
+		},
+		{
+			MethodName: "MovePackage",
+			Handler:    _RegistryService_MovePackage_Handler,
+		},
+		{
+			MethodName: "CreateNamespace",
+			Handler:    _RegistryService_CreateNamespace_Handler,
+		},
+		{
+			MethodName: "GetNamespace",
+			Handler:    _RegistryService_GetNamespace_Handler,
+		},
+		{
+			MethodName: "ListNamespaces",
+			Handler:    _RegistryService_ListNamespaces_Handler,
+		},
+		{
+			MethodName: "UpdateNamespace",
+			Handler:    _RegistryService_UpdateNamespace_Handler,
+		},
+		{
+			MethodName: "DeleteNamespace",
+			Handler:    _RegistryService_DeleteNamespace_Handler,
+		},
+		{
+			MethodName: "GetReplicationStatus",
+			Handler:    _RegistryService_GetReplicationStatus_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _RegistryService_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "GetPullStats",
+			Handler:    _RegistryService_GetPullStats_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -9,9 +9,11 @@ import (
 	"slices"
 	"time"
 
+	"github.com/EnclaveRunner/shareddeps/auth"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -34,15 +36,24 @@ func (s *Server) QueryArtifacts(
 		return &proto_gen.ArtifactListResponse{}, nil
 	}
 
-	pkg := &proto_gen.PackageName{}
-	if query.Namespace != nil {
-		pkg.Namespace = *query.Namespace
+	labels, err := labelSelectors(query.LabelSelectors)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid label selector in query")
+
+		return nil, err
 	}
-	if query.Name != nil {
-		pkg.Name = *query.Name
+
+	filter := orm.ArtifactFilter{
+		Namespace: query.GetNamespace(),
+		Name:      query.GetName(),
+		Labels:    labels,
 	}
+	filter.Symbols = append(
+		symbolFilters(orm.SymbolImport, query.Imports),
+		symbolFilters(orm.SymbolExport, query.Exports)...,
+	)
 
-	artifacts, err := s.db.GetArtifactMetasByFQN(ctx, pkg)
+	artifacts, err := s.db.QueryArtifactMetas(ctx, filter)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to query artifacts")
 
@@ -50,20 +61,13 @@ func (s *Server) QueryArtifacts(
 	}
 
 	// Convert []orm.Artifact to []*proto_gen.Artifact
+	packages := s.packageInfos(ctx, artifacts)
 	protoArtifacts := make([]*proto_gen.Artifact, 0, len(artifacts))
-	for _, a := range artifacts {
-		protoArtifacts = append(protoArtifacts, &proto_gen.Artifact{
-			Package: &proto_gen.PackageName{
-				Namespace: a.Namespace,
-				Name:      a.Name,
-			},
-			VersionHash: a.Hash,
-			Tags:        tagsToStrings(a.Tags),
-			Metadata: &proto_gen.MetaData{
-				Created: timestamppb.New(a.CreatedAt),
-				Pulls:   a.PullsCount,
-			},
-		})
+	for i := range artifacts {
+		artifact := artifactToProto(&artifacts[i])
+		artifact.PackageInfo = packages[artifacts[i].Namespace+"/"+
+			artifacts[i].Name]
+		protoArtifacts = append(protoArtifacts, artifact)
 	}
 
 	return &proto_gen.ArtifactListResponse{
@@ -121,35 +125,37 @@ func (s *Server) PullArtifact(
 		return newRegistryUnavailableError("artifact pull")
 	}
 
-	var artifactMeta *orm.Artifact
-
-	switch identifier := req.Identifier.(type) {
-	case *proto_gen.ArtifactIdentifier_VersionHash:
-		artifactMeta, err = s.db.GetArtifactMetaByHash(
-			serv.Context(),
-			req.Package,
-			identifier.VersionHash,
-		)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to get artifact by version hash")
+	artifactMeta, err := s.resolveReadIdentifier(serv.Context(), req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve artifact for pull")
 
-			return wrapServiceError(err, "retrieving artifact by version hash")
-		}
-	case *proto_gen.ArtifactIdentifier_Tag:
-		artifactMeta, err = s.db.GetArtifactMetaByTag(
-			serv.Context(),
-			req.Package,
-			identifier.Tag,
-		)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to get artifact by tag")
+		return err // Already wrapped by resolveReadIdentifier
+	}
+	// Differs from the requested package if that has been moved
+	pkg := &proto_gen.PackageName{
+		Namespace: artifactMeta.Namespace,
+		Name:      artifactMeta.Name,
+	}
 
-			return wrapServiceError(err, "retrieving artifact by tag")
+	if warning := versionWarning(artifactMeta); warning != nil {
+		if err := serv.SetHeader(warning); err != nil {
+			log.Warn().Err(err).Msg("Failed to set version warning header")
 		}
 	}
 
 	// Get the artifact from the registry
-	content, err := s.registry.GetArtifact(req.Package, artifactMeta.Hash)
+	content, err := s.registry.GetArtifact(serv.Context(), pkg, artifactMeta.Hash)
+	if err != nil && s.proxy != nil {
+		// The metadata may be known while the blob is missing locally
+		limit, exceeded := upstreamContentLimit(artifactMeta.SizeBytes)
+		content, err = s.fetchUpstreamContent(
+			serv.Context(),
+			pkg,
+			artifactMeta.Hash,
+			limit,
+			exceeded,
+		)
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get artifact for pull")
 
@@ -199,14 +205,15 @@ func (s *Server) PullArtifact(
 		Int("chunksCount", (totalSize+ChunkSize-1)/ChunkSize).
 		Msg("Successfully streamed complete artifact")
 
-	// Increment pull count
-	if err := s.db.IncreasePullCount(
-		serv.Context(),
-		req.Package,
-		versionHash,
-	); err != nil {
-		log.Warn().Err(err).Msg("Failed to increment pull count")
-	}
+	s.recordPull(serv.Context(), orm.Pull{
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+		Tag:       req.GetTag(),
+		Client:    callerFromContext(serv.Context()).User,
+		Time:      time.Now(),
+		Count:     1,
+	})
 
 	return nil
 }
@@ -240,6 +247,15 @@ func (s *Server) UploadArtifact(
 		return err
 	}
 
+	err = validateLabels(metadata.Labels)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Invalid labels in UploadArtifactRequest metadata")
+
+		return err
+	}
+
 	log.Info().
 		Str("namespace", metadata.Fqn.Namespace).
 		Str("name", metadata.Fqn.Name).
@@ -249,8 +265,27 @@ func (s *Server) UploadArtifact(
 		return newRegistryUnavailableError("artifact upload")
 	}
 
+	allowance, err := s.uploadAllowance(
+		stream.Context(),
+		metadata.Fqn.Namespace,
+	)
+	if err != nil {
+		return s.auditRejection(
+			stream.Context(),
+			orm.AuditUpload,
+			metadata.Fqn,
+			"",
+			metadata.Tags,
+			err,
+		)
+	}
+
 	pr, pw := io.Pipe()
 
+	// The Wasm interface is extracted while the content is stored
+	inspector := newUploadInspector()
+	defer inspector.close()
+
 	resultChan := make(chan struct {
 		versionHash string
 		err         error
@@ -267,7 +302,11 @@ func (s *Server) UploadArtifact(
 					Msg("Failed to close pipe reader in upload goroutine")
 			}
 		}()
-		versionHash, err := s.registry.StoreArtifact(metadata.Fqn, pr)
+		versionHash, err := s.registry.StoreArtifact(
+			ctx,
+			metadata.Fqn,
+			io.TeeReader(pr, inspector),
+		)
 		select {
 		case resultChan <- struct {
 			versionHash string
@@ -310,6 +349,21 @@ func (s *Server) UploadArtifact(
 			}
 		}
 
+		// Aborting the pipe keeps the blob from being committed
+		allowance -= int64(len(chunk.Data))
+		if allowance < 0 {
+			_ = pw.CloseWithError(ErrQuotaExceeded)
+
+			return s.auditRejection(
+				stream.Context(),
+				orm.AuditUpload,
+				metadata.Fqn,
+				"",
+				metadata.Tags,
+				newQuotaExceededError(metadata.Fqn.Namespace, "storage"),
+			)
+		}
+
 		_, err = pw.Write(chunk.Data)
 		if err != nil {
 			_ = pw.CloseWithError(err)
@@ -337,18 +391,69 @@ func (s *Server) UploadArtifact(
 		return wrapServiceError(err, "storing artifact")
 	}
 
-	err = s.db.CreateArtifactMeta(
+	inspector.close()
+	symbols := inspector.symbols(metadata.Fqn)
+	uploader := callerFromContext(stream.Context())
+	details := orm.ArtifactDetails{
+		SizeBytes: inspector.size,
+		MediaType: inspector.mediaType(),
+		Uploader:  uploader.User,
+		UserAgent: uploader.UserAgent,
+		Symbols:   symbols,
+		Labels:    metadata.Labels,
+	}
+
+	err = s.checkProtectedTags(
 		stream.Context(),
 		metadata.Fqn,
 		versionHash,
+		symbols,
+		metadata.Tags,
+	)
+	if err != nil {
+		s.discardUpload(stream.Context(), metadata.Fqn, versionHash)
+
+		return s.auditRejection(
+			stream.Context(),
+			orm.AuditUpload,
+			metadata.Fqn,
+			versionHash,
+			metadata.Tags,
+			err,
+		)
+	}
+
+	err = s.db.CreateArtifactMeta(
+		auditContext(stream.Context()),
+		metadata.Fqn,
+		versionHash,
+		details,
 		metadata.Tags...)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to store artifact metadata")
-		_ = s.registry.DeleteArtifact(metadata.Fqn, versionHash)
+		// The blob stays for the trashed version then
+		s.discardUpload(stream.Context(), metadata.Fqn, versionHash)
+		trashedErr := s.trashedVersionError(
+			stream.Context(),
+			metadata.Fqn,
+			versionHash,
+		)
+		if trashedErr != nil {
+			return trashedErr
+		}
 
 		return wrapServiceError(err, "storing artifact metadata")
 	}
 
+	owner := uploader.User
+	if owner == auth.UnauthenticatedUser {
+		owner = ""
+	}
+	err = s.db.EnsurePackage(stream.Context(), metadata.Fqn, owner)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to create package record")
+	}
+
 	log.Info().
 		Str("namespace", metadata.Fqn.Namespace).
 		Str("name", metadata.Fqn.Name).
@@ -360,9 +465,15 @@ func (s *Server) UploadArtifact(
 		VersionHash: versionHash,
 		Tags:        metadata.Tags,
 		Metadata: &proto_gen.MetaData{
-			Created: timestamppb.New(time.Now().UTC()),
-			Pulls:   0,
+			Created:   timestamppb.New(time.Now().UTC()),
+			Pulls:     0,
+			SizeBytes: details.SizeBytes,
+			MediaType: details.MediaType,
+			Uploader:  details.Uploader,
+			UserAgent: details.UserAgent,
 		},
+		Interface: interfaceToProto(symbols),
+		Labels:    metadata.Labels,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send upload artifact response")
@@ -403,34 +514,41 @@ func (s *Server) DeleteArtifact(
 		)
 	}
 
-	err = s.registry.DeleteArtifact(id.Package, artifactMeta.Hash)
+	if s.trashRetention > 0 {
+		err = s.db.TrashArtifactMeta(
+			auditContext(ctx),
+			id.Package,
+			artifactMeta.Hash,
+			callerFromContext(ctx).User,
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to move artifact to trash")
+
+			return nil, wrapServiceError(err, "moving artifact to trash")
+		}
+
+		return artifactToProto(artifactMeta), nil
+	}
+
+	err = s.registry.DeleteArtifact(ctx, id.Package, artifactMeta.Hash)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to delete artifact")
 
 		return nil, wrapServiceError(err, "deleting artifact")
 	}
 
-	err = s.db.DeleteArtifactMeta(id.Package, artifactMeta.Hash)
+	err = s.db.DeleteArtifactMeta(
+		auditContext(ctx),
+		id.Package,
+		artifactMeta.Hash,
+	)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to delete artifact metadata")
 
 		return nil, wrapServiceError(err, "deleting artifact metadata")
 	}
 
-	result := &proto_gen.Artifact{
-		Package: &proto_gen.PackageName{
-			Namespace: artifactMeta.Namespace,
-			Name:      artifactMeta.Name,
-		},
-		VersionHash: artifactMeta.Hash,
-		Tags:        tagsToStrings(artifactMeta.Tags),
-		Metadata: &proto_gen.MetaData{
-			Created: timestamppb.New(artifactMeta.CreatedAt),
-			Pulls:   artifactMeta.PullsCount,
-		},
-	}
-
-	return result, nil
+	return artifactToProto(artifactMeta), nil
 }
 
 func (s *Server) GetArtifact(
@@ -453,25 +571,26 @@ func (s *Server) GetArtifact(
 		return nil, newRegistryUnavailableError("artifact retrieval")
 	}
 
-	artifactMeta, err := s.resolveIdentifier(ctx, id)
+	artifactMeta, err := s.resolveReadIdentifier(ctx, id)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to resolve identifier to hash")
 
-		return nil, err // Already wrapped by resolveIdentifier
+		return nil, err // Already wrapped by resolveReadIdentifier
 	}
 
-	return &proto_gen.Artifact{
-		Package: &proto_gen.PackageName{
-			Namespace: artifactMeta.Namespace,
-			Name:      artifactMeta.Name,
-		},
-		VersionHash: artifactMeta.Hash,
-		Tags:        tagsToStrings(artifactMeta.Tags),
-		Metadata: &proto_gen.MetaData{
-			Created: timestamppb.New(artifactMeta.CreatedAt),
-			Pulls:   artifactMeta.PullsCount,
-		},
-	}, nil
+	if warning := versionWarning(artifactMeta); warning != nil {
+		if err := grpc.SetHeader(ctx, warning); err != nil {
+			log.Warn().Err(err).Msg("Failed to set version warning header")
+		}
+	}
+
+	artifact := artifactToProto(artifactMeta)
+	artifact.PackageInfo = s.packageInfos(
+		ctx,
+		[]orm.Artifact{*artifactMeta},
+	)[artifactMeta.Namespace+"/"+artifactMeta.Name]
+
+	return artifact, nil
 }
 
 func (s *Server) SetTags(
@@ -510,9 +629,10 @@ func (s *Server) SetTags(
 		return nil, err
 	}
 
-	var versionHash string
+	var artifactMeta *orm.Artifact
+	var err error
 	if tag, ok := request.Artifact.Identifier.(*proto_gen.ArtifactIdentifier_Tag); ok {
-		artifactMeta, err := s.db.GetArtifactMetaByTag(
+		artifactMeta, err = s.db.GetArtifactMetaByTag(
 			ctx,
 			request.Artifact.Package,
 			tag.Tag,
@@ -527,13 +647,11 @@ func (s *Server) SetTags(
 				"retrieving artifact by tag for SetTagsRequest",
 			)
 		}
-		versionHash = artifactMeta.Hash
 	} else {
-		versionHash = request.Artifact.GetVersionHash()
-		_, err := s.db.GetArtifactMetaByHash(
+		artifactMeta, err = s.db.GetArtifactMetaByHash(
 			ctx,
 			request.Artifact.Package,
-			versionHash,
+			request.Artifact.GetVersionHash(),
 		)
 		if err != nil {
 			log.Error().
@@ -547,8 +665,32 @@ func (s *Server) SetTags(
 		}
 
 	}
+	versionHash := artifactMeta.Hash
+
+	err = s.checkProtectedTags(
+		ctx,
+		request.Artifact.Package,
+		versionHash,
+		artifactMeta.Symbols,
+		request.Tags,
+	)
+	if err != nil {
+		return nil, s.auditRejection(
+			ctx,
+			orm.AuditSetTags,
+			request.Artifact.Package,
+			versionHash,
+			request.Tags,
+			err,
+		)
+	}
 
-	err := s.db.SetTags(ctx, request.Artifact.Package, versionHash, request.Tags)
+	err = s.db.SetTags(
+		auditContext(ctx),
+		request.Artifact.Package,
+		versionHash,
+		request.Tags,
+	)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to set tags")
 
@@ -601,11 +743,112 @@ func (s *Server) resolveIdentifier(
 
 			return nil, wrapServiceError(err, "resolving artifact by tag")
 		}
+		if err := rejectYankedTag(artifactMeta, identifier.Tag); err != nil {
+			return nil, err
+		}
 	}
 
 	return artifactMeta, nil
 }
 
+// resolveReadIdentifier resolves an identifier like resolveIdentifier, but
+// follows the redirect of a moved package if nothing is found under the
+// requested name. Only reads follow redirects, writes have to use the new
+// name. Without a redirect, proxies fall back to their upstream.
+func (s *Server) resolveReadIdentifier(
+	ctx context.Context,
+	id *proto_gen.ArtifactIdentifier,
+) (*orm.Artifact, error) {
+	artifactMeta, err := s.resolveIdentifier(ctx, id)
+	if status.Code(err) != codes.NotFound {
+		return artifactMeta, err
+	}
+
+	target := s.followRedirect(ctx, id.Package)
+	if target == nil && s.proxy != nil {
+		return s.resolveUpstream(ctx, id)
+	}
+	if target == nil {
+		return nil, err
+	}
+
+	log.Debug().
+		Str("from", id.Package.Namespace+"/"+id.Package.Name).
+		Str("to", target.Namespace+"/"+target.Name).
+		Msg("Following package redirect")
+
+	redirected := &proto_gen.ArtifactIdentifier{
+		Package:    target,
+		Identifier: id.Identifier,
+	}
+
+	return s.resolveIdentifier(ctx, redirected)
+}
+
+// discardUpload removes the blob of a rejected upload unless an existing
+// version with the same content still references it
+func (s *Server) discardUpload(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) {
+	// The blob is shared with an existing or trashed version of the same
+	// content
+	_, err := s.db.GetArtifactMetaByHash(ctx, pkg, versionHash)
+	var notFoundErr *orm.NotFoundError
+	if !errors.As(err, &notFoundErr) {
+		return
+	}
+	_, err = s.db.GetDeletedArtifactMeta(ctx, pkg, versionHash)
+	if !errors.As(err, &notFoundErr) {
+		return
+	}
+
+	if err := s.registry.DeleteArtifact(ctx, pkg, versionHash); err != nil {
+		log.Warn().Err(err).Msg("Failed to remove blob of rejected upload")
+	}
+}
+
+// artifactToProto converts stored artifact metadata into its API
+// representation
+func artifactToProto(a *orm.Artifact) *proto_gen.Artifact {
+	var copiedFrom *proto_gen.PackageName
+	if a.CopiedFromNamespace != "" {
+		copiedFrom = &proto_gen.PackageName{
+			Namespace: a.CopiedFromNamespace,
+			Name:      a.CopiedFromName,
+		}
+	}
+
+	var lastPulled *timestamppb.Timestamp
+	if a.LastPulledAt != nil {
+		lastPulled = timestamppb.New(*a.LastPulledAt)
+	}
+
+	return &proto_gen.Artifact{
+		Package: &proto_gen.PackageName{
+			Namespace: a.Namespace,
+			Name:      a.Name,
+		},
+		VersionHash: a.Hash,
+		Tags:        tagsToStrings(a.Tags),
+		Metadata: &proto_gen.MetaData{
+			Created:      timestamppb.New(a.CreatedAt),
+			Pulls:        a.PullsCount,
+			SizeBytes:    a.SizeBytes,
+			MediaType:    a.MediaType,
+			Uploader:     a.Uploader,
+			UserAgent:    a.UserAgent,
+			LastPulledAt: lastPulled,
+		},
+		Interface:   interfaceToProto(a.Symbols),
+		Labels:      labelsToMap(a.Labels),
+		State:       versionStateToProto(a.State),
+		StateReason: a.StateReason,
+		CopiedFrom:  copiedFrom,
+	}
+}
+
 func tagsToStrings(tags []orm.Tag) []string {
 	resultTags := make([]string, 0, len(tags))
 	for _, t := range tags {
@@ -0,0 +1,172 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+var auditActions = map[proto_gen.AuditAction]string{
+	proto_gen.AuditAction_UPLOAD:           orm.AuditUpload,
+	proto_gen.AuditAction_DELETE:           orm.AuditDelete,
+	proto_gen.AuditAction_TRASH:            orm.AuditTrash,
+	proto_gen.AuditAction_RESTORE:          orm.AuditRestore,
+	proto_gen.AuditAction_PURGE:            orm.AuditPurge,
+	proto_gen.AuditAction_SET_TAGS:         orm.AuditSetTags,
+	proto_gen.AuditAction_SET_LABELS:       orm.AuditSetLabels,
+	proto_gen.AuditAction_SET_STATE:        orm.AuditSetState,
+	proto_gen.AuditAction_MOVE_PACKAGE:     orm.AuditMovePackage,
+	proto_gen.AuditAction_DELETE_PACKAGE:   orm.AuditDeletePackage,
+	proto_gen.AuditAction_DELETE_NAMESPACE: orm.AuditDeleteNamespace,
+}
+
+// auditContext attributes the changes made with the returned context to the
+// caller of the request
+func auditContext(ctx context.Context) context.Context {
+	actor := orm.AuditActor{User: callerFromContext(ctx).User}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		actor.ClientAddress = p.Addr.String()
+	}
+
+	return orm.WithAuditActor(ctx, actor)
+}
+
+// auditRejection records a change that is rejected before it reaches the
+// store, so the audit log also shows attempts that never got that far. It
+// returns the rejection.
+func (s *Server) auditRejection(
+	ctx context.Context,
+	action string,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	tags []string,
+	rejection error,
+) error {
+	event := &orm.AuditEvent{
+		Action:    action,
+		Namespace: pkg.Namespace,
+		Name:      pkg.Name,
+		Hash:      versionHash,
+		TagsAfter: tags,
+	}
+	err := s.db.RecordRejection(auditContext(ctx), event, rejection)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to record rejected change in audit log")
+	}
+
+	return rejection
+}
+
+func (s *Server) ListAuditEvents(
+	ctx context.Context,
+	query *proto_gen.AuditQuery,
+) (*proto_gen.AuditEventListResponse, error) {
+	pageSize := int(query.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Page size must not be negative",
+		}
+	case pageSize == 0:
+		pageSize = defaultAuditPageSize
+	case pageSize > maxAuditPageSize:
+		pageSize = maxAuditPageSize
+	}
+
+	filter := orm.AuditFilter{
+		Actor:     query.GetActor(),
+		Namespace: query.GetNamespace(),
+		Name:      query.GetName(),
+		Hash:      query.GetVersionHash(),
+		// One more than requested tells whether there is another page
+		Limit: pageSize + 1,
+	}
+	if query.Action != nil {
+		action, ok := auditActions[query.GetAction()]
+		if !ok {
+			return nil, &ServiceError{
+				Code:    codes.InvalidArgument,
+				Message: "Unknown audit action " + query.GetAction().String(),
+			}
+		}
+		filter.Action = action
+	}
+	if query.Since != nil {
+		filter.Since = query.Since.AsTime()
+	}
+	if query.Until != nil {
+		filter.Until = query.Until.AsTime()
+	}
+	if query.PageToken != "" {
+		beforeID, err := strconv.ParseUint(query.PageToken, 10, 0)
+		if err != nil || beforeID == 0 {
+			return nil, &ServiceError{
+				Code:    codes.InvalidArgument,
+				Message: "Invalid page token " + strconv.Quote(query.PageToken),
+			}
+		}
+		filter.BeforeID = uint(beforeID)
+	}
+
+	events, err := s.db.GetAuditEvents(ctx, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list audit events")
+
+		return nil, wrapServiceError(err, "listing audit events")
+	}
+
+	response := &proto_gen.AuditEventListResponse{}
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		response.NextPageToken = strconv.FormatUint(
+			uint64(events[pageSize-1].ID),
+			10,
+		)
+	}
+
+	response.Events = make([]*proto_gen.AuditEvent, 0, len(events))
+	for i := range events {
+		response.Events = append(response.Events, auditEventToProto(&events[i]))
+	}
+
+	return response, nil
+}
+
+func auditEventToProto(event *orm.AuditEvent) *proto_gen.AuditEvent {
+	var action proto_gen.AuditAction
+	for protoAction, a := range auditActions {
+		if a == event.Action {
+			action = protoAction
+		}
+	}
+
+	return &proto_gen.AuditEvent{
+		Id:            uint64(event.ID),
+		Time:          timestamppb.New(event.CreatedAt),
+		Actor:         event.Actor,
+		ClientAddress: event.ClientAddress,
+		Action:        action,
+		Package: &proto_gen.PackageName{
+			Namespace: event.Namespace,
+			Name:      event.Name,
+		},
+		VersionHash: event.Hash,
+		TagsBefore:  event.TagsBefore,
+		TagsAfter:   event.TagsAfter,
+		Succeeded:   event.Succeeded,
+		Error:       event.Error,
+		Detail:      event.Detail,
+	}
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"artifact-registry/wasm"
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+const backfillBatchSize = 100
+
+// BackfillArtifactDetails derives size and media type of artifacts that were
+// uploaded before these were recorded by reading their blobs from the storage
+// backend. Updates are idempotent, so it is safe to run on several replicas at
+// once. Artifacts whose blob cannot be read are skipped and retried on the
+// next run.
+func (s *Server) BackfillArtifactDetails(ctx context.Context) error {
+	if s.registry == nil {
+		return newRegistryUnavailableError("artifact details backfill")
+	}
+
+	var after *orm.Artifact
+	updated, skipped := 0, 0
+	for {
+		batch, err := s.db.GetArtifactMetasWithoutDetails(
+			ctx,
+			after,
+			backfillBatchSize,
+		)
+		if err != nil {
+			return wrapServiceError(err, "listing artifacts to backfill")
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			artifact := &batch[i]
+			pkg := &proto_gen.PackageName{
+				Namespace: artifact.Namespace,
+				Name:      artifact.Name,
+			}
+
+			content, err := s.registry.GetArtifact(ctx, pkg, artifact.Hash)
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("namespace", artifact.Namespace).
+					Str("name", artifact.Name).
+					Str("versionHash", artifact.Hash).
+					Msg("Skipping backfill of artifact with unreadable blob")
+
+				skipped++
+
+				continue
+			}
+
+			err = s.db.SetArtifactSizeAndMediaType(
+				ctx,
+				pkg,
+				artifact.Hash,
+				int64(len(content)),
+				wasm.DetectMediaType(content),
+			)
+			if err != nil {
+				return wrapServiceError(err, "backfilling artifact details")
+			}
+			updated++
+		}
+
+		after = &batch[len(batch)-1]
+	}
+
+	log.Info().
+		Int("updated", updated).
+		Int("skipped", skipped).
+		Msg("Finished backfilling artifact size and media type")
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const MaxBatchSize = 1000 // identifiers per BatchGetArtifacts request
+
+func (s *Server) BatchGetArtifacts(
+	ctx context.Context,
+	request *proto_gen.BatchGetArtifactsRequest,
+) (*proto_gen.BatchGetArtifactsResponse, error) {
+	log.Info().
+		Int("identifiers", len(request.Identifiers)).
+		Msg("Batch of artifacts requested")
+
+	if len(request.Identifiers) > MaxBatchSize {
+		return nil, &ServiceError{
+			Code: codes.InvalidArgument,
+			Message: fmt.Sprintf(
+				"At most %d identifiers can be requested at once",
+				MaxBatchSize,
+			),
+		}
+	}
+
+	if s.registry == nil {
+		return nil, newRegistryUnavailableError("artifact retrieval")
+	}
+
+	// Invalid identifiers fail on their own and are left out of the query
+	results := make([]*proto_gen.ArtifactResult, len(request.Identifiers))
+	refs := make([]orm.ArtifactRef, 0, len(request.Identifiers))
+	positions := make([]int, 0, len(request.Identifiers))
+	for i, id := range request.Identifiers {
+		if err := validateArtifactIdentifier(id); err != nil {
+			results[i] = errorResult(err)
+
+			continue
+		}
+
+		refs = append(refs, orm.ArtifactRef{
+			Namespace: id.Package.Namespace,
+			Name:      id.Package.Name,
+			Hash:      id.GetVersionHash(),
+			Tag:       id.GetTag(),
+		})
+		positions = append(positions, i)
+	}
+
+	artifactMetas, err := s.db.GetArtifactMetasByRefs(ctx, refs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve batch of artifacts")
+
+		return nil, wrapServiceError(err, "resolving batch of artifacts")
+	}
+
+	// Versions of moved packages are looked up one by one, as redirects are
+	// only expected for a few stale references
+	found := make([]orm.Artifact, 0, len(artifactMetas))
+	for i, artifactMeta := range artifactMetas {
+		id := request.Identifiers[positions[i]]
+		err = nil
+		if artifactMeta == nil {
+			artifactMeta, err = s.resolveRedirected(ctx, id)
+		} else if tag := id.GetTag(); tag != "" {
+			err = rejectYankedTag(artifactMeta, tag)
+		}
+		if err != nil {
+			results[positions[i]] = errorResult(err)
+
+			continue
+		}
+
+		found = append(found, *artifactMeta)
+		results[positions[i]] = &proto_gen.ArtifactResult{
+			Result: &proto_gen.ArtifactResult_Artifact{
+				Artifact: artifactToProto(artifactMeta),
+			},
+		}
+	}
+
+	packages := s.packageInfos(ctx, found)
+	for _, result := range results {
+		if artifact := result.GetArtifact(); artifact != nil {
+			artifact.PackageInfo = packages[artifact.Package.Namespace+"/"+
+				artifact.Package.Name]
+		}
+	}
+
+	return &proto_gen.BatchGetArtifactsResponse{Results: results}, nil
+}
+
+// resolveRedirected resolves an identifier that was not found under its
+// package through the package's redirect
+func (s *Server) resolveRedirected(
+	ctx context.Context,
+	id *proto_gen.ArtifactIdentifier,
+) (*orm.Artifact, error) {
+	target := s.followRedirect(ctx, id.Package)
+	if target == nil {
+		return nil, &ServiceError{
+			Code:    codes.NotFound,
+			Message: "Artifact not found for batch retrieval",
+		}
+	}
+
+	return s.resolveIdentifier(ctx, &proto_gen.ArtifactIdentifier{
+		Package:    target,
+		Identifier: id.Identifier,
+	})
+}
+
+func errorResult(err error) *proto_gen.ArtifactResult {
+	st := status.Convert(err)
+
+	return &proto_gen.ArtifactResult{
+		Result: &proto_gen.ArtifactResult_Error{
+			Error: &proto_gen.ErrorStatus{
+				Code:    uint32(st.Code()),
+				Message: st.Message(),
+			},
+		},
+	}
+}
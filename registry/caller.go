@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/EnclaveRunner/shareddeps/auth"
+	"google.golang.org/grpc/metadata"
+)
+
+// UserMetadataKey is the gRPC metadata key that carries the identity of the
+// user on whose behalf a request is made. It is set by the API server, which
+// authenticates users before calling the registry.
+const UserMetadataKey = "x-enclave-user"
+
+// internalMetadataPrefix starts the metadata keys that only trusted callers
+// like the API server may set
+const internalMetadataPrefix = "x-enclave-"
+
+const userAgentMetadataKey = "user-agent"
+
+// caller describes who issued a request
+type caller struct {
+	User      string
+	UserAgent string
+}
+
+func callerFromContext(ctx context.Context) caller {
+	result := caller{User: auth.UnauthenticatedUser}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return result
+	}
+
+	if users := md.Get(UserMetadataKey); len(users) > 0 && users[0] != "" {
+		result.User = users[0]
+	}
+	if agents := md.Get(userAgentMetadataKey); len(agents) > 0 {
+		result.UserAgent = agents[0]
+	}
+
+	return result
+}
@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"artifact-registry/wasm"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+var changeTypes = map[wasm.ChangeType]proto_gen.InterfaceChange_Type{
+	wasm.ChangeAdded:   proto_gen.InterfaceChange_ADDED,
+	wasm.ChangeRemoved: proto_gen.InterfaceChange_REMOVED,
+	wasm.ChangeChanged: proto_gen.InterfaceChange_CHANGED,
+}
+
+func (s *Server) CompareVersions(
+	ctx context.Context,
+	request *proto_gen.CompareVersionsRequest,
+) (*proto_gen.CompareVersionsResponse, error) {
+	if request.Base == nil || request.Candidate == nil {
+		log.Error().Msg("CompareVersionsRequest missing base or candidate")
+
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Base and candidate must be provided",
+		}
+	}
+
+	if err := validateArtifactIdentifier(request.Base); err != nil {
+		return nil, err
+	}
+	if err := validateArtifactIdentifier(request.Candidate); err != nil {
+		return nil, err
+	}
+
+	if !proto.Equal(request.Base.Package, request.Candidate.Package) {
+		log.Error().Msg("CompareVersionsRequest spans different packages")
+
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Only versions of the same package can be compared",
+		}
+	}
+
+	log.Info().
+		Str("namespace", request.Base.Package.Namespace).
+		Str("name", request.Base.Package.Name).
+		Msg("Version comparison requested")
+
+	base, err := s.resolveIdentifier(ctx, request.Base)
+	if err != nil {
+		return nil, err // Already wrapped by resolveIdentifier
+	}
+	candidate, err := s.resolveIdentifier(ctx, request.Candidate)
+	if err != nil {
+		return nil, err // Already wrapped by resolveIdentifier
+	}
+
+	for _, a := range []*orm.Artifact{base, candidate} {
+		if len(a.Symbols) == 0 {
+			return nil, &ServiceError{
+				Code: codes.FailedPrecondition,
+				Message: "No Wasm interface recorded for version " +
+					a.Hash + ", it cannot be compared",
+				Inner: ErrNoInterface,
+			}
+		}
+	}
+
+	changes := wasm.Compare(
+		interfaceFromSymbols(base.Symbols),
+		interfaceFromSymbols(candidate.Symbols),
+	)
+
+	protoChanges := make([]*proto_gen.InterfaceChange, 0, len(changes))
+	for _, c := range changes {
+		protoChanges = append(protoChanges, &proto_gen.InterfaceChange{
+			Type:      changeTypes[c.Type],
+			Direction: string(c.Direction),
+			Before:    wasmSymbolToProto(c.Before),
+			After:     wasmSymbolToProto(c.After),
+			Breaking:  c.Breaking,
+		})
+	}
+
+	return &proto_gen.CompareVersionsResponse{
+		BaseVersionHash:      base.Hash,
+		CandidateVersionHash: candidate.Hash,
+		Compatible:           wasm.IsCompatible(changes),
+		Changes:              protoChanges,
+	}, nil
+}
+
+// checkProtectedTags rejects moving a protected tag to a version whose
+// interface breaks the interface of the version the tag currently points to.
+// Tags that do not exist yet and versions without a recorded interface are
+// not checked.
+func (s *Server) checkProtectedTags(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+	symbols []orm.ArtifactSymbol,
+	tags []string,
+) error {
+	for _, tag := range tags {
+		if !slices.Contains(s.protectedTags, tag) {
+			continue
+		}
+
+		current, err := s.db.GetArtifactMetaByTag(ctx, pkg, tag)
+		var notFoundErr *orm.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			continue
+		}
+		if err != nil {
+			return wrapServiceError(err, "resolving protected tag "+tag)
+		}
+
+		if current.Hash == versionHash || len(current.Symbols) == 0 {
+			continue
+		}
+
+		changes := wasm.Compare(
+			interfaceFromSymbols(current.Symbols),
+			interfaceFromSymbols(symbols),
+		)
+		if !wasm.IsCompatible(changes) {
+			log.Warn().
+				Str("namespace", pkg.Namespace).
+				Str("name", pkg.Name).
+				Str("tag", tag).
+				Str("versionHash", versionHash).
+				Msg("Rejected breaking interface change on protected tag")
+
+			return newBreakingChangeError(tag, changes)
+		}
+	}
+
+	return nil
+}
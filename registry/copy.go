@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"slices"
+
+	"github.com/EnclaveRunner/shareddeps/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+func (s *Server) CopyArtifact(
+	ctx context.Context,
+	request *proto_gen.CopyArtifactRequest,
+) (*proto_gen.Artifact, error) {
+	if request.Source == nil {
+		log.Error().Msg("CopyArtifactRequest missing source")
+
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Source artifact must be provided",
+		}
+	}
+	if err := validateArtifactIdentifier(request.Source); err != nil {
+		log.Error().Err(err).Msg("Invalid source in CopyArtifactRequest")
+
+		return nil, err
+	}
+	if err := validateFQN(request.Target); err != nil {
+		log.Error().Err(err).Msg("Invalid target in CopyArtifactRequest")
+
+		return nil, err
+	}
+	if proto.Equal(request.Source.Package, request.Target) {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Artifact cannot be copied into its own package",
+			Inner:   ErrInvalidPackage,
+		}
+	}
+	if slices.Contains(request.Tags, "") {
+		log.Error().Msg("CopyArtifactRequest contains empty tag")
+
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Provided an empty tag. Tags cannot be empty strings.",
+		}
+	}
+
+	tags := slices.Clone(request.Tags)
+	slices.Sort(tags)
+	tags = slices.Compact(tags)
+
+	log.Info().
+		Str("from", request.Source.Package.Namespace+"/"+
+			request.Source.Package.Name).
+		Str("to", request.Target.Namespace+"/"+request.Target.Name).
+		Msg("Artifact copy requested")
+
+	if s.registry == nil {
+		return nil, newRegistryUnavailableError("artifact copy")
+	}
+
+	source, err := s.resolveReadIdentifier(ctx, request.Source)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve artifact to copy")
+
+		return nil, err // Already wrapped by resolveReadIdentifier
+	}
+	// Differs from the requested package if that has been moved
+	sourcePkg := &proto_gen.PackageName{
+		Namespace: source.Namespace,
+		Name:      source.Name,
+	}
+
+	allowance, err := s.uploadAllowance(ctx, request.Target.Namespace)
+	if err == nil && source.SizeBytes > allowance {
+		err = newQuotaExceededError(request.Target.Namespace, "storage")
+	}
+	if err == nil {
+		err = s.checkProtectedTags(
+			ctx,
+			request.Target,
+			source.Hash,
+			source.Symbols,
+			tags,
+		)
+	}
+	if err != nil {
+		return nil, s.auditRejection(
+			ctx,
+			orm.AuditUpload,
+			request.Target,
+			source.Hash,
+			tags,
+			err,
+		)
+	}
+
+	err = s.registry.LinkArtifact(ctx, sourcePkg, request.Target, source.Hash)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to copy artifact content")
+
+		return nil, wrapServiceError(err, "copying artifact content")
+	}
+
+	caller := callerFromContext(ctx)
+	err = s.db.CreateArtifactMeta(
+		auditContext(ctx),
+		request.Target,
+		source.Hash,
+		orm.ArtifactDetails{
+			SizeBytes:  source.SizeBytes,
+			MediaType:  source.MediaType,
+			Uploader:   caller.User,
+			UserAgent:  caller.UserAgent,
+			Symbols:    copySymbols(source.Symbols),
+			Labels:     labelsToMap(source.Labels),
+			CopiedFrom: sourcePkg,
+		},
+		tags...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store metadata of copy")
+		s.discardUpload(ctx, request.Target, source.Hash)
+		trashedErr := s.trashedVersionError(ctx, request.Target, source.Hash)
+		if trashedErr != nil {
+			return nil, trashedErr
+		}
+
+		return nil, wrapServiceError(err, "storing metadata of copy")
+	}
+
+	owner := caller.User
+	if owner == auth.UnauthenticatedUser {
+		owner = ""
+	}
+	if err := s.db.EnsurePackage(ctx, request.Target, owner); err != nil {
+		log.Warn().Err(err).Msg("Failed to create package record")
+	}
+
+	log.Info().
+		Str("namespace", request.Target.Namespace).
+		Str("name", request.Target.Name).
+		Str("versionHash", source.Hash).
+		Msg("Artifact copied successfully")
+
+	return s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+		Package: request.Target,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: source.Hash,
+		},
+	})
+}
+
+// copySymbols returns the symbols of an artifact detached from their rows, so
+// they can be stored for another artifact
+func copySymbols(symbols []orm.ArtifactSymbol) []orm.ArtifactSymbol {
+	result := make([]orm.ArtifactSymbol, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol.ID = 0
+		result = append(result, symbol)
+	}
+
+	return result
+}
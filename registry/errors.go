@@ -2,20 +2,50 @@ package registry
 
 import (
 	"artifact-registry/orm"
+	"artifact-registry/wasm"
 	"errors"
+	"strings"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// errorDomain identifies this service in the ErrorInfo details of errors
+const errorDomain = "artifact-registry"
+
+// Clients are asked to wait this long before retrying on unavailable storage
+const storageRetryDelay = 5 * time.Second
+
 // Static errors to avoid err113 violations
-var ErrRegistryNil = errors.New("registry is nil")
+var (
+	ErrRegistryNil    = errors.New("registry is nil")
+	ErrNoInterface    = errors.New("no Wasm interface recorded")
+	ErrBreakingChange = errors.New("breaking interface change")
+	ErrQuotaExceeded  = errors.New("namespace quota exceeded")
+	ErrVersionTrashed = errors.New("version is in the trash")
+)
+
+// Errors of storage backends. Implementations of Registry wrap their failures
+// in them, so callers can tell failures apart independent of the backend.
+var (
+	ErrArtifactNotFound     = errors.New("artifact not found")
+	ErrArtifactExists       = errors.New("artifact already exists")
+	ErrArtifactCorrupt      = errors.New("artifact content is corrupt")
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+	ErrStorageUnavailable   = errors.New("storage unavailable")
+)
 
 // ServiceError represents public-facing errors from the registry service
 type ServiceError struct {
 	Code    codes.Code
 	Message string
 	Inner   error
+	// Details are attached to the gRPC status, e.g. errdetails messages
+	Details []protoadapt.MessageV1
 }
 
 func (e *ServiceError) Error() string {
@@ -27,7 +57,17 @@ func (e *ServiceError) Unwrap() error {
 }
 
 func (e *ServiceError) GRPCStatus() *status.Status {
-	return status.New(e.Code, e.Message)
+	st := status.New(e.Code, e.Message)
+	if len(e.Details) == 0 {
+		return st
+	}
+
+	withDetails, err := st.WithDetails(e.Details...)
+	if err != nil {
+		return st
+	}
+
+	return withDetails
 }
 
 // wrapServiceError converts internal errors to user-friendly service errors
@@ -55,6 +95,19 @@ func wrapServiceError(err error, operation string) error {
 		}
 	}
 
+	var badInputErr *orm.BadInputError
+	if errors.As(err, &badInputErr) {
+		return &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Invalid input for " + operation + ": " + badInputErr.Reason,
+			Inner:   err,
+		}
+	}
+
+	if storageErr := wrapStorageError(err, operation); storageErr != nil {
+		return storageErr
+	}
+
 	var dbErr *orm.DatabaseError
 	if errors.As(err, &dbErr) {
 		return &ServiceError{
@@ -72,6 +125,77 @@ func wrapServiceError(err error, operation string) error {
 	}
 }
 
+// wrapStorageError converts the errors of storage backends to service errors.
+// Returns nil for other errors.
+func wrapStorageError(err error, operation string) *ServiceError {
+	errorInfo := func(reason string) *errdetails.ErrorInfo {
+		return &errdetails.ErrorInfo{
+			Reason:   reason,
+			Domain:   errorDomain,
+			Metadata: map[string]string{"operation": operation},
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrArtifactNotFound):
+		return &ServiceError{
+			Code:    codes.NotFound,
+			Message: "Artifact content not found for " + operation,
+			Inner:   err,
+			Details: []protoadapt.MessageV1{
+				errorInfo("ARTIFACT_CONTENT_NOT_FOUND"),
+			},
+		}
+	case errors.Is(err, ErrArtifactExists):
+		return &ServiceError{
+			Code:    codes.AlreadyExists,
+			Message: "Artifact content already exists for " + operation,
+			Inner:   err,
+			Details: []protoadapt.MessageV1{
+				errorInfo("ARTIFACT_CONTENT_EXISTS"),
+			},
+		}
+	case errors.Is(err, ErrArtifactCorrupt):
+		return &ServiceError{
+			Code:    codes.DataLoss,
+			Message: "Artifact content is corrupt for " + operation,
+			Inner:   err,
+			Details: []protoadapt.MessageV1{
+				errorInfo("ARTIFACT_CONTENT_CORRUPT"),
+			},
+		}
+	case errors.Is(err, ErrStorageQuotaExceeded):
+		return &ServiceError{
+			Code:    codes.ResourceExhausted,
+			Message: "Storage is out of space for " + operation,
+			Inner:   err,
+			Details: []protoadapt.MessageV1{
+				errorInfo("STORAGE_QUOTA_EXCEEDED"),
+				&errdetails.QuotaFailure{
+					Violations: []*errdetails.QuotaFailure_Violation{{
+						Subject:     "storage",
+						Description: "The storage backend is out of space",
+					}},
+				},
+			},
+		}
+	case errors.Is(err, ErrStorageUnavailable):
+		return &ServiceError{
+			Code:    codes.Unavailable,
+			Message: "Storage unavailable for " + operation,
+			Inner:   err,
+			Details: []protoadapt.MessageV1{
+				errorInfo("STORAGE_UNAVAILABLE"),
+				&errdetails.RetryInfo{
+					RetryDelay: durationpb.New(storageRetryDelay),
+				},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
 // Common error constructors for specific operations
 func newInvalidIdentifierError() error {
 	return &ServiceError{
@@ -88,3 +212,39 @@ func newRegistryUnavailableError(operation string) error {
 		Inner:   ErrRegistryNil,
 	}
 }
+
+func newBreakingChangeError(tag string, changes []wasm.Change) error {
+	var breaking []string
+	for _, c := range changes {
+		if !c.Breaking {
+			continue
+		}
+		sym := c.After
+		if sym == nil {
+			sym = c.Before
+		}
+		name := sym.Name
+		if sym.Module != "" {
+			name = sym.Module + "." + name
+		}
+		breaking = append(
+			breaking,
+			string(c.Type)+" "+string(c.Direction)+" "+name,
+		)
+	}
+
+	return &ServiceError{
+		Code: codes.FailedPrecondition,
+		Message: "Version breaks the interface of protected tag " + tag + ": " +
+			strings.Join(breaking, ", "),
+		Inner: ErrBreakingChange,
+	}
+}
+
+func newQuotaExceededError(namespace, limit string) error {
+	return &ServiceError{
+		Code:    codes.ResourceExhausted,
+		Message: "Namespace " + namespace + " exceeds its " + limit + " quota",
+		Inner:   ErrQuotaExceeded,
+	}
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapStorageErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{ErrArtifactNotFound, codes.NotFound, "ARTIFACT_CONTENT_NOT_FOUND"},
+		{ErrArtifactExists, codes.AlreadyExists, "ARTIFACT_CONTENT_EXISTS"},
+		{ErrArtifactCorrupt, codes.DataLoss, "ARTIFACT_CONTENT_CORRUPT"},
+		{
+			ErrStorageQuotaExceeded,
+			codes.ResourceExhausted,
+			"STORAGE_QUOTA_EXCEEDED",
+		},
+		{ErrStorageUnavailable, codes.Unavailable, "STORAGE_UNAVAILABLE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.reason, func(t *testing.T) {
+			t.Parallel()
+
+			// Backends wrap the storage errors
+			err := wrapServiceError(
+				fmt.Errorf("reading blob: %w", test.err),
+				"pulling artifact",
+			)
+			require.ErrorIs(t, err, test.err)
+
+			st := status.Convert(err)
+			assert.Equal(t, test.code, st.Code())
+
+			var info *errdetails.ErrorInfo
+			for _, detail := range st.Details() {
+				switch detail := detail.(type) {
+				case *errdetails.ErrorInfo:
+					info = detail
+				case *errdetails.RetryInfo:
+					assert.Equal(t, codes.Unavailable, test.code)
+					assert.Positive(t, detail.GetRetryDelay().AsDuration())
+				case *errdetails.QuotaFailure:
+					assert.Equal(t, codes.ResourceExhausted, test.code)
+					assert.NotEmpty(t, detail.GetViolations())
+				default:
+					t.Errorf("unexpected detail %T", detail)
+				}
+			}
+			require.NotNil(t, info)
+			assert.Equal(t, test.reason, info.GetReason())
+			assert.Equal(t, errorDomain, info.GetDomain())
+			assert.Equal(t, "pulling artifact", info.GetMetadata()["operation"])
+		})
+	}
+}
@@ -2,12 +2,17 @@ package filesystemRegistry
 
 import (
 	"artifact-registry/proto_gen"
+	"artifact-registry/registry"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"syscall"
 
 	"github.com/google/uuid"
 )
@@ -41,7 +46,7 @@ func New(baseDir string) (*FilesystemRegistry, error) {
 	if err := os.MkdirAll(baseDir, 0o755); err != nil {
 		return nil, &IOError{
 			"creating base directory",
-			err,
+			storageError(err, registry.ErrStorageUnavailable),
 		}
 	}
 
@@ -51,6 +56,7 @@ func New(baseDir string) (*FilesystemRegistry, error) {
 // StoreArtifact stores an artifact in the filesystem and returns its version
 // hash
 func (r *FilesystemRegistry) StoreArtifact(
+	ctx context.Context,
 	pkg *proto_gen.PackageName,
 	reader io.Reader,
 ) (string, error) {
@@ -94,7 +100,7 @@ func (r *FilesystemRegistry) StoreArtifact(
 	if err := os.MkdirAll(absUploadDir, 0o755); err != nil {
 		return "", &IOError{
 			"creating upload directory",
-			err,
+			storageError(err, registry.ErrStorageUnavailable),
 		}
 	}
 
@@ -103,7 +109,7 @@ func (r *FilesystemRegistry) StoreArtifact(
 	if err != nil {
 		return "", &IOError{
 			"creating artifact temp file",
-			err,
+			storageError(err, registry.ErrStorageUnavailable),
 		}
 	}
 	defer func() {
@@ -122,10 +128,11 @@ func (r *FilesystemRegistry) StoreArtifact(
 	multiWriter := io.MultiWriter(file, h)
 
 	// Copy from reader to both file and hash
-	if _, err := io.Copy(multiWriter, reader); err != nil {
+	_, err = io.Copy(multiWriter, registry.ContextReader(ctx, reader))
+	if err != nil {
 		return "", &IOError{
 			"writing artifact content",
-			err,
+			storageError(err, registry.ErrStorageUnavailable),
 		}
 	}
 
@@ -138,16 +145,16 @@ func (r *FilesystemRegistry) StoreArtifact(
 	finalDir := filepath.Dir(finalPath)
 
 	//nolint:gosec,mnd // Directory permissions 0755 are intentional
-	if err := os.MkdirAll(finalDir, 0o755); err != nil {
+	if err = os.MkdirAll(finalDir, 0o755); err != nil {
 		return "", &IOError{
 			"creating artifact directory",
-			err,
+			storageError(err, registry.ErrStorageUnavailable),
 		}
 	}
-	if err := os.Rename(absTempFileNameClean, finalPath); err != nil {
+	if err = os.Rename(absTempFileNameClean, finalPath); err != nil {
 		return "", &IOError{
 			"renaming artifact file",
-			err,
+			storageError(err, registry.ErrStorageUnavailable),
 		}
 	}
 
@@ -156,6 +163,7 @@ func (r *FilesystemRegistry) StoreArtifact(
 
 // GetArtifact retrieves an artifact by identifier
 func (r *FilesystemRegistry) GetArtifact(
+	_ context.Context,
 	pkg *proto_gen.PackageName,
 	hash string,
 ) ([]byte, error) {
@@ -165,7 +173,19 @@ func (r *FilesystemRegistry) GetArtifact(
 	if err != nil {
 		return nil, &IOError{
 			"reading artifact",
-			err,
+			storageError(err, registry.ErrArtifactNotFound),
+		}
+	}
+
+	// Artifacts are stored under the hash of their content
+	if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != hash {
+		return nil, &IOError{
+			"verifying artifact",
+			fmt.Errorf(
+				"%w: content hashes to %x",
+				registry.ErrArtifactCorrupt,
+				sum,
+			),
 		}
 	}
 
@@ -174,6 +194,7 @@ func (r *FilesystemRegistry) GetArtifact(
 
 // DeleteArtifact deletes an artifact by identifier
 func (r *FilesystemRegistry) DeleteArtifact(
+	_ context.Context,
 	pkg *proto_gen.PackageName,
 	hash string,
 ) error {
@@ -182,13 +203,119 @@ func (r *FilesystemRegistry) DeleteArtifact(
 	if err := os.Remove(artifactPath); err != nil {
 		return &IOError{
 			"deleting artifact",
+			storageError(err, registry.ErrArtifactNotFound),
+		}
+	}
+
+	return nil
+}
+
+// LinkArtifact makes an artifact of one package available under another one.
+// The blob is hard linked where possible and copied otherwise. Linking onto
+// an existing artifact succeeds without changes.
+func (r *FilesystemRegistry) LinkArtifact(
+	_ context.Context,
+	from, to *proto_gen.PackageName,
+	hash string,
+) error {
+	sourcePath := r.getArtifactPath(from, hash)
+	targetPath := r.getArtifactPath(to, hash)
+
+	if _, err := os.Stat(sourcePath); err != nil {
+		return &IOError{
+			"reading artifact to link",
+			storageError(err, registry.ErrArtifactNotFound),
+		}
+	}
+
+	//nolint:gosec,mnd // Directory permissions 0755 are intentional
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+		return &IOError{
+			"creating artifact directory",
+			storageError(err, registry.ErrStorageUnavailable),
+		}
+	}
+
+	err := os.Link(sourcePath, targetPath)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return nil
+	}
+
+	// Hard links fail across file systems, fall back to a copy
+	return r.copyArtifact(sourcePath, targetPath)
+}
+
+func (r *FilesystemRegistry) copyArtifact(sourcePath, targetPath string) error {
+	//nolint:gosec // G304: File path is constructed internally
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return &IOError{
+			"opening artifact to copy",
+			storageError(err, registry.ErrArtifactNotFound),
+		}
+	}
+	defer func() { _ = source.Close() }()
+
+	uuidVal, err := uuid.NewUUID()
+	if err != nil {
+		return &IOError{
+			"generating temp file name",
 			err,
 		}
 	}
+	tempPath := filepath.Join(r.baseDir, uuidVal.String()+".tmp")
+
+	//nolint:gosec // G304: File path is constructed internally
+	target, err := os.Create(tempPath)
+	if err != nil {
+		return &IOError{
+			"creating artifact temp file",
+			storageError(err, registry.ErrStorageUnavailable),
+		}
+	}
+
+	_, err = io.Copy(target, source)
+	if cerr := target.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, targetPath)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+
+		return &IOError{
+			"copying artifact",
+			storageError(err, registry.ErrStorageUnavailable),
+		}
+	}
 
 	return nil
 }
 
+// storageError adds the matching storage error of the registry package to an
+// error of the file system. Missing files are reported as notFound, errors
+// without a counterpart are returned unchanged.
+func storageError(err, notFound error) error {
+	var kind error
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		kind = notFound
+	case errors.Is(err, fs.ErrExist):
+		kind = registry.ErrArtifactExists
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		kind = registry.ErrStorageQuotaExceeded
+	case errors.Is(err, fs.ErrPermission),
+		errors.Is(err, syscall.EROFS),
+		errors.Is(err, syscall.EIO):
+		kind = registry.ErrStorageUnavailable
+	default:
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
 // getArtifactPath returns the file path for an artifact
 func (r *FilesystemRegistry) getArtifactPath(
 	pkg *proto_gen.PackageName,
@@ -3,10 +3,15 @@ package filesystemRegistry
 import (
 	"artifact-registry/config"
 	"artifact-registry/proto_gen"
+	"artifact-registry/registry"
+	"artifact-registry/registry/registrytest"
 	"bytes"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
 
 	sharedepsConfig "github.com/EnclaveRunner/shareddeps/config"
 )
@@ -14,289 +19,126 @@ import (
 func TestFilesystemRegistry(t *testing.T) {
 	t.Parallel()
 
-	// Test StoreArtifact - should compute hash and store file
-	t.Run("StoreArtifact", func(t *testing.T) {
-		t.Parallel()
+	registrytest.RunTests(t, func(t *testing.T) registry.Registry {
+		t.Helper()
 
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
+		_, registry := setupTest(t)
 
-		fqn := &proto_gen.PackageName{
-			Namespace: "testuser",
-			Name:      "testapp",
-		}
-		content := []byte("test content for artifact")
-
-		versionHash, err := registry.StoreArtifact(fqn, bytes.NewReader(content))
-		if err != nil {
-			t.Fatalf("Failed to store artifact: %v", err)
-		}
-
-		// Verify version hash was generated and is a valid hex string
-		if versionHash == "" {
-			t.Error("Version hash was not generated")
-		}
-		if len(versionHash) != 64 { // SHA256 hex string should be 64 characters
-			t.Errorf("Expected version hash length 64, got %d", len(versionHash))
-		}
-
-		// Verify that the artifact file was actually created on disk
-		expectedPath := filepath.Join(
-			tmpDir,
-			fqn.Namespace,
-			fqn.Name,
-			versionHash+".wasm",
-		)
-		if _, err := os.Stat(expectedPath); os.IsNotExist(err) {
-			t.Errorf(
-				"Artifact file was not created at expected path: %s",
-				expectedPath,
-			)
-		}
+		return registry
 	})
+}
 
-	// Test GetArtifact by version hash - should retrieve the exact same content
-	t.Run("GetArtifactByHash", func(t *testing.T) {
-		t.Parallel()
-
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
-
-		fqn := &proto_gen.PackageName{
-			Namespace: "testuser",
-			Name:      "testapp",
-		}
-		content := []byte("test content for artifact")
-
-		// Store artifact first
-		storedVersionHash, err := registry.StoreArtifact(
-			fqn,
-			bytes.NewReader(content),
-		)
-		if err != nil {
-			t.Fatalf("Failed to store artifact: %v", err)
-		}
-
-		retrieved, err := registry.GetArtifact(fqn, storedVersionHash)
-		if err != nil {
-			t.Fatalf("Failed to get artifact: %v", err)
-		}
-
-		if !bytes.Equal(retrieved, content) {
-			t.Errorf(
-				"Content mismatch. Expected: %q, Got: %q",
-				string(content),
-				string(retrieved),
-			)
-		}
-
-		if len(retrieved) != len(content) {
-			t.Errorf(
-				"Content length mismatch. Expected: %d, Got: %d",
-				len(content),
-				len(retrieved),
-			)
-		}
-	})
-
-	// Test GetArtifact with non-existent hash - should return error
-	t.Run("GetNonExistentArtifact", func(t *testing.T) {
-		t.Parallel()
-
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
-
-		fqn := &proto_gen.PackageName{
-			Namespace: "testuser",
-			Name:      "testapp",
-		}
-
-		nonExistentHash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
-		_, err := registry.GetArtifact(fqn, nonExistentHash)
-		if err == nil {
-			t.Error("Expected error when getting non-existent artifact, but got none")
-		}
-	})
-
-	// Test StoreArtifact with different content - should generate different hash
-	t.Run("StoreArtifactDifferentContent", func(t *testing.T) {
-		t.Parallel()
-
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
-
-		fqn := &proto_gen.PackageName{
-			Namespace: "testuser",
-			Name:      "testapp",
-		}
-		content := []byte("test content for artifact")
-		differentContent := []byte("different test content")
-
-		// Store first artifact
-		storedVersionHash, err := registry.StoreArtifact(
-			fqn,
-			bytes.NewReader(content),
-		)
-		if err != nil {
-			t.Fatalf("Failed to store first artifact: %v", err)
-		}
-
-		versionHash2, err := registry.StoreArtifact(
-			fqn,
-			bytes.NewReader(differentContent),
-		)
-		if err != nil {
-			t.Fatalf("Failed to store second artifact: %v", err)
-		}
-
-		if versionHash2 == storedVersionHash {
-			t.Error("Different content should generate different version hash")
-		}
-
-		// Verify we can retrieve both artifacts
-		content1, err := registry.GetArtifact(fqn, storedVersionHash)
-		if err != nil {
-			t.Fatalf("Failed to get first artifact: %v", err)
-		}
-
-		content2, err := registry.GetArtifact(fqn, versionHash2)
-		if err != nil {
-			t.Fatalf("Failed to get second artifact: %v", err)
-		}
-
-		if bytes.Equal(content1, content2) {
-			t.Error("Retrieved contents should be different")
-		}
-	})
-
-	// Test DeleteArtifact - should remove file and make it unavailable
-	t.Run("DeleteArtifact", func(t *testing.T) {
-		t.Parallel()
-
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
-
-		fqn := &proto_gen.PackageName{
-			Namespace: "testuser",
-			Name:      "testapp",
-		}
-		content := []byte("test content for artifact")
+// Test the on-disk layout - artifacts are stored as
+// <base>/<namespace>/<name>/<hash>.wasm and removed on deletion
+func TestFilesystemRegistryLayout(t *testing.T) {
+	t.Parallel()
 
-		// Store artifact first
-		storedVersionHash, err := registry.StoreArtifact(
-			fqn,
-			bytes.NewReader(content),
-		)
-		if err != nil {
-			t.Fatalf("Failed to store artifact: %v", err)
-		}
+	tmpDir, registry := setupTest(t)
 
-		// Verify artifact exists before deletion
-		_, err = registry.GetArtifact(fqn, storedVersionHash)
-		if err != nil {
-			t.Fatalf("Artifact should exist before deletion: %v", err)
-		}
+	fqn := &proto_gen.PackageName{
+		Namespace: "complex-author",
+		Name:      "complex-name-with-dashes",
+	}
+	content := []byte("content for complex artifact")
 
-		// Delete the artifact
-		err = registry.DeleteArtifact(fqn, storedVersionHash)
-		if err != nil {
-			t.Fatalf("Failed to delete artifact: %v", err)
-		}
+	versionHash, err := registry.StoreArtifact(
+		t.Context(),
+		fqn,
+		bytes.NewReader(content),
+	)
+	if err != nil {
+		t.Fatalf("Failed to store artifact: %v", err)
+	}
 
-		// Verify artifact is gone from filesystem
-		expectedPath := filepath.Join(
-			tmpDir,
-			fqn.Namespace,
-			fqn.Name,
-			storedVersionHash+".wasm",
+	expectedPath := filepath.Join(
+		tmpDir,
+		fqn.Namespace,
+		fqn.Name,
+		versionHash+".wasm",
+	)
+	if _, err := os.Stat(expectedPath); err != nil {
+		t.Fatalf(
+			"Artifact file was not created at expected path: %s, error: %v",
+			expectedPath,
+			err,
 		)
-		if _, err := os.Stat(expectedPath); !os.IsNotExist(err) {
-			t.Error("Artifact file should have been deleted from filesystem")
-		}
+	}
 
-		// Verify artifact cannot be retrieved
-		_, err = registry.GetArtifact(fqn, storedVersionHash)
-		if err == nil {
-			t.Error("Expected error when getting deleted artifact, but got none")
-		}
-	})
+	err = registry.DeleteArtifact(t.Context(), fqn, versionHash)
+	if err != nil {
+		t.Fatalf("Failed to delete artifact: %v", err)
+	}
+	if _, err := os.Stat(expectedPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Artifact file should have been deleted from filesystem")
+	}
+}
 
-	// Test DeleteArtifact with non-existent hash - should return error
-	t.Run("DeleteNonExistentArtifact", func(t *testing.T) {
-		t.Parallel()
+// Test that blobs changed on disk are reported as corrupt
+func TestFilesystemRegistryCorruptArtifact(t *testing.T) {
+	t.Parallel()
 
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
+	tmpDir, reg := setupTest(t)
 
-		fqn := &proto_gen.PackageName{
-			Namespace: "testuser",
-			Name:      "testapp",
-		}
+	fqn := &proto_gen.PackageName{
+		Namespace: "testuser",
+		Name:      "testapp",
+	}
 
-		nonExistentHash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
-		err := registry.DeleteArtifact(fqn, nonExistentHash)
-		if err == nil {
-			t.Error(
-				"Expected error when deleting non-existent artifact, but got none",
-			)
-		}
-	})
+	versionHash, err := reg.StoreArtifact(
+		t.Context(),
+		fqn,
+		bytes.NewReader([]byte("original content")),
+	)
+	if err != nil {
+		t.Fatalf("Failed to store artifact: %v", err)
+	}
 
-	// Test directory structure creation
-	t.Run("DirectoryStructure", func(t *testing.T) {
-		t.Parallel()
+	artifactPath := filepath.Join(
+		tmpDir,
+		fqn.Namespace,
+		fqn.Name,
+		versionHash+".wasm",
+	)
+	//nolint:gosec // Test file permissions
+	err = os.WriteFile(artifactPath, []byte("tampered content"), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to overwrite artifact: %v", err)
+	}
 
-		// Setup
-		tmpDir, registry := setupTest(t)
-		//nolint:errcheck // defer in test
-		defer os.RemoveAll(tmpDir)
+	_, err = reg.GetArtifact(t.Context(), fqn, versionHash)
+	if !errors.Is(err, registry.ErrArtifactCorrupt) {
+		t.Errorf("Expected corrupt artifact error, got %v", err)
+	}
+}
 
-		// Store artifact with complex package name
-		complexFqn := &proto_gen.PackageName{
-			Namespace: "complex-author",
-			Name:      "complex-name-with-dashes",
-		}
+// Test that failed uploads do not leave temp files behind
+func TestFilesystemRegistryFailedUpload(t *testing.T) {
+	t.Parallel()
 
-		complexContent := []byte("content for complex artifact")
-		versionHash, err := registry.StoreArtifact(
-			complexFqn,
-			bytes.NewReader(complexContent),
-		)
-		if err != nil {
-			t.Fatalf("Failed to store complex artifact: %v", err)
-		}
+	tmpDir, registry := setupTest(t)
 
-		// Verify directory structure was created correctly
-		expectedDir := filepath.Join(
-			tmpDir,
-			complexFqn.Namespace,
-			complexFqn.Name,
-		)
-		if info, err := os.Stat(expectedDir); err != nil {
-			t.Errorf(
-				"Expected directory not created: %s, error: %v",
-				expectedDir,
-				err,
-			)
-		} else if !info.IsDir() {
-			t.Errorf("Expected path is not a directory: %s", expectedDir)
-		}
+	fqn := &proto_gen.PackageName{
+		Namespace: "testuser",
+		Name:      "testapp",
+	}
+	errUpload := errors.New("upload aborted")
+
+	_, err := registry.StoreArtifact(
+		t.Context(),
+		fqn,
+		iotest.ErrReader(errUpload),
+	)
+	if !errors.Is(err, errUpload) {
+		t.Fatalf("Expected upload error, got %v", err)
+	}
 
-		// Clean up
-		_ = registry.DeleteArtifact(complexFqn, versionHash)
-	})
+	temps, err := filepath.Glob(filepath.Join(tmpDir, "*.tmp"))
+	if err != nil {
+		t.Fatalf("Failed to list temp files: %v", err)
+	}
+	if len(temps) != 0 {
+		t.Errorf("Expected no temp files after failed upload, got %v", temps)
+	}
 }
 
 // setupTest creates a temporary directory and registry for testing
@@ -310,15 +152,9 @@ func setupTest(t *testing.T) (string, *FilesystemRegistry) {
 		"TESTING",
 		config.Defaults...)
 
-	tmpDir, err := os.MkdirTemp("", "registry-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
-	}
-
+	tmpDir := t.TempDir()
 	registry, err := New(tmpDir)
 	if err != nil {
-		//nolint:errcheck,gosec // defer in test
-		os.RemoveAll(tmpDir)
 		t.Fatalf("Failed to create registry: %v", err)
 	}
 
@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"artifact-registry/metrics"
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"artifact-registry/tracing"
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Scrapes fail instead of piling up while the database is slow
+const usageCollectTimeout = 5 * time.Second
+
+// InstrumentRegistry wraps a storage backend to trace its operations and to
+// record their latency and the bytes passing through it under the given
+// backend name
+func InstrumentRegistry(reg Registry, backend string) Registry {
+	if reg == nil {
+		return nil
+	}
+
+	return &instrumentedRegistry{Registry: reg, backend: backend}
+}
+
+type instrumentedRegistry struct {
+	Registry
+
+	backend string
+}
+
+func (r *instrumentedRegistry) StoreArtifact(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	reader io.Reader,
+) (string, error) {
+	ctx, span := r.startSpan(ctx, "StoreArtifact", pkg, "")
+	counter := &countingReader{Reader: reader}
+	start := time.Now()
+	hash, err := r.Registry.StoreArtifact(ctx, pkg, counter)
+	metrics.ObserveStorage(r.backend, "store", time.Since(start), err)
+	if err == nil {
+		metrics.AddStorageBytes(r.backend, "write", counter.n)
+	}
+	span.SetAttributes(
+		attribute.String("artifact.hash", hash),
+		attribute.Int("artifact.size", counter.n),
+	)
+	tracing.End(span, err)
+
+	//nolint:wrapcheck // Errors of the backend are passed on unchanged
+	return hash, err
+}
+
+func (r *instrumentedRegistry) GetArtifact(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	hash string,
+) ([]byte, error) {
+	ctx, span := r.startSpan(ctx, "GetArtifact", pkg, hash)
+	start := time.Now()
+	content, err := r.Registry.GetArtifact(ctx, pkg, hash)
+	metrics.ObserveStorage(r.backend, "get", time.Since(start), err)
+	if err == nil {
+		metrics.AddStorageBytes(r.backend, "read", len(content))
+	}
+	span.SetAttributes(attribute.Int("artifact.size", len(content)))
+	tracing.End(span, err)
+
+	//nolint:wrapcheck // Errors of the backend are passed on unchanged
+	return content, err
+}
+
+func (r *instrumentedRegistry) DeleteArtifact(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	hash string,
+) error {
+	ctx, span := r.startSpan(ctx, "DeleteArtifact", pkg, hash)
+	start := time.Now()
+	err := r.Registry.DeleteArtifact(ctx, pkg, hash)
+	metrics.ObserveStorage(r.backend, "delete", time.Since(start), err)
+	tracing.End(span, err)
+
+	//nolint:wrapcheck // Errors of the backend are passed on unchanged
+	return err
+}
+
+func (r *instrumentedRegistry) LinkArtifact(
+	ctx context.Context,
+	from, to *proto_gen.PackageName,
+	hash string,
+) error {
+	ctx, span := r.startSpan(ctx, "LinkArtifact", from, hash)
+	span.SetAttributes(
+		attribute.String("artifact.target", to.Namespace+"/"+to.Name),
+	)
+	start := time.Now()
+	err := r.Registry.LinkArtifact(ctx, from, to, hash)
+	metrics.ObserveStorage(r.backend, "link", time.Since(start), err)
+	tracing.End(span, err)
+
+	//nolint:wrapcheck // Errors of the backend are passed on unchanged
+	return err
+}
+
+func (r *instrumentedRegistry) startSpan(
+	ctx context.Context,
+	operation string,
+	pkg *proto_gen.PackageName,
+	hash string,
+) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		attribute.String("storage.backend", r.backend),
+		attribute.String("artifact.package", pkg.Namespace+"/"+pkg.Name),
+	}
+	if hash != "" {
+		attributes = append(attributes, attribute.String("artifact.hash", hash))
+	}
+
+	return tracing.Start(ctx, "storage."+operation, attributes...)
+}
+
+type countingReader struct {
+	io.Reader
+
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+
+	//nolint:wrapcheck // Errors of the reader are passed on unchanged
+	return n, err
+}
+
+// UsageCollector reports the storage used by each namespace whenever the
+// metrics are scraped
+func (s *Server) UsageCollector() prometheus.Collector {
+	return &usageCollector{db: s.db}
+}
+
+type usageCollector struct {
+	db orm.MetadataStore
+}
+
+func (c *usageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metrics.NamespaceBytesDesc
+	ch <- metrics.NamespaceVersionsDesc
+	ch <- metrics.NamespaceTrashedBytesDesc
+}
+
+func (c *usageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		usageCollectTimeout,
+	)
+	defer cancel()
+
+	usages, err := c.db.GetNamespaceUsages(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to collect namespace usage metrics")
+		ch <- prometheus.NewInvalidMetric(metrics.NamespaceBytesDesc, err)
+
+		return
+	}
+
+	for _, usage := range usages {
+		ch <- prometheus.MustNewConstMetric(
+			metrics.NamespaceBytesDesc,
+			prometheus.GaugeValue,
+			float64(usage.Bytes),
+			usage.Namespace,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			metrics.NamespaceVersionsDesc,
+			prometheus.GaugeValue,
+			float64(usage.Versions),
+			usage.Namespace,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			metrics.NamespaceTrashedBytesDesc,
+			prometheus.GaugeValue,
+			float64(usage.TrashedBytes),
+			usage.Namespace,
+		)
+	}
+}
@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"artifact-registry/wasm"
+	"io"
+
+	"github.com/rs/zerolog/log"
+)
+
+// inspectArtifact extracts the imports and exports of an uploaded binary.
+// Content that is not valid Wasm is still accepted, it just carries no
+// interface information.
+func inspectArtifact(
+	pkg *proto_gen.PackageName,
+	content []byte,
+) []orm.ArtifactSymbol {
+	iface, err := wasm.Parse(content)
+
+	return interfaceSymbols(pkg, iface, err)
+}
+
+func interfaceSymbols(
+	pkg *proto_gen.PackageName,
+	iface *wasm.Interface,
+	err error,
+) []orm.ArtifactSymbol {
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("namespace", pkg.Namespace).
+			Str("name", pkg.Name).
+			Msg("Could not extract Wasm interface from artifact")
+
+		return nil
+	}
+
+	symbols := make(
+		[]orm.ArtifactSymbol,
+		0,
+		len(iface.Imports)+len(iface.Exports),
+	)
+	for _, sym := range iface.Imports {
+		symbols = append(symbols, symbolToModel(orm.SymbolImport, sym))
+	}
+	for _, sym := range iface.Exports {
+		symbols = append(symbols, symbolToModel(orm.SymbolExport, sym))
+	}
+
+	return symbols
+}
+
+// uploadInspector extracts the interface, size and media type of an upload
+// while it is written to the storage backend, so the content never has to
+// be held in memory as a whole
+type uploadInspector struct {
+	pw     *io.PipeWriter
+	done   chan struct{}
+	size   int64
+	header []byte
+	iface  *wasm.Interface
+	err    error
+}
+
+func newUploadInspector() *uploadInspector {
+	pr, pw := io.Pipe()
+	inspector := &uploadInspector{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(inspector.done)
+		inspector.iface, inspector.err = wasm.ParseReader(pr)
+		// The parser stops at the end of the binary or at the first error,
+		// the rest of the upload still has to be consumed
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	return inspector
+}
+
+// Write never fails, content that cannot be inspected is still stored
+func (u *uploadInspector) Write(p []byte) (int, error) {
+	u.size += int64(len(p))
+	if missing := wasm.HeaderSize - len(u.header); missing > 0 {
+		u.header = append(u.header, p[:min(missing, len(p))]...)
+	}
+	_, _ = u.pw.Write(p)
+
+	return len(p), nil
+}
+
+// close marks the end of the upload and waits for the parser. The results
+// are only available afterwards.
+func (u *uploadInspector) close() {
+	_ = u.pw.Close()
+	<-u.done
+}
+
+func (u *uploadInspector) symbols(
+	pkg *proto_gen.PackageName,
+) []orm.ArtifactSymbol {
+	return interfaceSymbols(pkg, u.iface, u.err)
+}
+
+func (u *uploadInspector) mediaType() string {
+	return wasm.DetectMediaType(u.header)
+}
+
+func symbolToModel(direction string, sym wasm.Symbol) orm.ArtifactSymbol {
+	return orm.ArtifactSymbol{
+		Direction:  direction,
+		Module:     sym.Module,
+		SymbolName: sym.Name,
+		Kind:       string(sym.Kind),
+		Signature:  sym.Signature,
+	}
+}
+
+// interfaceToProto groups stored symbols into the API representation. Returns
+// nil if the artifact has no recorded interface.
+func interfaceToProto(symbols []orm.ArtifactSymbol) *proto_gen.WasmInterface {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	result := &proto_gen.WasmInterface{}
+	for _, sym := range symbols {
+		protoSymbol := &proto_gen.WasmSymbol{
+			Module:    sym.Module,
+			Name:      sym.SymbolName,
+			Kind:      sym.Kind,
+			Signature: sym.Signature,
+		}
+		if sym.Direction == orm.SymbolImport {
+			result.Imports = append(result.Imports, protoSymbol)
+		} else {
+			result.Exports = append(result.Exports, protoSymbol)
+		}
+	}
+
+	return result
+}
+
+func symbolFilters(
+	direction string,
+	filters []*proto_gen.SymbolFilter,
+) []orm.SymbolFilter {
+	result := make([]orm.SymbolFilter, 0, len(filters))
+	for _, f := range filters {
+		result = append(result, orm.SymbolFilter{
+			Direction: direction,
+			Module:    f.GetModule(),
+			Name:      f.Name,
+		})
+	}
+
+	return result
+}
+
+// interfaceFromSymbols restores the parsed form of stored symbols. Returns nil
+// if the artifact has no recorded interface.
+func interfaceFromSymbols(symbols []orm.ArtifactSymbol) *wasm.Interface {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	result := &wasm.Interface{}
+	for _, sym := range symbols {
+		wasmSymbol := wasm.Symbol{
+			Module:    sym.Module,
+			Name:      sym.SymbolName,
+			Kind:      wasm.Kind(sym.Kind),
+			Signature: sym.Signature,
+		}
+		if sym.Direction == orm.SymbolImport {
+			result.Imports = append(result.Imports, wasmSymbol)
+		} else {
+			result.Exports = append(result.Exports, wasmSymbol)
+		}
+	}
+
+	return result
+}
+
+func wasmSymbolToProto(sym *wasm.Symbol) *proto_gen.WasmSymbol {
+	if sym == nil {
+		return nil
+	}
+
+	return &proto_gen.WasmSymbol{
+		Module:    sym.Module,
+		Name:      sym.Name,
+		Kind:      string(sym.Kind),
+		Signature: sym.Signature,
+	}
+}
@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+)
+
+var labelOperators = map[proto_gen.LabelSelector_Operator]orm.LabelOperator{
+	proto_gen.LabelSelector_EQUALS:         orm.LabelEquals,
+	proto_gen.LabelSelector_NOT_EQUALS:     orm.LabelNotEquals,
+	proto_gen.LabelSelector_EXISTS:         orm.LabelExists,
+	proto_gen.LabelSelector_DOES_NOT_EXIST: orm.LabelDoesNotExist,
+	proto_gen.LabelSelector_IN:             orm.LabelIn,
+	proto_gen.LabelSelector_NOT_IN:         orm.LabelNotIn,
+}
+
+func (s *Server) SetLabels(
+	ctx context.Context,
+	request *proto_gen.SetLabelsRequest,
+) (*proto_gen.Artifact, error) {
+	if request.Artifact == nil {
+		log.Error().Msg("SetLabelsRequest missing artifact")
+
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Artifact must be provided",
+		}
+	}
+
+	if err := validateLabels(request.Labels); err != nil {
+		log.Error().Err(err).Msg("Invalid labels in SetLabelsRequest")
+
+		return nil, err
+	}
+
+	artifactMeta, err := s.resolveIdentifier(ctx, request.Artifact)
+	if err != nil {
+		return nil, err // Already wrapped by resolveIdentifier
+	}
+
+	log.Info().
+		Str("namespace", artifactMeta.Namespace).
+		Str("name", artifactMeta.Name).
+		Str("versionHash", artifactMeta.Hash).
+		Int("labels", len(request.Labels)).
+		Msg("Setting labels of artifact")
+
+	err = s.db.SetLabels(
+		auditContext(ctx),
+		request.Artifact.Package,
+		artifactMeta.Hash,
+		request.Labels,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set labels")
+
+		return nil, wrapServiceError(err, "setting labels on artifact")
+	}
+
+	return s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+		Package: request.Artifact.Package,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifactMeta.Hash,
+		},
+	})
+}
+
+func labelsToMap(labels []orm.ArtifactLabel) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(labels))
+	for _, l := range labels {
+		result[l.Key] = l.Value
+	}
+
+	return result
+}
+
+func labelSelectors(
+	selectors []*proto_gen.LabelSelector,
+) ([]orm.LabelSelector, error) {
+	result := make([]orm.LabelSelector, 0, len(selectors))
+	for _, selector := range selectors {
+		operator, ok := labelOperators[selector.Operator]
+		if !ok {
+			return nil, &ServiceError{
+				Code: codes.InvalidArgument,
+				Message: "Unknown label selector operator " +
+					selector.Operator.String(),
+			}
+		}
+		result = append(result, orm.LabelSelector{
+			Key:      selector.Key,
+			Operator: operator,
+			Values:   selector.Values,
+		})
+	}
+
+	return result, nil
+}
@@ -2,9 +2,10 @@ package memoryRegistry
 
 import (
 	"artifact-registry/proto_gen"
+	"artifact-registry/registry"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -23,8 +24,6 @@ func (e *IOError) Unwrap() error {
 	return e.Err
 }
 
-var ErrArtifactNotFound = errors.New("artifact not found")
-
 // MemoryRegistry implements the registry interface using in-memory storage.
 // Used only for testing.
 type MemoryRegistry struct {
@@ -41,11 +40,12 @@ func New() *MemoryRegistry {
 
 // StoreArtifact stores an artifact in memory and returns its version hash
 func (r *MemoryRegistry) StoreArtifact(
+	ctx context.Context,
 	pkg *proto_gen.PackageName,
 	reader io.Reader,
 ) (string, error) {
 	// Read all content from reader
-	content, err := io.ReadAll(reader)
+	content, err := io.ReadAll(registry.ContextReader(ctx, reader))
 	if err != nil {
 		return "", &IOError{
 			Operation: "reading artifact content",
@@ -71,6 +71,7 @@ func (r *MemoryRegistry) StoreArtifact(
 
 // GetArtifact retrieves an artifact by identifier
 func (r *MemoryRegistry) GetArtifact(
+	_ context.Context,
 	pkg *proto_gen.PackageName,
 	hash string,
 ) ([]byte, error) {
@@ -83,7 +84,7 @@ func (r *MemoryRegistry) GetArtifact(
 	if !exists {
 		return nil, &IOError{
 			Operation: "reading of artifact",
-			Err:       ErrArtifactNotFound,
+			Err:       registry.ErrArtifactNotFound,
 		}
 	}
 
@@ -96,6 +97,7 @@ func (r *MemoryRegistry) GetArtifact(
 
 // DeleteArtifact deletes an artifact by identifier
 func (r *MemoryRegistry) DeleteArtifact(
+	_ context.Context,
 	pkg *proto_gen.PackageName,
 	hash string,
 ) error {
@@ -107,7 +109,7 @@ func (r *MemoryRegistry) DeleteArtifact(
 	if _, exists := r.artifacts[key]; !exists {
 		return &IOError{
 			Operation: "deletion of artifact",
-			Err:       ErrArtifactNotFound,
+			Err:       registry.ErrArtifactNotFound,
 		}
 	}
 
@@ -116,6 +118,30 @@ func (r *MemoryRegistry) DeleteArtifact(
 	return nil
 }
 
+// LinkArtifact makes an artifact of one package available under another one
+// without copying its content
+func (r *MemoryRegistry) LinkArtifact(
+	_ context.Context,
+	from, to *proto_gen.PackageName,
+	hash string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	content, exists := r.artifacts[r.getArtifactKey(from, hash)]
+	if !exists {
+		return &IOError{
+			Operation: "linking of artifact",
+			Err:       registry.ErrArtifactNotFound,
+		}
+	}
+
+	// Stored content is never modified, so it can be shared
+	r.artifacts[r.getArtifactKey(to, hash)] = content
+
+	return nil
+}
+
 // Clear removes all artifacts from memory (useful for testing)
 func (r *MemoryRegistry) Clear() {
 	r.mu.Lock()
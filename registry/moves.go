@@ -0,0 +1,368 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Moves give up after this many attempts if the source package keeps
+// getting new versions
+const maxMoveAttempts = 3
+
+func (s *Server) MovePackage(
+	ctx context.Context,
+	request *proto_gen.MovePackageRequest,
+) (*proto_gen.Package, error) {
+	for _, name := range []*proto_gen.PackageName{request.From, request.To} {
+		if err := validateFQN(name); err != nil {
+			log.Error().Err(err).Msg("Invalid package in MovePackage request")
+
+			return nil, err
+		}
+	}
+	if proto.Equal(request.From, request.To) {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Package cannot be moved onto itself",
+			Inner:   ErrInvalidPackage,
+		}
+	}
+
+	log.Info().
+		Str("from", request.From.Namespace+"/"+request.From.Name).
+		Str("to", request.To.Namespace+"/"+request.To.Name).
+		Msg("Package move requested")
+
+	if s.registry == nil {
+		return nil, newRegistryUnavailableError("package move")
+	}
+
+	hashes, err := s.db.GetArtifactHashes(ctx, request.From)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list versions to move")
+
+		return nil, wrapServiceError(err, "listing versions to move")
+	}
+	if len(hashes) == 0 {
+		if _, err := s.db.GetPackage(ctx, request.From); err != nil {
+			return nil, wrapServiceError(err, "retrieving package to move")
+		}
+	}
+
+	ns, err := s.db.EnsureNamespace(
+		ctx,
+		s.namespaceFromProto(&proto_gen.Namespace{Name: request.To.Namespace}),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to ensure target namespace record")
+
+		return nil, wrapServiceError(err, "creating target namespace")
+	}
+	// Moves within a namespace do not change its usage
+	if request.From.Namespace != request.To.Namespace {
+		if err := s.checkMoveQuota(ctx, ns, request.From); err != nil {
+			return nil, s.auditRejection(
+				ctx,
+				orm.AuditMovePackage,
+				request.From,
+				"",
+				nil,
+				err,
+			)
+		}
+	}
+
+	move := &orm.PackageMove{
+		FromNamespace: request.From.Namespace,
+		FromName:      request.From.Name,
+		ToNamespace:   request.To.Namespace,
+		ToName:        request.To.Name,
+		State:         orm.MoveCopying,
+	}
+	if request.Redirect {
+		move.RedirectTTL = s.redirectTTL
+	}
+
+	if err := s.db.CreatePackageMove(ctx, move); err != nil {
+		var conflictErr *orm.ConflictError
+		if !errors.As(err, &conflictErr) {
+			log.Error().Err(err).Msg("Failed to start package move")
+
+			return nil, wrapServiceError(err, "starting package move")
+		}
+
+		// An earlier attempt failed, e.g. while copying blobs
+		move, err = s.pendingMove(ctx, move)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.completeMove(auditContext(ctx), move); err != nil {
+		return nil, err
+	}
+
+	pkg, err := s.db.GetPackage(ctx, request.To)
+	var notFoundErr *orm.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return &proto_gen.Package{Name: request.To}, nil
+	}
+	if err != nil {
+		return nil, wrapServiceError(err, "retrieving moved package")
+	}
+
+	return packageToProto(pkg), nil
+}
+
+// ResumePackageMoves completes all moves that were interrupted, e.g. by a
+// crash of the previous process
+func (s *Server) ResumePackageMoves(ctx context.Context) error {
+	if s.registry == nil {
+		return newRegistryUnavailableError("package move")
+	}
+
+	moves, err := s.db.GetPackageMoves(ctx)
+	if err != nil {
+		return wrapServiceError(err, "listing package moves")
+	}
+
+	for i := range moves {
+		log.Info().
+			Str("from", moves[i].FromNamespace+"/"+moves[i].FromName).
+			Str("to", moves[i].ToNamespace+"/"+moves[i].ToName).
+			Str("state", moves[i].State).
+			Msg("Resuming interrupted package move")
+
+		if err := s.completeMove(ctx, &moves[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completeMove drives a journaled move to its end. Every step can be repeated,
+// so a move interrupted at any point is completed by calling it again.
+func (s *Server) completeMove(
+	ctx context.Context,
+	move *orm.PackageMove,
+) error {
+	from := &proto_gen.PackageName{
+		Namespace: move.FromNamespace,
+		Name:      move.FromName,
+	}
+	to := &proto_gen.PackageName{
+		Namespace: move.ToNamespace,
+		Name:      move.ToName,
+	}
+
+	if move.State == orm.MoveCopying {
+		if err := s.copyMovedPackage(ctx, move, from, to); err != nil {
+			return err
+		}
+	}
+
+	// Source blobs are only removed if no version under the old name uses
+	// them, e.g. after the same content was uploaded there again
+	moved, err := s.db.GetArtifactHashes(ctx, to)
+	if err != nil {
+		return wrapServiceError(err, "listing moved versions")
+	}
+	remaining, err := s.db.GetArtifactHashes(ctx, from)
+	if err != nil {
+		return wrapServiceError(err, "listing versions under old name")
+	}
+
+	for _, hash := range moved {
+		if slices.Contains(remaining, hash) {
+			continue
+		}
+		if err := s.registry.DeleteArtifact(ctx, from, hash); err != nil {
+			log.Debug().
+				Err(err).
+				Str("versionHash", hash).
+				Msg("Source blob of moved package already gone")
+		}
+	}
+
+	if err := s.db.DeletePackageMove(ctx, move); err != nil {
+		return wrapServiceError(err, "finishing package move")
+	}
+
+	log.Info().
+		Str("from", move.FromNamespace+"/"+move.FromName).
+		Str("to", move.ToNamespace+"/"+move.ToName).
+		Int("versions", len(moved)).
+		Msg("Package moved")
+
+	return nil
+}
+
+// copyMovedPackage links the blobs of all versions to the target and commits
+// the move. Versions uploaded to the source in the meantime make the commit
+// fail, their blobs are linked in another attempt.
+func (s *Server) copyMovedPackage(
+	ctx context.Context,
+	move *orm.PackageMove,
+	from, to *proto_gen.PackageName,
+) error {
+	var linked []string
+	for attempt := 1; ; attempt++ {
+		hashes, err := s.db.GetArtifactHashes(ctx, from)
+		if err != nil {
+			return wrapServiceError(err, "listing versions to move")
+		}
+
+		for _, hash := range hashes {
+			if slices.Contains(linked, hash) {
+				continue
+			}
+			if err := s.registry.LinkArtifact(ctx, from, to, hash); err != nil {
+				log.Error().
+					Err(err).
+					Str("versionHash", hash).
+					Msg("Failed to copy blob of moved package")
+
+				return wrapServiceError(err, "copying blobs of package")
+			}
+			linked = append(linked, hash)
+		}
+
+		err = s.db.CommitPackageMove(ctx, move, hashes)
+		var conflictErr *orm.ConflictError
+		if !errors.As(err, &conflictErr) {
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to switch package metadata")
+
+				return wrapServiceError(err, "switching package metadata")
+			}
+
+			return nil
+		}
+
+		current, listErr := s.db.GetArtifactHashes(ctx, from)
+		changed := listErr == nil && !slices.Equal(current, hashes)
+		if changed && attempt < maxMoveAttempts {
+			log.Info().
+				Str("from", move.FromNamespace+"/"+move.FromName).
+				Msg("Versions of moved package changed, copying again")
+
+			continue
+		}
+
+		s.abortMove(ctx, move, linked)
+		if changed {
+			return &ServiceError{
+				Code:    codes.Aborted,
+				Message: "Package kept changing during the move, try again",
+				Inner:   err,
+			}
+		}
+
+		// The target was taken since the move started
+		return &ServiceError{
+			Code:    codes.AlreadyExists,
+			Message: "Target package already exists",
+			Inner:   err,
+		}
+	}
+}
+
+// pendingMove returns the journaled move of the same package, so a move that
+// failed can be completed by requesting it again
+func (s *Server) pendingMove(
+	ctx context.Context,
+	move *orm.PackageMove,
+) (*orm.PackageMove, error) {
+	moves, err := s.db.GetPackageMoves(ctx)
+	if err != nil {
+		return nil, wrapServiceError(err, "listing package moves")
+	}
+
+	for i := range moves {
+		if moves[i].FromNamespace != move.FromNamespace ||
+			moves[i].FromName != move.FromName {
+			continue
+		}
+		if moves[i].ToNamespace != move.ToNamespace ||
+			moves[i].ToName != move.ToName {
+			return nil, &ServiceError{
+				Code: codes.AlreadyExists,
+				Message: "Package is already being moved to " +
+					moves[i].ToNamespace + "/" + moves[i].ToName,
+				Inner: ErrInvalidPackage,
+			}
+		}
+
+		log.Info().
+			Str("from", move.FromNamespace+"/"+move.FromName).
+			Str("state", moves[i].State).
+			Msg("Resuming failed package move")
+
+		return &moves[i], nil
+	}
+
+	// The other move finished in the meantime
+	return nil, &ServiceError{
+		Code:    codes.Aborted,
+		Message: "Package was moved concurrently, try again",
+		Inner:   ErrInvalidPackage,
+	}
+}
+
+// abortMove removes the blobs copied for a move that cannot be committed,
+// except those that belong to versions of the target
+func (s *Server) abortMove(
+	ctx context.Context,
+	move *orm.PackageMove,
+	hashes []string,
+) {
+	to := &proto_gen.PackageName{
+		Namespace: move.ToNamespace,
+		Name:      move.ToName,
+	}
+
+	existing, err := s.db.GetArtifactHashes(ctx, to)
+	if err != nil {
+		log.Warn().Err(err).Msg("Keeping blobs of aborted package move")
+	} else {
+		for _, hash := range hashes {
+			if !slices.Contains(existing, hash) {
+				_ = s.registry.DeleteArtifact(ctx, to, hash)
+			}
+		}
+	}
+
+	if err := s.db.DeletePackageMove(ctx, move); err != nil {
+		log.Warn().Err(err).Msg("Failed to remove aborted package move")
+	}
+}
+
+// followRedirect returns the package a moved package now lives at, or nil if
+// it has not been moved
+func (s *Server) followRedirect(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+) *proto_gen.PackageName {
+	redirect, err := s.db.GetPackageRedirect(ctx, pkg)
+	if err != nil {
+		var notFoundErr *orm.NotFoundError
+		if !errors.As(err, &notFoundErr) {
+			log.Warn().Err(err).Msg("Failed to look up package redirect")
+		}
+
+		return nil
+	}
+
+	return &proto_gen.PackageName{
+		Namespace: redirect.TargetNamespace,
+		Name:      redirect.TargetName,
+	}
+}
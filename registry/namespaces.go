@@ -0,0 +1,354 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrInvalidNamespace = errors.New("invalid namespace")
+
+func (s *Server) CreateNamespace(
+	ctx context.Context,
+	request *proto_gen.Namespace,
+) (*proto_gen.Namespace, error) {
+	if err := validateNamespace(request); err != nil {
+		log.Error().Err(err).Msg("Invalid namespace in CreateNamespace request")
+
+		return nil, err
+	}
+
+	log.Info().Str("namespace", request.Name).Msg("Namespace creation requested")
+
+	err := s.db.CreateNamespace(ctx, s.namespaceFromProto(request))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create namespace")
+
+		return nil, wrapServiceError(err, "creating namespace")
+	}
+
+	return s.GetNamespace(ctx, &proto_gen.NamespaceName{Name: request.Name})
+}
+
+func (s *Server) GetNamespace(
+	ctx context.Context,
+	name *proto_gen.NamespaceName,
+) (*proto_gen.Namespace, error) {
+	if name.GetName() == "" {
+		return nil, newInvalidNamespaceError("Namespace name must be provided")
+	}
+
+	ns, err := s.db.GetNamespace(ctx, name.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get namespace")
+
+		return nil, wrapServiceError(err, "retrieving namespace")
+	}
+
+	usages, err := s.db.GetNamespaceUsages(ctx, name.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get namespace usage")
+
+		return nil, wrapServiceError(err, "retrieving namespace usage")
+	}
+
+	var usage orm.NamespaceUsage
+	if len(usages) > 0 {
+		usage = usages[0]
+	}
+
+	return namespaceToProto(ns, usage), nil
+}
+
+func (s *Server) ListNamespaces(
+	ctx context.Context,
+	_ *proto_gen.NamespaceQuery,
+) (*proto_gen.NamespaceListResponse, error) {
+	log.Info().Msg("Namespaces listed")
+
+	namespaces, err := s.db.ListNamespaces(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list namespaces")
+
+		return nil, wrapServiceError(err, "listing namespaces")
+	}
+
+	usages, err := s.db.GetNamespaceUsages(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get namespace usages")
+
+		return nil, wrapServiceError(err, "retrieving namespace usages")
+	}
+
+	usageByName := make(map[string]orm.NamespaceUsage, len(usages))
+	for _, u := range usages {
+		usageByName[u.Namespace] = u
+	}
+
+	result := make([]*proto_gen.Namespace, 0, len(namespaces))
+	for i := range namespaces {
+		result = append(
+			result,
+			namespaceToProto(&namespaces[i], usageByName[namespaces[i].Name]),
+		)
+	}
+
+	return &proto_gen.NamespaceListResponse{Namespaces: result}, nil
+}
+
+func (s *Server) UpdateNamespace(
+	ctx context.Context,
+	request *proto_gen.Namespace,
+) (*proto_gen.Namespace, error) {
+	if err := validateNamespace(request); err != nil {
+		log.Error().Err(err).Msg("Invalid namespace in UpdateNamespace request")
+
+		return nil, err
+	}
+
+	log.Info().Str("namespace", request.Name).Msg("Namespace update requested")
+
+	err := s.db.UpdateNamespace(ctx, s.namespaceFromProto(request))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update namespace")
+
+		return nil, wrapServiceError(err, "updating namespace")
+	}
+
+	return s.GetNamespace(ctx, &proto_gen.NamespaceName{Name: request.Name})
+}
+
+func (s *Server) DeleteNamespace(
+	ctx context.Context,
+	name *proto_gen.NamespaceName,
+) (*proto_gen.Namespace, error) {
+	log.Info().
+		Str("namespace", name.GetName()).
+		Msg("Namespace deletion requested")
+
+	if s.registry == nil {
+		return nil, newRegistryUnavailableError("namespace deletion")
+	}
+
+	ns, err := s.GetNamespace(ctx, name)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	notFoundErr := err
+
+	artifacts, err := s.db.QueryArtifactMetas(
+		ctx,
+		orm.ArtifactFilter{Namespace: name.Name},
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list artifacts of namespace")
+
+		return nil, wrapServiceError(err, "listing artifacts of namespace")
+	}
+
+	trashed, err := s.db.GetDeletedArtifactMetas(
+		ctx,
+		orm.TrashFilter{Namespace: name.Name},
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list trash of namespace")
+
+		return nil, wrapServiceError(err, "listing trash of namespace")
+	}
+	artifacts = append(artifacts, trashed...)
+
+	// Versions uploaded before namespaces had records are deleted all the
+	// same
+	if notFoundErr != nil {
+		if len(artifacts) == 0 {
+			return nil, notFoundErr
+		}
+		ns = &proto_gen.Namespace{Name: name.Name}
+	}
+
+	// Metadata goes first, so a failure cannot leave versions without blobs
+	// behind. Blobs that fail to be removed are merely orphaned.
+	if err := s.db.DeleteNamespace(auditContext(ctx), name.Name); err != nil {
+		log.Error().Err(err).Msg("Failed to delete namespace")
+
+		return nil, wrapServiceError(err, "deleting namespace")
+	}
+
+	for _, a := range artifacts {
+		pkg := &proto_gen.PackageName{Namespace: a.Namespace, Name: a.Name}
+		if err := s.registry.DeleteArtifact(ctx, pkg, a.Hash); err != nil {
+			log.Warn().
+				Err(err).
+				Str("namespace", a.Namespace).
+				Str("name", a.Name).
+				Str("versionHash", a.Hash).
+				Msg("Failed to delete blob of deleted namespace")
+		}
+	}
+
+	return ns, nil
+}
+
+// uploadAllowance creates the namespace of an upload if needed and returns
+// how many bytes may still be uploaded into it. It fails if the namespace
+// has already used up its quota. Trashed versions count towards the storage
+// quota, as their blobs are kept until purged, but not towards the version
+// quota. Concurrent uploads are not accounted for, so they can exceed the
+// quota by their combined size.
+func (s *Server) uploadAllowance(
+	ctx context.Context,
+	namespace string,
+) (int64, error) {
+	ns, err := s.db.EnsureNamespace(
+		ctx,
+		s.namespaceFromProto(&proto_gen.Namespace{Name: namespace}),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to ensure namespace record")
+
+		return 0, wrapServiceError(err, "creating namespace")
+	}
+
+	usages, err := s.db.GetNamespaceUsages(ctx, namespace)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get namespace usage")
+
+		return 0, wrapServiceError(err, "retrieving namespace usage")
+	}
+
+	var usage orm.NamespaceUsage
+	if len(usages) > 0 {
+		usage = usages[0]
+	}
+
+	if ns.QuotaVersions > 0 && usage.Versions >= ns.QuotaVersions {
+		return 0, newQuotaExceededError(namespace, "version")
+	}
+
+	if ns.QuotaBytes == 0 {
+		return math.MaxInt64, nil
+	}
+
+	// Trashed versions keep their blobs until they are purged
+	used := usage.Bytes + usage.TrashedBytes
+	if used >= ns.QuotaBytes {
+		return 0, newQuotaExceededError(namespace, "storage")
+	}
+
+	return ns.QuotaBytes - used, nil
+}
+
+// checkMoveQuota returns an error if the versions of pkg, including trashed
+// ones, do not fit into the quota of the namespace they are moved to
+func (s *Server) checkMoveQuota(
+	ctx context.Context,
+	ns *orm.Namespace,
+	pkg *proto_gen.PackageName,
+) error {
+	if ns.QuotaBytes == 0 && ns.QuotaVersions == 0 {
+		return nil
+	}
+
+	usages, err := s.db.GetNamespaceUsages(ctx, ns.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get namespace usage")
+
+		return wrapServiceError(err, "retrieving namespace usage")
+	}
+
+	var usage orm.NamespaceUsage
+	if len(usages) > 0 {
+		usage = usages[0]
+	}
+
+	versions, err := s.db.GetArtifactMetasByFQN(ctx, pkg)
+	if err != nil {
+		return wrapServiceError(err, "listing versions to move")
+	}
+	trashed, err := s.db.GetDeletedArtifactMetas(
+		ctx,
+		orm.TrashFilter{Namespace: pkg.Namespace, Name: pkg.Name},
+	)
+	if err != nil {
+		return wrapServiceError(err, "listing trashed versions to move")
+	}
+
+	if ns.QuotaVersions > 0 &&
+		usage.Versions+int64(len(versions)) > ns.QuotaVersions {
+		return newQuotaExceededError(ns.Name, "version")
+	}
+
+	used := usage.Bytes + usage.TrashedBytes
+	for _, artifact := range slices.Concat(versions, trashed) {
+		used += artifact.SizeBytes
+	}
+	if ns.QuotaBytes > 0 && used > ns.QuotaBytes {
+		return newQuotaExceededError(ns.Name, "storage")
+	}
+
+	return nil
+}
+
+func validateNamespace(ns *proto_gen.Namespace) error {
+	if ns.GetName() == "" {
+		return newInvalidNamespaceError("Namespace name must be provided")
+	}
+
+	if ns.Quota.GetMaxBytes() < 0 || ns.Quota.GetMaxVersions() < 0 {
+		return newInvalidNamespaceError("Namespace quota cannot be negative")
+	}
+
+	return nil
+}
+
+func newInvalidNamespaceError(message string) error {
+	return &ServiceError{
+		Code:    codes.InvalidArgument,
+		Message: message,
+		Inner:   ErrInvalidNamespace,
+	}
+}
+
+// namespaceFromProto converts a namespace, applying the default quota if
+// none is given
+func (s *Server) namespaceFromProto(ns *proto_gen.Namespace) *orm.Namespace {
+	quota := ns.Quota
+	if quota == nil {
+		quota = s.defaultQuota
+	}
+
+	return &orm.Namespace{
+		Name:          ns.Name,
+		Description:   ns.Description,
+		QuotaBytes:    quota.MaxBytes,
+		QuotaVersions: quota.MaxVersions,
+	}
+}
+
+func namespaceToProto(
+	ns *orm.Namespace,
+	usage orm.NamespaceUsage,
+) *proto_gen.Namespace {
+	return &proto_gen.Namespace{
+		Name:        ns.Name,
+		Description: ns.Description,
+		Quota: &proto_gen.NamespaceQuota{
+			MaxBytes:    ns.QuotaBytes,
+			MaxVersions: ns.QuotaVersions,
+		},
+		Usage: &proto_gen.NamespaceUsage{
+			Bytes:        usage.Bytes,
+			Versions:     usage.Versions,
+			TrashedBytes: usage.TrashedBytes,
+		},
+		Created: timestamppb.New(ns.CreatedAt),
+	}
+}
@@ -0,0 +1,1014 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"artifact-registry/wasm"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Media types of the OCI artifacts that hold Wasm binaries, following the
+// CNCF Wasm OCI artifact layout
+const (
+	OCIManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	OCIConfigMediaType   = "application/vnd.wasm.config.v0+json"
+	OCILayerMediaType    = "application/wasm"
+)
+
+const (
+	// How long pushed blobs and unfinished uploads are kept for a manifest
+	// after they were last touched
+	ociStagingTTL = time.Hour
+	// Manifests and configs are small JSON documents, anything larger is
+	// rejected
+	ociMaxManifestSize = 4 * 1024 * 1024
+	ociMaxConfigSize   = 4 * 1024 * 1024
+)
+
+// Layer media types accepted on push. wasm-to-oci uses the second one.
+var ociLayerMediaTypes = []string{
+	OCILayerMediaType,
+	"application/vnd.wasm.content.layer.v1+wasm",
+}
+
+var (
+	ErrDigestMismatch    = errors.New("digest does not match content")
+	ErrUnknownOCIUpload  = errors.New("unknown blob upload")
+	ErrOCIUploadTooLarge = errors.New("blob exceeds the maximum upload size")
+)
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	ArtifactType  string          `json:"artifactType,omitempty"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+// ociConfig is the Wasm config blob. Only immutable properties of a version
+// go into it, so the digests of its config and manifest never change.
+type ociConfig struct {
+	Created      string   `json:"created"`
+	Author       string   `json:"author,omitempty"`
+	Architecture string   `json:"architecture"`
+	OS           string   `json:"os"`
+	LayerDigests []string `json:"layerDigests"`
+}
+
+func (s *Server) registerOCIRoutes(mux *http.ServeMux) {
+	const repo = "/v2/{namespace}/{name}"
+
+	mux.HandleFunc("GET /v2/{$}", ociVersionCheck)
+	mux.HandleFunc("GET "+repo+"/manifests/{reference}", s.ociGetManifest)
+	mux.HandleFunc("PUT "+repo+"/manifests/{reference}", s.ociPutManifest)
+	mux.HandleFunc(
+		"DELETE "+repo+"/manifests/{reference}",
+		s.ociDeleteManifest,
+	)
+	mux.HandleFunc("GET "+repo+"/blobs/{digest}", s.ociGetBlob)
+	mux.HandleFunc("DELETE "+repo+"/blobs/{digest}", ociDeleteBlob)
+	mux.HandleFunc("POST "+repo+"/blobs/uploads/{$}", s.ociStartUpload)
+	mux.HandleFunc("GET "+repo+"/blobs/uploads/{id}", s.ociUploadStatus)
+	mux.HandleFunc("PATCH "+repo+"/blobs/uploads/{id}", s.ociPatchUpload)
+	mux.HandleFunc("PUT "+repo+"/blobs/uploads/{id}", s.ociFinishUpload)
+	mux.HandleFunc("DELETE "+repo+"/blobs/uploads/{id}", s.ociCancelUpload)
+	mux.HandleFunc("GET "+repo+"/tags/list", s.ociListTags)
+}
+
+func ociVersionCheck(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("{}"))
+}
+
+func (s *Server) ociGetManifest(w http.ResponseWriter, r *http.Request) {
+	ctx, headers := httpContext(r)
+	artifact, err := s.ociResolveManifest(ctx, r)
+	if err != nil {
+		writeOCIServiceError(w, err, "MANIFEST_UNKNOWN")
+
+		return
+	}
+
+	manifest, err := s.ociManifest(ctx, artifact)
+	if err != nil {
+		writeOCIServiceError(w, err, "MANIFEST_UNKNOWN")
+
+		return
+	}
+
+	copyHeaders(w.Header(), headers)
+	w.Header().Set("Content-Type", manifest.MediaType)
+	w.Header().Set("Docker-Content-Digest", manifest.Digest)
+	w.Header().Set("Content-Length", strconv.Itoa(len(manifest.Content)))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := w.Write(manifest.Content); err != nil {
+		log.Warn().Err(err).Msg("Failed to write OCI manifest")
+	}
+}
+
+// ociPutManifest turns the Wasm layer of a pushed manifest into an artifact
+// and tags it with the reference. The manifest and its config blob are stored
+// with the version and served as they were pushed.
+func (s *Server) ociPutManifest(w http.ResponseWriter, r *http.Request) {
+	pkg := ociPackage(r)
+	if err := validateFQN(pkg); err != nil {
+		writeOCIServiceError(w, err, "NAME_INVALID")
+
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, ociMaxManifestSize+1))
+	if err != nil || len(body) > ociMaxManifestSize {
+		writeOCIError(
+			w,
+			http.StatusRequestEntityTooLarge,
+			"SIZE_INVALID",
+			"Manifest is too large",
+		)
+
+		return
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		writeOCIError(
+			w,
+			http.StatusBadRequest,
+			"MANIFEST_INVALID",
+			"Manifest is not valid JSON: "+err.Error(),
+		)
+
+		return
+	}
+	layer, err := wasmLayer(&manifest)
+	if err != nil {
+		writeOCIError(w, http.StatusBadRequest, "MANIFEST_INVALID", err.Error())
+
+		return
+	}
+
+	digest := ociDigest(body)
+	reference := r.PathValue("reference")
+	tag := ""
+	if _, isDigest := ociDigestHash(reference); !isDigest {
+		tag = reference
+	} else if reference != digest {
+		writeOCIError(
+			w,
+			http.StatusBadRequest,
+			"DIGEST_INVALID",
+			ErrDigestMismatch.Error(),
+		)
+
+		return
+	}
+
+	// The config is looked up first, so a manifest without one does not
+	// leave a version behind
+	ctx, headers := httpContext(r)
+	config, err := s.ociPushedConfig(ctx, pkg, &manifest.Config)
+	if err != nil {
+		writeOCIServiceError(w, err, "MANIFEST_BLOB_UNKNOWN")
+
+		return
+	}
+
+	artifact, err := s.ociStoreLayer(ctx, pkg, layer, tag)
+	if err != nil {
+		writeOCIServiceError(w, err, "MANIFEST_BLOB_UNKNOWN")
+
+		return
+	}
+
+	mediaType := manifest.MediaType
+	if mediaType == "" {
+		mediaType = OCIManifestMediaType
+	}
+	err = s.db.SetOCIManifest(ctx, &orm.OCIManifest{
+		Namespace:    artifact.Package.Namespace,
+		Name:         artifact.Package.Name,
+		Hash:         artifact.VersionHash,
+		Digest:       digest,
+		MediaType:    mediaType,
+		Content:      body,
+		ConfigDigest: manifest.Config.Digest,
+		Config:       config,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store OCI manifest")
+		writeOCIServiceError(
+			w,
+			wrapServiceError(err, "storing OCI manifest"),
+			"MANIFEST_INVALID",
+		)
+
+		return
+	}
+	s.oci.drop(pkg, manifest.Config.Digest)
+
+	copyHeaders(w.Header(), headers)
+	w.Header().Set(
+		"Location",
+		"/v2/"+pkg.Namespace+"/"+pkg.Name+"/manifests/"+digest,
+	)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// ociDeleteManifest deletes the version for a digest, but only removes the
+// tag for a tag reference
+func (s *Server) ociDeleteManifest(w http.ResponseWriter, r *http.Request) {
+	ctx, headers := httpContext(r)
+	artifact, err := s.ociResolveManifest(ctx, r)
+	if err != nil {
+		writeOCIServiceError(w, err, "MANIFEST_UNKNOWN")
+
+		return
+	}
+
+	id := &proto_gen.ArtifactIdentifier{
+		Package: artifact.Package,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifact.VersionHash,
+		},
+	}
+	reference := r.PathValue("reference")
+	if _, isDigest := ociDigestHash(reference); isDigest {
+		_, err = s.DeleteArtifact(ctx, id)
+	} else {
+		_, err = s.SetTags(ctx, &proto_gen.SetTagsRequest{
+			Artifact: id,
+			Tags: slices.DeleteFunc(artifact.Tags, func(t string) bool {
+				return t == reference
+			}),
+		})
+	}
+	if err != nil {
+		writeOCIServiceError(w, err, "MANIFEST_UNKNOWN")
+
+		return
+	}
+
+	copyHeaders(w.Header(), headers)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ociGetBlob serves the Wasm layer of a version, the config blob of its
+// manifest or a blob that was pushed but not referenced by a manifest yet
+func (s *Server) ociGetBlob(w http.ResponseWriter, r *http.Request) {
+	pkg := ociPackage(r)
+	digest := r.PathValue("digest")
+	hash, ok := ociDigestHash(digest)
+	if !ok {
+		writeOCIError(
+			w,
+			http.StatusBadRequest,
+			"DIGEST_INVALID",
+			"Only sha256 digests are supported",
+		)
+
+		return
+	}
+
+	ctx, headers := httpContext(r)
+	copyHeaders(w.Header(), headers)
+	w.Header().Set("Docker-Content-Digest", digest)
+
+	layer, err := s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: hash,
+		},
+	})
+	if err == nil {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set(
+			"Content-Length",
+			strconv.FormatInt(layer.Metadata.GetSizeBytes(), 10),
+		)
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		err = s.PullArtifact(&proto_gen.ArtifactIdentifier{
+			Package: layer.Package,
+			Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+				VersionHash: hash,
+			},
+		}, &httpPullStream{
+			ctx: ctx,
+			w:   w,
+			end: layer.Metadata.GetSizeBytes() - 1,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to stream OCI blob")
+		}
+
+		return
+	}
+	if status.Code(err) != codes.NotFound {
+		writeOCIServiceError(w, err, "BLOB_UNKNOWN")
+
+		return
+	}
+
+	staged, err := s.oci.blob(pkg, digest)
+	if err != nil {
+		writeOCIServiceError(w, err, "BLOB_UNKNOWN")
+
+		return
+	}
+	var content io.Reader
+	var size int64
+	if staged != nil {
+		defer func() { _ = staged.Close() }()
+		info, err := staged.Stat()
+		if err != nil {
+			writeOCIServiceError(
+				w,
+				wrapServiceError(err, "reading staged OCI blob"),
+				"BLOB_UNKNOWN",
+			)
+
+			return
+		}
+		content, size = staged, info.Size()
+	} else {
+		config, err := s.ociConfigBlob(ctx, pkg, digest)
+		if err != nil {
+			writeOCIServiceError(w, err, "BLOB_UNKNOWN")
+
+			return
+		}
+		content, size = bytes.NewReader(config), int64(len(config))
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, content); err != nil {
+		log.Warn().Err(err).Msg("Failed to write OCI blob")
+	}
+}
+
+// ociDeleteBlob is not supported, as blobs are owned by their versions
+func ociDeleteBlob(w http.ResponseWriter, _ *http.Request) {
+	writeOCIError(
+		w,
+		http.StatusMethodNotAllowed,
+		"UNSUPPORTED",
+		"Blobs are deleted together with their manifest",
+	)
+}
+
+// ociStartUpload starts a blob upload. With a digest parameter the body is
+// the whole blob and the upload completes immediately.
+func (s *Server) ociStartUpload(w http.ResponseWriter, r *http.Request) {
+	pkg := ociPackage(r)
+	if err := validateFQN(pkg); err != nil {
+		writeOCIServiceError(w, err, "NAME_INVALID")
+
+		return
+	}
+
+	ctx, _ := httpContext(r)
+	if _, err := s.uploadAllowance(ctx, pkg.Namespace); err != nil {
+		err = s.auditRejection(ctx, orm.AuditUpload, pkg, "", nil, err)
+		writeOCIServiceError(w, err, "DENIED")
+
+		return
+	}
+
+	upload, err := s.oci.create(pkg)
+	if err != nil {
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_INVALID")
+
+		return
+	}
+
+	if digest := r.URL.Query().Get("digest"); digest != "" {
+		if err := s.ociAppend(r, upload); err != nil {
+			s.oci.remove(upload)
+			writeOCIServiceError(w, err, "BLOB_UPLOAD_INVALID")
+
+			return
+		}
+		s.ociFinish(w, upload, digest)
+
+		return
+	}
+
+	writeOCIUploadStatus(w, http.StatusAccepted, upload)
+}
+
+func (s *Server) ociUploadStatus(w http.ResponseWriter, r *http.Request) {
+	upload, unlock, err := s.oci.lock(ociPackage(r), r.PathValue("id"))
+	if err != nil {
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_UNKNOWN")
+
+		return
+	}
+	defer unlock()
+
+	writeOCIUploadStatus(w, http.StatusNoContent, upload)
+}
+
+func (s *Server) ociPatchUpload(w http.ResponseWriter, r *http.Request) {
+	upload, unlock, err := s.oci.lock(ociPackage(r), r.PathValue("id"))
+	if err != nil {
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_UNKNOWN")
+
+		return
+	}
+	defer unlock()
+
+	// Chunks have to be sent in order
+	if contentRange := r.Header.Get("Content-Range"); contentRange != "" {
+		start, _, _ := strings.Cut(contentRange, "-")
+		if start != strconv.FormatInt(upload.size, 10) {
+			writeOCIUploadStatus(
+				w,
+				http.StatusRequestedRangeNotSatisfiable,
+				upload,
+			)
+
+			return
+		}
+	}
+
+	if err := s.ociAppend(r, upload); err != nil {
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_INVALID")
+
+		return
+	}
+
+	writeOCIUploadStatus(w, http.StatusAccepted, upload)
+}
+
+func (s *Server) ociFinishUpload(w http.ResponseWriter, r *http.Request) {
+	upload, unlock, err := s.oci.lock(ociPackage(r), r.PathValue("id"))
+	if err != nil {
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_UNKNOWN")
+
+		return
+	}
+	defer unlock()
+
+	if err := s.ociAppend(r, upload); err != nil {
+		s.oci.remove(upload)
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_INVALID")
+
+		return
+	}
+
+	s.ociFinish(w, upload, r.URL.Query().Get("digest"))
+}
+
+func (s *Server) ociCancelUpload(w http.ResponseWriter, r *http.Request) {
+	upload, unlock, err := s.oci.lock(ociPackage(r), r.PathValue("id"))
+	if err != nil {
+		writeOCIServiceError(w, err, "BLOB_UPLOAD_UNKNOWN")
+
+		return
+	}
+	defer unlock()
+
+	s.oci.remove(upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ociFinish verifies the content of a completed upload and stages it. The
+// upload is removed either way. Must be called with the lock of the upload
+// held, unless it is not shared.
+func (s *Server) ociFinish(
+	w http.ResponseWriter,
+	upload *ociUpload,
+	digest string,
+) {
+	hash, ok := ociDigestHash(digest)
+	if !ok {
+		s.oci.remove(upload)
+		writeOCIError(
+			w,
+			http.StatusBadRequest,
+			"DIGEST_INVALID",
+			"A sha256 digest must be provided",
+		)
+
+		return
+	}
+
+	if err := s.oci.finish(upload, hash); err != nil {
+		writeOCIServiceError(w, err, "DIGEST_INVALID")
+
+		return
+	}
+
+	w.Header().Set(
+		"Location",
+		"/v2/"+upload.pkg.Namespace+"/"+upload.pkg.Name+"/blobs/"+digest,
+	)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// ociAppend adds a chunk of the request to an upload, as long as the
+// namespace quota and the maximum upload size allow. Chunks over the quota
+// are recorded as rejected uploads. Must be called with the lock of the
+// upload held, unless it is not shared.
+func (s *Server) ociAppend(r *http.Request, upload *ociUpload) error {
+	ctx, _ := httpContext(r)
+	allowance, err := s.uploadAllowance(ctx, upload.pkg.Namespace)
+	if err != nil {
+		return s.auditRejection(ctx, orm.AuditUpload, upload.pkg, "", nil, err)
+	}
+
+	limit, tooLarge := allowance, error(nil)
+	if maxSize := s.oci.maxUploadSize; maxSize > 0 && maxSize < allowance {
+		limit = maxSize
+		tooLarge = &ServiceError{
+			Code: codes.ResourceExhausted,
+			Message: fmt.Sprintf(
+				"Blob exceeds the maximum upload size of %d bytes",
+				maxSize,
+			),
+			Inner: ErrOCIUploadTooLarge,
+		}
+	}
+
+	exceeded, err := upload.append(r.Body, limit)
+	switch {
+	case err != nil || !exceeded:
+		return err
+	case tooLarge != nil:
+		return tooLarge
+	default:
+		return s.auditRejection(
+			ctx,
+			orm.AuditUpload,
+			upload.pkg,
+			"",
+			nil,
+			newQuotaExceededError(upload.pkg.Namespace, "storage"),
+		)
+	}
+}
+
+func (s *Server) ociListTags(w http.ResponseWriter, r *http.Request) {
+	pkg := ociPackage(r)
+	if err := validateFQN(pkg); err != nil {
+		writeOCIServiceError(w, err, "NAME_INVALID")
+
+		return
+	}
+
+	ctx, _ := httpContext(r)
+	versions, err := s.db.GetArtifactMetasByFQN(ctx, pkg)
+	if err != nil {
+		writeOCIServiceError(
+			w,
+			wrapServiceError(err, "listing tags"),
+			"NAME_UNKNOWN",
+		)
+
+		return
+	}
+	if len(versions) == 0 {
+		writeOCIError(
+			w,
+			http.StatusNotFound,
+			"NAME_UNKNOWN",
+			"Repository has no versions",
+		)
+
+		return
+	}
+
+	tags := []string{}
+	for i := range versions {
+		tags = append(tags, tagsToStrings(versions[i].Tags)...)
+	}
+	slices.Sort(tags)
+
+	if last := r.URL.Query().Get("last"); last != "" {
+		i, found := slices.BinarySearch(tags, last)
+		if found {
+			i++
+		}
+		tags = tags[i:]
+	}
+	if n, err := strconv.Atoi(r.URL.Query().Get("n")); err == nil && n >= 0 &&
+		n < len(tags) {
+		tags = tags[:n]
+		if n > 0 {
+			w.Header().Set("Link", fmt.Sprintf(
+				"</v2/%s/%s/tags/list?n=%d&last=%s>; rel=\"next\"",
+				pkg.Namespace,
+				pkg.Name,
+				n,
+				tags[n-1],
+			))
+		}
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"name": pkg.Namespace + "/" + pkg.Name,
+		"tags": tags,
+	})
+	if err != nil {
+		writeOCIServiceError(w, err, "UNKNOWN")
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		log.Warn().Err(err).Msg("Failed to write OCI tag list")
+	}
+}
+
+// ociResolveManifest resolves the manifest reference of a request, which is
+// either a tag or the digest of a pushed or derived manifest
+func (s *Server) ociResolveManifest(
+	ctx context.Context,
+	r *http.Request,
+) (*proto_gen.Artifact, error) {
+	pkg := ociPackage(r)
+	reference := r.PathValue("reference")
+	if _, isDigest := ociDigestHash(reference); !isDigest {
+		return s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+			Package:    pkg,
+			Identifier: &proto_gen.ArtifactIdentifier_Tag{Tag: reference},
+		})
+	}
+
+	if err := validateFQN(pkg); err != nil {
+		return nil, err
+	}
+	pushed, err := s.db.GetOCIManifest(
+		ctx,
+		pkg,
+		orm.OCIManifestFilter{Digest: reference},
+	)
+	var notFoundErr *orm.NotFoundError
+	switch {
+	case err == nil:
+		return s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+			Package: pkg,
+			Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+				VersionHash: pushed.Hash,
+			},
+		})
+	case !errors.As(err, &notFoundErr):
+		return nil, wrapServiceError(err, "getting OCI manifest")
+	}
+
+	versions, err := s.db.GetArtifactMetasByFQN(ctx, pkg)
+	if err != nil {
+		return nil, wrapServiceError(err, "listing versions")
+	}
+	for i := range versions {
+		artifact := artifactToProto(&versions[i])
+		if _, digest := ociManifestFor(artifact); digest == reference {
+			return artifact, nil
+		}
+	}
+
+	return nil, &ServiceError{
+		Code:    codes.NotFound,
+		Message: "Manifest " + reference + " does not exist",
+	}
+}
+
+// ociStoreLayer creates the version for the Wasm layer of a pushed manifest
+// from the staged blob, or tags it if the version already exists
+func (s *Server) ociStoreLayer(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	layer *ociDescriptor,
+	tag string,
+) (*proto_gen.Artifact, error) {
+	hash, _ := ociDigestHash(layer.Digest)
+	id := &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: hash,
+		},
+	}
+
+	// Unlike reads, pushes to the old name of a moved package do not follow
+	// the redirect
+	existingMeta, err := s.resolveIdentifier(ctx, id)
+	if err == nil {
+		existing := artifactToProto(existingMeta)
+		if tag == "" || slices.Contains(existing.Tags, tag) {
+			return existing, nil
+		}
+
+		return s.SetTags(ctx, &proto_gen.SetTagsRequest{
+			Artifact: id,
+			Tags:     append(existing.Tags, tag),
+		})
+	}
+	if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+
+	content, err := s.oci.blob(pkg, layer.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, &ServiceError{
+			Code:    codes.NotFound,
+			Message: "Layer " + layer.Digest + " has not been pushed",
+		}
+	}
+	defer func() { _ = content.Close() }()
+
+	uploadMetadata := &proto_gen.UploadMetadata{Fqn: pkg}
+	if tag != "" {
+		uploadMetadata.Tags = []string{tag}
+	}
+	stream := &httpUploadStream{
+		ctx:      ctx,
+		metadata: uploadMetadata,
+		body:     content,
+	}
+	if err := s.UploadArtifact(stream); err != nil {
+		return nil, err
+	}
+	s.oci.drop(pkg, layer.Digest)
+
+	return s.GetArtifact(ctx, id)
+}
+
+// ociManifest returns the manifest pushed for a version, or derives one for
+// versions that were uploaded through the other APIs
+func (s *Server) ociManifest(
+	ctx context.Context,
+	artifact *proto_gen.Artifact,
+) (*orm.OCIManifest, error) {
+	pushed, err := s.db.GetOCIManifest(
+		ctx,
+		artifact.Package,
+		orm.OCIManifestFilter{Hash: artifact.VersionHash},
+	)
+	var notFoundErr *orm.NotFoundError
+	switch {
+	case err == nil:
+		return pushed, nil
+	case !errors.As(err, &notFoundErr):
+		return nil, wrapServiceError(err, "getting OCI manifest")
+	}
+
+	content, digest := ociManifestFor(artifact)
+
+	return &orm.OCIManifest{
+		Digest:    digest,
+		MediaType: OCIManifestMediaType,
+		Content:   content,
+	}, nil
+}
+
+// ociPushedConfig returns the config blob a pushed manifest references. It
+// has been pushed along with the manifest, or the client skipped it as it
+// already exists.
+func (s *Server) ociPushedConfig(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	config *ociDescriptor,
+) ([]byte, error) {
+	if _, ok := ociDigestHash(config.Digest); !ok {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Config digest must be a sha256 digest",
+		}
+	}
+
+	staged, err := s.oci.blob(pkg, config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if staged == nil {
+		return s.ociConfigBlob(ctx, pkg, config.Digest)
+	}
+	defer func() { _ = staged.Close() }()
+
+	// Config blobs are stored in the metadata database
+	content, err := io.ReadAll(io.LimitReader(staged, ociMaxConfigSize+1))
+	if err != nil {
+		return nil, wrapServiceError(err, "reading staged OCI blob")
+	}
+	if len(content) > ociMaxConfigSize {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Config blob is too large",
+		}
+	}
+
+	return content, nil
+}
+
+// ociConfigBlob finds the config blob with the digest among the pushed
+// manifests of a package and the config blobs derived from its versions
+func (s *Server) ociConfigBlob(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	digest string,
+) ([]byte, error) {
+	pushed, err := s.db.GetOCIManifest(
+		ctx,
+		pkg,
+		orm.OCIManifestFilter{ConfigDigest: digest},
+	)
+	var notFoundErr *orm.NotFoundError
+	switch {
+	case err == nil:
+		return pushed.Config, nil
+	case !errors.As(err, &notFoundErr):
+		return nil, wrapServiceError(err, "getting OCI manifest")
+	}
+
+	versions, err := s.db.GetArtifactMetasByFQN(ctx, pkg)
+	if err != nil {
+		return nil, wrapServiceError(err, "listing versions")
+	}
+
+	for i := range versions {
+		config := ociConfigFor(artifactToProto(&versions[i]))
+		if ociDigest(config) == digest {
+			return config, nil
+		}
+	}
+
+	return nil, &ServiceError{
+		Code:    codes.NotFound,
+		Message: "Blob " + digest + " does not exist",
+	}
+}
+
+// wasmLayer returns the single Wasm layer of a pushed manifest
+func wasmLayer(manifest *ociManifest) (*ociDescriptor, error) {
+	//nolint:mnd // Only schema version 2 exists
+	if manifest.SchemaVersion != 2 {
+		return nil, errors.New("schemaVersion must be 2")
+	}
+	if len(manifest.Layers) != 1 {
+		return nil, errors.New("manifest must have exactly one Wasm layer")
+	}
+
+	layer := &manifest.Layers[0]
+	if !slices.Contains(ociLayerMediaTypes, layer.MediaType) {
+		return nil, fmt.Errorf(
+			"layer media type must be one of %v",
+			ociLayerMediaTypes,
+		)
+	}
+	if _, ok := ociDigestHash(layer.Digest); !ok {
+		return nil, errors.New("layer digest must be a sha256 digest")
+	}
+
+	return layer, nil
+}
+
+// ociManifestFor derives the manifest of a version that was not pushed
+// through the OCI API and its digest
+func ociManifestFor(artifact *proto_gen.Artifact) ([]byte, string) {
+	config := ociConfigFor(artifact)
+	manifest, _ := json.Marshal(ociManifest{
+		SchemaVersion: 2, //nolint:mnd // Current OCI manifest schema
+		MediaType:     OCIManifestMediaType,
+		ArtifactType:  OCIConfigMediaType,
+		Config: ociDescriptor{
+			MediaType: OCIConfigMediaType,
+			Digest:    ociDigest(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []ociDescriptor{{
+			MediaType: OCILayerMediaType,
+			Digest:    "sha256:" + artifact.VersionHash,
+			Size:      artifact.Metadata.GetSizeBytes(),
+		}},
+	})
+
+	return manifest, ociDigest(manifest)
+}
+
+func ociConfigFor(artifact *proto_gen.Artifact) []byte {
+	targetOS := "wasip1"
+	if artifact.Metadata.GetMediaType() == wasm.MediaTypeComponent {
+		targetOS = "wasip2"
+	}
+
+	config, _ := json.Marshal(ociConfig{
+		Created: artifact.Metadata.GetCreated().AsTime().
+			Format(time.RFC3339),
+		Author:       artifact.Metadata.GetUploader(),
+		Architecture: "wasm",
+		OS:           targetOS,
+		LayerDigests: []string{"sha256:" + artifact.VersionHash},
+	})
+
+	return config
+}
+
+func ociDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ociDigestHash returns the hex hash of a sha256 digest
+func ociDigestHash(digest string) (string, bool) {
+	hash, found := strings.CutPrefix(digest, "sha256:")
+
+	return hash, found && isVersionHash(hash)
+}
+
+// ociPackage maps the OCI repository name onto a package
+func ociPackage(r *http.Request) *proto_gen.PackageName {
+	return &proto_gen.PackageName{
+		Namespace: r.PathValue("namespace"),
+		Name:      r.PathValue("name"),
+	}
+}
+
+func writeOCIUploadStatus(
+	w http.ResponseWriter,
+	code int,
+	upload *ociUpload,
+) {
+	w.Header().Set(
+		"Location",
+		"/v2/"+upload.pkg.Namespace+"/"+upload.pkg.Name+
+			"/blobs/uploads/"+upload.id,
+	)
+	w.Header().Set("Docker-Upload-UUID", upload.id)
+	w.Header().Set("Range", fmt.Sprintf("0-%d", max(upload.size-1, 0)))
+	w.WriteHeader(code)
+}
+
+// writeOCIServiceError reports an error of the registry handlers in the OCI
+// error format. code is used for client errors that are not about quotas or
+// permissions.
+func writeOCIServiceError(w http.ResponseWriter, err error, code string) {
+	st := status.Convert(err)
+
+	switch httpStatus := httpStatusFromCode(st.Code()); {
+	case st.Code() == codes.Unauthenticated:
+		code = "UNAUTHORIZED"
+	case st.Code() == codes.ResourceExhausted,
+		st.Code() == codes.PermissionDenied,
+		st.Code() == codes.FailedPrecondition:
+		code = "DENIED"
+	case httpStatus >= http.StatusInternalServerError:
+		code = "UNKNOWN"
+	}
+
+	writeOCIError(w, httpStatusFromCode(st.Code()), code, st.Message())
+}
+
+func writeOCIError(
+	w http.ResponseWriter,
+	httpStatus int,
+	code, message string,
+) {
+	body, _ := json.Marshal(map[string]any{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if _, err := w.Write(body); err != nil {
+		log.Warn().Err(err).Msg("Failed to write OCI error")
+	}
+}
@@ -0,0 +1,307 @@
+package registry
+
+import (
+	"artifact-registry/proto_gen"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+)
+
+// How often expired uploads and blobs are looked for
+const ociPruneInterval = time.Minute
+
+// ociStaging keeps blobs pushed through the OCI API in files until a manifest
+// references them. Only the Wasm layer becomes an artifact, so config blobs
+// and abandoned pushes never reach the storage backend.
+//
+// Uploads and blobs are files below a directory per package, so replicas that
+// share the directory can continue each other's uploads, and uploads survive
+// restarts. Files that were not touched for ociStagingTTL are removed.
+type ociStaging struct {
+	dir string
+	// Zero means unlimited
+	maxUploadSize int64
+
+	mu sync.Mutex
+	// Held while an upload is read or written, chunks of one upload can
+	// arrive concurrently. Only uploads on this replica are serialized.
+	locks     map[string]*sync.Mutex
+	lastPrune time.Time
+}
+
+// ociUpload is an unfinished blob upload, whose data is appended to a file
+type ociUpload struct {
+	pkg  *proto_gen.PackageName
+	id   string
+	path string
+	size int64
+}
+
+func newOCIStaging(dir string, maxUploadSize int64) *ociStaging {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "artifact-registry-oci")
+	}
+
+	return &ociStaging{
+		dir:           dir,
+		maxUploadSize: maxUploadSize,
+		locks:         map[string]*sync.Mutex{},
+	}
+}
+
+// packageDir returns the staging directory of a package. Names are hashed,
+// as they can contain anything.
+func (o *ociStaging) packageDir(pkg *proto_gen.PackageName) string {
+	sum := sha256.Sum256([]byte(pkg.Namespace + "/" + pkg.Name))
+
+	return filepath.Join(o.dir, hex.EncodeToString(sum[:]))
+}
+
+func (o *ociStaging) uploadPath(pkg *proto_gen.PackageName, id string) string {
+	return filepath.Join(o.packageDir(pkg), "uploads", id)
+}
+
+func (o *ociStaging) blobPath(pkg *proto_gen.PackageName, hash string) string {
+	return filepath.Join(o.packageDir(pkg), "blobs", hash)
+}
+
+// create starts an empty upload
+func (o *ociStaging) create(pkg *proto_gen.PackageName) (*ociUpload, error) {
+	o.prune()
+
+	upload := &ociUpload{pkg: pkg, id: rand.Text()}
+	upload.path = o.uploadPath(pkg, upload.id)
+	//nolint:gosec,mnd // Directory permissions 0755 are intentional
+	if err := os.MkdirAll(filepath.Dir(upload.path), 0o755); err != nil {
+		return nil, wrapServiceError(err, "creating OCI staging directory")
+	}
+
+	//nolint:gosec,mnd // File permissions 0644 are intentional
+	file, err := os.OpenFile(
+		upload.path,
+		os.O_WRONLY|os.O_CREATE|os.O_EXCL,
+		0o644,
+	)
+	if err != nil {
+		return nil, wrapServiceError(err, "creating OCI upload")
+	}
+
+	return upload, wrapServiceError(file.Close(), "creating OCI upload")
+}
+
+// lock opens an upload of a package and holds its lock until unlock is
+// called. The upload is marked as in use, so it does not expire.
+func (o *ociStaging) lock(
+	pkg *proto_gen.PackageName,
+	id string,
+) (*ociUpload, func(), error) {
+	o.prune()
+
+	unknown := &ServiceError{
+		Code:    codes.NotFound,
+		Message: "Blob upload " + id + " does not exist",
+		Inner:   ErrUnknownOCIUpload,
+	}
+	// IDs are created by rand.Text, anything else could escape the directory
+	if id == "" || strings.Trim(id, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567") != "" {
+		return nil, nil, unknown
+	}
+
+	o.mu.Lock()
+	lock, ok := o.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		o.locks[id] = lock
+	}
+	o.mu.Unlock()
+	lock.Lock()
+
+	upload := &ociUpload{pkg: pkg, id: id, path: o.uploadPath(pkg, id)}
+	now := time.Now()
+	err := os.Chtimes(upload.path, now, now)
+	var info fs.FileInfo
+	if err == nil {
+		info, err = os.Stat(upload.path)
+	}
+	if err != nil {
+		lock.Unlock()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, unknown
+		}
+
+		return nil, nil, wrapServiceError(err, "opening OCI upload")
+	}
+	upload.size = info.Size()
+
+	return upload, lock.Unlock, nil
+}
+
+// remove drops an upload. Must be called with the lock of the upload held.
+func (o *ociStaging) remove(upload *ociUpload) {
+	if err := os.Remove(upload.path); err != nil &&
+		!errors.Is(err, fs.ErrNotExist) {
+		log.Warn().Err(err).Str("upload", upload.id).
+			Msg("Failed to remove OCI upload")
+	}
+
+	o.mu.Lock()
+	delete(o.locks, upload.id)
+	o.mu.Unlock()
+}
+
+// finish turns a completed upload into a staged blob if its content matches
+// the hash. The upload is gone afterwards either way. Must be called with the
+// lock of the upload held.
+func (o *ociStaging) finish(upload *ociUpload, hash string) error {
+	defer o.remove(upload)
+
+	file, err := os.Open(upload.path)
+	if err != nil {
+		return wrapServiceError(err, "opening OCI upload")
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	_ = file.Close()
+	if err != nil {
+		return wrapServiceError(err, "reading OCI upload")
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hash {
+		return &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: ErrDigestMismatch.Error(),
+			Inner:   ErrDigestMismatch,
+		}
+	}
+
+	// Blobs are addressed by their content, so replacing a blob that was
+	// pushed before does not change it
+	path := o.blobPath(upload.pkg, hash)
+	//nolint:gosec,mnd // Directory permissions 0755 are intentional
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return wrapServiceError(err, "creating OCI staging directory")
+	}
+
+	return wrapServiceError(os.Rename(upload.path, path), "staging OCI blob")
+}
+
+// blob opens a staged blob. It returns nil if there is none.
+func (o *ociStaging) blob(
+	pkg *proto_gen.PackageName,
+	digest string,
+) (*os.File, error) {
+	o.prune()
+
+	hash, ok := ociDigestHash(digest)
+	if !ok {
+		return nil, nil
+	}
+
+	file, err := os.Open(o.blobPath(pkg, hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return file, wrapServiceError(err, "opening staged OCI blob")
+}
+
+// drop removes a staged blob once it has been stored with a version
+func (o *ociStaging) drop(pkg *proto_gen.PackageName, digest string) {
+	hash, ok := ociDigestHash(digest)
+	if !ok {
+		return
+	}
+
+	err := os.Remove(o.blobPath(pkg, hash))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Warn().Err(err).Str("digest", digest).
+			Msg("Failed to remove staged OCI blob")
+	}
+}
+
+// prune removes uploads and blobs that expired, at most once per
+// ociPruneInterval
+func (o *ociStaging) prune() {
+	o.mu.Lock()
+	if time.Since(o.lastPrune) < ociPruneInterval {
+		o.mu.Unlock()
+
+		return
+	}
+	o.lastPrune = time.Now()
+	o.mu.Unlock()
+
+	deadline := time.Now().Add(-ociStagingTTL)
+	walk := func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil //nolint:nilerr // Other files are still pruned
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(deadline) {
+			return nil //nolint:nilerr // Vanished files need no pruning
+		}
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Warn().Err(err).Str("path", path).
+				Msg("Failed to remove expired OCI staging file")
+		}
+
+		return nil
+	}
+	if err := filepath.WalkDir(o.dir, walk); err != nil {
+		log.Warn().Err(err).Msg("Failed to prune OCI staging directory")
+	}
+}
+
+// append adds a chunk to the upload as long as it stays within limit bytes.
+// Chunks are kept as a whole or not at all, exceeded tells whether the
+// limit was the reason. Must be called with the lock of the upload held.
+func (u *ociUpload) append(
+	body io.Reader,
+	limit int64,
+) (exceeded bool, err error) {
+	//nolint:gosec,mnd // File permissions 0644 are intentional
+	file, err := os.OpenFile(u.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return false, wrapServiceError(err, "opening OCI upload")
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = wrapServiceError(cerr, "writing OCI upload")
+		}
+	}()
+
+	// One byte more than remaining tells whether the limit is exceeded,
+	// without overflowing for unlimited uploads
+	remaining := max(limit-u.size, 0)
+	n, err := io.Copy(
+		file,
+		io.LimitReader(body, min(remaining, math.MaxInt64-1)+1),
+	)
+	if err != nil || n > remaining {
+		if terr := file.Truncate(u.size); terr != nil {
+			log.Warn().Err(terr).Str("upload", u.id).
+				Msg("Failed to discard rejected OCI chunk")
+		}
+		if err != nil {
+			return false, wrapServiceError(err, "receiving blob chunk")
+		}
+
+		return true, nil
+	}
+	u.size += n
+
+	return false, nil
+}
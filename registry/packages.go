@@ -0,0 +1,315 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+
+	"github.com/EnclaveRunner/shareddeps/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrInvalidPackage = errors.New("invalid package")
+
+var visibilities = map[proto_gen.Visibility]string{
+	proto_gen.Visibility_PUBLIC:  orm.VisibilityPublic,
+	proto_gen.Visibility_PRIVATE: orm.VisibilityPrivate,
+}
+
+func (s *Server) CreatePackage(
+	ctx context.Context,
+	request *proto_gen.Package,
+) (*proto_gen.Package, error) {
+	if err := validatePackage(request); err != nil {
+		log.Error().Err(err).Msg("Invalid package in CreatePackage request")
+
+		return nil, err
+	}
+
+	log.Info().
+		Str("namespace", request.Name.Namespace).
+		Str("name", request.Name.Name).
+		Msg("Package creation requested")
+
+	pkg := packageFromProto(request)
+	if len(pkg.Owners) == 0 {
+		if user := callerFromContext(ctx).User; user != auth.UnauthenticatedUser {
+			pkg.Owners = []orm.PackageOwner{{Owner: user}}
+		}
+	}
+
+	if err := s.db.CreatePackage(ctx, pkg); err != nil {
+		log.Error().Err(err).Msg("Failed to create package")
+
+		return nil, wrapServiceError(err, "creating package")
+	}
+
+	return s.GetPackage(ctx, request.Name)
+}
+
+func (s *Server) GetPackage(
+	ctx context.Context,
+	name *proto_gen.PackageName,
+) (*proto_gen.Package, error) {
+	if err := validateFQN(name); err != nil {
+		log.Error().Err(err).Msg("Invalid package in GetPackage request")
+
+		return nil, err
+	}
+
+	pkg, err := s.db.GetPackage(ctx, name)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get package")
+
+		return nil, wrapServiceError(err, "retrieving package")
+	}
+
+	return packageToProto(pkg), nil
+}
+
+func (s *Server) ListPackages(
+	ctx context.Context,
+	query *proto_gen.PackageQuery,
+) (*proto_gen.PackageListResponse, error) {
+	log.Info().
+		Str("namespace", query.GetNamespace()).
+		Msg("Packages listed")
+
+	packages, err := s.db.ListPackages(ctx, query.GetNamespace())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list packages")
+
+		return nil, wrapServiceError(err, "listing packages")
+	}
+
+	result := make([]*proto_gen.Package, 0, len(packages))
+	for i := range packages {
+		result = append(result, packageToProto(&packages[i]))
+	}
+
+	return &proto_gen.PackageListResponse{Packages: result}, nil
+}
+
+func (s *Server) UpdatePackage(
+	ctx context.Context,
+	request *proto_gen.Package,
+) (*proto_gen.Package, error) {
+	if err := validatePackage(request); err != nil {
+		log.Error().Err(err).Msg("Invalid package in UpdatePackage request")
+
+		return nil, err
+	}
+
+	log.Info().
+		Str("namespace", request.Name.Namespace).
+		Str("name", request.Name.Name).
+		Msg("Package update requested")
+
+	if err := s.db.UpdatePackage(ctx, packageFromProto(request)); err != nil {
+		log.Error().Err(err).Msg("Failed to update package")
+
+		return nil, wrapServiceError(err, "updating package")
+	}
+
+	return s.GetPackage(ctx, request.Name)
+}
+
+func (s *Server) DeletePackage(
+	ctx context.Context,
+	request *proto_gen.DeletePackageRequest,
+) (*proto_gen.Package, error) {
+	name := request.GetPackage()
+	if err := validateFQN(name); err != nil {
+		log.Error().Err(err).Msg("Invalid package in DeletePackage request")
+
+		return nil, err
+	}
+
+	log.Info().
+		Str("namespace", name.Namespace).
+		Str("name", name.Name).
+		Bool("deleteVersions", request.DeleteVersions).
+		Msg("Package deletion requested")
+
+	versions, err := s.db.GetArtifactMetasByFQN(ctx, name)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list package versions")
+
+		return nil, wrapServiceError(err, "listing package versions")
+	}
+	if len(versions) > 0 && !request.DeleteVersions {
+		return nil, &ServiceError{
+			Code:    codes.FailedPrecondition,
+			Message: "Package still has versions, delete them first",
+			Inner:   ErrInvalidPackage,
+		}
+	}
+
+	var notFoundErr *orm.NotFoundError
+	result := &proto_gen.Package{Name: name}
+	pkg, err := s.db.GetPackage(ctx, name)
+	switch {
+	case err == nil:
+		result = packageToProto(pkg)
+	case !errors.As(err, &notFoundErr) || len(versions) == 0:
+		log.Error().Err(err).Msg("Failed to get package for deletion")
+
+		return nil, wrapServiceError(err, "retrieving package for deletion")
+	}
+
+	for i := range versions {
+		_, err := s.DeleteArtifact(ctx, &proto_gen.ArtifactIdentifier{
+			Package: name,
+			Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+				VersionHash: versions[i].Hash,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pkg != nil {
+		if err := s.db.DeletePackage(auditContext(ctx), name); err != nil {
+			log.Error().Err(err).Msg("Failed to delete package")
+
+			return nil, wrapServiceError(err, "deleting package")
+		}
+	}
+
+	return result, nil
+}
+
+// packageInfos loads the package records of the given artifacts, keyed by
+// "namespace/name". Failures are logged and yield no package info, as it is
+// only supplementary to the artifact.
+func (s *Server) packageInfos(
+	ctx context.Context,
+	artifacts []orm.Artifact,
+) map[string]*proto_gen.Package {
+	names := make([]*proto_gen.PackageName, 0, len(artifacts))
+	for _, a := range artifacts {
+		name := &proto_gen.PackageName{Namespace: a.Namespace, Name: a.Name}
+		if !slices.ContainsFunc(names, func(n *proto_gen.PackageName) bool {
+			return n.Namespace == name.Namespace && n.Name == name.Name
+		}) {
+			names = append(names, name)
+		}
+	}
+
+	packages, err := s.db.GetPackages(ctx, names)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load package records")
+
+		return nil
+	}
+
+	result := make(map[string]*proto_gen.Package, len(packages))
+	for i := range packages {
+		result[packages[i].Namespace+"/"+packages[i].Name] = packageToProto(
+			&packages[i],
+		)
+	}
+
+	return result
+}
+
+func validatePackage(pkg *proto_gen.Package) error {
+	if pkg == nil {
+		return &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Package must be provided",
+			Inner:   ErrInvalidPackage,
+		}
+	}
+
+	if err := validateFQN(pkg.Name); err != nil {
+		return err
+	}
+
+	if _, ok := visibilities[pkg.Visibility]; !ok {
+		return &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Unknown visibility " + pkg.Visibility.String(),
+			Inner:   ErrInvalidPackage,
+		}
+	}
+
+	if pkg.Homepage != "" {
+		homepage, err := url.Parse(pkg.Homepage)
+		if err != nil ||
+			(homepage.Scheme != "http" && homepage.Scheme != "https") {
+			return &ServiceError{
+				Code:    codes.InvalidArgument,
+				Message: "Homepage must be an http(s) URL",
+				Inner:   ErrInvalidPackage,
+			}
+		}
+	}
+
+	if slices.Contains(pkg.Owners, "") {
+		return &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Owners cannot be empty strings",
+			Inner:   ErrInvalidPackage,
+		}
+	}
+
+	return nil
+}
+
+func packageFromProto(pkg *proto_gen.Package) *orm.Package {
+	owners := slices.Clone(pkg.Owners)
+	slices.Sort(owners)
+	owners = slices.Compact(owners)
+
+	result := &orm.Package{
+		Namespace:          pkg.Name.Namespace,
+		Name:               pkg.Name.Name,
+		Description:        pkg.Description,
+		Readme:             pkg.Readme,
+		Homepage:           pkg.Homepage,
+		Visibility:         visibilities[pkg.Visibility],
+		Deprecated:         pkg.Deprecated,
+		DeprecationMessage: pkg.DeprecationMessage,
+		Owners:             make([]orm.PackageOwner, 0, len(owners)),
+	}
+	for _, owner := range owners {
+		result.Owners = append(result.Owners, orm.PackageOwner{Owner: owner})
+	}
+
+	return result
+}
+
+func packageToProto(pkg *orm.Package) *proto_gen.Package {
+	owners := make([]string, 0, len(pkg.Owners))
+	for _, o := range pkg.Owners {
+		owners = append(owners, o.Owner)
+	}
+
+	visibility := proto_gen.Visibility_PUBLIC
+	if pkg.Visibility == orm.VisibilityPrivate {
+		visibility = proto_gen.Visibility_PRIVATE
+	}
+
+	return &proto_gen.Package{
+		Name: &proto_gen.PackageName{
+			Namespace: pkg.Namespace,
+			Name:      pkg.Name,
+		},
+		Description:        pkg.Description,
+		Readme:             pkg.Readme,
+		Homepage:           pkg.Homepage,
+		Owners:             owners,
+		Visibility:         visibility,
+		Deprecated:         pkg.Deprecated,
+		DeprecationMessage: pkg.DeprecationMessage,
+		Created:            timestamppb.New(pkg.CreatedAt),
+		Updated:            timestamppb.New(pkg.UpdatedAt),
+	}
+}
@@ -0,0 +1,336 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"artifact-registry/wasm"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrUpstreamDigestMismatch = errors.New(
+	"content from upstream does not match its version hash",
+)
+
+// upstreamProxy fetches versions that are missing locally from an upstream
+// registry
+type upstreamProxy struct {
+	client proto_gen.RegistryServiceClient
+	tagTTL time.Duration
+
+	mu sync.Mutex
+	// Tags resolved through the upstream, keyed by "namespace/name:tag"
+	tags map[string]cachedTag
+	// Expired tags are swept at most once per tagTTL
+	lastSweep time.Time
+}
+
+type cachedTag struct {
+	hash    string
+	expires time.Time
+}
+
+func newUpstreamProxy(
+	client proto_gen.RegistryServiceClient,
+	tagTTL time.Duration,
+) *upstreamProxy {
+	return &upstreamProxy{
+		client: client,
+		tagTTL: tagTTL,
+		tags:   map[string]cachedTag{},
+	}
+}
+
+func (p *upstreamProxy) lookupTag(
+	pkg *proto_gen.PackageName,
+	tag string,
+) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := pkg.Namespace + "/" + pkg.Name + ":" + tag
+	cached, ok := p.tags[key]
+	if !ok {
+		return ""
+	}
+	if time.Now().After(cached.expires) {
+		delete(p.tags, key)
+
+		return ""
+	}
+
+	return cached.hash
+}
+
+func (p *upstreamProxy) cacheTag(
+	pkg *proto_gen.PackageName,
+	tag, hash string,
+) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// lookupTag only drops the expired tags that are requested again
+	now := time.Now()
+	if now.Sub(p.lastSweep) >= p.tagTTL {
+		p.lastSweep = now
+		maps.DeleteFunc(p.tags, func(_ string, cached cachedTag) bool {
+			return now.After(cached.expires)
+		})
+	}
+
+	p.tags[pkg.Namespace+"/"+pkg.Name+":"+tag] = cachedTag{
+		hash:    hash,
+		expires: now.Add(p.tagTTL),
+	}
+}
+
+// resolveUpstream resolves an identifier that is unknown locally through the
+// upstream. The version is stored locally before it is returned, but tags
+// are only cached, so they follow the upstream once the cache expires.
+func (s *Server) resolveUpstream(
+	ctx context.Context,
+	id *proto_gen.ArtifactIdentifier,
+) (*orm.Artifact, error) {
+	var remote *proto_gen.Artifact
+	hash := id.GetVersionHash()
+	tag := id.GetTag()
+	if tag != "" {
+		hash = s.proxy.lookupTag(id.Package, tag)
+	}
+
+	if hash == "" {
+		var err error
+		remote, err = s.proxy.client.GetArtifact(ctx, id)
+		if err != nil {
+			return nil, upstreamError(err, "resolving tag upstream")
+		}
+		hash = remote.VersionHash
+		s.proxy.cacheTag(id.Package, tag, hash)
+	}
+
+	// The version may already have been fetched for another tag
+	artifactMeta, err := s.db.GetArtifactMetaByHash(ctx, id.Package, hash)
+	var notFoundErr *orm.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		artifactMeta, err = s.fetchUpstream(ctx, id.Package, hash, remote)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, wrapServiceError(err, "resolving artifact from upstream")
+	}
+
+	if tag != "" {
+		if err := rejectYankedTag(artifactMeta, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	return artifactMeta, nil
+}
+
+// fetchUpstream stores a version of the upstream locally. remote is the
+// version's metadata if it has already been retrieved.
+func (s *Server) fetchUpstream(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	hash string,
+	remote *proto_gen.Artifact,
+) (*orm.Artifact, error) {
+	log.Info().
+		Str("namespace", pkg.Namespace).
+		Str("name", pkg.Name).
+		Str("versionHash", hash).
+		Msg("Fetching artifact from upstream")
+
+	id := &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: hash,
+		},
+	}
+	if remote == nil {
+		var err error
+		remote, err = s.proxy.client.GetArtifact(ctx, id)
+		if err != nil {
+			return nil, upstreamError(err, "retrieving artifact upstream")
+		}
+	}
+
+	// Fetched versions count towards the quota like uploaded ones
+	allowance, err := s.uploadAllowance(ctx, pkg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	quotaErr := newQuotaExceededError(pkg.Namespace, "storage")
+	size := remote.Metadata.GetSizeBytes()
+	if size > allowance {
+		return nil, quotaErr
+	}
+	limit, exceeded := upstreamContentLimit(size)
+	if allowance < limit {
+		limit, exceeded = allowance, quotaErr
+	}
+
+	content, err := s.fetchUpstreamContent(ctx, pkg, hash, limit, exceeded)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := inspectArtifact(pkg, content)
+	err = s.db.CreateArtifactMeta(ctx, pkg, hash, orm.ArtifactDetails{
+		SizeBytes: int64(len(content)),
+		MediaType: wasm.DetectMediaType(content),
+		Uploader:  remote.Metadata.GetUploader(),
+		UserAgent: remote.Metadata.GetUserAgent(),
+		Symbols:   symbols,
+		Labels:    remote.Labels,
+	})
+	// Another request fetched the same version concurrently, or it was
+	// deleted here and is in the trash
+	var conflictErr *orm.ConflictError
+	if err != nil && !errors.As(err, &conflictErr) {
+		return nil, wrapServiceError(err, "storing metadata of fetched artifact")
+	}
+	if err != nil {
+		if trashedErr := s.trashedVersionError(ctx, pkg, hash); trashedErr != nil {
+			return nil, trashedErr
+		}
+	}
+
+	if state := versionStates[remote.State]; state != orm.ArtifactActive {
+		err := s.db.SetArtifactState(ctx, pkg, hash, state, remote.StateReason)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to copy state of fetched artifact")
+		}
+	}
+
+	if err := s.db.EnsurePackage(ctx, pkg, ""); err != nil {
+		log.Warn().Err(err).Msg("Failed to create package record")
+	}
+
+	artifactMeta, err := s.db.GetArtifactMetaByHash(ctx, pkg, hash)
+	if err != nil {
+		return nil, wrapServiceError(err, "resolving artifact from upstream")
+	}
+
+	return artifactMeta, nil
+}
+
+// upstreamContentLimit returns how many bytes of a version of the given size
+// are fetched from the upstream, and the error for content beyond that. The
+// size is unknown for versions uploaded before sizes were recorded.
+func upstreamContentLimit(size int64) (int64, error) {
+	if size <= 0 {
+		return math.MaxInt64, nil
+	}
+
+	return size, &ServiceError{
+		Code:    codes.DataLoss,
+		Message: "Content from upstream is larger than its version",
+		Inner:   ErrUpstreamDigestMismatch,
+	}
+}
+
+// fetchUpstreamContent pulls the content of a version from the upstream and
+// stores it locally once its hash has been verified. Pulls that exceed limit
+// bytes fail with exceeded as soon as they do.
+func (s *Server) fetchUpstreamContent(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	hash string,
+	limit int64,
+	exceeded error,
+) ([]byte, error) {
+	stream, err := s.proxy.client.PullArtifact(
+		ctx,
+		&proto_gen.ArtifactIdentifier{
+			Package: pkg,
+			Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+				VersionHash: hash,
+			},
+		},
+	)
+	if err != nil {
+		return nil, upstreamError(err, "pulling artifact upstream")
+	}
+
+	var content bytes.Buffer
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, upstreamError(err, "pulling artifact upstream")
+		}
+		if int64(content.Len()+len(chunk.Data)) > limit {
+			return nil, exceeded
+		}
+		content.Write(chunk.Data)
+	}
+
+	sum := sha256.Sum256(content.Bytes())
+	if hex.EncodeToString(sum[:]) != hash {
+		log.Error().
+			Str("versionHash", hash).
+			Msg("Upstream returned content with a different hash")
+
+		return nil, &ServiceError{
+			Code:    codes.DataLoss,
+			Message: "Content from upstream does not match its version hash",
+			Inner:   ErrUpstreamDigestMismatch,
+		}
+	}
+
+	storedHash, err := s.registry.StoreArtifact(
+		ctx,
+		pkg,
+		bytes.NewReader(content.Bytes()),
+	)
+	if err != nil {
+		return nil, wrapServiceError(err, "storing fetched artifact")
+	}
+	if storedHash != hash {
+		return nil, &ServiceError{
+			Code:    codes.DataLoss,
+			Message: "Stored content does not match its version hash",
+			Inner:   ErrUpstreamDigestMismatch,
+		}
+	}
+
+	return content.Bytes(), nil
+}
+
+// upstreamError keeps NotFound errors of the upstream, so a miss there is a
+// miss of the proxy. Any other failure makes the proxy unavailable.
+func upstreamError(err error, operation string) error {
+	if status.Code(err) == codes.NotFound {
+		return &ServiceError{
+			Code:    codes.NotFound,
+			Message: "Artifact not found for " + operation,
+			Inner:   err,
+		}
+	}
+
+	log.Error().Err(err).Msg("Upstream registry request failed")
+
+	return &ServiceError{
+		Code:    codes.Unavailable,
+		Message: "Upstream registry failed during " + operation,
+		Inner:   err,
+	}
+}
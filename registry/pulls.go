@@ -0,0 +1,259 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const pullFlushBatchSize = 500
+
+var pullStatsIntervals = map[proto_gen.PullStatsInterval]string{
+	proto_gen.PullStatsInterval_DAILY:  orm.PullsDaily,
+	proto_gen.PullStatsInterval_HOURLY: orm.PullsHourly,
+}
+
+var pullStatsGroupings = map[proto_gen.PullStatsGrouping]string{
+	proto_gen.PullStatsGrouping_TOTAL:      "",
+	proto_gen.PullStatsGrouping_BY_VERSION: orm.PullsByVersion,
+	proto_gen.PullStatsGrouping_BY_TAG:     orm.PullsByTag,
+	proto_gen.PullStatsGrouping_BY_CLIENT:  orm.PullsByClient,
+}
+
+// pullBuffer sums up pulls in memory until they are flushed, so a hot version
+// costs one update per flush instead of one per pull
+type pullBuffer struct {
+	mu    sync.Mutex
+	pulls map[pullKey]*orm.Pull
+}
+
+// pullKey identifies pulls that are counted in the same buckets
+type pullKey struct {
+	namespace, name, hash, tag, client string
+	hour                               time.Time
+}
+
+func newPullBuffer() *pullBuffer {
+	return &pullBuffer{pulls: map[pullKey]*orm.Pull{}}
+}
+
+func (b *pullBuffer) add(pull orm.Pull) {
+	key := pullKey{
+		namespace: pull.Namespace,
+		name:      pull.Name,
+		hash:      pull.Hash,
+		tag:       pull.Tag,
+		client:    pull.Client,
+		hour:      pull.Time.UTC().Truncate(time.Hour),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	buffered, ok := b.pulls[key]
+	if !ok {
+		b.pulls[key] = &pull
+
+		return
+	}
+	buffered.Count += pull.Count
+	if pull.Time.After(buffered.Time) {
+		buffered.Time = pull.Time
+	}
+}
+
+// take empties the buffer and returns the pulls it held
+func (b *pullBuffer) take() []orm.Pull {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pulls := make([]orm.Pull, 0, len(b.pulls))
+	for _, pull := range b.pulls {
+		pulls = append(pulls, *pull)
+	}
+	clear(b.pulls)
+
+	return pulls
+}
+
+// recordPull counts a pull, either right away or once the buffer is flushed
+func (s *Server) recordPull(ctx context.Context, pull orm.Pull) {
+	if s.pulls != nil {
+		s.pulls.add(pull)
+
+		return
+	}
+
+	if err := s.db.AddPulls(ctx, []orm.Pull{pull}); err != nil {
+		log.Warn().Err(err).Msg("Failed to increment pull count")
+	}
+}
+
+// FlushPulls writes the buffered pulls in batches. Pulls that could not be
+// written are kept for the next attempt.
+func (s *Server) FlushPulls(ctx context.Context) error {
+	if s.pulls == nil {
+		return nil
+	}
+
+	pulls := s.pulls.take()
+	for start := 0; start < len(pulls); start += pullFlushBatchSize {
+		batch := pulls[start:min(start+pullFlushBatchSize, len(pulls))]
+		if err := s.db.AddPulls(ctx, batch); err != nil {
+			for _, pull := range pulls[start:] {
+				s.pulls.add(pull)
+			}
+
+			return wrapServiceError(err, "flushing pulls")
+		}
+	}
+
+	return nil
+}
+
+// RunPullFlusher flushes buffered pulls in the given interval until the
+// context is cancelled, and once more afterwards. It returns right away if
+// pulls are not buffered.
+func (s *Server) RunPullFlusher(ctx context.Context, interval time.Duration) {
+	if s.pulls == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			err := s.FlushPulls(context.WithoutCancel(ctx))
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to flush pulls")
+			}
+
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.FlushPulls(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to flush pulls")
+		}
+	}
+}
+
+func (s *Server) GetPullStats(
+	ctx context.Context,
+	request *proto_gen.PullStatsRequest,
+) (*proto_gen.PullStats, error) {
+	if err := validateFQN(request.Package); err != nil {
+		log.Error().Err(err).Msg("Invalid package in GetPullStats request")
+
+		return nil, err
+	}
+
+	granularity, ok := pullStatsIntervals[request.Interval]
+	if !ok {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Unknown pull stats interval " + request.Interval.String(),
+		}
+	}
+	groupBy, ok := pullStatsGroupings[request.GroupBy]
+	if !ok {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Unknown pull stats grouping " + request.GroupBy.String(),
+		}
+	}
+
+	filter := orm.PullStatsFilter{
+		Namespace:   request.Package.Namespace,
+		Name:        request.Package.Name,
+		Hash:        request.GetVersionHash(),
+		Granularity: granularity,
+		GroupBy:     groupBy,
+	}
+	if request.Since != nil {
+		filter.Since = request.Since.AsTime()
+	}
+	if request.Until != nil {
+		filter.Until = request.Until.AsTime()
+	}
+
+	stats, err := s.db.GetPullStats(ctx, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pull stats")
+
+		return nil, wrapServiceError(err, "retrieving pull stats")
+	}
+
+	// Stats are ordered by series, so each series is a contiguous run
+	result := &proto_gen.PullStats{}
+	var series *proto_gen.PullSeries
+	for _, stat := range stats {
+		if series == nil || series.Key != stat.Series {
+			series = &proto_gen.PullSeries{Key: stat.Series}
+			result.Series = append(result.Series, series)
+		}
+		series.Total += stat.Pulls
+		series.Buckets = append(series.Buckets, &proto_gen.PullCount{
+			Start: timestamppb.New(stat.Start),
+			Pulls: stat.Pulls,
+		})
+	}
+
+	return result, nil
+}
+
+// PrunePullStats deletes the pull buckets that are older than their retention
+func (s *Server) PrunePullStats(ctx context.Context) error {
+	for granularity, retention := range map[string]time.Duration{
+		orm.PullsHourly: s.hourlyPullRetention,
+		orm.PullsDaily:  s.dailyPullRetention,
+	} {
+		pruned, err := s.db.PrunePullBuckets(
+			ctx,
+			granularity,
+			time.Now().Add(-retention),
+		)
+		if err != nil {
+			return wrapServiceError(err, "pruning pull stats")
+		}
+
+		if pruned > 0 {
+			log.Info().
+				Str("granularity", granularity).
+				Int("pruned", pruned).
+				Msg("Pruned pull stats")
+		}
+	}
+
+	return nil
+}
+
+// RunPullStatsPruner prunes pull buckets in the given interval until the
+// context is cancelled
+func (s *Server) RunPullStatsPruner(
+	ctx context.Context,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.PrunePullStats(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to prune pull stats")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,20 +1,90 @@
 package registry
 
 import (
+	"artifact-registry/config"
 	"artifact-registry/orm"
 	"artifact-registry/proto_gen"
+	"context"
 	"io"
+	"time"
+
+	"github.com/EnclaveRunner/shareddeps"
 )
 
 // Registry interface defines the methods that any registry implementation must
-// provide
+// provide. Failures wrap the storage errors of this package, e.g.
+// ErrArtifactNotFound for missing artifacts.
 type Registry interface {
+	// StoreArtifact must give up once ctx is done, even while reader blocks,
+	// and keep nothing of the aborted stream. See ContextReader.
 	StoreArtifact(
+		ctx context.Context,
 		pkg *proto_gen.PackageName,
 		reader io.Reader,
 	) (string, error)
-	GetArtifact(pkg *proto_gen.PackageName, hash string) ([]byte, error)
-	DeleteArtifact(pkg *proto_gen.PackageName, hash string) error
+	GetArtifact(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		hash string,
+	) ([]byte, error)
+	DeleteArtifact(
+		ctx context.Context,
+		pkg *proto_gen.PackageName,
+		hash string,
+	) error
+	// LinkArtifact makes a stored artifact available under another package,
+	// reusing the blob where the backend allows. Linking onto an existing
+	// artifact must succeed, so interrupted operations can be retried.
+	LinkArtifact(
+		ctx context.Context,
+		from, to *proto_gen.PackageName,
+		hash string,
+	) error
+}
+
+// ContextReader returns a reader that fails with the context error once ctx is
+// done, without waiting for a blocked read of r to return. An abandoned read
+// finishes in the background, so the reader must not be used afterwards.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r, results: make(chan readResult, 1)}
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+type contextReader struct {
+	ctx context.Context //nolint:containedctx // Bound to a single stream
+	r   io.Reader
+	// Reads go to buf, which an abandoned read may still write to
+	buf     []byte
+	results chan readResult
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(r.buf) < len(p) {
+		r.buf = make([]byte, len(p))
+	}
+	buf := r.buf[:len(p)]
+
+	go func() {
+		n, err := r.r.Read(buf)
+		r.results <- readResult{n, err}
+	}()
+
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	case result := <-r.results:
+		copy(p, buf[:result.n])
+
+		return result.n, result.err
+	}
 }
 
 var _ proto_gen.RegistryServiceServer = (*Server)(nil)
@@ -22,14 +92,65 @@ var _ proto_gen.RegistryServiceServer = (*Server)(nil)
 type Server struct {
 	proto_gen.UnimplementedRegistryServiceServer
 
-	registry Registry
-	db       orm.DB
+	registry      Registry
+	db            orm.MetadataStore
+	protectedTags []string
+	defaultQuota  *proto_gen.NamespaceQuota
+	// Zero disables the trash
+	trashRetention time.Duration
+	redirectTTL    time.Duration
+	oci            *ociStaging
+	// Nil unless an upstream registry is configured
+	proxy       *upstreamProxy
+	replication replication
+	// How long hourly and daily pull buckets are kept
+	hourlyPullRetention time.Duration
+	dailyPullRetention  time.Duration
+	// Nil if pulls are written immediately
+	pulls *pullBuffer
 }
 
 // NewServer creates a new server with the specified registry implementation
-func NewServer(reg Registry, db orm.DB) *Server {
-	return &Server{
-		registry: reg,
-		db:       db,
+func NewServer(
+	reg Registry,
+	db orm.MetadataStore,
+	cfg *config.AppConfig,
+) *Server {
+	replication := newReplication(cfg)
+	if len(replication.peers) > 0 {
+		db = db.WithReplicationOutbox()
 	}
+
+	server := &Server{
+		registry:      reg,
+		db:            db,
+		protectedTags: cfg.Compatibility.ProtectedTags,
+		defaultQuota: &proto_gen.NamespaceQuota{
+			MaxBytes:    cfg.Quota.MaxBytes,
+			MaxVersions: cfg.Quota.MaxVersions,
+		},
+		trashRetention: cfg.Trash.Retention,
+		redirectTTL:    cfg.RedirectTTL,
+		oci:            newOCIStaging(cfg.OCI.StagingDir, cfg.OCI.MaxUploadSize),
+		replication:    replication,
+
+		hourlyPullRetention: cfg.PullStats.HourlyRetention,
+		dailyPullRetention:  cfg.PullStats.DailyRetention,
+	}
+
+	if cfg.PullStats.FlushInterval > 0 {
+		server.pulls = newPullBuffer()
+	}
+
+	if cfg.Proxy.UpstreamHost != "" {
+		server.proxy = newUpstreamProxy(
+			proto_gen.NewRegistryServiceClient(shareddeps.InitGRPCClient(
+				cfg.Proxy.UpstreamHost,
+				cfg.Proxy.UpstreamPort,
+			)),
+			cfg.Proxy.TagTTL,
+		)
+	}
+
+	return server
 }
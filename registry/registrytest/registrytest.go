@@ -0,0 +1,353 @@
+// Package registrytest is a conformance test suite for implementations of
+// registry.Registry, the blob storage behind the registry server.
+package registrytest
+
+import (
+	"artifact-registry/proto_gen"
+	"artifact-registry/registry"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	largeBlobSize    = 8 << 20
+	concurrentStores = 16
+	cancelTimeout    = 5 * time.Second
+)
+
+// RunTests runs the suite against registries created by newRegistry. Every
+// test gets a registry of its own.
+func RunTests(
+	t *testing.T,
+	newRegistry func(t *testing.T) registry.Registry,
+) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, reg registry.Registry)
+	}{
+		{"StoreAndGet", testStoreAndGet},
+		{"EmptyContent", testEmptyContent},
+		{"SameContent", testSameContent},
+		{"Packages", testPackages},
+		{"Delete", testDelete},
+		{"NotFound", testNotFound},
+		{"Link", testLink},
+		{"GetReturnsCopy", testGetReturnsCopy},
+		{"ConcurrentStores", testConcurrentStores},
+		{"LargeBlob", testLargeBlob},
+		{"CancelledStream", testCancelledStream},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			test.run(t, newRegistry(t))
+		})
+	}
+}
+
+var missingHash = strings.Repeat("0123456789abcdef", 4)
+
+func pkgName(namespace, name string) *proto_gen.PackageName {
+	return &proto_gen.PackageName{Namespace: namespace, Name: name}
+}
+
+func hashOf(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// store stores content and checks the returned hash
+func store(
+	t *testing.T,
+	reg registry.Registry,
+	pkg *proto_gen.PackageName,
+	content []byte,
+) string {
+	t.Helper()
+
+	hash, err := reg.StoreArtifact(t.Context(), pkg, bytes.NewReader(content))
+	require.NoError(t, err)
+	require.Equal(t, hashOf(content), hash)
+
+	return hash
+}
+
+func requireContent(
+	t *testing.T,
+	reg registry.Registry,
+	pkg *proto_gen.PackageName,
+	hash string,
+	content []byte,
+) {
+	t.Helper()
+
+	stored, err := reg.GetArtifact(t.Context(), pkg, hash)
+	require.NoError(t, err)
+	require.True(
+		t,
+		bytes.Equal(content, stored),
+		"stored content of %d bytes differs from the %d bytes uploaded",
+		len(stored),
+		len(content),
+	)
+}
+
+func requireNotFound(t *testing.T, err error) {
+	t.Helper()
+
+	require.ErrorIs(t, err, registry.ErrArtifactNotFound)
+}
+
+func testStoreAndGet(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := []byte("test content for artifact")
+	other := []byte("different test content")
+
+	hash := store(t, reg, pkg, content)
+	otherHash := store(t, reg, pkg, other)
+	assert.NotEqual(t, hash, otherHash)
+
+	requireContent(t, reg, pkg, hash, content)
+	requireContent(t, reg, pkg, otherHash, other)
+}
+
+func testEmptyContent(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+
+	hash := store(t, reg, pkg, []byte{})
+	stored, err := reg.GetArtifact(t.Context(), pkg, hash)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func testSameContent(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := []byte("identical content")
+
+	// Storing existing content again succeeds with the same hash
+	hash := store(t, reg, pkg, content)
+	assert.Equal(t, hash, store(t, reg, pkg, content))
+	requireContent(t, reg, pkg, hash, content)
+}
+
+func testPackages(t *testing.T, reg registry.Registry) {
+	first := pkgName("author1", "app")
+	second := pkgName("author2", "app")
+	content := []byte("same content for both")
+
+	hash := store(t, reg, first, content)
+	_, err := reg.GetArtifact(t.Context(), second, hash)
+	requireNotFound(t, err)
+
+	// Packages keep their artifacts apart even with identical content
+	store(t, reg, second, content)
+	require.NoError(t, reg.DeleteArtifact(t.Context(), first, hash))
+	requireContent(t, reg, second, hash, content)
+}
+
+func testDelete(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := []byte("test content for artifact")
+	hash := store(t, reg, pkg, content)
+	kept := store(t, reg, pkg, []byte("kept content"))
+
+	require.NoError(t, reg.DeleteArtifact(t.Context(), pkg, hash))
+	_, err := reg.GetArtifact(t.Context(), pkg, hash)
+	requireNotFound(t, err)
+	requireNotFound(t, reg.DeleteArtifact(t.Context(), pkg, hash))
+	requireContent(t, reg, pkg, kept, []byte("kept content"))
+
+	// Deleted content can be stored again
+	store(t, reg, pkg, content)
+	requireContent(t, reg, pkg, hash, content)
+}
+
+func testNotFound(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+
+	_, err := reg.GetArtifact(t.Context(), pkg, missingHash)
+	requireNotFound(t, err)
+	requireNotFound(t, reg.DeleteArtifact(t.Context(), pkg, missingHash))
+	requireNotFound(t, reg.LinkArtifact(
+		t.Context(),
+		pkg,
+		pkgName("other", "testapp"),
+		missingHash,
+	))
+	_, err = reg.GetArtifact(t.Context(), pkgName("missing", "app"), missingHash)
+	requireNotFound(t, err)
+}
+
+func testLink(t *testing.T, reg registry.Registry) {
+	from := pkgName("ci", "testapp")
+	to := pkgName("prod", "testapp")
+	content := []byte("test content for linked artifact")
+	hash := store(t, reg, from, content)
+
+	require.NoError(t, reg.LinkArtifact(t.Context(), from, to, hash))
+	// Linking again must succeed, so interrupted moves can be retried
+	require.NoError(t, reg.LinkArtifact(t.Context(), from, to, hash))
+
+	// The link survives deletion of the original
+	require.NoError(t, reg.DeleteArtifact(t.Context(), from, hash))
+	requireContent(t, reg, to, hash, content)
+	requireNotFound(t, reg.LinkArtifact(t.Context(), from, to, hash))
+
+	// Deleting the link leaves nothing behind
+	require.NoError(t, reg.DeleteArtifact(t.Context(), to, hash))
+	_, err := reg.GetArtifact(t.Context(), to, hash)
+	requireNotFound(t, err)
+}
+
+func testGetReturnsCopy(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := []byte("test content for artifact")
+	hash := store(t, reg, pkg, content)
+
+	stored, err := reg.GetArtifact(t.Context(), pkg, hash)
+	require.NoError(t, err)
+	stored[0] = 'X'
+
+	requireContent(t, reg, pkg, hash, content)
+}
+
+func testConcurrentStores(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := make([]byte, 1<<20)
+	_, _ = rand.Read(content)
+
+	var wg sync.WaitGroup
+	hashes := make([]string, concurrentStores)
+	storeErrs := make([]error, concurrentStores)
+	for i := range concurrentStores {
+		wg.Go(func() {
+			hashes[i], storeErrs[i] = reg.StoreArtifact(
+				t.Context(),
+				pkg,
+				bytes.NewReader(content),
+			)
+		})
+	}
+	wg.Wait()
+
+	for i := range concurrentStores {
+		require.NoError(t, storeErrs[i])
+		assert.Equal(t, hashOf(content), hashes[i])
+	}
+	requireContent(t, reg, pkg, hashes[0], content)
+
+	// All stores share one artifact
+	require.NoError(t, reg.DeleteArtifact(t.Context(), pkg, hashes[0]))
+	_, err := reg.GetArtifact(t.Context(), pkg, hashes[0])
+	requireNotFound(t, err)
+}
+
+func testLargeBlob(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := make([]byte, largeBlobSize)
+	_, _ = rand.Read(content)
+
+	// Upload streams arrive in chunks
+	hash, err := reg.StoreArtifact(
+		t.Context(),
+		pkg,
+		&chunkReader{data: content, chunk: 64 << 10},
+	)
+	require.NoError(t, err)
+	require.Equal(t, hashOf(content), hash)
+	requireContent(t, reg, pkg, hash, content)
+}
+
+func testCancelledStream(t *testing.T, reg registry.Registry) {
+	pkg := pkgName("testuser", "testapp")
+	content := make([]byte, 1<<20)
+	_, _ = rand.Read(content)
+	partial := content[:len(content)/2]
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	reader := &blockingReader{
+		chunkReader: chunkReader{data: partial, chunk: 32 << 10},
+		blocked:     make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	t.Cleanup(func() { close(reader.release) })
+
+	stored := make(chan error, 1)
+	go func() {
+		_, err := reg.StoreArtifact(ctx, pkg, reader)
+		stored <- err
+	}()
+
+	// The client goes away while the backend waits for more data
+	<-reader.blocked
+	cancel()
+
+	select {
+	case err := <-stored:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(cancelTimeout):
+		require.FailNow(t, "StoreArtifact ignored the cancelled context")
+	}
+
+	// Nothing of the partial upload is kept
+	_, err := reg.GetArtifact(t.Context(), pkg, hashOf(partial))
+	requireNotFound(t, err)
+
+	hash := store(t, reg, pkg, content)
+	requireContent(t, reg, pkg, hash, content)
+}
+
+// chunkReader returns its data in reads of at most chunk bytes
+type chunkReader struct {
+	data  []byte
+	chunk int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p[:min(len(p), r.chunk)], r.data)
+	r.data = r.data[n:]
+
+	return n, nil
+}
+
+// blockingReader returns its data and then blocks like an upload stream
+// waiting for its client, until release is closed
+type blockingReader struct {
+	chunkReader
+
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		close(r.blocked)
+		<-r.release
+
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return r.chunkReader.Read(p)
+}
@@ -0,0 +1,374 @@
+package registry
+
+import (
+	"artifact-registry/config"
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EnclaveRunner/shareddeps"
+	"github.com/EnclaveRunner/shareddeps/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// Changes are replicated with a delay, so transactions that recorded
+	// changes with lower event IDs have committed before the cursor passes
+	// them
+	replicationSettleTime = 5 * time.Second
+	replicationBatchSize  = 100
+)
+
+// replication holds the peers that changes are replicated to. It is empty if
+// replication is disabled.
+type replication struct {
+	peers            []replicationPeer
+	pollInterval     time.Duration
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+}
+
+type replicationPeer struct {
+	// host:port as configured, which identifies the peer's cursor
+	address string
+	client  proto_gen.RegistryServiceClient
+}
+
+func newReplication(cfg *config.AppConfig) replication {
+	peers := make([]replicationPeer, 0, len(cfg.Replication.Peers))
+	for _, address := range cfg.Replication.Peers {
+		host, portString, err := net.SplitHostPort(address)
+		if err != nil {
+			log.Fatal().Err(err).Str("peer", address).Msg("Invalid peer address")
+		}
+		port, err := strconv.Atoi(portString)
+		if err != nil {
+			log.Fatal().Err(err).Str("peer", address).Msg("Invalid peer port")
+		}
+
+		peers = append(peers, replicationPeer{
+			address: address,
+			client: proto_gen.NewRegistryServiceClient(
+				shareddeps.InitGRPCClient(host, port),
+			),
+		})
+	}
+
+	return replication{
+		peers:            peers,
+		pollInterval:     cfg.Replication.PollInterval,
+		retryInterval:    cfg.Replication.RetryInterval,
+		maxRetryInterval: cfg.Replication.MaxRetryInterval,
+	}
+}
+
+func (s *Server) GetReplicationStatus(
+	ctx context.Context,
+	_ *proto_gen.ReplicationStatusRequest,
+) (*proto_gen.ReplicationStatus, error) {
+	peers := make(
+		[]*proto_gen.PeerReplicationStatus,
+		0,
+		len(s.replication.peers),
+	)
+	for _, peer := range s.replication.peers {
+		cursor, err := s.db.GetReplicationCursor(ctx, peer.address)
+		if err != nil {
+			return nil, wrapServiceError(err, "retrieving replication cursor")
+		}
+
+		pending, oldest, err := s.db.CountReplicationEvents(
+			ctx,
+			cursor.LastEventID,
+		)
+		if err != nil {
+			return nil, wrapServiceError(err, "counting pending changes")
+		}
+
+		peerStatus := &proto_gen.PeerReplicationStatus{
+			Peer:           peer.address,
+			PendingChanges: pending,
+			Lag:            durationpb.New(0),
+			Failures:       cursor.Failures,
+			LastError:      cursor.LastError,
+		}
+		if pending > 0 {
+			peerStatus.Lag = durationpb.New(time.Since(oldest))
+		}
+		if cursor.LastReplicatedAt != nil {
+			peerStatus.LastReplicated = timestamppb.New(*cursor.LastReplicatedAt)
+		}
+		peers = append(peers, peerStatus)
+	}
+
+	return &proto_gen.ReplicationStatus{Peers: peers}, nil
+}
+
+// RunReplication replays recorded changes to all configured peers until the
+// context is cancelled
+func (s *Server) RunReplication(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, peer := range s.replication.peers {
+		wg.Go(func() { s.replicateToPeer(ctx, peer) })
+	}
+	wg.Wait()
+}
+
+func (s *Server) replicateToPeer(ctx context.Context, peer replicationPeer) {
+	retryInterval := s.replication.retryInterval
+
+	for {
+		wait := s.replication.pollInterval
+		more, err := s.replicateBatch(ctx, peer)
+		switch {
+		case err != nil:
+			log.Warn().
+				Err(err).
+				Str("peer", peer.address).
+				Dur("retryIn", retryInterval).
+				Msg("Failed to replicate changes")
+			wait = retryInterval
+			retryInterval = min(2*retryInterval, s.replication.maxRetryInterval)
+		case more:
+			wait = 0
+			retryInterval = s.replication.retryInterval
+		default:
+			retryInterval = s.replication.retryInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// replicateBatch replicates the next changes to a peer and reports whether
+// more are pending. Changes the peer rejects for good are skipped, so they
+// do not block the ones after them.
+func (s *Server) replicateBatch(
+	ctx context.Context,
+	peer replicationPeer,
+) (bool, error) {
+	cursor, err := s.db.GetReplicationCursor(ctx, peer.address)
+	if err != nil {
+		return false, wrapServiceError(err, "retrieving replication cursor")
+	}
+
+	events, err := s.db.GetReplicationEvents(
+		ctx,
+		cursor.LastEventID,
+		time.Now().Add(-replicationSettleTime),
+		replicationBatchSize,
+	)
+	if err != nil {
+		return false, wrapServiceError(err, "retrieving changes to replicate")
+	}
+
+	for i := range events {
+		err := s.replicateChange(ctx, peer, &events[i])
+		if err != nil {
+			cursor.LastError = err.Error()
+		}
+		if err != nil && isTransient(err) {
+			cursor.Failures++
+			if err := s.db.SaveReplicationCursor(ctx, cursor); err != nil {
+				log.Warn().Err(err).Msg("Failed to record replication failure")
+			}
+
+			return false, err
+		}
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("peer", peer.address).
+				Uint("event", events[i].ID).
+				Msg("Peer rejected replicated change, skipping it")
+		}
+
+		now := time.Now()
+		cursor.LastEventID = events[i].ID
+		cursor.Failures = 0
+		cursor.LastReplicatedAt = &now
+		if err := s.db.SaveReplicationCursor(ctx, cursor); err != nil {
+			return false, wrapServiceError(err, "saving replication cursor")
+		}
+	}
+
+	if len(events) > 0 {
+		addresses := make([]string, 0, len(s.replication.peers))
+		for _, p := range s.replication.peers {
+			addresses = append(addresses, p.address)
+		}
+		if err := s.db.PruneReplicationEvents(ctx, addresses); err != nil {
+			log.Warn().Err(err).Msg("Failed to prune replicated changes")
+		}
+	}
+
+	return len(events) == replicationBatchSize, nil
+}
+
+// replicateChange sends the changed version to a peer, with its current
+// tags, or deletes it there. Replaying a change that has already been applied
+// is harmless. Labels are only sent with the upload and version states are
+// not replicated, changes to them after the upload stay local.
+func (s *Server) replicateChange(
+	ctx context.Context,
+	peer replicationPeer,
+	event *orm.ReplicationEvent,
+) error {
+	pkg := &proto_gen.PackageName{Namespace: event.Namespace, Name: event.Name}
+	id := &proto_gen.ArtifactIdentifier{
+		Package: pkg,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: event.Hash,
+		},
+	}
+
+	switch event.Kind {
+	case orm.ReplicateDelete:
+		_, err := peer.client.DeleteArtifact(ctx, id)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+
+		//nolint:wrapcheck // Status of the peer is kept
+		return err
+	case orm.ReplicateDeletePackage:
+		// Versions were deleted by earlier changes, the peer removes what
+		// is left of the package
+		_, err := peer.client.DeletePackage(ctx, &proto_gen.DeletePackageRequest{
+			Package:        pkg,
+			DeleteVersions: true,
+		})
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+
+		//nolint:wrapcheck // Status of the peer is kept
+		return err
+	}
+
+	artifactMeta, err := s.db.GetArtifactMetaByHash(ctx, pkg, event.Hash)
+	var notFoundErr *orm.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		// Deleted since, which a later change replicates
+		return nil
+	}
+	if err != nil {
+		return wrapServiceError(err, "retrieving artifact to replicate")
+	}
+	artifact := artifactToProto(artifactMeta)
+
+	if event.Kind == orm.ReplicateUpload {
+		err := s.replicateUpload(ctx, peer, artifact)
+		switch status.Code(err) {
+		case codes.AlreadyExists:
+		case codes.FailedPrecondition:
+			// The version was restored from the trash, unless the peer has
+			// it elsewhere
+			_, restoreErr := peer.client.RestoreArtifact(
+				ctx,
+				&proto_gen.DeletedIdentifier{Package: pkg, VersionHash: event.Hash},
+			)
+			if status.Code(restoreErr) == codes.NotFound {
+				return err
+			}
+			if restoreErr != nil {
+				//nolint:wrapcheck // Status of the peer is kept
+				return restoreErr
+			}
+		default:
+			return err
+		}
+	}
+
+	// The tags of a version the peer already has are brought up to date
+	_, err = peer.client.SetTags(ctx, &proto_gen.SetTagsRequest{
+		Artifact: id,
+		Tags:     artifact.Tags,
+	})
+
+	//nolint:wrapcheck // Status of the peer is kept
+	return err
+}
+
+func (s *Server) replicateUpload(
+	ctx context.Context,
+	peer replicationPeer,
+	artifact *proto_gen.Artifact,
+) error {
+	content, err := s.registry.GetArtifact(
+		ctx,
+		artifact.Package,
+		artifact.VersionHash,
+	)
+	if err != nil {
+		return wrapServiceError(err, "retrieving artifact to replicate")
+	}
+
+	// The peer records the original uploader
+	if uploader := artifact.Metadata.GetUploader(); uploader != "" &&
+		uploader != auth.UnauthenticatedUser {
+		ctx = metadata.AppendToOutgoingContext(ctx, UserMetadataKey, uploader)
+	}
+
+	stream, err := peer.client.UploadArtifact(ctx)
+	if err != nil {
+		//nolint:wrapcheck // Status of the peer is kept
+		return err
+	}
+
+	// Send fails once the peer aborted the stream, its error is returned by
+	// CloseAndRecv
+	err = stream.Send(&proto_gen.UploadArtifactRequest{
+		Request: &proto_gen.UploadArtifactRequest_Metadata{
+			Metadata: &proto_gen.UploadMetadata{
+				Fqn:    artifact.Package,
+				Tags:   artifact.Tags,
+				Labels: artifact.Labels,
+			},
+		},
+	})
+	for offset := 0; err == nil && offset < len(content); offset += ChunkSize {
+		end := min(offset+ChunkSize, len(content))
+		err = stream.Send(&proto_gen.UploadArtifactRequest{
+			Request: &proto_gen.UploadArtifactRequest_Content{
+				Content: &proto_gen.ArtifactContent{Data: content[offset:end]},
+			},
+		})
+	}
+
+	_, err = stream.CloseAndRecv()
+
+	//nolint:wrapcheck // Status of the peer is kept
+	return err
+}
+
+// isTransient reports whether a failed replication is worth retrying. Local
+// failures are reported as Internal and retried as well.
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.Canceled,
+		codes.Internal,
+		codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// WarningMetadataKey is the gRPC response header that carries the reason a
+// deprecated or yanked version was served
+const WarningMetadataKey = "x-enclave-warning"
+
+var ErrYanked = errors.New("version is yanked")
+
+var versionStates = map[proto_gen.VersionState]string{
+	proto_gen.VersionState_ACTIVE:     orm.ArtifactActive,
+	proto_gen.VersionState_DEPRECATED: orm.ArtifactDeprecated,
+	proto_gen.VersionState_YANKED:     orm.ArtifactYanked,
+}
+
+func (s *Server) SetVersionState(
+	ctx context.Context,
+	request *proto_gen.SetVersionStateRequest,
+) (*proto_gen.Artifact, error) {
+	if request.Artifact == nil {
+		log.Error().Msg("SetVersionStateRequest missing artifact")
+
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Artifact must be provided",
+		}
+	}
+
+	state, ok := versionStates[request.State]
+	if !ok {
+		return nil, &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "Unknown version state " + request.State.String(),
+		}
+	}
+
+	artifactMeta, err := s.resolveIdentifier(ctx, request.Artifact)
+	if err != nil {
+		return nil, err // Already wrapped by resolveIdentifier
+	}
+
+	log.Info().
+		Str("namespace", artifactMeta.Namespace).
+		Str("name", artifactMeta.Name).
+		Str("versionHash", artifactMeta.Hash).
+		Str("state", state).
+		Msg("Setting state of artifact")
+
+	reason := request.Reason
+	if state == orm.ArtifactActive {
+		reason = ""
+	}
+
+	err = s.db.SetArtifactState(
+		auditContext(ctx),
+		request.Artifact.Package,
+		artifactMeta.Hash,
+		state,
+		reason,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set artifact state")
+
+		return nil, wrapServiceError(err, "setting state of artifact")
+	}
+
+	return s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+		Package: request.Artifact.Package,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: artifactMeta.Hash,
+		},
+	})
+}
+
+// rejectYankedTag hides yanked versions from tag resolution
+func rejectYankedTag(artifactMeta *orm.Artifact, tag string) error {
+	if artifactMeta.State != orm.ArtifactYanked {
+		return nil
+	}
+
+	return &ServiceError{
+		Code:    codes.NotFound,
+		Message: "Tag " + tag + " points to a yanked version",
+		Inner:   ErrYanked,
+	}
+}
+
+// versionWarning returns the response header announcing that a deprecated or
+// yanked version is served, or nil for active versions
+func versionWarning(artifactMeta *orm.Artifact) metadata.MD {
+	if artifactMeta.State == orm.ArtifactActive || artifactMeta.State == "" {
+		return nil
+	}
+
+	warning := "version " + artifactMeta.Hash + " is " + artifactMeta.State
+	if artifactMeta.StateReason != "" {
+		warning += ": " + artifactMeta.StateReason
+	}
+
+	return metadata.Pairs(WarningMetadataKey, escapeMetadataValue(warning))
+}
+
+// escapeMetadataValue escapes everything but printable ASCII, which is all a
+// gRPC metadata value may hold. Reasons are free text, they may even come
+// from an upstream registry.
+func escapeMetadataValue(value string) string {
+	var escaped strings.Builder
+	for _, r := range value {
+		if r >= ' ' && r <= '~' {
+			escaped.WriteRune(r)
+
+			continue
+		}
+		quoted := strconv.QuoteRuneToASCII(r)
+		escaped.WriteString(quoted[1 : len(quoted)-1])
+	}
+
+	return escaped.String()
+}
+
+func versionStateToProto(state string) proto_gen.VersionState {
+	for protoState, s := range versionStates {
+		if s == state {
+			return protoState
+		}
+	}
+
+	return proto_gen.VersionState_ACTIVE
+}
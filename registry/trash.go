@@ -0,0 +1,247 @@
+package registry
+
+import (
+	"artifact-registry/orm"
+	"artifact-registry/proto_gen"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Server) ListDeleted(
+	ctx context.Context,
+	query *proto_gen.DeletedQuery,
+) (*proto_gen.DeletedListResponse, error) {
+	log.Info().
+		Str("namespace", query.GetNamespace()).
+		Str("name", query.GetName()).
+		Msg("Deleted artifacts listed")
+
+	artifacts, err := s.db.GetDeletedArtifactMetas(ctx, orm.TrashFilter{
+		Namespace: query.GetNamespace(),
+		Name:      query.GetName(),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list deleted artifacts")
+
+		return nil, wrapServiceError(err, "listing deleted artifacts")
+	}
+
+	result := make([]*proto_gen.DeletedArtifact, 0, len(artifacts))
+	for i := range artifacts {
+		result = append(result, s.deletedArtifactToProto(&artifacts[i]))
+	}
+
+	return &proto_gen.DeletedListResponse{Artifacts: result}, nil
+}
+
+func (s *Server) RestoreArtifact(
+	ctx context.Context,
+	id *proto_gen.DeletedIdentifier,
+) (*proto_gen.Artifact, error) {
+	if err := validateDeletedIdentifier(id); err != nil {
+		log.Error().Err(err).Msg("Invalid RestoreArtifact request")
+
+		return nil, err
+	}
+
+	log.Info().
+		Str("namespace", id.Package.Namespace).
+		Str("name", id.Package.Name).
+		Str("versionHash", id.VersionHash).
+		Msg("Restore of artifact requested")
+
+	err := s.db.RestoreArtifactMeta(
+		auditContext(ctx),
+		id.Package,
+		id.VersionHash,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to restore artifact")
+
+		return nil, wrapServiceError(err, "restoring artifact")
+	}
+
+	return s.GetArtifact(ctx, &proto_gen.ArtifactIdentifier{
+		Package: id.Package,
+		Identifier: &proto_gen.ArtifactIdentifier_VersionHash{
+			VersionHash: id.VersionHash,
+		},
+	})
+}
+
+func (s *Server) PurgeArtifact(
+	ctx context.Context,
+	id *proto_gen.DeletedIdentifier,
+) (*proto_gen.DeletedArtifact, error) {
+	if err := validateDeletedIdentifier(id); err != nil {
+		log.Error().Err(err).Msg("Invalid PurgeArtifact request")
+
+		return nil, err
+	}
+
+	log.Info().
+		Str("namespace", id.Package.Namespace).
+		Str("name", id.Package.Name).
+		Str("versionHash", id.VersionHash).
+		Msg("Purge of artifact requested")
+
+	if s.registry == nil {
+		return nil, newRegistryUnavailableError("artifact purge")
+	}
+
+	artifactMeta, err := s.db.GetDeletedArtifactMeta(
+		ctx,
+		id.Package,
+		id.VersionHash,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get deleted artifact")
+
+		return nil, wrapServiceError(err, "retrieving deleted artifact")
+	}
+
+	if err := s.purge(auditContext(ctx), artifactMeta); err != nil {
+		return nil, err
+	}
+
+	return s.deletedArtifactToProto(artifactMeta), nil
+}
+
+// PurgeExpiredArtifacts removes all versions that stayed in the trash for
+// longer than the retention period. Versions that fail to be purged do not
+// hold up the others, their errors are returned together.
+func (s *Server) PurgeExpiredArtifacts(ctx context.Context) error {
+	if s.registry == nil {
+		return newRegistryUnavailableError("trash purge")
+	}
+
+	expired, err := s.db.GetDeletedArtifactMetas(ctx, orm.TrashFilter{
+		DeletedBefore: time.Now().Add(-s.trashRetention),
+	})
+	if err != nil {
+		return wrapServiceError(err, "listing expired artifacts")
+	}
+
+	var errs []error
+	for i := range expired {
+		if err := s.purge(ctx, &expired[i]); err != nil {
+			log.Error().
+				Err(err).
+				Str("namespace", expired[i].Namespace).
+				Str("name", expired[i].Name).
+				Str("versionHash", expired[i].Hash).
+				Msg("Failed to purge expired artifact")
+			errs = append(errs, err)
+		}
+	}
+
+	if purged := len(expired) - len(errs); purged > 0 {
+		log.Info().Int("purged", purged).Msg("Purged expired artifacts")
+	}
+
+	return errors.Join(errs...)
+}
+
+// RunTrashPurger purges expired artifacts in the given interval until the
+// context is cancelled
+func (s *Server) RunTrashPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.PurgeExpiredArtifacts(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to purge expired artifacts")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge deletes the metadata of a trashed artifact before its blob, so a
+// failure can only leave an orphaned blob behind
+func (s *Server) purge(ctx context.Context, artifactMeta *orm.Artifact) error {
+	pkg := &proto_gen.PackageName{
+		Namespace: artifactMeta.Namespace,
+		Name:      artifactMeta.Name,
+	}
+
+	err := s.db.PurgeArtifactMeta(ctx, pkg, artifactMeta.Hash)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge artifact metadata")
+
+		return wrapServiceError(err, "purging artifact metadata")
+	}
+
+	if err := s.registry.DeleteArtifact(ctx, pkg, artifactMeta.Hash); err != nil {
+		log.Warn().
+			Err(err).
+			Str("namespace", artifactMeta.Namespace).
+			Str("name", artifactMeta.Name).
+			Str("versionHash", artifactMeta.Hash).
+			Msg("Failed to delete blob of purged artifact")
+	}
+
+	return nil
+}
+
+// trashedVersionError returns a FailedPrecondition error if the version is in
+// the trash, nil otherwise. A trashed version keeps its key, so it has to be
+// restored rather than stored again.
+func (s *Server) trashedVersionError(
+	ctx context.Context,
+	pkg *proto_gen.PackageName,
+	versionHash string,
+) error {
+	if _, err := s.db.GetDeletedArtifactMeta(ctx, pkg, versionHash); err != nil {
+		return nil
+	}
+
+	return &ServiceError{
+		Code: codes.FailedPrecondition,
+		Message: "Version " + versionHash + " of " + pkg.Namespace + "/" +
+			pkg.Name + " is in the trash, restore it with RestoreArtifact",
+		Inner: ErrVersionTrashed,
+	}
+}
+
+func validateDeletedIdentifier(id *proto_gen.DeletedIdentifier) error {
+	if err := validateFQN(id.GetPackage()); err != nil {
+		return err
+	}
+
+	if id.VersionHash == "" {
+		return &ServiceError{
+			Code:    codes.InvalidArgument,
+			Message: "versionHash cannot be empty",
+			Inner:   ErrEmptyVersionHash,
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) deletedArtifactToProto(
+	a *orm.Artifact,
+) *proto_gen.DeletedArtifact {
+	tags := make([]string, 0, len(a.DeletedTags))
+	for _, t := range a.DeletedTags {
+		tags = append(tags, t.TagName)
+	}
+
+	return &proto_gen.DeletedArtifact{
+		Artifact:  artifactToProto(a),
+		Tags:      tags,
+		DeletedBy: a.DeletedBy,
+		Deleted:   timestamppb.New(a.DeletedAt.Time),
+		Expires:   timestamppb.New(a.DeletedAt.Time.Add(s.trashRetention)),
+	}
+}
@@ -3,6 +3,7 @@ package registry
 import (
 	"artifact-registry/proto_gen"
 	"errors"
+	"fmt"
 
 	"google.golang.org/grpc/codes"
 )
@@ -12,6 +13,12 @@ var (
 	ErrInvalidIdentifier = errors.New("no valid identifier provided")
 	ErrEmptyTag          = errors.New("tag cannot be empty")
 	ErrEmptyVersionHash  = errors.New("versionHash cannot be empty")
+	ErrInvalidLabel      = errors.New("invalid label")
+)
+
+const (
+	maxLabelKeyLength   = 255
+	maxLabelValueLength = 1024
 )
 
 func validateFQN(pkg *proto_gen.PackageName) error {
@@ -54,3 +61,31 @@ func validateArtifactIdentifier(id *proto_gen.ArtifactIdentifier) error {
 
 	return nil
 }
+
+func validateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if key == "" || len(key) > maxLabelKeyLength {
+			return &ServiceError{
+				Code: codes.InvalidArgument,
+				Message: fmt.Sprintf(
+					"Label keys must be between 1 and %d characters long",
+					maxLabelKeyLength,
+				),
+				Inner: ErrInvalidLabel,
+			}
+		}
+		if len(value) > maxLabelValueLength {
+			return &ServiceError{
+				Code: codes.InvalidArgument,
+				Message: fmt.Sprintf(
+					"Value of label %q exceeds %d characters",
+					key,
+					maxLabelValueLength,
+				),
+				Inner: ErrInvalidLabel,
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// Package tracing sets up OpenTelemetry tracing of the registry
+package tracing
+
+import (
+	"artifact-registry/config"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const serviceName = "artifact-registry"
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Init installs the tracer provider configured in cfg globally. The returned
+// function flushes pending spans and stops the provider.
+func Init(
+	ctx context.Context,
+	cfg *config.AppConfig,
+) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Tracing.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(cfg.Tracing.OTLPEndpoint),
+		}
+		if cfg.Tracing.OTLPInsecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf(
+			"%w: %q",
+			ErrUnknownExporter,
+			cfg.Tracing.Exporter,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"creating %s exporter: %w",
+			cfg.Tracing.Exporter,
+			err,
+		)
+	}
+
+	provider := NewProvider(exporter, cfg.Tracing.SampleRatio)
+	Install(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Install makes provider the global tracer provider and enables propagation
+// of W3C trace contexts
+func Install(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// NewProvider creates a tracer provider that samples the given ratio of new
+// traces and sends them to exporter, e.g. an in-memory exporter in tests.
+// Requests that carry a trace context keep its sampling decision.
+func NewProvider(
+	exporter sdktrace.SpanExporter,
+	sampleRatio float64,
+) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(sampleRatio),
+		)),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+		)),
+	)
+}
+
+// ServerOptions extracts the trace context from incoming gRPC metadata and
+// starts a span for every RPC
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+	}
+}
+
+// Start starts a span as child of the one in ctx. The tracer is looked up on
+// every call, so spans follow a provider installed later on.
+func Start(
+	ctx context.Context,
+	name string,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	//nolint:spancheck // Ended by the caller
+	return otel.Tracer(serviceName).Start(
+		ctx,
+		name,
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// End marks the span as failed if err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"artifact-registry/config"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartAndEnd(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, 1)
+	Install(provider)
+
+	ctx, parent := Start(t.Context(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("failed"))
+	End(parent, nil)
+	assert.NoError(t, provider.ForceFlush(t.Context()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Len(t, spans[0].Events, 1)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t, "parent", spans[1].Name)
+	assert.Equal(t, codes.Unset, spans[1].Status.Code)
+}
+
+func TestInit(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.AppConfig{}
+	cfg.Tracing.Exporter = ExporterNone
+	shutdown, err := Init(t.Context(), cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(t.Context()))
+
+	cfg.Tracing.Exporter = "zipkin"
+	_, err = Init(t.Context(), cfg)
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+}
@@ -0,0 +1,774 @@
+package wasm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sorts of component items. Extern descriptors use the same numbering.
+const (
+	sortCore byte = iota
+	sortFunc
+	sortValue
+	sortType
+	sortComponent
+	sortInstance
+)
+
+// unknownWIT stands in for types that could not be decoded, e.g. because
+// they are defined in a nested component
+const unknownWIT = "unknown"
+
+// witItem is an entry of an index space of a component, rendered in WIT
+// syntax
+type witItem struct {
+	// How the item is referred to, the name of named types and the structure
+	// of everything else. Empty if the item could not be decoded.
+	wit string
+	// Definition of named types
+	def string
+	// Exports of instances, and of instance and component types
+	exports []witExport
+}
+
+type witExport struct {
+	name string
+	kind Kind
+	item witItem
+}
+
+// signature is how the item is shown as an import or export
+func (i witItem) signature() string {
+	if i.def != "" {
+		return i.def
+	}
+
+	return i.wit
+}
+
+// ref is how the item is shown where another type refers to it
+func (i witItem) ref() string {
+	if i.wit == "" {
+		return unknownWIT
+	}
+
+	return i.wit
+}
+
+// instance is the item described by an instance, component or function
+// type, e.g. the function an import with that type brings in
+func (i witItem) instance() witItem {
+	return witItem{wit: i.signature(), exports: i.exports}
+}
+
+// named is the type referred to by the given name, defined as the item. A
+// type that is passed on under its own name keeps its definition.
+func (i witItem) named(name string) witItem {
+	if i.wit == name {
+		return i
+	}
+
+	return witItem{wit: name, def: i.wit, exports: i.exports}
+}
+
+func (i witItem) export(name string) witItem {
+	for _, e := range i.exports {
+		if e.name == name {
+			return e.item
+		}
+	}
+
+	return witItem{}
+}
+
+func (e witExport) String() string {
+	switch {
+	case e.kind == KindType && e.item.def == "resource":
+		return "resource " + e.name
+	case e.kind == KindType:
+		def := e.item.def
+		if def == "" {
+			def = unknownWIT
+		}
+
+		return "type " + e.name + " = " + def
+	default:
+		return e.name + ": " + e.item.ref()
+	}
+}
+
+func instanceItem(exports []witExport) witItem {
+	entries := make([]string, 0, len(exports))
+	for _, e := range exports {
+		entries = append(entries, e.String())
+	}
+
+	return witItem{wit: renderWIT("instance", entries), exports: exports}
+}
+
+func renderWIT(keyword string, entries []string) string {
+	if len(entries) == 0 {
+		return keyword + " {}"
+	}
+
+	return keyword + " { " + strings.Join(entries, "; ") + " }"
+}
+
+// witScope holds the index spaces of a component, or of a component or
+// instance type, that signatures refer to
+type witScope struct {
+	parent *witScope
+	spaces map[byte][]witItem
+	// Set once a definition could not be decoded. The index spaces no longer
+	// line up with the binary then, so every lookup comes back unknown.
+	incomplete bool
+}
+
+func newWITScope(parent *witScope) *witScope {
+	return &witScope{parent: parent, spaces: map[byte][]witItem{}}
+}
+
+// add appends an item to the index space of its sort. Core items are not
+// tracked.
+func (s *witScope) add(sort byte, item witItem) {
+	if sort != sortCore {
+		s.spaces[sort] = append(s.spaces[sort], item)
+	}
+}
+
+func (s *witScope) at(sort byte, index int) witItem {
+	space := s.spaces[sort]
+	if s.incomplete || index >= len(space) {
+		return witItem{}
+	}
+
+	return space[index]
+}
+
+// declare adds an imported or exported item to its index space. Types are
+// referred to by the name they are imported or exported as from then on.
+func declare(scope *witScope, sort byte, name string, item witItem) witItem {
+	if sort == sortType {
+		item = item.named(name)
+	}
+	scope.add(sort, item)
+
+	return item
+}
+
+// sortIndex is a reference to an item of one of the index spaces
+type sortIndex struct {
+	sort  byte
+	kind  Kind
+	index int
+}
+
+var sortKinds = map[byte]Kind{
+	sortFunc:      KindFunc,
+	sortValue:     KindValue,
+	sortType:      KindType,
+	sortComponent: KindComponent,
+	sortInstance:  KindInstance,
+}
+
+//nolint:mnd // Sorts are defined by the binary format
+func (r *reader) sortIdx() (sortIndex, error) {
+	sort, err := r.byte()
+	if err != nil {
+		return sortIndex{}, err
+	}
+
+	kind, ok := sortKinds[sort]
+	switch {
+	case sort == sortCore:
+		coreSort, err := r.byte()
+		if err != nil {
+			return sortIndex{}, err
+		}
+		kind = KindModule
+		if coreSort != 0x11 {
+			kind = Kind(fmt.Sprintf("core(0x%02x)", coreSort))
+		}
+	case !ok:
+		return sortIndex{}, r.fail(
+			fmt.Errorf("%w: sort 0x%02x", ErrMalformed, sort),
+		)
+	}
+	index, err := r.u32()
+
+	return sortIndex{sort: sort, kind: kind, index: index}, err
+}
+
+// externItem reads an extern descriptor and returns the sort and kind of the
+// item it describes along with the item
+//
+//nolint:mnd // Extern descriptors are defined by the binary format
+func (r *reader) externItem(scope *witScope) (byte, Kind, witItem, error) {
+	desc, err := r.byte()
+	if err != nil {
+		return 0, "", witItem{}, err
+	}
+
+	switch desc {
+	case 0x00: // core module, prefixed by the core:sort byte
+		if err := r.skip(1); err != nil {
+			return 0, "", witItem{}, err
+		}
+		_, err = r.u32()
+
+		return sortCore, KindModule, witItem{}, err
+	case 0x02: // value bound: eq index or value type
+		bound, err := r.byte()
+		if err != nil {
+			return 0, "", witItem{}, err
+		}
+		if bound == 0x00 {
+			index, err := r.u32()
+
+			return sortValue, KindValue, scope.at(sortValue, index), err
+		}
+		t, err := r.witValType(scope)
+
+		return sortValue, KindValue, witItem{wit: t}, err
+	case 0x03: // type bound: eq index or sub resource
+		bound, err := r.byte()
+		if err != nil {
+			return 0, "", witItem{}, err
+		}
+		if bound == 0x00 {
+			index, err := r.u32()
+
+			return sortType, KindType, scope.at(sortType, index), err
+		}
+
+		return sortType, KindType, witItem{wit: "resource"}, nil
+	case sortFunc, sortComponent, sortInstance:
+		index, err := r.u32()
+
+		return desc, sortKinds[desc], scope.at(sortType, index).instance(), err
+	default:
+		return 0, "", witItem{}, r.fail(
+			fmt.Errorf("%w: extern descriptor 0x%02x", ErrMalformed, desc),
+		)
+	}
+}
+
+func (r *reader) optionalExternItem(scope *witScope) (witItem, bool, error) {
+	present, err := r.byte()
+	if err != nil || present == 0x00 {
+		return witItem{}, false, err
+	}
+	_, _, item, err := r.externItem(scope)
+
+	return item, true, err
+}
+
+// witDefinitions reads a section that defines items of the component
+// without importing or exporting them
+func (r *reader) witDefinitions(id byte, scope *witScope) error {
+	return vec(r, func() error {
+		switch id {
+		case sectionComponentType:
+			item, err := r.witDefType(scope)
+			scope.add(sortType, item)
+
+			return err
+		case sectionComponentAlias:
+			return r.witAlias(scope)
+		case sectionComponentCanon:
+			return r.witCanon(scope)
+		default:
+			return r.witInstance(scope)
+		}
+	})
+}
+
+// witAlias reads an alias and adds the item it refers to
+//
+//nolint:mnd // Alias targets are defined by the binary format
+func (r *reader) witAlias(scope *witScope) error {
+	sort, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if sort == sortCore {
+		if err := r.skip(1); err != nil {
+			return err
+		}
+	}
+	target, err := r.byte()
+	if err != nil {
+		return err
+	}
+
+	switch target {
+	case 0x00: // export of an instance
+		instance, err := r.u32()
+		if err != nil {
+			return err
+		}
+		name, err := r.name()
+		scope.add(sort, scope.at(sortInstance, instance).export(name))
+
+		return err
+	case 0x01: // export of a core instance
+		if _, err := r.u32(); err != nil {
+			return err
+		}
+		_, err := r.name()
+
+		return err
+	case 0x02: // item of an enclosing component
+		count, err := r.u32()
+		if err != nil {
+			return err
+		}
+		index, err := r.u32()
+		outer := scope
+		for range count {
+			if outer != nil {
+				outer = outer.parent
+			}
+		}
+		item := witItem{}
+		if outer != nil {
+			item = outer.at(sort, index)
+		}
+		scope.add(sort, item)
+
+		return err
+	default:
+		return r.fail(fmt.Errorf("%w: alias target 0x%02x", ErrMalformed, target))
+	}
+}
+
+// witCanon reads a canonical definition. Only lifted functions are
+// component-level items, the others define core functions.
+//
+//nolint:mnd // Canonical definitions are defined by the binary format
+func (r *reader) witCanon(scope *witScope) error {
+	op, err := r.byte()
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case 0x00: // lift
+		if err := r.skip(1); err != nil {
+			return err
+		}
+		if _, err := r.u32(); err != nil {
+			return err
+		}
+		if err := r.skipCanonOpts(); err != nil {
+			return err
+		}
+		typeIdx, err := r.u32()
+		scope.add(sortFunc, scope.at(sortType, typeIdx).instance())
+
+		return err
+	case 0x01: // lower
+		if err := r.skip(1); err != nil {
+			return err
+		}
+		if _, err := r.u32(); err != nil {
+			return err
+		}
+
+		return r.skipCanonOpts()
+	case 0x02, 0x03, 0x04, 0x07: // resource.new, drop, rep and drop async
+		_, err := r.u32()
+
+		return err
+	default:
+		return r.fail(
+			fmt.Errorf("%w: canonical definition 0x%02x", ErrUnsupportedType, op),
+		)
+	}
+}
+
+//nolint:mnd // Canonical options are defined by the binary format
+func (r *reader) skipCanonOpts() error {
+	return vec(r, func() error {
+		opt, err := r.byte()
+		if err != nil {
+			return err
+		}
+
+		switch opt {
+		case 0x00, 0x01, 0x02, 0x06: // string encodings and async
+			return nil
+		case 0x03, 0x04, 0x05, 0x07: // memory, realloc, post-return, callback
+			_, err := r.u32()
+
+			return err
+		default:
+			return r.fail(
+				fmt.Errorf("%w: canonical option 0x%02x", ErrUnsupportedType, opt),
+			)
+		}
+	})
+}
+
+// witInstance reads an instance definition
+//
+//nolint:mnd // Instance expressions are defined by the binary format
+func (r *reader) witInstance(scope *witScope) error {
+	form, err := r.byte()
+	if err != nil {
+		return err
+	}
+
+	switch form {
+	case 0x00: // instantiation of a component
+		component, err := r.u32()
+		if err != nil {
+			return err
+		}
+		err = vec(r, func() error {
+			if _, err := r.name(); err != nil {
+				return err
+			}
+			_, err := r.sortIdx()
+
+			return err
+		})
+		if item := scope.at(sortComponent, component); item.wit != "" {
+			scope.add(sortInstance, instanceItem(item.exports))
+		} else {
+			scope.add(sortInstance, witItem{})
+		}
+
+		return err
+	case 0x01: // bundle of inline exports
+		var exports []witExport
+		err := vec(r, func() error {
+			name, err := r.externName()
+			if err != nil {
+				return err
+			}
+			idx, err := r.sortIdx()
+			item := scope.at(idx.sort, idx.index)
+			if idx.sort == sortType {
+				item = item.named(name)
+			}
+			exports = append(exports, witExport{name, idx.kind, item})
+
+			return err
+		})
+		scope.add(sortInstance, instanceItem(exports))
+
+		return err
+	default:
+		return r.fail(fmt.Errorf("%w: instance form 0x%02x", ErrMalformed, form))
+	}
+}
+
+// witDefType reads a type definition
+//
+//nolint:mnd // Type encodings are defined by the binary format
+func (r *reader) witDefType(scope *witScope) (witItem, error) {
+	form, err := r.byte()
+	if err != nil {
+		return witItem{}, err
+	}
+	if t, ok := primitiveWITTypes[form]; ok {
+		return witItem{wit: t}, nil
+	}
+
+	switch form {
+	case 0x40, 0x43:
+		wit, err := r.witFuncType(scope)
+		if form == 0x43 {
+			wit = "async " + wit
+		}
+
+		return witItem{wit: wit}, err
+	case 0x41, 0x42:
+		return r.witDeclType(scope, form == 0x41)
+	case 0x3f: // resource with a core representation and optional destructor
+		if err := r.skip(1); err != nil {
+			return witItem{}, err
+		}
+		present, err := r.byte()
+		if err == nil && present == 0x01 {
+			_, err = r.u32()
+		}
+
+		return witItem{wit: "resource"}, err
+	default:
+		wit, err := r.witDefValType(scope, form)
+
+		return witItem{wit: wit}, err
+	}
+}
+
+var primitiveWITTypes = map[byte]string{
+	0x7f: "bool",
+	0x7e: "s8",
+	0x7d: "u8",
+	0x7c: "s16",
+	0x7b: "u16",
+	0x7a: "s32",
+	0x79: "u32",
+	0x78: "s64",
+	0x77: "u64",
+	0x76: "f32",
+	0x75: "f64",
+	0x74: "char",
+	0x73: "string",
+	0x64: "error-context",
+}
+
+// witValType reads a value type, either a primitive or a reference to the
+// type index space
+func (r *reader) witValType(scope *witScope) (string, error) {
+	start := r.pos
+	//nolint:mnd // Value types are encoded as s33
+	v, err := r.sleb(33)
+	if err != nil {
+		return "", err
+	}
+	if v >= 0 {
+		return scope.at(sortType, int(v)).ref(), nil
+	}
+	if t, ok := primitiveWITTypes[r.data[start]]; ok {
+		return t, nil
+	}
+
+	return unknownWIT, nil
+}
+
+func (r *reader) optionalWITValType(scope *witScope) (string, error) {
+	present, err := r.byte()
+	if err != nil || present == 0x00 {
+		return "", err
+	}
+
+	return r.witValType(scope)
+}
+
+// witDefValType reads the definition of a compound value type
+//
+//nolint:mnd // Type encodings are defined by the binary format
+func (r *reader) witDefValType(scope *witScope, form byte) (string, error) {
+	switch form {
+	case 0x72:
+		fields, err := r.witLabeledTypes(scope)
+
+		return "record { " + fields + " }", err
+	case 0x71:
+		var cases []string
+		err := vec(r, func() error {
+			label, err := r.name()
+			if err != nil {
+				return err
+			}
+			t, err := r.optionalWITValType(scope)
+			if err != nil {
+				return err
+			}
+			if t != "" {
+				label += "(" + t + ")"
+			}
+			cases = append(cases, label)
+
+			// Refinement, always absent
+			return r.skip(1)
+		})
+
+		return "variant { " + strings.Join(cases, ", ") + " }", err
+	case 0x70:
+		t, err := r.witValType(scope)
+
+		return "list<" + t + ">", err
+	case 0x67:
+		t, err := r.witValType(scope)
+		if err != nil {
+			return "", err
+		}
+		length, err := r.u32()
+
+		return "list<" + t + ", " + strconv.Itoa(length) + ">", err
+	case 0x6f:
+		var types []string
+		err := vec(r, func() error {
+			t, err := r.witValType(scope)
+			types = append(types, t)
+
+			return err
+		})
+
+		return "tuple<" + strings.Join(types, ", ") + ">", err
+	case 0x6e, 0x6d:
+		var labels []string
+		err := vec(r, func() error {
+			label, err := r.name()
+			labels = append(labels, label)
+
+			return err
+		})
+		keyword := "flags"
+		if form == 0x6d {
+			keyword = "enum"
+		}
+
+		return keyword + " { " + strings.Join(labels, ", ") + " }", err
+	case 0x6b:
+		t, err := r.witValType(scope)
+
+		return "option<" + t + ">", err
+	case 0x6a:
+		ok, err := r.optionalWITValType(scope)
+		if err != nil {
+			return "", err
+		}
+		failure, err := r.optionalWITValType(scope)
+
+		return resultWIT(ok, failure), err
+	case 0x69, 0x68:
+		index, err := r.u32()
+		resource := scope.at(sortType, index).ref()
+		if form == 0x68 {
+			return "borrow<" + resource + ">", err
+		}
+
+		return resource, err
+	case 0x66, 0x65:
+		t, err := r.optionalWITValType(scope)
+		keyword := "stream"
+		if form == 0x65 {
+			keyword = "future"
+		}
+		if t == "" {
+			return keyword, err
+		}
+
+		return keyword + "<" + t + ">", err
+	default:
+		return "", r.fail(
+			fmt.Errorf("%w: component type 0x%02x", ErrUnsupportedType, form),
+		)
+	}
+}
+
+func resultWIT(ok, failure string) string {
+	switch {
+	case ok == "" && failure == "":
+		return "result"
+	case failure == "":
+		return "result<" + ok + ">"
+	case ok == "":
+		return "result<_, " + failure + ">"
+	default:
+		return "result<" + ok + ", " + failure + ">"
+	}
+}
+
+// witLabeledTypes reads a vector of labeled value types, e.g. the fields of
+// a record or the parameters of a function
+func (r *reader) witLabeledTypes(scope *witScope) (string, error) {
+	var labeled []string
+	err := vec(r, func() error {
+		label, err := r.name()
+		if err != nil {
+			return err
+		}
+		t, err := r.witValType(scope)
+		labeled = append(labeled, label+": "+t)
+
+		return err
+	})
+
+	return strings.Join(labeled, ", "), err
+}
+
+//nolint:mnd // Result lists are defined by the binary format
+func (r *reader) witFuncType(scope *witScope) (string, error) {
+	params, err := r.witLabeledTypes(scope)
+	if err != nil {
+		return "", err
+	}
+	wit := "func(" + params + ")"
+
+	form, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	switch form {
+	case 0x00:
+		t, err := r.witValType(scope)
+
+		return wit + " -> " + t, err
+	case 0x01: // named results, none in current encodings
+		results, err := r.witLabeledTypes(scope)
+		if results != "" {
+			wit += " -> (" + results + ")"
+		}
+
+		return wit, err
+	default:
+		return "", r.fail(fmt.Errorf("%w: result list 0x%02x", ErrMalformed, form))
+	}
+}
+
+// witDeclType reads an instance or component type. Its declarations have an
+// index space of their own, aliases reach the enclosing ones.
+//
+//nolint:mnd // Declarations are defined by the binary format
+func (r *reader) witDeclType(scope *witScope, component bool) (witItem, error) {
+	inner := newWITScope(scope)
+	// Imports only occur in component types
+	var entries []string
+	var exports []witExport
+
+	err := vec(r, func() error {
+		decl, err := r.byte()
+		if err != nil {
+			return err
+		}
+
+		switch decl {
+		case 0x01:
+			item, err := r.witDefType(inner)
+			inner.add(sortType, item)
+
+			return err
+		case 0x02:
+			return r.witAlias(inner)
+		case 0x03, 0x04:
+			name, err := r.externName()
+			if err != nil {
+				return err
+			}
+			sort, kind, item, err := r.externItem(inner)
+			if err != nil {
+				return err
+			}
+			export := witExport{name, kind, declare(inner, sort, name, item)}
+			if decl == 0x03 {
+				entries = append(entries, "import "+export.String())
+
+				return nil
+			}
+			exports = append(exports, export)
+			entries = append(entries, "export "+export.String())
+
+			return nil
+		default:
+			return r.fail(
+				fmt.Errorf("%w: declaration 0x%02x", ErrUnsupportedType, decl),
+			)
+		}
+	})
+	if err != nil {
+		return witItem{}, err
+	}
+
+	if !component {
+		return instanceItem(exports), nil
+	}
+
+	return witItem{wit: renderWIT("component", entries), exports: exports}, nil
+}
@@ -0,0 +1,172 @@
+package wasm
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Direction tells whether a symbol is imported or exported
+type Direction string
+
+const (
+	DirectionImport Direction = "import"
+	DirectionExport Direction = "export"
+)
+
+// ChangeType classifies a difference between two interfaces
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// Change is a single difference between two interfaces. Before is nil for
+// added symbols, After is nil for removed ones.
+type Change struct {
+	Type      ChangeType
+	Direction Direction
+	Before    *Symbol
+	After     *Symbol
+	// Breaking is set if consumers of the base interface can break. That is
+	// the case for removed or changed exports and for added or changed
+	// imports, which the host now has to provide.
+	Breaking bool
+}
+
+type symbolKey struct {
+	module string
+	name   string
+}
+
+// Compare lists the differences between the interface of a base version and
+// a candidate version. A nil interface is treated as empty. The result is
+// ordered by direction, module and name.
+func Compare(base, candidate *Interface) []Change {
+	if base == nil {
+		base = &Interface{}
+	}
+	if candidate == nil {
+		candidate = &Interface{}
+	}
+
+	changes := compareSymbols(DirectionImport, base.Imports, candidate.Imports)
+	changes = append(
+		changes,
+		compareSymbols(DirectionExport, base.Exports, candidate.Exports)...,
+	)
+
+	return changes
+}
+
+// IsCompatible reports whether none of the changes is breaking
+func IsCompatible(changes []Change) bool {
+	return !slices.ContainsFunc(changes, func(c Change) bool {
+		return c.Breaking
+	})
+}
+
+func compareSymbols(direction Direction, base, candidate []Symbol) []Change {
+	before := indexSymbols(base)
+	after := indexSymbols(candidate)
+
+	var changes []Change
+	for key, olds := range before {
+		removed, added := unmatchedSymbols(olds, after[key])
+		for i, old := range removed {
+			if i < len(added) {
+				changes = append(changes, Change{
+					Type:      ChangeChanged,
+					Direction: direction,
+					Before:    old,
+					After:     added[i],
+					Breaking:  true,
+				})
+
+				continue
+			}
+			changes = append(changes, Change{
+				Type:      ChangeRemoved,
+				Direction: direction,
+				Before:    old,
+				Breaking:  direction == DirectionExport,
+			})
+		}
+		for _, updated := range added[min(len(removed), len(added)):] {
+			changes = append(changes, Change{
+				Type:      ChangeAdded,
+				Direction: direction,
+				After:     updated,
+				Breaking:  direction == DirectionImport,
+			})
+		}
+	}
+	for key, news := range after {
+		if _, ok := before[key]; ok {
+			continue
+		}
+		for _, added := range news {
+			changes = append(changes, Change{
+				Type:      ChangeAdded,
+				Direction: direction,
+				After:     added,
+				Breaking:  direction == DirectionImport,
+			})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		ka, kb := a.symbol(), b.symbol()
+
+		return cmp.Or(
+			cmp.Compare(ka.Module, kb.Module),
+			cmp.Compare(ka.Name, kb.Name),
+			cmp.Compare(ka.Kind, kb.Kind),
+			cmp.Compare(ka.Signature, kb.Signature),
+		)
+	})
+
+	return changes
+}
+
+func (c Change) symbol() *Symbol {
+	if c.Before != nil {
+		return c.Before
+	}
+
+	return c.After
+}
+
+// indexSymbols groups symbols by module and name. A module may import the
+// same name more than once, e.g. with different types.
+func indexSymbols(symbols []Symbol) map[symbolKey][]*Symbol {
+	index := make(map[symbolKey][]*Symbol, len(symbols))
+	for i := range symbols {
+		key := symbolKey{symbols[i].Module, symbols[i].Name}
+		index[key] = append(index[key], &symbols[i])
+	}
+
+	return index
+}
+
+// unmatchedSymbols drops the symbols of the same kind and signature from both
+// groups, leaving those that were removed or added
+func unmatchedSymbols(olds, news []*Symbol) ([]*Symbol, []*Symbol) {
+	added := slices.Clone(news)
+
+	var removed []*Symbol
+	for _, old := range olds {
+		i := slices.IndexFunc(added, func(s *Symbol) bool {
+			return s.Kind == old.Kind && s.Signature == old.Signature
+		})
+		if i < 0 {
+			removed = append(removed, old)
+
+			continue
+		}
+		added = slices.Delete(added, i, i+1)
+	}
+
+	return removed, added
+}
@@ -0,0 +1,153 @@
+package wasm
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	base := &Interface{
+		Imports: []Symbol{
+			{Module: "env", Name: "log", Kind: KindFunc, Signature: "(i32) -> ()"},
+			{Module: "env", Name: "clock", Kind: KindFunc, Signature: "() -> (i64)"},
+		},
+		Exports: []Symbol{
+			{Name: "run", Kind: KindFunc, Signature: "() -> ()"},
+			{Name: "init", Kind: KindFunc, Signature: "() -> ()"},
+			{Name: "memory", Kind: KindMemory, Signature: "min=1"},
+		},
+	}
+
+	t.Run("Identical", func(t *testing.T) {
+		t.Parallel()
+
+		changes := Compare(base, base)
+		assert.Empty(t, changes)
+		assert.True(t, IsCompatible(changes))
+	})
+
+	t.Run("CompatibleChanges", func(t *testing.T) {
+		t.Parallel()
+
+		candidate := &Interface{
+			// Dropping an import is fine
+			Imports: base.Imports[:1],
+			// Adding an export is fine
+			Exports: append(slices.Clone(base.Exports), Symbol{
+				Name:      "stop",
+				Kind:      KindFunc,
+				Signature: "() -> ()",
+			}),
+		}
+
+		changes := Compare(base, candidate)
+		assert.Equal(t, []Change{
+			{
+				Type:      ChangeRemoved,
+				Direction: DirectionImport,
+				Before:    &base.Imports[1],
+			},
+			{
+				Type:      ChangeAdded,
+				Direction: DirectionExport,
+				After:     &candidate.Exports[3],
+			},
+		}, changes)
+		assert.True(t, IsCompatible(changes))
+	})
+
+	t.Run("BreakingChanges", func(t *testing.T) {
+		t.Parallel()
+
+		candidate := &Interface{
+			Imports: append(slices.Clone(base.Imports), Symbol{
+				Module:    "env",
+				Name:      "random",
+				Kind:      KindFunc,
+				Signature: "() -> (i32)",
+			}),
+			Exports: []Symbol{
+				{Name: "run", Kind: KindFunc, Signature: "(i32) -> ()"},
+				{Name: "memory", Kind: KindMemory, Signature: "min=1"},
+			},
+		}
+
+		changes := Compare(base, candidate)
+		assert.False(t, IsCompatible(changes))
+		assert.Len(t, changes, 3)
+
+		assert.Equal(t, ChangeAdded, changes[0].Type)
+		assert.Equal(t, "random", changes[0].After.Name)
+		assert.True(t, changes[0].Breaking)
+
+		assert.Equal(t, ChangeRemoved, changes[1].Type)
+		assert.Equal(t, "init", changes[1].Before.Name)
+		assert.True(t, changes[1].Breaking)
+
+		assert.Equal(t, ChangeChanged, changes[2].Type)
+		assert.Equal(t, "() -> ()", changes[2].Before.Signature)
+		assert.Equal(t, "(i32) -> ()", changes[2].After.Signature)
+		assert.True(t, changes[2].Breaking)
+	})
+
+	t.Run("DuplicateImports", func(t *testing.T) {
+		t.Parallel()
+
+		// The same name imported twice, as a function and as a global
+		log := Symbol{
+			Module:    "env",
+			Name:      "log",
+			Kind:      KindFunc,
+			Signature: "(i32) -> ()",
+		}
+		level := Symbol{
+			Module:    "env",
+			Name:      "log",
+			Kind:      KindGlobal,
+			Signature: "i32",
+		}
+		duplicated := &Interface{Imports: []Symbol{log, level}}
+
+		assert.Empty(t, Compare(duplicated, duplicated))
+
+		// Dropping either is noticed
+		changes := Compare(&Interface{Imports: []Symbol{log}}, duplicated)
+		if assert.Len(t, changes, 1) {
+			assert.Equal(t, ChangeAdded, changes[0].Type)
+			assert.Equal(t, KindGlobal, changes[0].After.Kind)
+			assert.True(t, changes[0].Breaking)
+		}
+
+		changes = Compare(duplicated, &Interface{Imports: []Symbol{level}})
+		if assert.Len(t, changes, 1) {
+			assert.Equal(t, ChangeRemoved, changes[0].Type)
+			assert.Equal(t, KindFunc, changes[0].Before.Kind)
+			assert.False(t, changes[0].Breaking)
+		}
+
+		// A changed duplicate is paired with its replacement
+		changed := log
+		changed.Signature = "(i64) -> ()"
+		changes = Compare(
+			duplicated,
+			&Interface{Imports: []Symbol{level, changed}},
+		)
+		if assert.Len(t, changes, 1) {
+			assert.Equal(t, ChangeChanged, changes[0].Type)
+			assert.Equal(t, log, *changes[0].Before)
+			assert.Equal(t, changed, *changes[0].After)
+		}
+	})
+
+	t.Run("NilCandidate", func(t *testing.T) {
+		t.Parallel()
+
+		changes := Compare(base, nil)
+		assert.Len(t, changes, 5)
+		assert.False(t, IsCompatible(changes))
+	})
+}
@@ -0,0 +1,29 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// Static errors to avoid err113 violations
+	ErrNotWasm            = errors.New("content is not a WebAssembly binary")
+	ErrUnsupportedVersion = errors.New("unsupported WebAssembly version")
+	ErrUnexpectedEOF      = errors.New("unexpected end of binary")
+	ErrMalformed          = errors.New("malformed binary")
+	ErrUnsupportedType    = errors.New("unsupported type encoding")
+)
+
+// ParseError describes where in a binary the parser gave up
+type ParseError struct {
+	Offset int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("wasm parse error at offset %d: %s", e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
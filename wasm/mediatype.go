@@ -0,0 +1,27 @@
+package wasm
+
+import "bytes"
+
+const (
+	MediaTypeModule    = "application/wasm"
+	MediaTypeComponent = "application/vnd.wasm.component"
+	// MediaTypeUnknown is used for content that is not a Wasm binary
+	MediaTypeUnknown = "application/octet-stream"
+)
+
+// DetectMediaType classifies content by its header only, without parsing any
+// sections
+func DetectMediaType(content []byte) string {
+	if len(content) < HeaderSize || !bytes.Equal(content[:4], magic) {
+		return MediaTypeUnknown
+	}
+
+	switch {
+	case content[4] == moduleVersion && content[6] == 0:
+		return MediaTypeModule
+	case content[4] == componentVersion && content[6] == componentLayer:
+		return MediaTypeComponent
+	default:
+		return MediaTypeUnknown
+	}
+}
@@ -0,0 +1,743 @@
+// Package wasm extracts the interface (imports and exports) of WebAssembly
+// core modules and components without instantiating them.
+package wasm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Layer distinguishes core modules from component-model components
+type Layer int
+
+const (
+	LayerModule Layer = iota
+	LayerComponent
+)
+
+func (l Layer) String() string {
+	if l == LayerComponent {
+		return "component"
+	}
+
+	return "module"
+}
+
+// Kind is the kind of an imported or exported item
+type Kind string
+
+const (
+	KindFunc      Kind = "func"
+	KindTable     Kind = "table"
+	KindMemory    Kind = "memory"
+	KindGlobal    Kind = "global"
+	KindTag       Kind = "tag"
+	KindModule    Kind = "module"
+	KindValue     Kind = "value"
+	KindType      Kind = "type"
+	KindComponent Kind = "component"
+	KindInstance  Kind = "instance"
+)
+
+// Symbol is a single import or export. Module is only set for core module
+// imports, which are two-level names. Signature is a human-readable rendering
+// of the item's type where the binary carries one: core functions, globals,
+// tables and memories, and the WIT types of component items.
+type Symbol struct {
+	Module    string
+	Name      string
+	Kind      Kind
+	Signature string
+}
+
+// Interface is everything the registry records about a binary's surface. For
+// components, Imports and Exports are the top-level items of the component,
+// i.e. the WIT world it targets.
+type Interface struct {
+	Layer   Layer
+	Imports []Symbol
+	Exports []Symbol
+}
+
+var magic = []byte{0x00, 0x61, 0x73, 0x6d}
+
+const (
+	// HeaderSize is the length of the header, which is all DetectMediaType
+	// looks at
+	HeaderSize = 8
+
+	moduleVersion    = 0x01
+	componentVersion = 0x0d
+	componentLayer   = 0x01
+)
+
+// Parse decodes the header and the import/export related sections of a core
+// module or component.
+func Parse(content []byte) (*Interface, error) {
+	return ParseReader(bytes.NewReader(content))
+}
+
+// ParseReader is Parse for a binary read from a stream. Only the sections
+// that describe the interface are held in memory, the others are skipped as
+// they are read. It stops reading at the end of the binary or at the first
+// error.
+func ParseReader(content io.Reader) (*Interface, error) {
+	header := make([]byte, HeaderSize)
+	_, err := io.ReadFull(content, header)
+	if err != nil || !bytes.Equal(header[:4], magic) {
+		return nil, &ParseError{Offset: 0, Err: ErrNotWasm}
+	}
+
+	version := header[4]
+	layer := header[6]
+	src := &sectionSource{r: bufio.NewReader(content), offset: HeaderSize}
+
+	switch {
+	case version == moduleVersion && layer == 0:
+		return parseModule(src)
+	case version == componentVersion && layer == componentLayer:
+		return parseComponent(src)
+	default:
+		return nil, &ParseError{Offset: 4, Err: ErrUnsupportedVersion}
+	}
+}
+
+// sectionSource reads the sections of a binary from a stream
+type sectionSource struct {
+	r *bufio.Reader
+	// Offset of the next byte in the whole binary
+	offset int
+}
+
+func (src *sectionSource) fail(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = ErrUnexpectedEOF
+	}
+
+	return &ParseError{Offset: src.offset, Err: err}
+}
+
+// u32 reads the unsigned LEB128 size of a section
+func (src *sectionSource) u32() (int, error) {
+	var result uint64
+	var shift uint
+	for {
+		b, err := src.r.ReadByte()
+		if err != nil {
+			return 0, src.fail(err)
+		}
+		src.offset++
+		result |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return int(result), nil
+		}
+		//nolint:mnd // LEB128 width of a u32
+		if shift >= 35 {
+			return 0, src.fail(fmt.Errorf("%w: integer too long", ErrMalformed))
+		}
+	}
+}
+
+// sections iterates over the sections of a module or component and hands a
+// reader over the payload of each wanted section to fn. The payloads of the
+// other sections are discarded without being buffered, fn gets a nil reader
+// for them.
+func sections(
+	src *sectionSource,
+	wanted func(id byte) bool,
+	fn func(id byte, s *reader) error,
+) error {
+	for {
+		id, err := src.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return src.fail(err)
+		}
+		src.offset++
+		size, err := src.u32()
+		if err != nil {
+			return err
+		}
+
+		var s *reader
+		if wanted(id) {
+			payload, err := io.ReadAll(io.LimitReader(src.r, int64(size)))
+			if err != nil {
+				return src.fail(err)
+			}
+			if len(payload) < size {
+				return src.fail(ErrUnexpectedEOF)
+			}
+			// Keep offsets relative to the whole binary for error reporting
+			s = &reader{data: payload, base: src.offset}
+		} else {
+			discarded, err := src.r.Discard(size)
+			if err != nil {
+				src.offset += discarded
+
+				return src.fail(err)
+			}
+		}
+		src.offset += size
+
+		if err := fn(id, s); err != nil {
+			return err
+		}
+	}
+}
+
+//nolint:mnd // Section ids are defined by the binary format
+const (
+	sectionType     = 1
+	sectionImport   = 2
+	sectionFunction = 3
+	sectionTable    = 4
+	sectionMemory   = 5
+	sectionGlobal   = 6
+	sectionExport   = 7
+	sectionTag      = 13
+
+	sectionNestedComponent   = 4
+	sectionComponentInstance = 5
+	sectionComponentAlias    = 6
+	sectionComponentType     = 7
+	sectionComponentCanon    = 8
+	sectionComponentImport   = 10
+	sectionComponentExport   = 11
+)
+
+// moduleIndex tracks the index spaces needed to give exports a signature
+type moduleIndex struct {
+	types    []string
+	funcs    []string
+	tables   []string
+	memories []string
+	globals  []string
+	tags     []string
+}
+
+// moduleSections are the sections of a module that parseModule decodes
+var moduleSections = map[byte]bool{
+	sectionType:     true,
+	sectionImport:   true,
+	sectionFunction: true,
+	sectionTable:    true,
+	sectionMemory:   true,
+	sectionGlobal:   true,
+	sectionExport:   true,
+	sectionTag:      true,
+}
+
+func parseModule(src *sectionSource) (*Interface, error) {
+	result := &Interface{Layer: LayerModule}
+	idx := &moduleIndex{}
+
+	type rawExport struct {
+		name  string
+		kind  byte
+		index int
+	}
+	var exports []rawExport
+
+	wanted := func(id byte) bool { return moduleSections[id] }
+	err := sections(src, wanted, func(id byte, s *reader) error {
+		switch id {
+		case sectionType:
+			return vec(s, func() error {
+				sig, err := s.funcType()
+				idx.types = append(idx.types, sig)
+
+				return err
+			})
+		case sectionImport:
+			return vec(s, func() error {
+				sym, err := s.coreImport(idx)
+				result.Imports = append(result.Imports, sym)
+
+				return err
+			})
+		case sectionFunction:
+			return vec(s, func() error {
+				typeIdx, err := s.u32()
+				if err != nil {
+					return err
+				}
+				idx.funcs = append(idx.funcs, idx.typeAt(typeIdx))
+
+				return nil
+			})
+		case sectionTable:
+			return vec(s, func() error {
+				sig, err := s.tableType()
+				idx.tables = append(idx.tables, sig)
+
+				return err
+			})
+		case sectionMemory:
+			return vec(s, func() error {
+				sig, err := s.limits()
+				idx.memories = append(idx.memories, sig)
+
+				return err
+			})
+		case sectionGlobal:
+			return vec(s, func() error {
+				sig, err := s.globalType()
+				if err != nil {
+					return err
+				}
+				idx.globals = append(idx.globals, sig)
+
+				return s.skipConstExpr()
+			})
+		case sectionTag:
+			return vec(s, func() error {
+				sig, err := s.tagType(idx)
+				idx.tags = append(idx.tags, sig)
+
+				return err
+			})
+		case sectionExport:
+			return vec(s, func() error {
+				name, err := s.name()
+				if err != nil {
+					return err
+				}
+				kind, err := s.byte()
+				if err != nil {
+					return err
+				}
+				index, err := s.u32()
+				exports = append(exports, rawExport{name, kind, index})
+
+				return err
+			})
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range exports {
+		kind, space := idx.space(e.kind)
+		sym := Symbol{Name: e.name, Kind: kind}
+		if e.index < len(space) {
+			sym.Signature = space[e.index]
+		}
+		result.Exports = append(result.Exports, sym)
+	}
+
+	return result, nil
+}
+
+func (idx *moduleIndex) typeAt(i int) string {
+	if i < len(idx.types) {
+		return idx.types[i]
+	}
+
+	return ""
+}
+
+//nolint:mnd // External kinds are defined by the binary format
+func (idx *moduleIndex) space(kind byte) (Kind, []string) {
+	switch kind {
+	case 0x00:
+		return KindFunc, idx.funcs
+	case 0x01:
+		return KindTable, idx.tables
+	case 0x02:
+		return KindMemory, idx.memories
+	case 0x03:
+		return KindGlobal, idx.globals
+	case 0x04:
+		return KindTag, idx.tags
+	default:
+		return Kind(fmt.Sprintf("unknown(0x%02x)", kind)), nil
+	}
+}
+
+func vec(r *reader, fn func() error) error {
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for range n {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//nolint:mnd // Import descriptors are defined by the binary format
+func (r *reader) coreImport(idx *moduleIndex) (Symbol, error) {
+	module, err := r.name()
+	if err != nil {
+		return Symbol{}, err
+	}
+	name, err := r.name()
+	if err != nil {
+		return Symbol{}, err
+	}
+	desc, err := r.byte()
+	if err != nil {
+		return Symbol{}, err
+	}
+
+	sym := Symbol{Module: module, Name: name}
+	switch desc {
+	case 0x00:
+		typeIdx, err := r.u32()
+		if err != nil {
+			return Symbol{}, err
+		}
+		sym.Kind, sym.Signature = KindFunc, idx.typeAt(typeIdx)
+		idx.funcs = append(idx.funcs, sym.Signature)
+	case 0x01:
+		sym.Kind = KindTable
+		sym.Signature, err = r.tableType()
+		idx.tables = append(idx.tables, sym.Signature)
+	case 0x02:
+		sym.Kind = KindMemory
+		sym.Signature, err = r.limits()
+		idx.memories = append(idx.memories, sym.Signature)
+	case 0x03:
+		sym.Kind = KindGlobal
+		sym.Signature, err = r.globalType()
+		idx.globals = append(idx.globals, sym.Signature)
+	case 0x04:
+		sym.Kind = KindTag
+		sym.Signature, err = r.tagType(idx)
+		idx.tags = append(idx.tags, sym.Signature)
+	default:
+		return Symbol{}, r.fail(
+			fmt.Errorf("%w: import descriptor 0x%02x", ErrMalformed, desc),
+		)
+	}
+
+	return sym, err
+}
+
+func (r *reader) funcType() (string, error) {
+	form, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	//nolint:mnd // 0x60 marks a function type
+	if form != 0x60 {
+		return "", r.fail(
+			fmt.Errorf("%w: type form 0x%02x", ErrUnsupportedType, form),
+		)
+	}
+
+	params, err := r.resultType()
+	if err != nil {
+		return "", err
+	}
+	results, err := r.resultType()
+	if err != nil {
+		return "", err
+	}
+
+	return "(" + params + ") -> (" + results + ")", nil
+}
+
+func (r *reader) resultType() (string, error) {
+	var types []string
+	err := vec(r, func() error {
+		t, err := r.valType()
+		types = append(types, t)
+
+		return err
+	})
+
+	return strings.Join(types, ", "), err
+}
+
+var numericTypes = map[byte]string{
+	0x7f: "i32",
+	0x7e: "i64",
+	0x7d: "f32",
+	0x7c: "f64",
+	0x7b: "v128",
+}
+
+var abstractHeapTypes = map[byte]string{
+	0x73: "nofunc",
+	0x72: "noextern",
+	0x71: "none",
+	0x70: "func",
+	0x6f: "extern",
+	0x6e: "any",
+	0x6d: "eq",
+	0x6c: "i31",
+	0x6b: "struct",
+	0x6a: "array",
+	0x69: "exn",
+}
+
+//nolint:mnd // Value type encodings are defined by the binary format
+func (r *reader) valType() (string, error) {
+	b, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	if t, ok := numericTypes[b]; ok {
+		return t, nil
+	}
+	if ht, ok := abstractHeapTypes[b]; ok {
+		return ht + "ref", nil
+	}
+
+	switch b {
+	case 0x64, 0x63:
+		ht, err := r.heapType()
+		if err != nil {
+			return "", err
+		}
+		if b == 0x63 {
+			return "(ref null " + ht + ")", nil
+		}
+
+		return "(ref " + ht + ")", nil
+	default:
+		return "", r.fail(
+			fmt.Errorf("%w: value type 0x%02x", ErrUnsupportedType, b),
+		)
+	}
+}
+
+func (r *reader) heapType() (string, error) {
+	start := r.pos
+	//nolint:mnd // Heap types are encoded as s33
+	v, err := r.sleb(33)
+	if err != nil {
+		return "", err
+	}
+	if v >= 0 {
+		return strconv.FormatInt(v, 10), nil
+	}
+	if ht, ok := abstractHeapTypes[r.data[start]]; ok {
+		return ht, nil
+	}
+
+	return "", r.fail(
+		fmt.Errorf("%w: heap type 0x%02x", ErrUnsupportedType, r.data[start]),
+	)
+}
+
+func (r *reader) tableType() (string, error) {
+	ref, err := r.valType()
+	if err != nil {
+		return "", err
+	}
+	lim, err := r.limits()
+	if err != nil {
+		return "", err
+	}
+
+	return ref + " " + lim, nil
+}
+
+func (r *reader) globalType() (string, error) {
+	t, err := r.valType()
+	if err != nil {
+		return "", err
+	}
+	mut, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	if mut == 1 {
+		return "mut " + t, nil
+	}
+
+	return t, nil
+}
+
+func (r *reader) tagType(idx *moduleIndex) (string, error) {
+	// Attribute byte, always 0x00 (exception)
+	if err := r.skip(1); err != nil {
+		return "", err
+	}
+	typeIdx, err := r.u32()
+
+	return idx.typeAt(typeIdx), err
+}
+
+// skipConstExpr skips over the initializer expression of a global
+//
+//nolint:mnd // Opcodes are defined by the binary format
+func (r *reader) skipConstExpr() error {
+	for {
+		op, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case 0x0b: // end
+			return nil
+		case 0x41: // i32.const
+			_, err = r.sleb(32)
+		case 0x42: // i64.const
+			_, err = r.sleb(64)
+		case 0x43: // f32.const
+			err = r.skip(4)
+		case 0x44: // f64.const
+			err = r.skip(8)
+		case 0x23, 0xd2: // global.get, ref.func
+			_, err = r.u32()
+		case 0xd0: // ref.null
+			_, err = r.heapType()
+		case 0x6a, 0x6b, 0x6c, 0x7c, 0x7d, 0x7e: // extended const arithmetic
+		case 0xfd: // v128.const
+			var sub int
+			sub, err = r.u32()
+			if err == nil && sub != 12 {
+				err = r.fail(fmt.Errorf("%w: opcode 0xfd %d", ErrUnsupportedType, sub))
+			}
+			if err == nil {
+				err = r.skip(16)
+			}
+		default:
+			return r.fail(
+				fmt.Errorf("%w: constant opcode 0x%02x", ErrUnsupportedType, op),
+			)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// componentSections are the sections of a component that parseComponent
+// decodes. Nested modules and components are skipped, they do not contribute
+// to the signatures of the component's own imports and exports.
+var componentSections = map[byte]bool{
+	sectionComponentInstance: true,
+	sectionComponentAlias:    true,
+	sectionComponentType:     true,
+	sectionComponentCanon:    true,
+	sectionComponentImport:   true,
+	sectionComponentExport:   true,
+}
+
+func parseComponent(src *sectionSource) (*Interface, error) {
+	result := &Interface{Layer: LayerComponent}
+	scope := newWITScope(nil)
+
+	wanted := func(id byte) bool { return componentSections[id] }
+	err := sections(src, wanted, func(id byte, s *reader) error {
+		switch id {
+		case sectionComponentImport:
+			return vec(s, func() error {
+				name, err := s.externName()
+				if err != nil {
+					return err
+				}
+				sort, kind, item, err := s.externItem(scope)
+				if err != nil {
+					return err
+				}
+				item = declare(scope, sort, name, item)
+				result.Imports = append(result.Imports, Symbol{
+					Name:      name,
+					Kind:      kind,
+					Signature: item.signature(),
+				})
+
+				return nil
+			})
+		case sectionComponentExport:
+			return vec(s, func() error {
+				name, err := s.externName()
+				if err != nil {
+					return err
+				}
+				idx, err := s.sortIdx()
+				if err != nil {
+					return err
+				}
+				item := scope.at(idx.sort, idx.index)
+				ascribed, ok, err := s.optionalExternItem(scope)
+				if err != nil {
+					return err
+				}
+				if ok {
+					item = ascribed
+				}
+				item = declare(scope, idx.sort, name, item)
+				result.Exports = append(result.Exports, Symbol{
+					Name:      name,
+					Kind:      idx.kind,
+					Signature: item.signature(),
+				})
+
+				return nil
+			})
+		case sectionComponentInstance,
+			sectionComponentAlias,
+			sectionComponentType,
+			sectionComponentCanon:
+			// Signatures are best effort, a definition that cannot be decoded
+			// only leaves the items that depend on it without one
+			if err := s.witDefinitions(id, scope); err != nil {
+				scope.incomplete = true
+			}
+
+			return nil
+		case sectionNestedComponent:
+			// Skipped, but it still takes an index
+			scope.add(sortComponent, witItem{})
+
+			return nil
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// externName reads an importname' or exportname', including the optional
+// version suffix
+func (r *reader) externName() (string, error) {
+	form, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	name, err := r.name()
+	if err != nil {
+		return "", err
+	}
+
+	switch form {
+	case 0x00:
+		return name, nil
+	case 0x01:
+		suffix, err := r.name()
+		if err != nil {
+			return "", err
+		}
+
+		return name + suffix, nil
+	default:
+		return "", r.fail(
+			fmt.Errorf("%w: extern name form 0x%02x", ErrMalformed, form),
+		)
+	}
+}
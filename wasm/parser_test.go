@@ -0,0 +1,246 @@
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeName(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+func encodeSection(id byte, payload ...[]byte) []byte {
+	var body []byte
+	for _, p := range payload {
+		body = append(body, p...)
+	}
+
+	return append([]byte{id, byte(len(body))}, body...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+
+	return out
+}
+
+// testModule imports a function and a memory from "env", defines one
+// function and one mutable global and exports both
+func testModule() []byte {
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		encodeSection(sectionType,
+			[]byte{0x02},
+			[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f},
+			[]byte{0x60, 0x00, 0x00},
+		),
+		encodeSection(sectionImport,
+			[]byte{0x02},
+			encodeName("env"), encodeName("log"), []byte{0x00, 0x01},
+			encodeName("env"), encodeName("memory"), []byte{0x02, 0x00, 0x01},
+		),
+		encodeSection(sectionFunction, []byte{0x01, 0x00}),
+		encodeSection(sectionGlobal, []byte{0x01, 0x7f, 0x01, 0x41, 0x2a, 0x0b}),
+		encodeSection(sectionExport,
+			[]byte{0x02},
+			encodeName("run"), []byte{0x00, 0x01},
+			encodeName("counter"), []byte{0x03, 0x00},
+		),
+		// custom section that must be skipped
+		encodeSection(0x00, encodeName("name"), []byte{0xff, 0xff}),
+	)
+}
+
+func testComponent() []byte {
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00},
+		encodeSection(sectionComponentImport,
+			[]byte{0x01, 0x00},
+			encodeName("wasi:cli/environment@0.2.0"),
+			[]byte{0x05, 0x00},
+		),
+		encodeSection(sectionComponentExport,
+			[]byte{0x01, 0x00},
+			encodeName("wasi:cli/run@0.2.0"),
+			[]byte{0x05, 0x01, 0x00},
+		),
+	)
+}
+
+// testTypedComponent imports an instance with a resource, an enum and a
+// function using both, lifts a function and exports it along with the enum
+// it aliases from the instance. It embeds a core module and a component,
+// which have to be skipped.
+func testTypedComponent() []byte {
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00},
+		encodeSection(0x01, []byte{0xde, 0xad, 0xbe, 0xef}),
+		encodeSection(0x04, []byte{0xde, 0xad, 0xbe, 0xef}),
+		encodeSection(sectionComponentType,
+			[]byte{0x02},
+			// instance type
+			[]byte{0x42, 0x08},
+			[]byte{0x04, 0x00}, encodeName("descriptor"), []byte{0x03, 0x01},
+			[]byte{0x01, 0x6d, 0x02}, encodeName("access"), encodeName("busy"),
+			[]byte{0x04, 0x00}, encodeName("error-code"), []byte{0x03, 0x00, 0x01},
+			[]byte{0x01, 0x68, 0x00},
+			[]byte{0x01, 0x70, 0x7d},
+			[]byte{0x01, 0x6a, 0x01, 0x04, 0x01, 0x02},
+			[]byte{0x01, 0x40, 0x02},
+			encodeName("self"), []byte{0x03},
+			encodeName("len"), []byte{0x77},
+			[]byte{0x00, 0x05},
+			[]byte{0x04, 0x00}, encodeName("read"), []byte{0x01, 0x06},
+			// exported function
+			[]byte{0x40, 0x01}, encodeName("name"), []byte{0x73, 0x00, 0x79},
+		),
+		encodeSection(sectionComponentImport,
+			[]byte{0x01, 0x00},
+			encodeName("wasi:filesystem/types@0.2.0"),
+			[]byte{0x05, 0x00},
+		),
+		encodeSection(sectionComponentAlias,
+			[]byte{0x01, 0x03, 0x00, 0x00},
+			encodeName("error-code"),
+		),
+		encodeSection(sectionComponentCanon,
+			[]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x01},
+		),
+		encodeSection(sectionComponentExport,
+			[]byte{0x02, 0x00},
+			encodeName("count"), []byte{0x01, 0x00, 0x00},
+			[]byte{0x00}, encodeName("error-code"), []byte{0x03, 0x02, 0x00},
+		),
+	)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("CoreModule", func(t *testing.T) {
+		t.Parallel()
+
+		iface, err := Parse(testModule())
+		require.NoError(t, err)
+
+		assert.Equal(t, LayerModule, iface.Layer)
+		assert.Equal(t, []Symbol{
+			{
+				Module:    "env",
+				Name:      "log",
+				Kind:      KindFunc,
+				Signature: "() -> ()",
+			},
+			{
+				Module:    "env",
+				Name:      "memory",
+				Kind:      KindMemory,
+				Signature: "min=1",
+			},
+		}, iface.Imports)
+		assert.Equal(t, []Symbol{
+			{Name: "run", Kind: KindFunc, Signature: "(i32, i32) -> (i32)"},
+			{Name: "counter", Kind: KindGlobal, Signature: "mut i32"},
+		}, iface.Exports)
+	})
+
+	t.Run("Component", func(t *testing.T) {
+		t.Parallel()
+
+		iface, err := Parse(testComponent())
+		require.NoError(t, err)
+
+		assert.Equal(t, LayerComponent, iface.Layer)
+		assert.Equal(t, []Symbol{
+			{Name: "wasi:cli/environment@0.2.0", Kind: KindInstance},
+		}, iface.Imports)
+		assert.Equal(t, []Symbol{
+			{Name: "wasi:cli/run@0.2.0", Kind: KindInstance},
+		}, iface.Exports)
+	})
+
+	t.Run("ComponentSignatures", func(t *testing.T) {
+		t.Parallel()
+
+		iface, err := Parse(testTypedComponent())
+		require.NoError(t, err)
+
+		assert.Equal(t, []Symbol{{
+			Name: "wasi:filesystem/types@0.2.0",
+			Kind: KindInstance,
+			Signature: "instance { resource descriptor; " +
+				"type error-code = enum { access, busy }; " +
+				"read: func(self: borrow<descriptor>, len: u64) -> " +
+				"result<list<u8>, error-code> }",
+		}}, iface.Imports)
+		assert.Equal(t, []Symbol{
+			{
+				Name:      "count",
+				Kind:      KindFunc,
+				Signature: "func(name: string) -> u32",
+			},
+			{
+				Name:      "error-code",
+				Kind:      KindType,
+				Signature: "enum { access, busy }",
+			},
+		}, iface.Exports)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		t.Parallel()
+
+		for _, content := range [][]byte{testModule(), testTypedComponent()} {
+			expected, err := Parse(content)
+			require.NoError(t, err)
+
+			iface, err := ParseReader(
+				iotest.OneByteReader(bytes.NewReader(content)),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, expected, iface)
+		}
+	})
+
+	t.Run("NotWasm", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Parse([]byte("definitely not a wasm binary"))
+		assert.True(t, errors.Is(err, ErrNotWasm))
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Parse([]byte{0x00, 0x61, 0x73, 0x6d, 0x02, 0x00, 0x00, 0x00})
+		assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		t.Parallel()
+
+		module := testModule()
+		_, err := Parse(module[:len(module)-10])
+
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.True(t, errors.Is(err, ErrUnexpectedEOF))
+	})
+}
+
+func TestDetectMediaType(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, MediaTypeModule, DetectMediaType(testModule()))
+	assert.Equal(t, MediaTypeComponent, DetectMediaType(testComponent()))
+	assert.Equal(t, MediaTypeUnknown, DetectMediaType([]byte("plain text")))
+	assert.Equal(t, MediaTypeUnknown, DetectMediaType(nil))
+}
@@ -0,0 +1,146 @@
+package wasm
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// reader is a cursor over a WebAssembly binary that decodes the primitive
+// encodings (LEB128 integers, names, vectors) shared by modules and components
+type reader struct {
+	data []byte
+	pos  int
+	// Offset of data in the whole binary
+	base int
+}
+
+func (r *reader) eof() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) fail(err error) error {
+	return &ParseError{Offset: r.base + r.pos, Err: err}
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.eof() {
+		return 0, r.fail(ErrUnexpectedEOF)
+	}
+	b := r.data[r.pos]
+	r.pos++
+
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, r.fail(ErrUnexpectedEOF)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return b, nil
+}
+
+func (r *reader) skip(n int) error {
+	_, err := r.bytes(n)
+
+	return err
+}
+
+// uleb reads an unsigned LEB128 integer of at most the given bit width
+func (r *reader) uleb(bits uint) (uint64, error) {
+	var result uint64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return result, nil
+		}
+		if shift >= bits {
+			return 0, r.fail(fmt.Errorf("%w: integer too long", ErrMalformed))
+		}
+	}
+}
+
+// sleb reads a signed LEB128 integer of at most the given bit width
+func (r *reader) sleb(bits uint) (int64, error) {
+	var result int64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				result |= -1 << shift
+			}
+
+			return result, nil
+		}
+		if shift >= bits {
+			return 0, r.fail(fmt.Errorf("%w: integer too long", ErrMalformed))
+		}
+	}
+}
+
+func (r *reader) u32() (int, error) {
+	//nolint:mnd // LEB128 width of a u32
+	v, err := r.uleb(35)
+
+	return int(v), err
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", r.fail(fmt.Errorf("%w: name is not valid UTF-8", ErrMalformed))
+	}
+
+	return string(b), nil
+}
+
+// limits reads a table or memory limits encoding and formats it
+func (r *reader) limits() (string, error) {
+	flags, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+	//nolint:mnd // memory64 limits are 64 bit wide
+	minimum, err := r.uleb(70)
+	if err != nil {
+		return "", err
+	}
+	result := fmt.Sprintf("min=%d", minimum)
+	if flags&0x01 != 0 {
+		//nolint:mnd // memory64 limits are 64 bit wide
+		maximum, err := r.uleb(70)
+		if err != nil {
+			return "", err
+		}
+		result += fmt.Sprintf(" max=%d", maximum)
+	}
+	if flags&0x02 != 0 {
+		result += " shared"
+	}
+	if flags&0x04 != 0 {
+		result += " i64"
+	}
+
+	return result, nil
+}